@@ -18,6 +18,8 @@ The commands are:
 
 	apply                 applies a batch spec to create or update a batch
 	                      change
+	cache                 manages the cache directory of batch spec executions
+	diff                  executes a batch spec and prints the resulting diffs
 	new                   creates a new batch spec YAML file
 	preview               creates a batch spec to be previewed or applied
 	remote                creates server side batch changes
@@ -26,7 +26,7 @@ Examples:
 
     $ src batch apply batch.spec.yaml
 
-`
+` + batchHooksUsage + batchOutputsUsage + batchFailedTasksUsage
 
 	flagSet := flag.NewFlagSet("apply", flag.ExitOnError)
 	flags := newBatchExecuteFlags(flagSet, batchDefaultCacheDir(), batchDefaultTempDirPrefix())
@@ -36,7 +36,7 @@ Examples:
 			return err
 		}
 
-		file, err := getBatchSpecFile(flagSet, &flags.file)
+		file, err := getBatchSpecFile(flagSet, flags.file)
 		if err != nil {
 			return err
 		}
@@ -46,7 +46,7 @@ Examples:
 
 		if err = executeBatchSpec(ctx, executeBatchSpecOpts{
 			flags:  flags,
-			client: cfg.apiClient(flags.api, flagSet.Output()),
+			client: flags.apiClient(flagSet.Output()),
 			file:   file,
 
 			applyBatchSpec: true,
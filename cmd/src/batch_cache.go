@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+)
+
+var batchCacheCommands commander
+
+func init() {
+	usage := `'src batch cache' manages the cache directory of batch spec executions,
+which holds the results of steps and the archives of repositories.
+
+Usage:
+
+	src batch cache command [command options]
+
+The commands are:
+
+	size     prints how much disk space the cache uses
+	prune    removes cache entries that weren't used for a while
+
+Use "src batch cache [command] -h" for more information about a command.
+`
+
+	flagSet := flag.NewFlagSet("cache", flag.ExitOnError)
+	handler := func(args []string) error {
+		batchCacheCommands.run(flagSet, "src batch cache", usage, args)
+		return nil
+	}
+
+	// Register the command.
+	batchCommands = append(batchCommands, &command{
+		flagSet: flagSet,
+		handler: handler,
+		usageFunc: func() {
+			fmt.Println(usage)
+		},
+	})
+}
+
+// batchCacheDirFlag registers the -cache flag, and its -cache-dir alias, of
+// the cache commands.
+func batchCacheDirFlag(flagSet *flag.FlagSet) *string {
+	dir := flagSet.String("cache", batchDefaultCacheDir(), "Directory for caching results and repository archives.")
+	flagSet.StringVar(dir, "cache-dir", batchDefaultCacheDir(), "Alias for -cache.")
+	return dir
+}
+
+// parseBatchCacheAge parses an age such as 30d or 12h. On top of the units
+// of time.ParseDuration, it accepts d for days.
+func parseBatchCacheAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil || n < 0 {
+			return 0, errors.Newf("invalid age %q", s)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, errors.Newf("invalid age %q", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
+	"github.com/sourcegraph/src-cli/internal/batches/repozip"
+	"github.com/sourcegraph/src-cli/internal/cmderrors"
+)
+
+func init() {
+	usage := `
+'src batch cache prune' removes the entries of the cache directory of batch
+spec executions that weren't used for a while: repository archives and cached
+results of steps. An entry is used when it's written, and whenever it's
+reused. Other files in the cache directory, such as the failed tasks of the
+last execution or the diffs recorded by 'src batch diff', are kept.
+
+Usage:
+
+    src batch cache prune -older-than AGE [-dry-run] [-cache DIR]
+
+Examples:
+
+  Remove the entries that weren't used in the last 30 days:
+
+    $ src batch cache prune -older-than 30d
+
+  List the entries that would be removed, without removing them:
+
+    $ src batch cache prune -older-than 12h -dry-run
+
+`
+
+	flagSet := flag.NewFlagSet("prune", flag.ExitOnError)
+	cacheDir := batchCacheDirFlag(flagSet)
+	var (
+		olderThanFlag = flagSet.String("older-than", "", "Remove the entries that weren't used for this long, such as 30d or 12h. Required.")
+		dryRunFlag    = flagSet.Bool("dry-run", false, "Print the entries that would be removed, without removing them.")
+	)
+
+	handler := func(args []string) error {
+		if err := flagSet.Parse(args); err != nil {
+			return err
+		}
+		if flagSet.NArg() != 0 {
+			return cmderrors.Usage("additional arguments not allowed")
+		}
+		if *olderThanFlag == "" {
+			return cmderrors.Usage("-older-than is required")
+		}
+		olderThan, err := parseBatchCacheAge(*olderThanFlag)
+		if err != nil {
+			return cmderrors.Usagef("-older-than: %s", err)
+		}
+
+		removed, err := pruneBatchCache(*cacheDir, time.Now().Add(-olderThan), *dryRunFlag)
+
+		var freed int64
+		for _, f := range removed {
+			freed += f.Size
+			if *dryRunFlag || *verbose {
+				fmt.Println(f.Path)
+			}
+		}
+		if *dryRunFlag {
+			fmt.Printf("Would remove %d files (%s) from %s.\n", len(removed), humanize.IBytes(uint64(freed)), *cacheDir)
+		} else {
+			fmt.Printf("Removed %d files (%s) from %s.\n", len(removed), humanize.IBytes(uint64(freed)), *cacheDir)
+		}
+		return err
+	}
+
+	batchCacheCommands = append(batchCacheCommands, &command{
+		flagSet: flagSet,
+		handler: handler,
+		usageFunc: func() {
+			fmt.Fprintf(flag.CommandLine.Output(), "Usage of 'src batch cache %s':\n", flagSet.Name())
+			flagSet.PrintDefaults()
+			fmt.Println(usage)
+		},
+	})
+}
+
+// pruneBatchCache removes the entries of the cache directory dir that were
+// last used before cutoff, and the directories of cached results left empty,
+// and returns the removed entries. With dryRun, nothing is removed, and the
+// entries that would be are returned. If removing an entry fails, the entries
+// removed so far are returned with the error.
+func pruneBatchCache(dir string, cutoff time.Time, dryRun bool) ([]repozip.CacheEntry, error) {
+	entries, err := repozip.ListCacheEntries(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []repozip.CacheEntry
+	for _, e := range entries {
+		if !e.LastUse.Before(cutoff) {
+			continue
+		}
+		if !dryRun {
+			if err := os.Remove(e.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return removed, errors.Wrapf(err, "removing %s from cache", e.Path)
+			}
+			if !e.Archive {
+				// Only succeeds once the last result in the directory is
+				// removed.
+				_ = os.Remove(filepath.Dir(e.Path))
+			}
+		}
+		removed = append(removed, e)
+	}
+	return removed, nil
+}
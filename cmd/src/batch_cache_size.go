@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/sourcegraph/src-cli/internal/batches/repozip"
+	"github.com/sourcegraph/src-cli/internal/cmderrors"
+)
+
+func init() {
+	usage := `
+'src batch cache size' prints how much disk space the cache directory of batch
+spec executions uses, in total and for each of its entries: the repository
+archives, and the cached results of steps in each repository. Other files in
+the cache directory, such as the failed tasks of the last execution, aren't
+counted.
+
+Usage:
+
+    src batch cache size [-cache DIR]
+
+Examples:
+
+    $ src batch cache size
+
+    $ src batch cache size -cache ~/batch-cache
+
+`
+
+	flagSet := flag.NewFlagSet("size", flag.ExitOnError)
+	cacheDir := batchCacheDirFlag(flagSet)
+
+	handler := func(args []string) error {
+		if err := flagSet.Parse(args); err != nil {
+			return err
+		}
+		if flagSet.NArg() != 0 {
+			return cmderrors.Usage("additional arguments not allowed")
+		}
+
+		files, err := repozip.ListCacheEntries(*cacheDir)
+		if err != nil {
+			return err
+		}
+
+		// Group the files by the top-level entry of the cache they belong to,
+		// such as the results of a batch spec, or the repository archives.
+		var total int64
+		sizes := map[string]int64{}
+		for _, f := range files {
+			total += f.Size
+			entry := f.Path
+			if rel, err := filepath.Rel(*cacheDir, f.Path); err == nil {
+				entry, _, _ = strings.Cut(filepath.ToSlash(rel), "/")
+			}
+			sizes[entry] += f.Size
+		}
+		entries := make([]string, 0, len(sizes))
+		for entry := range sizes {
+			entries = append(entries, entry)
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			if sizes[entries[i]] != sizes[entries[j]] {
+				return sizes[entries[i]] > sizes[entries[j]]
+			}
+			return entries[i] < entries[j]
+		})
+
+		for _, entry := range entries {
+			fmt.Printf("%10s  %s\n", humanize.IBytes(uint64(sizes[entry])), entry)
+		}
+		fmt.Printf("%10s  total in %d files in %s\n", humanize.IBytes(uint64(total)), len(files), *cacheDir)
+		return nil
+	}
+
+	batchCacheCommands = append(batchCacheCommands, &command{
+		flagSet: flagSet,
+		handler: handler,
+		usageFunc: func() {
+			fmt.Fprintf(flag.CommandLine.Output(), "Usage of 'src batch cache %s':\n", flagSet.Name())
+			flagSet.PrintDefaults()
+			fmt.Println(usage)
+		},
+	})
+}
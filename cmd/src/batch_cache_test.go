@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPruneBatchCache(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	writeFile := func(name string, lastUse time.Time) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("cached"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, lastUse, lastUse); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	const (
+		repoA = "github.com-sourcegraph-a-0123456789abcdef0123456789abcdef01234567"
+		repoB = "github.com-sourcegraph-b-0123456789abcdef0123456789abcdef01234567"
+		repoC = "github.com-sourcegraph-c-0123456789abcdef0123456789abcdef01234567"
+	)
+	stale := writeFile(repoA+"/step-0.json", now.Add(-40*24*time.Hour))
+	staleArchive := writeFile(repoA+".zip", now.Add(-31*24*time.Hour))
+	fresh := writeFile(repoB+"/step-0.json", now.Add(-time.Hour))
+	mixedStale := writeFile(repoC+"/step-0.json", now.Add(-60*24*time.Hour))
+	mixedFresh := writeFile(repoC+"/step-1.json", now)
+	// State isn't a cache entry, however old it is.
+	failedTasks := writeFile("failed-tasks.json", now.Add(-90*24*time.Hour))
+	diffs := writeFile("diffs/0123456789abcdef.json", now.Add(-90*24*time.Hour))
+
+	cutoff := now.Add(-30 * 24 * time.Hour)
+
+	removed, err := pruneBatchCache(dir, cutoff, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 3 {
+		t.Fatalf("dry run: want 3 files to be removed, got %d: %v", len(removed), removed)
+	}
+	for _, path := range []string{stale, staleArchive, fresh, mixedStale, mixedFresh} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("dry run removed %s: %s", path, err)
+		}
+	}
+
+	removed, err = pruneBatchCache(dir, cutoff, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 3 {
+		t.Fatalf("want 3 files to be removed, got %d: %v", len(removed), removed)
+	}
+	for _, path := range []string{stale, staleArchive, mixedStale} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s was not removed", path)
+		}
+	}
+	for _, path := range []string{fresh, mixedFresh, failedTasks, diffs} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("%s was removed: %s", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, repoA)); !os.IsNotExist(err) {
+		t.Errorf("empty directory %s was not removed", repoA)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Errorf("cache directory was removed: %s", err)
+	}
+}
+
+func TestPruneBatchCache_MissingDir(t *testing.T) {
+	removed, err := pruneBatchCache(filepath.Join(t.TempDir(), "missing"), time.Now(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 0 {
+		t.Fatalf("want nothing removed, got %v", removed)
+	}
+}
+
+func TestParseBatchCacheAge(t *testing.T) {
+	for input, want := range map[string]time.Duration{
+		"30d":  30 * 24 * time.Hour,
+		"1.5d": 36 * time.Hour,
+		"12h":  12 * time.Hour,
+		"90m":  90 * time.Minute,
+	} {
+		got, err := parseBatchCacheAge(input)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", input, err)
+		} else if got != want {
+			t.Errorf("%s: want %s, got %s", input, want, got)
+		}
+	}
+
+	for _, input := range []string{"", "d", "-1d", "30", "soon"} {
+		if _, err := parseBatchCacheAge(input); err == nil {
+			t.Errorf("%s: want error", input)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"path"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strings"
 	"syscall"
 	"time"
@@ -89,14 +90,35 @@ type batchExecuteFlags struct {
 	apply         bool
 	cacheDir      string
 	tempDir       string
-	file          string
+	cleanTmp      bool
+	file          *string
+	input         *batchSpecInputFlags
 	keepLogs      bool
 	parallelism   int
 	timeout       time.Duration
+	runTimeout    time.Duration
 	workspace     string
 	cleanArchives bool
+	cacheMaxSize  int64
 	skipErrors    bool
 	runAsRoot     bool
+	platform      batchPlatformFlag
+	stepOverrides string
+	noBinary      bool
+	gitAuthor     bool
+
+	warnNondeterministic bool
+
+	beforeRun string
+	afterRun  string
+
+	dumpOutputs string
+
+	failedTasks string
+	retryFailed string
+
+	circuitBreakerFailures int
+	repoBatchSize          int
 
 	// EXPERIMENTAL
 	textOnly bool
@@ -131,12 +153,16 @@ func newBatchExecuteFlags(flagSet *flag.FlagSet, cacheDir, tempDir string) *batc
 		&caf.tempDir, "tmp", tempDir,
 		"Directory for storing temporary data, such as log files. Default is /tmp. Can also be set with environment variable SRC_BATCH_TMP_DIR; if both are set, this flag will be used and not the environment variable.",
 	)
+	flagSet.StringVar(&caf.tempDir, "tmp-dir", tempDir, "Alias for -tmp.")
 
-	flagSet.StringVar(
-		&caf.file, "f", "",
-		"The batch spec file to read, or - to read from standard input.",
+	flagSet.BoolVar(
+		&caf.cleanTmp, "clean-tmp", false,
+		"If true, removes the temporary directories that executions which were interrupted left in the -tmp directory, instead of only warning about them.",
 	)
 
+	caf.input = newBatchSpecInputFlags(flagSet)
+	caf.file = caf.input.fileFlag(flagSet, "The batch spec file to read, or - to read from standard input.")
+
 	flagSet.IntVar(
 		&caf.parallelism, "j", 0,
 		"The maximum number of parallel jobs. Default (or 0) is the number of CPU cores available to Docker.",
@@ -147,11 +173,21 @@ func newBatchExecuteFlags(flagSet *flag.FlagSet, cacheDir, tempDir string) *batc
 		"The maximum duration a single batch spec step can take.",
 	)
 
+	flagSet.DurationVar(
+		&caf.runTimeout, "run-timeout", 0,
+		"The maximum duration of executing all steps. Once it's exceeded, running tasks are canceled and no more are started. Combine with -skip-errors to continue with the completed tasks. Default (or 0) is no limit.",
+	)
+
 	flagSet.BoolVar(
 		&caf.cleanArchives, "clean-archives", true,
 		"If true, deletes downloaded repository archives after executing batch spec steps. Note that only the archives related to the actual repositories matched by the batch spec will be cleaned up, and clean up will not occur if src exits unexpectedly.",
 	)
 
+	flagSet.Int64Var(
+		&caf.cacheMaxSize, "archive-cache-max-size", 0,
+		"The maximum size in bytes of the repository archives in the cache directory. If set, the least recently used archives are evicted before and after execution to stay below it. Cached results of steps aren't evicted. Default (or 0) is unbounded.",
+	)
+
 	flagSet.StringVar(
 		&caf.workspace, "workspace", "auto",
 		`Workspace mode to use ("auto", "bind", or "volume")`,
@@ -164,11 +200,216 @@ func newBatchExecuteFlags(flagSet *flag.FlagSet, cacheDir, tempDir string) *batc
 		"If true, forces all step containers to run as root.",
 	)
 
+	flagSet.Var(
+		&caf.platform, "platform",
+		"The platform to pull and run step container images for, such as linux/amd64. Default is the platform of the Docker host. "+
+			"IMAGE=PLATFORM, such as alpine:3=linux/arm64, sets the platform of the steps using that image instead. Can be given more than once.",
+	)
+
+	flagSet.StringVar(
+		&caf.stepOverrides, "step-overrides", "",
+		"A YAML or JSON file mapping repository names to the step numbers that must not be run in that repository.",
+	)
+
+	flagSet.BoolVar(
+		&caf.noBinary, "no-binary", false,
+		"If true, fails the execution in repositories where the steps changed binary files, rather than only warning about them.",
+	)
+
+	flagSet.BoolVar(
+		&caf.gitAuthor, "commit-author-from-git", false,
+		"If true, uses user.name and user.email from the local git config as the commit author when the changeset template doesn't specify one.",
+	)
+	flagSet.BoolVar(&caf.gitAuthor, "author-from-git", false, "Alias for -commit-author-from-git.")
+
+	flagSet.BoolVar(
+		&caf.warnNondeterministic, "warn-nondeterministic", false,
+		"If true, executes the steps twice in the first repository before the actual execution, and warns if the diffs differ. "+
+			"This is a heuristic to catch steps that embed dates or random values, which prevent caching.",
+	)
+
+	flagSet.StringVar(
+		&caf.beforeRun, "before-run", "",
+		"A shell command that is run once locally before the tasks are executed. If it fails, the execution is aborted. See 'Hooks' in the usage.",
+	)
+
+	flagSet.StringVar(
+		&caf.afterRun, "after-run", "",
+		"A shell command that is run once locally after the tasks have been executed, whether they succeeded or not. If it fails, a warning is printed. See 'Hooks' in the usage.",
+	)
+
+	flagSet.StringVar(
+		&caf.dumpOutputs, "dump-outputs", "",
+		"A file to write the outputs of the steps in every workspace to, as JSON, so that they can be used by other tools or a later batch spec. See 'Outputs' in the usage.",
+	)
+
+	flagSet.StringVar(
+		&caf.failedTasks, "failed-tasks", "",
+		"The file to write the tasks that failed to, as JSON. Default is failed-tasks.json in the -cache directory. See 'Retrying failed tasks' in the usage.",
+	)
+
+	flagSet.IntVar(
+		&caf.circuitBreakerFailures, "circuit-breaker-failures", 0,
+		"If set, the number of consecutive requests to Sourcegraph that may fail within a minute before further requests fail immediately for 30 seconds, so that an execution against an unavailable instance doesn't wait for every request to time out. Default (or 0) disables this.",
+	)
+
+	flagSet.IntVar(
+		&caf.repoBatchSize, "repo-batch-size", 0,
+		repoBatchSizeUsage,
+	)
+
 	return caf
 }
 
+// addRetryFailedFlag adds -retry-failed to flagSet. Only the commands that
+// don't apply the batch spec have it: applying the changeset specs of only the
+// retried workspaces would close the changesets of all other workspaces.
+func (caf *batchExecuteFlags) addRetryFailedFlag(flagSet *flag.FlagSet) {
+	flagSet.StringVar(
+		&caf.retryFailed, "retry-failed", "",
+		"A file written by a previous execution with the tasks that failed. If set, only these tasks are executed. See 'Retrying failed tasks' in the usage.",
+	)
+}
+
+// repoBatchSizeUsage is the usage of the -repo-batch-size flag, which all
+// commands that resolve the workspaces of a batch spec locally have.
+const repoBatchSizeUsage = "The maximum number of entries of the batch spec's 'on' list whose repositories are resolved in a single request. " +
+	"Resolving them in several requests avoids timeouts when a batch spec matches tens of thousands of repositories. Default (or 0) resolves all of them in one request."
+
+// apiClient returns the API client used to execute batch specs. If
+// -circuit-breaker-failures is set, it has a circuit breaker.
+func (flags *batchExecuteFlags) apiClient(out io.Writer) api.Client {
+	opts := cfg.apiClientOpts(flags.api, out)
+	if flags.circuitBreakerFailures > 0 {
+		opts.CircuitBreaker = &api.CircuitBreakerOpts{
+			Failures: flags.circuitBreakerFailures,
+			Window:   time.Minute,
+			Cooldown: 30 * time.Second,
+		}
+	}
+	return api.NewClient(opts)
+}
+
 var errAdditionalArguments = cmderrors.Usage("additional arguments not allowed")
 
+// batchSpecInputFlags control how a batch spec is read and parsed, and are
+// shared by all commands that read a batch spec.
+type batchSpecInputFlags struct {
+	dir                   string
+	changesetTemplateFile string
+
+	// file is the batch spec file given with -f, and overlays are the files
+	// given with further -f flags, which are merged into it.
+	file     string
+	overlays []string
+}
+
+func newBatchSpecInputFlags(flagSet *flag.FlagSet) *batchSpecInputFlags {
+	bif := &batchSpecInputFlags{}
+
+	flagSet.StringVar(
+		&bif.dir, "dir", "",
+		"The directory that relative mount paths are resolved against when the batch spec is read from standard input. Required if the batch spec mounts relative paths.",
+	)
+	flagSet.StringVar(
+		&bif.changesetTemplateFile, "changeset-template-file", "",
+		"A YAML or JSON file containing a changesetTemplate that is merged into the batch spec. Fields defined in the batch spec take precedence.",
+	)
+
+	return bif
+}
+
+// fileFlag adds the -f flag, which can be given more than once, to flagSet and
+// returns the batch spec file it is set to.
+func (bif *batchSpecInputFlags) fileFlag(flagSet *flag.FlagSet, usage string) *string {
+	flagSet.Var(
+		(*batchSpecFileFlag)(bif), "f",
+		usage+" If given more than once, the later files are overlays merged into the first one: their mappings are merged, with their values taking precedence, "+
+			"the items of their on, steps, importChangesets and workspaces lists are appended, and their other lists replace the existing ones.",
+	)
+	return &bif.file
+}
+
+type batchSpecFileFlag batchSpecInputFlags
+
+func (f *batchSpecFileFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return strings.Join(append([]string{f.file}, f.overlays...), ", ")
+}
+
+func (f *batchSpecFileFlag) Set(value string) error {
+	if f.file == "" {
+		f.file = value
+		return nil
+	}
+	if value == "-" {
+		return errors.New("only the first batch spec file can be read from standard input")
+	}
+	f.overlays = append(f.overlays, value)
+	return nil
+}
+
+// batchPlatformFlag holds the platforms given with -platform: the platform of
+// all step images, and the platforms of individual images, which take
+// precedence. Platforms are given by image rather than by step, so that they
+// stay the same when steps are added, reordered or skipped for a repository.
+type batchPlatformFlag struct {
+	all    string
+	images map[string]string
+}
+
+func (f *batchPlatformFlag) String() string {
+	var parts []string
+	for image, platform := range f.images {
+		parts = append(parts, image+"="+platform)
+	}
+	sort.Strings(parts)
+	if f.all != "" {
+		parts = append([]string{f.all}, parts...)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (f *batchPlatformFlag) Set(value string) error {
+	image, platform, ok := strings.Cut(value, "=")
+	if !ok {
+		if value == "" {
+			return errors.New("platform must not be empty")
+		}
+		f.all = value
+		return nil
+	}
+	if image == "" || platform == "" {
+		return errors.Newf("invalid platform %q, expected PLATFORM or IMAGE=PLATFORM such as alpine:3=linux/amd64", value)
+	}
+	if f.images == nil {
+		f.images = make(map[string]string)
+	}
+	f.images[image] = platform
+	return nil
+}
+
+// validate checks that every image given a platform is used by a step.
+func (f *batchPlatformFlag) validate(steps []batcheslib.Step) error {
+	used := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		used[step.Container] = true
+	}
+	var images []string
+	for image := range f.images {
+		if !used[image] {
+			images = append(images, image)
+		}
+	}
+	sort.Strings(images)
+	if len(images) > 0 {
+		return errors.Newf("-platform: no step of the batch spec uses the image %s", strings.Join(images, ", "))
+	}
+	return nil
+}
+
 func getBatchSpecFile(flagSet *flag.FlagSet, fileFlag *string) (string, error) {
 	if fileFlag == nil || *fileFlag != "" {
 		if flagSet.NArg() != 0 {
@@ -253,6 +494,10 @@ type executeBatchSpecOpts struct {
 	applyBatchSpec bool
 	file           string
 
+	// diff, if set, stops after execution and writes the resulting diffs
+	// instead of uploading anything to Sourcegraph.
+	diff *batchDiffOpts
+
 	client api.Client
 }
 
@@ -288,7 +533,8 @@ func executeBatchSpec(ctx context.Context, opts executeBatchSpecOpts) (err error
 	}()
 
 	svc := service.New(&service.Opts{
-		Client: opts.client,
+		Client:        opts.client,
+		RepoBatchSize: opts.flags.repoBatchSize,
 	})
 
 	lr, ffs, err := svc.DetermineLicenseAndFeatureFlags(ctx, opts.flags.skipErrors)
@@ -301,7 +547,16 @@ func executeBatchSpec(ctx context.Context, opts executeBatchSpecOpts) (err error
 		execUI = &ui.JSONLines{BinaryDiffs: true}
 	}
 
-	imageCache := docker.NewImageCache()
+	platform := opts.flags.platform
+	imageCache := docker.NewPlatformImageCache(platform.all)
+	if platform.all != "" && !docker.PlatformMatches(platform.all, "linux/"+runtime.GOARCH) {
+		cliLog.Printf("WARNING: requested platform %s differs from the host architecture %s; steps may run slowly under emulation", platform.all, runtime.GOARCH)
+	}
+	for image, p := range platform.images {
+		if !docker.PlatformMatches(p, "linux/"+runtime.GOARCH) {
+			cliLog.Printf("WARNING: requested platform %s for image %s differs from the host architecture %s; its steps may run slowly under emulation", p, image, runtime.GOARCH)
+		}
+	}
 
 	if err := validateSourcegraphVersionConstraint(ffs); err != nil {
 		if !opts.flags.skipErrors {
@@ -349,9 +604,48 @@ func executeBatchSpec(ctx context.Context, opts executeBatchSpecOpts) (err error
 		}
 	}
 
+	// Executions that were interrupted leave their temporary directories
+	// behind, which slowly fill the disk of long-lived CI agents.
+	orphans, err := findOrphanedBatchRunDirs(opts.flags.tempDir)
+	if err != nil {
+		return err
+	}
+	for _, dir := range orphans {
+		if opts.flags.cleanTmp {
+			if err := os.RemoveAll(dir); err != nil {
+				return errors.Wrap(err, "-clean-tmp")
+			}
+			cliLog.Printf("Removed temporary directory of an interrupted execution: %s", dir)
+		} else {
+			cliLog.Printf("WARNING: found temporary directory of an interrupted execution, run with -clean-tmp to remove it: %s", dir)
+		}
+	}
+
+	runDir, err := createBatchRunDir(opts.flags.tempDir)
+	if err != nil {
+		return err
+	}
+	// The logs of failed tasks are kept even without -keep-logs, so the run
+	// directory they're in is kept if there are any.
+	keepRunDir := opts.flags.keepLogs
+	defer func() {
+		if err := removeBatchRunDir(runDir, keepRunDir); err != nil {
+			cliLog.Printf("WARNING: removing temporary directory: %s", err)
+		}
+	}()
+	opts.flags.tempDir = runDir
+
+	// Make room in the cache before doing anything expensive, so that we fail
+	// now rather than when the disk is full halfway through the execution.
+	if opts.flags.cacheMaxSize > 0 {
+		if _, err := repozip.PrepareCache(opts.flags.cacheDir, opts.flags.cacheMaxSize); err != nil {
+			return errors.Wrap(err, "-archive-cache-max-size")
+		}
+	}
+
 	// Parse flags and build up our service and executor options.
 	execUI.ParsingBatchSpec()
-	batchSpec, batchSpecDir, rawSpec, err := parseBatchSpec(ctx, opts.file, svc)
+	batchSpec, batchSpecDir, rawSpec, err := parseBatchSpec(ctx, opts.file, opts.flags.input, svc)
 	if err != nil {
 		var multiErr errors.MultiError
 		if errors.As(err, &multiErr) {
@@ -363,8 +657,32 @@ func executeBatchSpec(ctx context.Context, opts executeBatchSpecOpts) (err error
 			return err
 		}
 	}
+	// Using features the instance doesn't support fails with a clear message
+	// here, instead of an opaque error from the instance later on.
+	if err := ffs.CheckBatchSpec(batchSpec); err != nil {
+		if !opts.flags.skipErrors {
+			execUI.ParsingBatchSpecFailure(err)
+			return cmderrors.ExitCode(2, nil)
+		}
+		cliLog.Printf("WARNING: %s", err)
+	}
 	execUI.ParsingBatchSpecSuccess()
 
+	var stepOverrides service.StepOverrides
+	if opts.flags.stepOverrides != "" {
+		stepOverrides, err = service.ReadStepOverrides(opts.flags.stepOverrides)
+		if err != nil {
+			return err
+		}
+		if err := stepOverrides.Validate(len(batchSpec.Steps)); err != nil {
+			return err
+		}
+	}
+
+	if err := platform.validate(batchSpec.Steps); err != nil {
+		return cmderrors.Usage(err.Error())
+	}
+
 	execUI.ResolvingNamespace()
 	namespace, err := svc.ResolveNamespace(ctx, opts.flags.namespace)
 	if err != nil {
@@ -376,16 +694,31 @@ func executeBatchSpec(ctx context.Context, opts executeBatchSpecOpts) (err error
 
 	if len(batchSpec.Steps) > 0 {
 		execUI.PreparingContainerImages()
+		// Images with their own platform are pulled separately, so that they
+		// aren't pulled for the default platform as well.
+		var defaultPlatformSteps []batcheslib.Step
+		for _, step := range batchSpec.Steps {
+			if _, ok := platform.images[step.Container]; !ok {
+				defaultPlatformSteps = append(defaultPlatformSteps, step)
+			}
+		}
 		images, err := svc.EnsureDockerImages(
 			ctx,
 			imageCache,
-			batchSpec.Steps,
+			defaultPlatformSteps,
 			parallelism,
 			execUI.PreparingContainerImagesProgress,
 		)
 		if err != nil {
 			return err
 		}
+		for image, p := range platform.images {
+			img, err := imageCache.EnsurePlatform(ctx, image, p)
+			if err != nil {
+				return err
+			}
+			images[image] = img
+		}
 		execUI.PreparingContainerImagesSuccess()
 
 		execUI.DeterminingWorkspaceCreatorType()
@@ -415,31 +748,96 @@ func executeBatchSpec(ctx context.Context, opts executeBatchSpecOpts) (err error
 		execUI.DeterminingWorkspacesSuccess(len(workspaces), len(repos), nil, nil)
 	}
 
+	if opts.diff != nil {
+		workspaces = opts.diff.filterWorkspaces(workspaces)
+	}
+	if opts.flags.retryFailed != "" {
+		previous, err := readBatchFailedTasks(opts.flags.retryFailed)
+		if err != nil {
+			return errors.Wrap(err, "-retry-failed")
+		}
+		if len(previous.Tasks) == 0 {
+			return errors.Newf("-retry-failed: no failed tasks in %s", opts.flags.retryFailed)
+		}
+		workspaces = previous.filterWorkspaces(workspaces)
+		if len(workspaces) < len(previous.Tasks) {
+			cliLog.Printf("WARNING: %d of the failed tasks in %s don't match a workspace of the batch spec anymore", len(previous.Tasks)-len(workspaces), opts.flags.retryFailed)
+		}
+	}
+
+	var fallbackAuthor *batcheslib.ChangesetSpecAuthor
+	if opts.flags.gitAuthor {
+		fallbackAuthor, err = service.GitConfigAuthor()
+		if err != nil {
+			return errors.Wrap(err, "-commit-author-from-git")
+		}
+	}
+
+	srcIgnore, err := executor.ReadSrcIgnore(batchSpecDir)
+	if err != nil {
+		return err
+	}
+	excludedFiles := map[string][]string{}
+	var excludedRepos []string
+	filesExcluded := func(task *executor.Task, paths []string) {
+		name := task.Repository.Name
+		if task.Path != "" {
+			name += "/" + task.Path
+		}
+		if _, ok := excludedFiles[name]; !ok {
+			excludedRepos = append(excludedRepos, name)
+		}
+		excludedFiles[name] = append(excludedFiles[name], paths...)
+	}
+
+	var (
+		outputs     batchOutputs
+		taskOutputs func(*executor.Task, map[string]interface{})
+	)
+	if opts.flags.dumpOutputs != "" {
+		taskOutputs = outputs.add
+	}
+	var failedTasks batchFailedTasks
+
+	var cacheKeyChecked func(*executor.CacheKeyInfo)
+	if *verbose {
+		cacheKeyChecked = execUI.CacheKeyChecked
+	}
+
 	archiveRegistry := repozip.NewArchiveRegistry(opts.client, opts.flags.cacheDir, opts.flags.cleanArchives)
 	logManager := log.NewDiskManager(opts.flags.tempDir, opts.flags.keepLogs)
+	execOpts := executor.NewExecutorOpts{
+		Logger:              logManager,
+		RepoArchiveRegistry: archiveRegistry,
+		Creator:             workspaceCreator,
+		EnsureImage:         imageCache.EnsurePlatform,
+		Parallelism:         parallelism,
+		WorkingDirectory:    batchSpecDir,
+		Timeout:             opts.flags.timeout,
+		TempDir:             opts.flags.tempDir,
+		GlobalEnv:           os.Environ(),
+		ForceRoot:           opts.flags.runAsRoot,
+		Platform:            platform.all,
+		FailOnBinaryFiles:   opts.flags.noBinary,
+		BinaryDiffs:         ffs.BinaryDiffs,
+	}
 	coord := executor.NewCoordinator(
 		executor.NewCoordinatorOpts{
-			ExecOpts: executor.NewExecutorOpts{
-				Logger:              logManager,
-				RepoArchiveRegistry: archiveRegistry,
-				Creator:             workspaceCreator,
-				EnsureImage:         imageCache.Ensure,
-				Parallelism:         parallelism,
-				WorkingDirectory:    batchSpecDir,
-				Timeout:             opts.flags.timeout,
-				TempDir:             opts.flags.tempDir,
-				GlobalEnv:           os.Environ(),
-				ForceRoot:           opts.flags.runAsRoot,
-				BinaryDiffs:         ffs.BinaryDiffs,
-			},
-			Logger:      logManager,
-			Cache:       executor.NewDiskCache(opts.flags.cacheDir),
-			BinaryDiffs: ffs.BinaryDiffs,
-			GlobalEnv:   os.Environ(),
+			ExecOpts:        execOpts,
+			Logger:          logManager,
+			Cache:           executor.NewDiskCache(opts.flags.cacheDir),
+			BinaryDiffs:     ffs.BinaryDiffs,
+			GlobalEnv:       os.Environ(),
+			FallbackAuthor:  fallbackAuthor,
+			SrcIgnore:       srcIgnore,
+			FilesExcluded:   filesExcluded,
+			CacheKeyChecked: cacheKeyChecked,
+			TaskOutputs:     taskOutputs,
+			TaskFailed:      failedTasks.add,
+			RunTimeout:      opts.flags.runTimeout,
 		},
 	)
 
-	execUI.CheckingCache()
 	tasks := svc.BuildTasks(
 		&template.BatchChangeAttributes{
 			Name:        batchSpec.Name,
@@ -447,7 +845,24 @@ func executeBatchSpec(ctx context.Context, opts executeBatchSpecOpts) (err error
 		},
 		batchSpec.Steps,
 		workspaces,
+		stepOverrides,
 	)
+	for _, task := range tasks {
+		task.Platform = platform.all
+		task.ImagePlatforms = platform.images
+	}
+
+	if opts.flags.warnNondeterministic && len(tasks) > 0 && len(batchSpec.Steps) > 0 {
+		// Only the first task is checked, since this executes its steps twice.
+		execUI.CheckingDeterminism(tasks[0].Repository.Name)
+		report, err := executor.CheckDeterminism(ctx, execOpts, tasks[0])
+		if err != nil {
+			return errors.Wrap(err, "checking steps for non-determinism")
+		}
+		execUI.CheckingDeterminismSuccess(report)
+	}
+
+	execUI.CheckingCache()
 	var (
 		specs         []*batcheslib.ChangesetSpec
 		uncachedTasks []*executor.Task
@@ -466,16 +881,73 @@ func executeBatchSpec(ctx context.Context, opts executeBatchSpecOpts) (err error
 	}
 	execUI.CheckingCacheSuccess(len(specs), len(uncachedTasks))
 
+	hookSummary := batchRunSummary{
+		specName:   batchSpec.Name,
+		workspaces: len(workspaces),
+		tasks:      len(uncachedTasks),
+	}
+	if opts.flags.beforeRun != "" {
+		if err := runBatchHook(ctx, "-before-run", opts.flags.beforeRun, hookSummary); err != nil {
+			return err
+		}
+	}
+
 	taskExecUI := execUI.ExecutingTasks(*verbose, parallelism)
 	freshSpecs, logFiles, execErr := coord.ExecuteAndBuildSpecs(ctx, batchSpec, uncachedTasks, taskExecUI)
-	// Add external changeset specs.
-	importedSpecs, importErr := svc.CreateImportChangesetSpecs(ctx, batchSpec)
+	if len(logFiles) > 0 {
+		keepRunDir = true
+	}
+	// Add external changeset specs. They have no diffs, so there's nothing to
+	// import when we only want to look at the changes.
+	var (
+		importedSpecs []*batcheslib.ChangesetSpec
+		importErr     error
+	)
+	if opts.diff == nil {
+		importedSpecs, importErr = svc.CreateImportChangesetSpecs(ctx, batchSpec)
+	}
 	if execErr != nil {
 		err = errors.Append(err, execErr)
+		var timeoutErr *executor.RunTimeoutError
+		if errors.As(execErr, &timeoutErr) {
+			printRunTimeout(timeoutErr)
+		}
 	}
 	if importErr != nil {
 		err = errors.Append(err, importErr)
 	}
+	if opts.flags.cacheMaxSize > 0 {
+		if _, evictErr := repozip.EvictLRU(opts.flags.cacheDir, opts.flags.cacheMaxSize); evictErr != nil {
+			err = errors.Append(err, evictErr)
+		}
+	}
+	if opts.flags.dumpOutputs != "" {
+		if dumpErr := outputs.write(opts.flags.dumpOutputs); dumpErr != nil {
+			err = errors.Append(err, dumpErr)
+		}
+	}
+	failedTasksFile := opts.flags.failedTasks
+	if failedTasksFile == "" {
+		failedTasksFile = filepath.Join(opts.flags.cacheDir, "failed-tasks.json")
+	}
+	if writeErr := failedTasks.write(failedTasksFile); writeErr != nil {
+		err = errors.Append(err, writeErr)
+	} else if len(failedTasks.Tasks) > 0 {
+		if opts.applyBatchSpec {
+			cliLog.Printf("%d task(s) failed. To only retry them, run 'src batch preview' with -retry-failed %s, and then apply the batch spec again", len(failedTasks.Tasks), failedTasksFile)
+		} else {
+			cliLog.Printf("%d task(s) failed. To only retry them, run again with -retry-failed %s", len(failedTasks.Tasks), failedTasksFile)
+		}
+	}
+	if opts.flags.afterRun != "" {
+		hookSummary.afterRun = true
+		hookSummary.failed = err != nil
+		hookSummary.changesetSpecs = append(append(append([]*batcheslib.ChangesetSpec{}, specs...), freshSpecs...), importedSpecs...)
+		if hookErr := runBatchHook(ctx, "-after-run", opts.flags.afterRun, hookSummary); hookErr != nil {
+			// The hook doesn't change the result of the execution.
+			cliLog.Printf("WARNING: %s", hookErr)
+		}
+	}
 	if err != nil && !opts.flags.skipErrors {
 		return err
 	}
@@ -492,13 +964,25 @@ func executeBatchSpec(ctx context.Context, opts executeBatchSpecOpts) (err error
 		}
 	}
 
-	if len(logFiles) > 0 && opts.flags.keepLogs {
+	if len(logFiles) > 0 {
 		execUI.LogFilesKept(logFiles)
 	}
 
+	for _, name := range excludedRepos {
+		execUI.FilesExcluded(name, excludedFiles[name])
+	}
+
 	specs = append(specs, freshSpecs...)
 	specs = append(specs, importedSpecs...)
 
+	if err := warnAboutBinaryFiles(execUI, specs, repos); err != nil {
+		return err
+	}
+
+	if opts.diff != nil {
+		return opts.diff.write(batchSpec.Name, specs, repos)
+	}
+
 	err = svc.ValidateChangesetSpecs(repos, specs)
 	if err != nil {
 		return err
@@ -563,6 +1047,36 @@ func executeBatchSpec(ctx context.Context, opts executeBatchSpecOpts) (err error
 	return nil
 }
 
+// warnAboutBinaryFiles reports the binary files changed by each changeset
+// spec, since those produce diffs that can't be reviewed.
+func warnAboutBinaryFiles(execUI ui.ExecUI, specs []*batcheslib.ChangesetSpec, repos []*graphql.Repository) error {
+	names := make(map[string]string, len(repos))
+	for _, r := range repos {
+		names[r.ID] = r.Name
+	}
+
+	for _, spec := range specs {
+		var paths []string
+		for _, c := range spec.Commits {
+			p, err := executor.BinaryFilesInDiff(c.Diff)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, p...)
+		}
+		if len(paths) == 0 {
+			continue
+		}
+
+		name, ok := names[spec.BaseRepository]
+		if !ok {
+			name = spec.BaseRepository
+		}
+		execUI.BinaryFilesChanged(name, paths)
+	}
+	return nil
+}
+
 func setReadDeadlineOnCancel(ctx context.Context, f *os.File) {
 	go func() {
 		// When user cancels, we set the read deadline to now() so the runtime
@@ -573,8 +1087,10 @@ func setReadDeadlineOnCancel(ctx context.Context, f *os.File) {
 }
 
 // parseBatchSpec parses and validates the given batch spec. If the spec has
-// validation errors, they are returned.
-func parseBatchSpec(ctx context.Context, file string, svc *service.Service) (*batcheslib.BatchSpec, string, string, error) {
+// validation errors, they are returned. If input specifies a changeset template
+// file, it is merged into the batch spec before validation, and the returned
+// raw spec includes it.
+func parseBatchSpec(ctx context.Context, file string, input *batchSpecInputFlags, svc *service.Service) (*batcheslib.BatchSpec, string, string, error) {
 	f, err := batchOpenFileFlag(file)
 	if err != nil {
 		return nil, "", "", err
@@ -592,21 +1108,71 @@ func parseBatchSpec(ctx context.Context, file string, svc *service.Service) (*ba
 		return nil, "", "", errors.Wrap(err, "reading batch spec")
 	}
 
-	dir, err := getBatchSpecDirectory(file)
+	specDir, err := getBatchSpecDirectory(file, input.dir)
 	if err != nil {
 		return nil, "", "", errors.Wrap(err, "batch spec path")
 	}
 
-	spec, err := svc.ParseBatchSpec(dir, data)
-	return spec, dir, string(data), err
+	data, err = service.ResolveIncludes(specDir, data)
+	if err != nil {
+		return nil, "", "", errors.Wrap(err, "resolving includes")
+	}
+
+	for _, overlayFile := range input.overlays {
+		overlay, err := os.ReadFile(overlayFile)
+		if err != nil {
+			return nil, "", "", errors.Wrap(err, "reading batch spec overlay")
+		}
+		overlay, err = service.ResolveIncludes(filepath.Dir(overlayFile), overlay)
+		if err != nil {
+			return nil, "", "", errors.Wrapf(err, "resolving includes of batch spec overlay %s", overlayFile)
+		}
+		data, err = service.MergeBatchSpecs(data, overlay)
+		if err != nil {
+			return nil, "", "", errors.Wrapf(err, "merging batch spec overlay %s", overlayFile)
+		}
+	}
+
+	if input.changesetTemplateFile != "" {
+		tmpl, err := os.ReadFile(input.changesetTemplateFile)
+		if err != nil {
+			return nil, "", "", errors.Wrap(err, "reading changeset template")
+		}
+		data, err = service.MergeChangesetTemplate(data, tmpl)
+		if err != nil {
+			return nil, "", "", errors.Wrap(err, "merging changeset template")
+		}
+	}
+
+	// When reading from standard input without an explicit -dir, we don't know
+	// where the batch spec lives, so relative mount paths can't be resolved.
+	mountDir := specDir
+	if isStdinBatchSpec(file) && input.dir == "" {
+		mountDir = ""
+	}
+
+	spec, err := svc.ParseBatchSpec(mountDir, data)
+	if errors.Is(err, service.ErrRelativeMountWithoutDir) {
+		err = errors.Wrap(err, "batch spec read from standard input; use -dir to set the directory relative mount paths are resolved against")
+	}
+	return spec, specDir, string(data), err
+}
+
+func isStdinBatchSpec(file string) bool {
+	return file == "" || file == "-"
 }
 
-func getBatchSpecDirectory(file string) (string, error) {
+func getBatchSpecDirectory(file, dir string) (string, error) {
 	var workingDirectory string
 	var err error
-	// if the batch spec is being provided via standard input, set the working directory to the current directory
-	if file == "" || file == "-" {
-		workingDirectory, err = os.Getwd()
+	// if the batch spec is being provided via standard input, use the given
+	// directory or default to the current directory
+	if isStdinBatchSpec(file) {
+		if dir != "" {
+			workingDirectory, err = filepath.Abs(dir)
+		} else {
+			workingDirectory, err = os.Getwd()
+		}
 		if err != nil {
 			return "", errors.Wrap(err, "batch spec path")
 		}
@@ -666,3 +1232,23 @@ func validateSourcegraphVersionConstraint(ffs *batches.FeatureFlags) error {
 	}
 	return errors.Newf("\n\n * Warning:\n This version of src-cli requires Sourcegraph version 4.0 or newer. If you're not on Sourcegraph 4.0 or newer, please use the 3.x release of src-cli that corresponds to your Sourcegraph version.\n\n")
 }
+
+// printRunTimeout logs which tasks completed, were canceled or never started
+// when -run-timeout was exceeded. The completed tasks are only listed with -v.
+func printRunTimeout(err *executor.RunTimeoutError) {
+	cliLog.Printf("WARNING: %s", err)
+	list := func(what string, tasks []*executor.Task) {
+		for _, task := range tasks {
+			name := task.Repository.Name
+			if task.Path != "" {
+				name += "/" + task.Path
+			}
+			cliLog.Printf("  %s: %s", what, name)
+		}
+	}
+	if *verbose {
+		list("completed", err.Completed)
+	}
+	list("canceled", err.Canceled)
+	list("not started", err.NotStarted)
+}
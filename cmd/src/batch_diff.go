@@ -0,0 +1,336 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
+	"github.com/sourcegraph/src-cli/internal/batches/graphql"
+	"github.com/sourcegraph/src-cli/internal/batches/service"
+	"github.com/sourcegraph/src-cli/internal/cmderrors"
+)
+
+func init() {
+	usage := `
+'src batch diff' executes the steps in a batch spec and prints the resulting
+diff for each repository. Nothing is uploaded to Sourcegraph.
+
+Usage:
+
+    src batch diff [command options] [-f FILE]
+    src batch diff [command options] FILE
+
+Examples:
+
+    $ src batch diff batch.spec.yaml
+
+    $ src batch diff -repo 'github.com/sourcegraph/.*' batch.spec.yaml
+
+    $ src batch diff -o changes.patch batch.spec.yaml
+
+    $ src batch diff -only-changed batch.spec.yaml
+
+Iterating on a batch spec:
+
+  Every run records the diff of each repository in the cache directory. With
+  -only-changed, only the diffs that differ from the ones of the previous run
+  of the batch spec are printed, and the repositories whose diffs changed are
+  listed. Since the results of steps are cached, changing the last step of a
+  batch spec only re-runs that step, from the cached result of the one before.
+
+` + batchHooksUsage + batchOutputsUsage + batchFailedTasksUsage
+
+	flagSet := flag.NewFlagSet("diff", flag.ExitOnError)
+	flags := newBatchExecuteFlags(flagSet, batchDefaultCacheDir(), batchDefaultTempDirPrefix())
+	flags.addRetryFailedFlag(flagSet)
+
+	var (
+		repoFlag   = flagSet.String("repo", "", "Only execute the batch spec in repositories whose name matches this regular expression.")
+		outputFlag = flagSet.String("o", "", "Write the combined patch to this file instead of standard output.")
+		onlyFlag   = flagSet.Bool("only-changed", false, "Only print the diffs that changed since the previous run of the batch spec, and list the repositories they belong to.")
+	)
+
+	handler := func(args []string) error {
+		if err := flagSet.Parse(args); err != nil {
+			return err
+		}
+
+		file, err := getBatchSpecFile(flagSet, flags.file)
+		if err != nil {
+			return err
+		}
+
+		diffOpts := &batchDiffOpts{
+			out:         os.Stdout,
+			onlyChanged: *onlyFlag,
+			stateDir:    filepath.Join(flags.cacheDir, "diffs"),
+			report:      os.Stderr,
+		}
+		if *repoFlag != "" {
+			diffOpts.repo, err = regexp.Compile(*repoFlag)
+			if err != nil {
+				return cmderrors.Usage(fmt.Sprintf("invalid -repo pattern: %s", err))
+			}
+		}
+		if *outputFlag != "" {
+			f, err := os.Create(*outputFlag)
+			if err != nil {
+				return errors.Wrap(err, "creating output file")
+			}
+			defer f.Close()
+			diffOpts.out = f
+		}
+
+		ctx, cancel := contextCancelOnInterrupt(context.Background())
+		defer cancel()
+
+		if err = executeBatchSpec(ctx, executeBatchSpecOpts{
+			flags:  flags,
+			client: flags.apiClient(flagSet.Output()),
+			file:   file,
+			diff:   diffOpts,
+		}); err != nil {
+			return cmderrors.ExitCode(1, nil)
+		}
+
+		return nil
+	}
+
+	batchCommands = append(batchCommands, &command{
+		flagSet: flagSet,
+		handler: handler,
+		usageFunc: func() {
+			fmt.Fprintf(flag.CommandLine.Output(), "Usage of 'src batch %s':\n", flagSet.Name())
+			flagSet.PrintDefaults()
+			fmt.Println(usage)
+		},
+	})
+}
+
+// batchDiffOpts configures the diff-only mode of executeBatchSpec.
+type batchDiffOpts struct {
+	// repo, if set, limits execution to repositories with a matching name.
+	repo *regexp.Regexp
+	out  io.Writer
+
+	// onlyChanged limits the diffs that are written to the ones that differ
+	// from the previous run, which are listed on report.
+	onlyChanged bool
+	report      io.Writer
+	// stateDir is where the diffs of the previous run of every batch spec
+	// are recorded.
+	stateDir string
+}
+
+func (o *batchDiffOpts) filterWorkspaces(workspaces []service.RepoWorkspace) []service.RepoWorkspace {
+	if o.repo == nil {
+		return workspaces
+	}
+
+	var filtered []service.RepoWorkspace
+	for _, ws := range workspaces {
+		if o.repo.MatchString(ws.Repo.Name) {
+			filtered = append(filtered, ws)
+		}
+	}
+	return filtered
+}
+
+// write prints the diff of every changeset spec, preceded by a header naming
+// the repository and branch. The headers are written as comments, so the
+// output as a whole can still be applied with git apply.
+func (o *batchDiffOpts) write(specName string, specs []*batcheslib.ChangesetSpec, repos []*graphql.Repository) error {
+	names := make(map[string]string, len(repos))
+	for _, r := range repos {
+		names[r.ID] = r.Name
+	}
+
+	var diffs []batchRepoDiff
+	for _, spec := range specs {
+		var diff bytes.Buffer
+		for _, c := range spec.Commits {
+			diff.Write(c.Diff)
+		}
+		if diff.Len() == 0 {
+			continue
+		}
+		name, ok := names[spec.BaseRepository]
+		if !ok {
+			name = spec.BaseRepository
+		}
+		diffs = append(diffs, batchRepoDiff{repo: name, branch: spec.HeadRef, diff: diff.Bytes()})
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].repo != diffs[j].repo {
+			return diffs[i].repo < diffs[j].repo
+		}
+		return diffs[i].branch < diffs[j].branch
+	})
+
+	if o.stateDir != "" {
+		statePath := filepath.Join(o.stateDir, batchDiffStateFile(specName))
+		previous, err := readBatchDiffState(statePath)
+		if err != nil {
+			return err
+		}
+		current := newBatchDiffState(diffs)
+		if o.onlyChanged {
+			if previous == nil {
+				fmt.Fprintf(o.report, "No previous run of batch spec %q to compare to, printing all diffs.\n", specName)
+			} else {
+				diffs = previous.compare(diffs, o.inScope, o.report)
+			}
+		}
+		// Repositories excluded with -repo weren't executed, so their
+		// recorded diffs are kept for the next run to compare to.
+		current.keep(previous, func(key string) bool { return !o.inScope(key) })
+		if err := current.write(statePath); err != nil {
+			return err
+		}
+	}
+
+	for _, d := range diffs {
+		if _, err := fmt.Fprintf(o.out, "# Repository: %s\n# Branch: %s\n", d.repo, d.branch); err != nil {
+			return errors.Wrap(err, "writing diff")
+		}
+		if _, err := o.out.Write(d.diff); err != nil {
+			return errors.Wrap(err, "writing diff")
+		}
+		if !bytes.HasSuffix(d.diff, []byte("\n")) {
+			fmt.Fprintln(o.out)
+		}
+	}
+	return nil
+}
+
+// inScope reports whether the repository of the batchRepoDiff key is executed
+// by this run.
+func (o *batchDiffOpts) inScope(key string) bool {
+	if o.repo == nil {
+		return true
+	}
+	repo, _, _ := strings.Cut(key, "@")
+	return o.repo.MatchString(repo)
+}
+
+type batchRepoDiff struct {
+	repo, branch string
+	diff         []byte
+}
+
+func (d batchRepoDiff) key() string {
+	return d.repo + "@" + d.branch
+}
+
+// batchDiffState records the diffs of a run of 'src batch diff', as hashes by
+// repository and branch.
+type batchDiffState struct {
+	Diffs map[string]string `json:"diffs"`
+}
+
+// batchDiffStateFile returns the name of the file the diffs of the batch spec
+// with the given name are recorded in.
+func batchDiffStateFile(specName string) string {
+	sum := sha256.Sum256([]byte(specName))
+	return hex.EncodeToString(sum[:8]) + ".json"
+}
+
+func newBatchDiffState(diffs []batchRepoDiff) *batchDiffState {
+	state := &batchDiffState{Diffs: make(map[string]string, len(diffs))}
+	for _, d := range diffs {
+		sum := sha256.Sum256(d.diff)
+		state.Diffs[d.key()] = hex.EncodeToString(sum[:])
+	}
+	return state
+}
+
+// readBatchDiffState returns the state recorded at path, or nil if there is
+// none.
+func readBatchDiffState(path string) (*batchDiffState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "reading diffs of previous run")
+	}
+	var state batchDiffState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, errors.Wrap(err, "parsing diffs of previous run")
+	}
+	return &state, nil
+}
+
+// keep copies the diffs recorded in previous whose keys match into s.
+func (s *batchDiffState) keep(previous *batchDiffState, match func(key string) bool) {
+	if previous == nil {
+		return
+	}
+	for key, sum := range previous.Diffs {
+		if match(key) {
+			s.Diffs[key] = sum
+		}
+	}
+}
+
+func (s *batchDiffState) write(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrap(err, "recording diffs")
+	}
+	return errors.Wrap(os.WriteFile(path, data, 0644), "recording diffs")
+}
+
+// compare returns the diffs that differ from the ones recorded in s, and lists
+// the repositories whose diffs changed, are new or disappeared on report.
+// Recorded diffs whose keys aren't inScope don't count as disappeared.
+func (s *batchDiffState) compare(diffs []batchRepoDiff, inScope func(key string) bool, report io.Writer) []batchRepoDiff {
+	current := newBatchDiffState(diffs)
+
+	var changed []batchRepoDiff
+	var lines []string
+	for _, d := range diffs {
+		previous, ok := s.Diffs[d.key()]
+		switch {
+		case !ok:
+			lines = append(lines, fmt.Sprintf("  new:       %s (%s)", d.repo, d.branch))
+		case previous != current.Diffs[d.key()]:
+			lines = append(lines, fmt.Sprintf("  changed:   %s (%s)", d.repo, d.branch))
+		default:
+			continue
+		}
+		changed = append(changed, d)
+	}
+	var removed []string
+	for key := range s.Diffs {
+		if _, ok := current.Diffs[key]; !ok && inScope(key) {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(removed)
+	for _, key := range removed {
+		lines = append(lines, fmt.Sprintf("  no diff:   %s", key))
+	}
+
+	total := len(diffs) + len(removed)
+	fmt.Fprintf(report, "%d of %d repositories changed since the previous run.\n", len(lines), total)
+	for _, line := range lines {
+		fmt.Fprintln(report, line)
+	}
+	return changed
+}
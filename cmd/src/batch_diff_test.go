@@ -0,0 +1,123 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
+
+	"github.com/sourcegraph/src-cli/internal/batches/graphql"
+)
+
+func TestBatchDiffOnlyChanged(t *testing.T) {
+	repos := []*graphql.Repository{
+		{ID: "repo-a", Name: "github.com/sourcegraph/a"},
+		{ID: "repo-b", Name: "github.com/sourcegraph/b"},
+		{ID: "repo-c", Name: "github.com/sourcegraph/c"},
+	}
+	spec := func(repo, diff string) *batcheslib.ChangesetSpec {
+		return &batcheslib.ChangesetSpec{
+			BaseRepository: repo,
+			HeadRef:        "refs/heads/my-change",
+			Commits:        []batcheslib.GitCommitDescription{{Diff: []byte(diff)}},
+		}
+	}
+
+	stateDir := t.TempDir()
+	run := func(specs ...*batcheslib.ChangesetSpec) (out, report string) {
+		var outBuf, reportBuf strings.Builder
+		opts := &batchDiffOpts{
+			out:         &outBuf,
+			onlyChanged: true,
+			report:      &reportBuf,
+			stateDir:    stateDir,
+		}
+		if err := opts.write("my-spec", specs, repos); err != nil {
+			t.Fatal(err)
+		}
+		return outBuf.String(), reportBuf.String()
+	}
+
+	out, report := run(spec("repo-a", "diff a\n"), spec("repo-b", "diff b\n"))
+	if !strings.Contains(out, "diff a") || !strings.Contains(out, "diff b") {
+		t.Errorf("expected all diffs without a previous run, got:\n%s", out)
+	}
+	if want := "No previous run of batch spec \"my-spec\" to compare to, printing all diffs.\n"; report != want {
+		t.Errorf("wrong report\ngot:  %q\nwant: %q", report, want)
+	}
+
+	out, report = run(spec("repo-a", "diff a\n"), spec("repo-c", "diff c\n"))
+	if want := "# Repository: github.com/sourcegraph/c\n# Branch: refs/heads/my-change\ndiff c\n"; out != want {
+		t.Errorf("wrong diffs\ngot:\n%s\nwant:\n%s", out, want)
+	}
+	wantReport := `2 of 3 repositories changed since the previous run.
+  new:       github.com/sourcegraph/c (refs/heads/my-change)
+  no diff:   github.com/sourcegraph/b@refs/heads/my-change
+`
+	if report != wantReport {
+		t.Errorf("wrong report\ngot:\n%s\nwant:\n%s", report, wantReport)
+	}
+
+	out, report = run(spec("repo-a", "diff a, changed\n"), spec("repo-c", "diff c\n"))
+	if !strings.Contains(out, "diff a, changed") || strings.Contains(out, "diff c") {
+		t.Errorf("expected only the changed diff, got:\n%s", out)
+	}
+	if !strings.Contains(report, "  changed:   github.com/sourcegraph/a (refs/heads/my-change)") {
+		t.Errorf("expected the changed repository in the report, got:\n%s", report)
+	}
+}
+
+func TestBatchDiffOnlyChanged_RepoFilter(t *testing.T) {
+	repos := []*graphql.Repository{
+		{ID: "repo-a", Name: "github.com/sourcegraph/a"},
+		{ID: "repo-b", Name: "github.com/sourcegraph/b"},
+	}
+	spec := func(repo, diff string) *batcheslib.ChangesetSpec {
+		return &batcheslib.ChangesetSpec{
+			BaseRepository: repo,
+			HeadRef:        "refs/heads/my-change",
+			Commits:        []batcheslib.GitCommitDescription{{Diff: []byte(diff)}},
+		}
+	}
+
+	stateDir := t.TempDir()
+	run := func(repo *regexp.Regexp, specs ...*batcheslib.ChangesetSpec) (out, report string) {
+		var outBuf, reportBuf strings.Builder
+		opts := &batchDiffOpts{
+			out:         &outBuf,
+			onlyChanged: true,
+			report:      &reportBuf,
+			stateDir:    stateDir,
+			repo:        repo,
+		}
+		if err := opts.write("my-spec", specs, repos); err != nil {
+			t.Fatal(err)
+		}
+		return outBuf.String(), reportBuf.String()
+	}
+
+	run(nil, spec("repo-a", "diff a\n"), spec("repo-b", "diff b\n"))
+
+	// A filtered run only compares, and records, the repositories it
+	// executed in.
+	out, report := run(regexp.MustCompile(`/a$`), spec("repo-a", "diff a, changed\n"))
+	if !strings.Contains(out, "diff a, changed") {
+		t.Errorf("expected the changed diff, got:\n%s", out)
+	}
+	wantReport := `1 of 1 repositories changed since the previous run.
+  changed:   github.com/sourcegraph/a (refs/heads/my-change)
+`
+	if report != wantReport {
+		t.Errorf("wrong report of filtered run\ngot:\n%s\nwant:\n%s", report, wantReport)
+	}
+
+	// The next unfiltered run still knows the diff of the other repository.
+	out, report = run(nil, spec("repo-a", "diff a, changed\n"), spec("repo-b", "diff b\n"))
+	if out != "" {
+		t.Errorf("expected no diffs, got:\n%s", out)
+	}
+	if want := "0 of 2 repositories changed since the previous run.\n"; report != want {
+		t.Errorf("wrong report of unfiltered run\ngot:  %q\nwant: %q", report, want)
+	}
+}
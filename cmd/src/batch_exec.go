@@ -203,7 +203,7 @@ func executeBatchSpecInWorkspaces(ctx context.Context, flags *executorModeFlags)
 	opts := &executor.RunStepsOpts{
 		Logger:      &log.NoopTaskLogger{},
 		WC:          workspace.NewExecutorWorkspaceCreator(tempDir, repoDir),
-		EnsureImage: imageCache.Ensure,
+		EnsureImage: imageCache.EnsurePlatform,
 		Task:        task,
 		// TODO: Should be slightly less than the executor timeout. Can we somehow read that?
 		Timeout:          flags.timeout,
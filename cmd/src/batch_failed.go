@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
+	"github.com/sourcegraph/src-cli/internal/batches/executor"
+	"github.com/sourcegraph/src-cli/internal/batches/service"
+)
+
+// batchFailedTasksVersion is the version of the format of failed task files. It
+// is increased when the format changes in a way that isn't backwards
+// compatible.
+const batchFailedTasksVersion = 1
+
+// batchFailedTasks is the content of a failed task file, which lists the tasks
+// that failed in an execution so that -retry-failed can run only them again.
+type batchFailedTasks struct {
+	Version int               `json:"version"`
+	Tasks   []batchFailedTask `json:"tasks"`
+}
+
+// batchFailedTask identifies a task that failed, by the repository, branch and
+// path of its workspace.
+type batchFailedTask struct {
+	RepositoryID string                 `json:"repositoryID"`
+	Repository   string                 `json:"repository"`
+	Branch       string                 `json:"branch"`
+	Path         string                 `json:"path"`
+	Category     executor.ErrorCategory `json:"category"`
+	Error        string                 `json:"error"`
+}
+
+func (t batchFailedTask) key() [3]string {
+	return [3]string{t.RepositoryID, t.Branch, t.Path}
+}
+
+// add records that task failed with err. It's used as the TaskFailed callback
+// of the coordinator.
+func (f *batchFailedTasks) add(task *executor.Task, err error) {
+	f.Tasks = append(f.Tasks, batchFailedTask{
+		RepositoryID: task.Repository.ID,
+		Repository:   task.Repository.Name,
+		Branch:       task.Repository.BaseRef(),
+		Path:         task.Path,
+		Category:     executor.CategoryOf(err),
+		Error:        err.Error(),
+	})
+}
+
+// write writes the failed tasks to path as JSON, sorted by repository, branch
+// and path so that the file doesn't depend on the order the tasks failed in.
+func (f *batchFailedTasks) write(path string) error {
+	f.Version = batchFailedTasksVersion
+	if f.Tasks == nil {
+		f.Tasks = []batchFailedTask{}
+	}
+	sort.Slice(f.Tasks, func(i, j int) bool {
+		a, b := f.Tasks[i], f.Tasks[j]
+		if a.Repository != b.Repository {
+			return a.Repository < b.Repository
+		}
+		if a.Branch != b.Branch {
+			return a.Branch < b.Branch
+		}
+		return a.Path < b.Path
+	})
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshalling failed tasks")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return errors.Wrap(err, "writing failed tasks")
+	}
+	return errors.Wrap(os.WriteFile(path, append(data, '\n'), 0644), "writing failed tasks")
+}
+
+// readBatchFailedTasks reads the failed task file at path.
+func readBatchFailedTasks(path string) (*batchFailedTasks, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading failed tasks")
+	}
+	var f batchFailedTasks
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, errors.Wrapf(err, "parsing failed tasks in %s", path)
+	}
+	if f.Version != batchFailedTasksVersion {
+		return nil, errors.Newf("unsupported version %d of failed tasks in %s", f.Version, path)
+	}
+	return &f, nil
+}
+
+// filterWorkspaces returns the workspaces of the failed tasks.
+func (f *batchFailedTasks) filterWorkspaces(workspaces []service.RepoWorkspace) []service.RepoWorkspace {
+	failed := make(map[[3]string]bool, len(f.Tasks))
+	for _, t := range f.Tasks {
+		failed[t.key()] = true
+	}
+
+	var filtered []service.RepoWorkspace
+	for _, ws := range workspaces {
+		key := batchFailedTask{RepositoryID: ws.Repo.ID, Branch: ws.Repo.BaseRef(), Path: ws.Path}.key()
+		if failed[key] {
+			filtered = append(filtered, ws)
+		}
+	}
+	return filtered
+}
+
+// batchFailedTasksUsage documents -retry-failed for the commands that execute
+// batch specs.
+const batchFailedTasksUsage = `Retrying failed tasks:
+
+  Every execution writes the tasks that failed to a JSON file, by default
+  failed-tasks.json in the -cache directory:
+
+    {
+      "version": 1,
+      "tasks": [
+        {
+          "repositoryID": "UmVwb3NpdG9yeTox",
+          "repository": "github.com/sourcegraph/src-cli",
+          "branch": "refs/heads/main",
+          "path": "",
+          "category": "step-nonzero-exit",
+          "error": "..."
+        }
+      ]
+    }
+
+  With 'src batch preview' and 'src batch diff', -retry-failed FILE only
+  executes the batch spec in the workspaces listed in FILE, which is a quick
+  way to recover from failures that were transient. 'src batch apply' doesn't
+  accept -retry-failed, since applying the changeset specs of only some
+  workspaces would close the changesets of all others. Once the retry
+  succeeds, apply the batch spec: the results of all workspaces then come
+  from the cache.
+
+`
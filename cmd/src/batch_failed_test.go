@@ -0,0 +1,63 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
+	"github.com/sourcegraph/src-cli/internal/batches/executor"
+	"github.com/sourcegraph/src-cli/internal/batches/graphql"
+	"github.com/sourcegraph/src-cli/internal/batches/service"
+)
+
+func TestBatchFailedTasks(t *testing.T) {
+	repoA := &graphql.Repository{ID: "repo-a", Name: "github.com/sourcegraph/a", Branch: graphql.Branch{Name: "main"}}
+	repoB := &graphql.Repository{ID: "repo-b", Name: "github.com/sourcegraph/b", Branch: graphql.Branch{Name: "main"}}
+
+	var failed batchFailedTasks
+	failed.add(&executor.Task{Repository: repoB, Path: "sub"}, errors.New("step 1 failed"))
+	failed.add(&executor.Task{Repository: repoA}, errors.New("timeout"))
+
+	path := filepath.Join(t.TempDir(), "failed", "tasks.json")
+	if err := failed.write(path); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := readBatchFailedTasks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read.Tasks) != 2 || read.Tasks[0].Repository != "github.com/sourcegraph/a" || read.Tasks[1].Path != "sub" {
+		t.Fatalf("unexpected failed tasks: %+v", read.Tasks)
+	}
+	if read.Tasks[0].Category != executor.ErrorCategoryUnknown {
+		t.Errorf("wrong category: %s", read.Tasks[0].Category)
+	}
+
+	workspaces := read.filterWorkspaces([]service.RepoWorkspace{
+		{Repo: repoA},
+		{Repo: repoA, Path: "sub"},
+		{Repo: repoB},
+		{Repo: repoB, Path: "sub"},
+	})
+	if len(workspaces) != 2 || workspaces[0].Repo != repoA || workspaces[0].Path != "" || workspaces[1].Repo != repoB || workspaces[1].Path != "sub" {
+		t.Errorf("unexpected workspaces: %+v", workspaces)
+	}
+}
+
+func TestBatchRetryFailedFlag(t *testing.T) {
+	for _, cmd := range batchCommands {
+		hasFlag := cmd.flagSet.Lookup("retry-failed") != nil
+		switch name := cmd.flagSet.Name(); name {
+		case "preview", "diff":
+			if !hasFlag {
+				t.Errorf("src batch %s doesn't accept -retry-failed", name)
+			}
+		case "apply":
+			if hasFlag {
+				t.Error("src batch apply accepts -retry-failed")
+			}
+		}
+	}
+}
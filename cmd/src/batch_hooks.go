@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strconv"
+
+	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+)
+
+// batchRunSummary describes an execution to the -before-run and -after-run
+// hooks. Fields that aren't known yet when a hook runs are left empty.
+type batchRunSummary struct {
+	specName       string
+	workspaces     int
+	tasks          int
+	changesetSpecs []*batcheslib.ChangesetSpec
+	failed         bool
+	afterRun       bool
+}
+
+// env returns the environment variables the summary is passed to hooks in.
+func (s batchRunSummary) env() []string {
+	env := []string{
+		"SRC_BATCH_SPEC_NAME=" + s.specName,
+		"SRC_BATCH_WORKSPACE_COUNT=" + strconv.Itoa(s.workspaces),
+		"SRC_BATCH_TASK_COUNT=" + strconv.Itoa(s.tasks),
+	}
+	if !s.afterRun {
+		return env
+	}
+
+	changed := map[string]struct{}{}
+	for _, spec := range s.changesetSpecs {
+		if len(spec.Commits) > 0 {
+			changed[spec.BaseRepository] = struct{}{}
+		}
+	}
+	result := "success"
+	if s.failed {
+		result = "failure"
+	}
+	return append(env,
+		"SRC_BATCH_CHANGESET_SPEC_COUNT="+strconv.Itoa(len(s.changesetSpecs)),
+		"SRC_BATCH_CHANGED_REPO_COUNT="+strconv.Itoa(len(changed)),
+		"SRC_BATCH_RESULT="+result,
+	)
+}
+
+// runBatchHook runs command with sh in the current directory, with the
+// environment of src and the summary. Its output is written to stderr, like the
+// rest of the execution output.
+func runBatchHook(ctx context.Context, flag, command string, summary batchRunSummary) error {
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Env = append(os.Environ(), summary.env()...)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "running %s command", flag)
+	}
+	return nil
+}
+
+// batchHooksUsage documents -before-run and -after-run for the commands that
+// execute batch specs.
+const batchHooksUsage = `Hooks:
+
+  -before-run and -after-run take shell commands that are run once, before
+  the tasks are executed and after they completed. They can be used for setup
+  and teardown shared by all repositories, for example to log the run. They
+  are passed these environment variables:
+
+    SRC_BATCH_SPEC_NAME              the name of the batch spec
+    SRC_BATCH_WORKSPACE_COUNT        the number of workspaces
+    SRC_BATCH_TASK_COUNT             the number of tasks to execute, without
+                                     the cached ones
+
+  The -after-run command is also passed:
+
+    SRC_BATCH_CHANGESET_SPEC_COUNT   the number of changeset specs
+    SRC_BATCH_CHANGED_REPO_COUNT     the number of repositories with changes
+    SRC_BATCH_RESULT                 success or failure
+
+`
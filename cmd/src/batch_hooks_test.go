@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
+)
+
+func TestRunBatchHook(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "out")
+	summary := batchRunSummary{
+		specName:   "hello-world",
+		workspaces: 3,
+		tasks:      2,
+		changesetSpecs: []*batcheslib.ChangesetSpec{
+			{BaseRepository: "repo-1", Commits: []batcheslib.GitCommitDescription{{}}},
+			{BaseRepository: "repo-1", Commits: []batcheslib.GitCommitDescription{{}}},
+			{BaseRepository: "repo-2", ExternalID: "123"},
+		},
+		afterRun: true,
+	}
+
+	cmd := `echo "$SRC_BATCH_SPEC_NAME $SRC_BATCH_TASK_COUNT $SRC_BATCH_CHANGESET_SPEC_COUNT $SRC_BATCH_CHANGED_REPO_COUNT $SRC_BATCH_RESULT" > ` + out
+	if err := runBatchHook(context.Background(), "-after-run", cmd, summary); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "hello-world 2 3 1 success\n"; string(got) != want {
+		t.Errorf("wrong environment. want=%q have=%q", want, string(got))
+	}
+
+	if err := runBatchHook(context.Background(), "-before-run", "exit 1", summary); err == nil {
+		t.Error("expected error for failing command")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	cliLog "log"
+	"os"
 
 	"github.com/sourcegraph/src-cli/internal/api"
 	"github.com/sourcegraph/src-cli/internal/batches/service"
@@ -18,21 +19,29 @@ fields.
 
 Usage:
 
-    src batch new [-f FILE]
+    src batch new [-f FILE] [-dir DIR] [-force] [-minimal] [-stdout]
 
 Examples:
 
 
     $ src batch new -f batch.spec.yaml
 
+    $ src batch new -dir ./batch-changes -f hello.batch.yaml
+
+    $ src batch new -minimal -stdout > batch.spec.yaml
+
 `
 
 	flagSet := flag.NewFlagSet("new", flag.ExitOnError)
 	apiFlags := api.NewFlags(flagSet)
 
 	var (
-		fileFlag   = flagSet.String("f", "batch.yaml", "The name of the batch spec file to create.")
-		skipErrors bool
+		fileFlag    = flagSet.String("f", "batch.yaml", "The name of the batch spec file to create.")
+		minimalFlag = flagSet.Bool("minimal", false, "Only include the required fields, without any comments.")
+		stdoutFlag  = flagSet.Bool("stdout", false, "Print the batch spec to stdout instead of creating a file.")
+		dirFlag     = flagSet.String("dir", "", "The directory to create the batch spec file in. Default is the current directory.")
+		forceFlag   = flagSet.Bool("force", false, "Overwrite the batch spec file if it already exists.")
+		skipErrors  bool
 	)
 	flagSet.BoolVar(
 		&skipErrors, "skip-errors", false,
@@ -67,11 +76,21 @@ Examples:
 			}
 		}
 
-		if err := svc.GenerateExampleSpec(ctx, *fileFlag); err != nil {
+		opts := service.ExampleSpecOpts{
+			Minimal: *minimalFlag,
+			Dir:     *dirFlag,
+			Force:   *forceFlag,
+		}
+		if *stdoutFlag {
+			return svc.WriteExampleSpec(ctx, os.Stdout, opts)
+		}
+
+		path, err := svc.GenerateExampleSpec(ctx, *fileFlag, opts)
+		if err != nil {
 			return err
 		}
 
-		fmt.Printf("%s created.\n", *fileFlag)
+		fmt.Printf("%s created.\n", path)
 		return nil
 	}
 
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
+	"github.com/sourcegraph/src-cli/internal/batches/executor"
+)
+
+// batchOutputsVersion is the version of the format of -dump-outputs files. It
+// is increased when the format changes in a way that isn't backwards
+// compatible.
+const batchOutputsVersion = 1
+
+// batchOutputs is the content of a -dump-outputs file.
+type batchOutputs struct {
+	Version    int                     `json:"version"`
+	Workspaces []batchWorkspaceOutputs `json:"workspaces"`
+}
+
+// batchWorkspaceOutputs are the outputs of the steps in one workspace.
+type batchWorkspaceOutputs struct {
+	RepositoryID string                 `json:"repositoryID"`
+	Repository   string                 `json:"repository"`
+	Branch       string                 `json:"branch"`
+	Path         string                 `json:"path"`
+	Outputs      map[string]interface{} `json:"outputs"`
+}
+
+// add records the outputs of task. It's used as the TaskOutputs callback of the
+// coordinator.
+func (o *batchOutputs) add(task *executor.Task, outputs map[string]interface{}) {
+	if outputs == nil {
+		outputs = map[string]interface{}{}
+	}
+	o.Workspaces = append(o.Workspaces, batchWorkspaceOutputs{
+		RepositoryID: task.Repository.ID,
+		Repository:   task.Repository.Name,
+		Branch:       task.Repository.BaseRef(),
+		Path:         task.Path,
+		Outputs:      outputs,
+	})
+}
+
+// write writes the outputs to path as JSON, sorted by repository, branch and
+// path so that the file doesn't depend on the order the tasks completed in.
+func (o *batchOutputs) write(path string) error {
+	o.Version = batchOutputsVersion
+	if o.Workspaces == nil {
+		o.Workspaces = []batchWorkspaceOutputs{}
+	}
+	sort.Slice(o.Workspaces, func(i, j int) bool {
+		a, b := o.Workspaces[i], o.Workspaces[j]
+		if a.Repository != b.Repository {
+			return a.Repository < b.Repository
+		}
+		if a.Branch != b.Branch {
+			return a.Branch < b.Branch
+		}
+		return a.Path < b.Path
+	})
+
+	data, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshalling outputs")
+	}
+	return errors.Wrap(os.WriteFile(path, append(data, '\n'), 0644), "writing outputs")
+}
+
+// batchOutputsUsage documents -dump-outputs for the commands that execute batch
+// specs.
+const batchOutputsUsage = `Outputs:
+
+  -dump-outputs writes the outputs of the steps in every workspace that
+  completed to a JSON file, including workspaces whose results were cached:
+
+    {
+      "version": 1,
+      "workspaces": [
+        {
+          "repositoryID": "UmVwb3NpdG9yeTox",
+          "repository": "github.com/sourcegraph/src-cli",
+          "branch": "refs/heads/main",
+          "path": "",
+          "outputs": {"goVersion": "1.22"}
+        }
+      ]
+    }
+
+  The file can be read by other tools, or mounted into the steps of a later
+  batch spec to use the values computed by this one.
+
+`
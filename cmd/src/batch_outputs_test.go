@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sourcegraph/src-cli/internal/batches/executor"
+	"github.com/sourcegraph/src-cli/internal/batches/graphql"
+)
+
+func TestBatchOutputsWrite(t *testing.T) {
+	var outputs batchOutputs
+	outputs.add(&executor.Task{
+		Repository: &graphql.Repository{ID: "repo-2", Name: "github.com/sourcegraph/b", Branch: graphql.Branch{Name: "main"}},
+		Path:       "sub",
+	}, map[string]interface{}{"count": 2})
+	outputs.add(&executor.Task{
+		Repository: &graphql.Repository{ID: "repo-1", Name: "github.com/sourcegraph/a", Branch: graphql.Branch{Name: "main"}},
+	}, nil)
+
+	path := filepath.Join(t.TempDir(), "outputs.json")
+	if err := outputs.write(path); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+  "version": 1,
+  "workspaces": [
+    {
+      "repositoryID": "repo-1",
+      "repository": "github.com/sourcegraph/a",
+      "branch": "refs/heads/main",
+      "path": "",
+      "outputs": {}
+    },
+    {
+      "repositoryID": "repo-2",
+      "repository": "github.com/sourcegraph/b",
+      "branch": "refs/heads/main",
+      "path": "sub",
+      "outputs": {
+        "count": 2
+      }
+    }
+  ]
+}
+`
+	if string(got) != want {
+		t.Errorf("wrong outputs file. want=%q have=%q", want, string(got))
+	}
+}
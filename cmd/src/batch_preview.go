@@ -24,17 +24,18 @@ Examples:
 
     $ src batch preview batch.spec.yaml
 
-`
+` + batchHooksUsage + batchOutputsUsage + batchFailedTasksUsage
 
 	flagSet := flag.NewFlagSet("preview", flag.ExitOnError)
 	flags := newBatchExecuteFlags(flagSet, batchDefaultCacheDir(), batchDefaultTempDirPrefix())
+	flags.addRetryFailedFlag(flagSet)
 
 	handler := func(args []string) error {
 		if err := flagSet.Parse(args); err != nil {
 			return err
 		}
 
-		file, err := getBatchSpecFile(flagSet, &flags.file)
+		file, err := getBatchSpecFile(flagSet, flags.file)
 		if err != nil {
 			return err
 		}
@@ -44,7 +45,7 @@ Examples:
 
 		if err = executeBatchSpec(ctx, executeBatchSpecOpts{
 			flags:  flags,
-			client: cfg.apiClient(flags.api, flagSet.Output()),
+			client: flags.apiClient(flagSet.Output()),
 			file:   file,
 
 			// Do not apply the uploaded batch spec
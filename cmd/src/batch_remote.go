@@ -33,7 +33,8 @@ Examples:
 	flags := newBatchExecutionFlags(flagSet)
 
 	var (
-		fileFlag = flagSet.String("f", "", "The name of the batch spec file to run.")
+		input    = newBatchSpecInputFlags(flagSet)
+		fileFlag = input.fileFlag(flagSet, "The name of the batch spec file to run.")
 	)
 
 	handler := func(args []string) error {
@@ -73,7 +74,7 @@ Examples:
 		// may as well validate it at the same time so we don't even have to go to
 		// the backend if it's invalid.
 		ui.ParsingBatchSpec()
-		spec, batchSpecDir, raw, err := parseBatchSpec(ctx, file, svc)
+		spec, batchSpecDir, raw, err := parseBatchSpec(ctx, file, input, svc)
 		if err != nil {
 			ui.ParsingBatchSpecFailure(err)
 			return err
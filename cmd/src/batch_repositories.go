@@ -36,7 +36,8 @@ Examples:
 	flagSet := flag.NewFlagSet("repositories", flag.ExitOnError)
 
 	var (
-		fileFlag = flagSet.String("f", "", "The batch spec file to read, or - to read from standard input.")
+		input    = newBatchSpecInputFlags(flagSet)
+		fileFlag = input.fileFlag(flagSet, "The batch spec file to read, or - to read from standard input.")
 		apiFlags = api.NewFlags(flagSet)
 	)
 
@@ -44,6 +45,7 @@ Examples:
 		allowUnsupported bool
 		allowIgnored     bool
 		skipErrors       bool
+		repoBatchSize    int
 	)
 	flagSet.BoolVar(
 		&allowUnsupported, "allow-unsupported", false,
@@ -53,6 +55,7 @@ Examples:
 		&allowIgnored, "force-override-ignore", false,
 		"Do not ignore repositories that have a .batchignore file.",
 	)
+	flagSet.IntVar(&repoBatchSize, "repo-batch-size", 0, repoBatchSizeUsage)
 	flagSet.BoolVar(
 		&skipErrors, "skip-errors", false,
 		"If true, errors encountered won't stop the program, but only log them.",
@@ -72,7 +75,8 @@ Examples:
 		client := cfg.apiClient(apiFlags, flagSet.Output())
 
 		svc := service.New(&service.Opts{
-			Client: client,
+			Client:        client,
+			RepoBatchSize: repoBatchSize,
 		})
 
 		_, ffs, err := svc.DetermineLicenseAndFeatureFlags(ctx, skipErrors)
@@ -89,7 +93,7 @@ Examples:
 		}
 
 		out := output.NewOutput(flagSet.Output(), output.OutputOpts{Verbose: *verbose})
-		spec, _, _, err := parseBatchSpec(ctx, file, svc)
+		spec, _, _, err := parseBatchSpec(ctx, file, input, svc)
 		if err != nil {
 			ui := &ui.TUI{Out: out}
 			ui.ParsingBatchSpecFailure(err)
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+)
+
+// Every execution of a batch spec keeps its temporary files, such as
+// workspaces and logs, in a directory of its own in the -tmp directory. The
+// directory contains a marker file with the PID and host of the src process, so
+// that the directories of executions that were interrupted before they could
+// clean up can be found later.
+const (
+	batchRunDirPrefix = "src-batch-run-"
+	batchRunMarker    = ".src-batch-run"
+)
+
+// createBatchRunDir creates the temporary directory for an execution in
+// tempDir.
+func createBatchRunDir(tempDir string) (string, error) {
+	if err := os.MkdirAll(tempDir, 0777); err != nil {
+		return "", errors.Wrap(err, "creating temp directory")
+	}
+	dir, err := os.MkdirTemp(tempDir, batchRunDirPrefix)
+	if err != nil {
+		return "", errors.Wrap(err, "creating temp directory for execution")
+	}
+	if err := os.Chmod(dir, 0755); err != nil {
+		return "", err
+	}
+
+	host, _ := os.Hostname()
+	marker := fmt.Sprintf("%d %s\n", os.Getpid(), host)
+	if err := os.WriteFile(filepath.Join(dir, batchRunMarker), []byte(marker), 0644); err != nil {
+		return "", errors.Wrap(err, "writing temp directory marker")
+	}
+	return dir, nil
+}
+
+// removeBatchRunDir removes the temporary directory of an execution. If the
+// logs are kept, only the marker is removed, so that the directory isn't
+// reported as orphaned.
+func removeBatchRunDir(dir string, keepLogs bool) error {
+	if keepLogs {
+		return os.Remove(filepath.Join(dir, batchRunMarker))
+	}
+	return os.RemoveAll(dir)
+}
+
+// findOrphanedBatchRunDirs returns the temporary directories in tempDir of
+// executions on this host whose src process isn't running anymore.
+func findOrphanedBatchRunDirs(tempDir string) ([]string, error) {
+	entries, err := os.ReadDir(tempDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "reading temp directory")
+	}
+
+	host, _ := os.Hostname()
+	var orphans []string
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), batchRunDirPrefix) {
+			continue
+		}
+		dir := filepath.Join(tempDir, entry.Name())
+		data, err := os.ReadFile(filepath.Join(dir, batchRunMarker))
+		if err != nil {
+			// Without a marker, the directory was cleaned up with the logs
+			// kept, or isn't ours.
+			continue
+		}
+
+		fields := strings.Fields(string(data))
+		if len(fields) == 2 && fields[1] != host {
+			// The temp directory is shared with another host, whose
+			// processes can't be checked.
+			continue
+		}
+		if len(fields) > 0 {
+			if pid, err := strconv.Atoi(fields[0]); err == nil && processRunning(pid) {
+				continue
+			}
+		}
+		orphans = append(orphans, dir)
+	}
+	return orphans, nil
+}
+
+// processRunning reports whether a process with the given PID exists.
+func processRunning(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	defer p.Release()
+	if runtime.GOOS == "windows" {
+		// FindProcess only succeeds for existing processes on Windows.
+		return true
+	}
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBatchRunDirs(t *testing.T) {
+	tempDir := t.TempDir()
+
+	running, err := createBatchRunDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(running, batchRunMarker)); err != nil {
+		t.Fatalf("marker not written: %s", err)
+	}
+
+	// An execution whose process doesn't exist anymore.
+	host, _ := os.Hostname()
+	interrupted := filepath.Join(tempDir, batchRunDirPrefix+"interrupted")
+	if err := os.Mkdir(interrupted, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(interrupted, batchRunMarker), []byte("99999999 "+host+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// An execution on another host sharing the temp directory.
+	otherHost := filepath.Join(tempDir, batchRunDirPrefix+"other-host")
+	if err := os.Mkdir(otherHost, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(otherHost, batchRunMarker), []byte("99999999 "+host+"-other\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// An execution whose logs were kept.
+	kept, err := createBatchRunDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := removeBatchRunDir(kept, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Fatalf("directory with kept logs removed: %s", err)
+	}
+
+	orphans, err := findOrphanedBatchRunDirs(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orphans) != 1 || orphans[0] != interrupted {
+		t.Errorf("wrong orphans. want=%v have=%v", []string{interrupted}, orphans)
+	}
+
+	if err := removeBatchRunDir(running, false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(running); !os.IsNotExist(err) {
+		t.Errorf("directory not removed: %v", err)
+	}
+}
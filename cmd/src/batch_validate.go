@@ -32,7 +32,8 @@ Examples:
 
 	flagSet := flag.NewFlagSet("validate", flag.ExitOnError)
 	apiFlags := api.NewFlags(flagSet)
-	fileFlag := flagSet.String("f", "", "The batch spec file to read, or - to read from standard input.")
+	input := newBatchSpecInputFlags(flagSet)
+	fileFlag := input.fileFlag(flagSet, "The batch spec file to read, or - to read from standard input.")
 
 	var (
 		allowUnsupported bool
@@ -88,7 +89,7 @@ Examples:
 			return err
 		}
 
-		if _, _, _, err := parseBatchSpec(ctx, file, svc); err != nil {
+		if _, _, _, err := parseBatchSpec(ctx, file, input, svc); err != nil {
 			ui.ParsingBatchSpecFailure(err)
 			return err
 		}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
 	"fmt"
 	"log"
@@ -27,6 +28,10 @@ type command struct {
 	// flagSet.Usage function to invoke on e.g. -h flag. If nil, a default one is
 	// used.
 	usageFunc func()
+
+	// configOptional is set for commands that run even if the configuration
+	// can't be read. They find the error in cfgErr, and cfg is nil.
+	configOptional bool
 }
 
 // matches tells if the given name matches this command or one of its aliases.
@@ -86,7 +91,10 @@ func (c commander) run(flagSet *flag.FlagSet, cmdName, usageText string, args []
 		var err error
 		cfg, err = readConfig()
 		if err != nil {
-			log.Fatal("reading config: ", err)
+			if !cmd.configOptional {
+				log.Fatal("reading config: ", err)
+			}
+			cfgErr = err
 		}
 
 		// Print help to stdout if requested
@@ -108,6 +116,11 @@ func (c commander) run(flagSet *flag.FlagSet, cmdName, usageText string, args []
 
 		// Execute the subcommand.
 		if err := cmd.handler(flagSet.Args()[1:]); err != nil {
+			if *jsonErrors {
+				je := newJSONError(cmdName, cmd, err)
+				_ = json.NewEncoder(os.Stderr).Encode(je)
+				os.Exit(je.Code)
+			}
 			if _, ok := err.(*cmderrors.UsageError); ok {
 				log.Printf("error: %s\n\n", err)
 				cmd.flagSet.SetOutput(os.Stderr)
@@ -129,6 +142,30 @@ func (c commander) run(flagSet *flag.FlagSet, cmdName, usageText string, args []
 	log.Fatalf("Run '%s help' for usage.", cmdName)
 }
 
+// jsonError is how an error returned by a command is printed when the
+// -json-errors flag is set.
+type jsonError struct {
+	Error string `json:"error"`
+	Code  int    `json:"code"`
+	Hint  string `json:"hint,omitempty"`
+}
+
+// newJSONError converts err, returned by the given command, into a jsonError
+// with the exit code src exits with.
+func newJSONError(cmdName string, cmd *command, err error) jsonError {
+	if _, ok := err.(*cmderrors.UsageError); ok {
+		return jsonError{
+			Error: err.Error(),
+			Code:  2,
+			Hint:  fmt.Sprintf("Run '%s %s -h' for usage.", cmdName, cmd.flagSet.Name()),
+		}
+	}
+	if e, ok := err.(*cmderrors.ExitCodeError); ok {
+		return jsonError{Error: e.Error(), Code: e.Code()}
+	}
+	return jsonError{Error: err.Error(), Code: 1}
+}
+
 func didYouMeanOtherCommand(actual string, suggested []string) *command {
 	fullSuggestions := make([]string, len(suggested))
 	for i, s := range suggested {
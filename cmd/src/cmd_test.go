@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
+	"github.com/sourcegraph/src-cli/internal/cmderrors"
+)
+
+func TestNewJSONError(t *testing.T) {
+	cmd := &command{flagSet: flag.NewFlagSet("preview", flag.ExitOnError)}
+
+	for name, tc := range map[string]struct {
+		err  error
+		want jsonError
+	}{
+		"usage error": {
+			err: cmderrors.Usage("missing batch spec"),
+			want: jsonError{
+				Error: "missing batch spec",
+				Code:  2,
+				Hint:  "Run 'src batch preview -h' for usage.",
+			},
+		},
+		"exit code error": {
+			err:  cmderrors.ExitCode(3, errors.New("validation failed")),
+			want: jsonError{Error: "validation failed (exit code: 3)", Code: 3},
+		},
+		"exit code without error": {
+			err:  cmderrors.ExitCode1,
+			want: jsonError{Error: "exit code: 1", Code: 1},
+		},
+		"other error": {
+			err:  errors.New("boom"),
+			want: jsonError{Error: "boom", Code: 1},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, newJSONError("src batch", cmd, tc.err)); diff != "" {
+				t.Errorf("unexpected JSON error (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -49,6 +49,21 @@ Examples:
     	$ src code-intel upload -github-token=BAZ, or
     	$ src code-intel upload -gitlab-token=BAZ
 
+  Upload a SCIP index read from stdin, such as one produced by a previous
+  step of a pipeline:
+
+    	$ cat index.scip | src code-intel upload -file=-
+
+  Check the inferred arguments and how the index would be uploaded,
+  without uploading it:
+
+    	$ src code-intel upload -dry-run
+
+  Upload a SCIP index and wait until it has been processed, failing if
+  processing fails or takes longer than 10 minutes:
+
+    	$ src code-intel upload -wait -wait-timeout=10m
+
   For any of these commands, an LSIF index (default name: dump.lsif) can be
   used instead of a SCIP index (default name: index.scip).
 `
@@ -79,6 +94,9 @@ func handleCodeIntelUpload(args []string) error {
 	ctx := context.Background()
 
 	out, isSCIPAvailable, err := parseAndValidateCodeIntelUploadFlags(args)
+	if codeintelUploadFlags.stdinDir != "" {
+		defer os.RemoveAll(codeintelUploadFlags.stdinDir)
+	}
 	if !codeintelUploadFlags.json {
 		if out != nil {
 			printInferredArguments(out)
@@ -91,9 +109,15 @@ func handleCodeIntelUpload(args []string) error {
 		return handleUploadError(cfg.AccessToken, err)
 	}
 
+	if codeintelUploadFlags.dryRun {
+		return printCodeIntelUploadDryRun(out, isSCIPAvailable)
+	}
+
 	client := api.NewClient(api.ClientOpts{
-		Out:   io.Discard,
-		Flags: codeintelUploadFlags.apiFlags,
+		Out:                io.Discard,
+		Flags:              codeintelUploadFlags.apiFlags,
+		InsecureSkipVerify: cfg.InsecureSkipVerify,
+		CACert:             cfg.CACert,
 	})
 
 	uploadOptions := codeintelUploadOptions(out, isSCIPAvailable)
@@ -108,7 +132,7 @@ func handleCodeIntelUpload(args []string) error {
 	}
 
 	if codeintelUploadFlags.json {
-		serialized, err := json.Marshal(map[string]interface{}{
+		result := map[string]interface{}{
 			"repo":           codeintelUploadFlags.repo,
 			"commit":         codeintelUploadFlags.commit,
 			"root":           codeintelUploadFlags.root,
@@ -117,18 +141,35 @@ func handleCodeIntelUpload(args []string) error {
 			"indexerVersion": codeintelUploadFlags.indexerVersion,
 			"uploadId":       uploadID,
 			"uploadUrl":      uploadURL,
-		})
+		}
+
+		var waitErr error
+		if codeintelUploadFlags.wait {
+			result["state"], waitErr = waitForCodeIntelUploadProcessing(ctx, nil, uploadID)
+		}
+
+		serialized, err := json.Marshal(result)
 		if err != nil {
 			return err
 		}
 
 		fmt.Println(string(serialized))
+		if waitErr != nil {
+			return waitErr
+		}
 	} else {
 		if out == nil {
 			out = emergencyOutput()
 		}
 
 		out.WriteLine(output.Linef(output.EmojiLightbulb, output.StyleItalic, "View processing status at %s", uploadURL))
+
+		if codeintelUploadFlags.wait {
+			if _, err := waitForCodeIntelUploadProcessing(ctx, out, uploadID); err != nil {
+				return err
+			}
+			out.WriteLine(output.Line(output.EmojiSuccess, output.StyleSuccess, "Upload processed"))
+		}
 	}
 
 	if codeintelUploadFlags.open {
@@ -140,6 +181,14 @@ func handleCodeIntelUpload(args []string) error {
 	return nil
 }
 
+// waitForCodeIntelUploadProcessing blocks until the upload with the given identifier
+// has been processed, as requested by -wait.
+func waitForCodeIntelUploadProcessing(ctx context.Context, out *output.Output, uploadID int) (string, error) {
+	client := cfg.apiClient(codeintelUploadFlags.apiFlags, io.Discard)
+	getState := codeintelUploadState(client, uploadID)
+	return waitForCodeIntelUpload(ctx, out, getState, codeintelUploadFlags.waitTimeout, codeintelUploadPollInterval)
+}
+
 // codeintelUploadOptions creates a set of upload options given the values in the flags.
 func codeintelUploadOptions(out *output.Output, isSCIPAvailable bool) upload.UploadOptions {
 	var associatedIndexID *int
@@ -147,12 +196,8 @@ func codeintelUploadOptions(out *output.Output, isSCIPAvailable bool) upload.Upl
 		associatedIndexID = &codeintelUploadFlags.associatedIndexID
 	}
 
-	cfg.AdditionalHeaders["Content-Type"] = "application/x-ndjson+lsif"
-	path := codeintelUploadFlags.uploadRoute
-	if isSCIPAvailable && filepath.Ext(codeintelUploadFlags.file) == ".scip" {
-		cfg.AdditionalHeaders["Content-Type"] = "application/x-protobuf+scip"
-		path = strings.ReplaceAll(path, "lsif", "scip")
-	}
+	path, contentType := codeintelUploadRoute(codeintelUploadFlags.file, isSCIPAvailable)
+	cfg.AdditionalHeaders["Content-Type"] = contentType
 
 	logger := upload.NewRequestLogger(
 		os.Stdout,
@@ -191,6 +236,15 @@ func codeintelUploadOptions(out *output.Output, isSCIPAvailable bool) upload.Upl
 	}
 }
 
+// codeintelUploadRoute returns the path of the upload route and the content
+// type to upload the given file with.
+func codeintelUploadRoute(file string, isSCIPAvailable bool) (path, contentType string) {
+	if isSCIPAvailable && filepath.Ext(file) == ".scip" {
+		return strings.ReplaceAll(codeintelUploadFlags.uploadRoute, "lsif", "scip"), "application/x-protobuf+scip"
+	}
+	return codeintelUploadFlags.uploadRoute, "application/x-ndjson+lsif"
+}
+
 // printInferredArguments prints a block showing the effective values of flags that are
 // inferrably defined. This function is called on all paths except for -json uploads. This
 // function no-ops if the given output object is nil.
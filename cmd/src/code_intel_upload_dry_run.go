@@ -0,0 +1,133 @@
+package main
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/sourcegraph/sourcegraph/lib/output"
+)
+
+// codeintelUploadPlan describes how `src code-intel upload` would upload the
+// index, as printed by -dry-run.
+type codeintelUploadPlan struct {
+	// File is the index file that would be uploaded.
+	File string
+	// ConvertedFrom is the file that File would be converted from, if any.
+	ConvertedFrom string
+
+	Route       string
+	ContentType string
+
+	// Size and CompressedSize are the sizes of the index before conversion,
+	// in bytes. CompressedSize is approximate, as the upload compresses the
+	// index with different settings.
+	Size           int64
+	CompressedSize int64
+	MaxPayloadSize int64
+	// Parts is the number of requests the index would be uploaded in.
+	Parts int64
+}
+
+// planCodeIntelUpload returns how the index in the configured file would be
+// uploaded, without contacting the Sourcegraph instance.
+//
+// Note: This function must not be called before parseAndValidateCodeIntelUploadFlags.
+func planCodeIntelUpload(isSCIPAvailable bool) (*codeintelUploadPlan, error) {
+	plan := &codeintelUploadPlan{
+		File:           codeintelUploadFlags.file,
+		MaxPayloadSize: codeintelUploadFlags.maxPayloadSizeMb * 1000 * 1000,
+		Parts:          1,
+	}
+	if ext := path.Ext(plan.File); isSCIPAvailable && !skipConversionToSCIP && (ext == ".lsif" || ext == ".dump") {
+		plan.ConvertedFrom = plan.File
+		plan.File = replaceExtension(plan.File, ".scip")
+	}
+	plan.Route, plan.ContentType = codeintelUploadRoute(plan.File, isSCIPAvailable)
+
+	f, err := os.Open(codeintelUploadFlags.file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var compressed countingWriter
+	gzipWriter := gzip.NewWriter(&compressed)
+	if plan.Size, err = io.Copy(gzipWriter, f); err != nil {
+		return nil, err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return nil, err
+	}
+	plan.CompressedSize = int64(compressed)
+
+	if plan.CompressedSize > plan.MaxPayloadSize {
+		plan.Parts = (plan.CompressedSize + plan.MaxPayloadSize - 1) / plan.MaxPayloadSize
+	}
+	return plan, nil
+}
+
+// printCodeIntelUploadDryRun prints how the index would be uploaded, after
+// the inferred arguments have been printed, as -dry-run does.
+func printCodeIntelUploadDryRun(out *output.Output, isSCIPAvailable bool) error {
+	plan, err := planCodeIntelUpload(isSCIPAvailable)
+	if err != nil {
+		return err
+	}
+
+	if codeintelUploadFlags.json {
+		serialized, err := json.Marshal(map[string]interface{}{
+			"repo":           codeintelUploadFlags.repo,
+			"commit":         codeintelUploadFlags.commit,
+			"root":           codeintelUploadFlags.root,
+			"file":           plan.File,
+			"convertedFrom":  plan.ConvertedFrom,
+			"indexer":        codeintelUploadFlags.indexer,
+			"indexerVersion": codeintelUploadFlags.indexerVersion,
+			"uploadRoute":    plan.Route,
+			"contentType":    plan.ContentType,
+			"size":           plan.Size,
+			"compressedSize": plan.CompressedSize,
+			"maxPayloadSize": plan.MaxPayloadSize,
+			"parts":          plan.Parts,
+			"dryRun":         true,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(serialized))
+		return nil
+	}
+
+	if out == nil {
+		out = emergencyOutput()
+	}
+
+	block := out.Block(output.Line(output.EmojiLightbulb, output.StyleItalic, "Upload plan"))
+	if plan.ConvertedFrom != "" {
+		block.Writef("convert: %s -> %s", plan.ConvertedFrom, plan.File)
+	}
+	block.Writef("route: %s", plan.Route)
+	block.Writef("content type: %s", plan.ContentType)
+	block.Writef("size: %.2fMB (about %.2fMB compressed)", float64(plan.Size)/1000/1000, float64(plan.CompressedSize)/1000/1000)
+	if plan.Parts > 1 {
+		block.Writef("requests: %d parts of at most %.2fMB", plan.Parts, float64(plan.MaxPayloadSize)/1000/1000)
+	} else {
+		block.Writef("requests: 1")
+	}
+	block.Close()
+
+	out.WriteLine(output.Line(output.EmojiInfo, output.StyleItalic, "Dry run: nothing was uploaded"))
+	return nil
+}
+
+// countingWriter counts the bytes written to it.
+type countingWriter int64
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
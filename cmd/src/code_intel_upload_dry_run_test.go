@@ -0,0 +1,73 @@
+package main
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanCodeIntelUpload(t *testing.T) {
+	oldFlags := codeintelUploadFlags
+	t.Cleanup(func() { codeintelUploadFlags = oldFlags })
+	codeintelUploadFlags.uploadRoute = "/.api/lsif/upload"
+	codeintelUploadFlags.maxPayloadSizeMb = 1
+
+	t.Run("scip", func(t *testing.T) {
+		scipFile, _ := createTempSCIPFile(t, "index.scip")
+		codeintelUploadFlags.file = scipFile
+
+		plan, err := planCodeIntelUpload(true)
+		require.NoError(t, err)
+		require.Equal(t, scipFile, plan.File)
+		require.Empty(t, plan.ConvertedFrom)
+		require.Equal(t, "/.api/scip/upload", plan.Route)
+		require.Equal(t, "application/x-protobuf+scip", plan.ContentType)
+		require.Equal(t, int64(len(exampleSCIPBytes(t))), plan.Size)
+		require.Equal(t, int64(1), plan.Parts)
+	})
+
+	t.Run("lsif converted to scip", func(t *testing.T) {
+		lsifFile := filepath.Join(t.TempDir(), "dump.lsif")
+		require.NoError(t, os.WriteFile(lsifFile, []byte(exampleLSIFString), 0644))
+		codeintelUploadFlags.file = lsifFile
+
+		plan, err := planCodeIntelUpload(true)
+		require.NoError(t, err)
+		require.Equal(t, lsifFile, plan.ConvertedFrom)
+		require.Equal(t, filepath.Join(filepath.Dir(lsifFile), "dump.scip"), plan.File)
+		require.Equal(t, "/.api/scip/upload", plan.Route)
+
+		// The conversion is only planned.
+		_, err = os.Stat(plan.File)
+		require.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("lsif without scip support", func(t *testing.T) {
+		lsifFile := filepath.Join(t.TempDir(), "dump.lsif")
+		require.NoError(t, os.WriteFile(lsifFile, []byte(exampleLSIFString), 0644))
+		codeintelUploadFlags.file = lsifFile
+
+		plan, err := planCodeIntelUpload(false)
+		require.NoError(t, err)
+		require.Empty(t, plan.ConvertedFrom)
+		require.Equal(t, "/.api/lsif/upload", plan.Route)
+		require.Equal(t, "application/x-ndjson+lsif", plan.ContentType)
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		// Random data doesn't compress, so it takes three 1MB parts.
+		data := make([]byte, 2500*1000)
+		rand.New(rand.NewSource(1)).Read(data)
+		file := filepath.Join(t.TempDir(), "index.scip")
+		require.NoError(t, os.WriteFile(file, data, 0644))
+		codeintelUploadFlags.file = file
+
+		plan, err := planCodeIntelUpload(true)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(data)), plan.Size)
+		require.Equal(t, int64(3), plan.Parts)
+	})
+}
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"flag"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path"
@@ -24,6 +27,9 @@ import (
 
 var codeintelUploadFlags struct {
 	file string
+	// stdinDir is the temporary directory the index read from stdin with
+	// -file - is written to, and empty otherwise.
+	stdinDir string
 
 	// UploadRecordOptions
 	repo              string
@@ -48,15 +54,19 @@ var codeintelUploadFlags struct {
 	verbosity            int
 	json                 bool
 	open                 bool
+	wait                 bool
+	waitTimeout          time.Duration
+	dryRun               bool
 	apiFlags             *api.Flags
 }
 
 var (
 	codeintelUploadFlagSet = flag.NewFlagSet("upload", flag.ExitOnError)
 	apiClientFlagSet       = flag.NewFlagSet("upload client", flag.ExitOnError)
-	// Used to include the insecure-skip-verify flag in the help output, as we don't use any of the
-	// other api.Client methods, so only the insecureSkipVerify flag is relevant here.
-	dummyflag bool
+	// Used to include the insecure-skip-verify and cacert flags in the help output, as we don't use
+	// any of the other api.Client methods, so only the TLS flags are relevant here.
+	dummyflag       bool
+	dummyCACertFlag string
 
 	// Used to skip the LSIF -> SCIP conversion during the migration. Not expected to be used outside
 	// of codeintel-qa pipelines and not expected to last much longer than a few releases while we
@@ -65,12 +75,12 @@ var (
 )
 
 func init() {
-	codeintelUploadFlagSet.StringVar(&codeintelUploadFlags.file, "file", "", `The path to the LSIF dump file.`)
+	codeintelUploadFlagSet.StringVar(&codeintelUploadFlags.file, "file", "", `The path to the LSIF dump file, or - to read the index from stdin.`)
 
 	// UploadRecordOptions
 	codeintelUploadFlagSet.StringVar(&codeintelUploadFlags.repo, "repo", "", `The name of the repository (e.g. github.com/gorilla/mux). By default, derived from the origin remote.`)
 	codeintelUploadFlagSet.StringVar(&codeintelUploadFlags.commit, "commit", "", `The 40-character hash of the commit. Defaults to the currently checked-out commit.`)
-	codeintelUploadFlagSet.StringVar(&codeintelUploadFlags.root, "root", "", `The path in the repository that matches the LSIF projectRoot (e.g. cmd/project1). Defaults to the directory where the dump file is located.`)
+	codeintelUploadFlagSet.StringVar(&codeintelUploadFlags.root, "root", "", `The path in the repository that matches the LSIF projectRoot (e.g. cmd/project1). Defaults to the directory where the dump file is located, or the working directory with -file -.`)
 	codeintelUploadFlagSet.StringVar(&codeintelUploadFlags.indexer, "indexer", "", `The name of the indexer that generated the dump. This will override the 'toolInfo.name' field in the metadata vertex of the LSIF dump file. This must be supplied if the indexer does not set this field (in which case the upload will fail with an explicit message).`)
 	codeintelUploadFlagSet.StringVar(&codeintelUploadFlags.indexerVersion, "indexerVersion", "", `The version of the indexer that generated the dump. This will override the 'toolInfo.version' field in the metadata vertex of the LSIF dump file. This must be supplied if the indexer does not set this field (in which case the upload will fail with an explicit message).`)
 	codeintelUploadFlagSet.IntVar(&codeintelUploadFlags.associatedIndexID, "associated-index-id", -1, "ID of the associated index record for this upload. For internal use only.")
@@ -90,7 +100,11 @@ func init() {
 	codeintelUploadFlagSet.IntVar(&codeintelUploadFlags.verbosity, "trace", 0, "-trace=0 shows no logs; -trace=1 shows requests and response metadata; -trace=2 shows headers, -trace=3 shows response body")
 	codeintelUploadFlagSet.BoolVar(&codeintelUploadFlags.json, "json", false, `Output relevant state in JSON on success.`)
 	codeintelUploadFlagSet.BoolVar(&codeintelUploadFlags.open, "open", false, `Open the LSIF upload page in your browser.`)
-	codeintelUploadFlagSet.BoolVar(&dummyflag, "insecure-skip-verify", false, "Skip validation of TLS certificates against trusted chains")
+	codeintelUploadFlagSet.BoolVar(&codeintelUploadFlags.wait, "wait", false, `Wait until the upload has been processed, and exit with a non-zero status code if processing fails.`)
+	codeintelUploadFlagSet.DurationVar(&codeintelUploadFlags.waitTimeout, "wait-timeout", 30*time.Minute, `The maximum time to wait for the upload to be processed when -wait is given. 0 waits indefinitely.`)
+	codeintelUploadFlagSet.BoolVar(&codeintelUploadFlags.dryRun, "dry-run", false, `Print the inferred arguments and how the index would be uploaded, then exit without contacting the Sourcegraph instance.`)
+	codeintelUploadFlagSet.BoolVar(&dummyflag, "insecure-skip-verify", false, "Skip validation of TLS certificates against trusted chains. Overrides the global -insecure-skip-verify option")
+	codeintelUploadFlagSet.StringVar(&dummyCACertFlag, "cacert", "", "Path to a PEM file with CA certificates to trust in addition to the system ones. Overrides the global -cacert option")
 
 	// Testing flags
 	codeintelUploadFlagSet.BoolVar(&skipConversionToSCIP, "skip-scip", false, "Skip converting LSIF index to SCIP if the instance supports it; this option should only used for debugging")
@@ -109,22 +123,28 @@ func parseAndValidateCodeIntelUploadFlags(args []string) (*output.Output, bool,
 
 	out := codeintelUploadOutput()
 
-	// extract only the -insecure-skip-verify flag so we dont get 'flag provided but not defined'
-	var insecureSkipVerifyFlag []string
-	for _, s := range args {
-		if strings.HasPrefix(s, "-insecure-skip-verify") {
-			insecureSkipVerifyFlag = append(insecureSkipVerifyFlag, s)
+	// Pass the TLS flags on to the api client flags. The other api client flags
+	// aren't supported by upload, and -trace has a different meaning here.
+	var apiClientArgs []string
+	codeintelUploadFlagSet.Visit(func(f *flag.Flag) {
+		if f.Name == "insecure-skip-verify" || f.Name == "cacert" {
+			apiClientArgs = append(apiClientArgs, "-"+f.Name+"="+f.Value.String())
 		}
-	}
-
-	// parse the api client flags separately and then populate the codeintelUploadFlags struct with the result
-	// we could just use insecureSkipVerify but I'm including everything here because it costs nothing
-	// and maybe we'll use some in the future
+	})
 	codeintelUploadFlags.apiFlags = api.NewFlags(apiClientFlagSet)
-	if err := apiClientFlagSet.Parse(insecureSkipVerifyFlag); err != nil {
+	if err := apiClientFlagSet.Parse(apiClientArgs); err != nil {
 		return nil, false, err
 	}
 
+	if codeintelUploadFlags.file == "-" {
+		file, err := readCodeIntelIndexFromStdin(os.Stdin)
+		if err != nil {
+			return nil, false, err
+		}
+		codeintelUploadFlags.file = file
+		codeintelUploadFlags.stdinDir = filepath.Dir(file)
+	}
+
 	if !isFlagSet(codeintelUploadFlagSet, "file") {
 		defaultFile, err := inferDefaultFile()
 		if err != nil {
@@ -142,18 +162,25 @@ func parseAndValidateCodeIntelUploadFlags(args []string) (*output.Output, bool,
 		return nil, false, errors.Newf("file %q does not exist", codeintelUploadFlags.file)
 	}
 
-	isSCIPAvailable, err := isSCIPAvailable()
-	if err != nil {
-		return nil, false, err
-	}
-
-	if !isSCIPAvailable {
-		if err := handleSCIP(out); err != nil {
+	// A dry run doesn't contact the instance, so it assumes that the instance
+	// accepts SCIP indexes, and leaves the conversion of LSIF indexes to
+	// planCodeIntelUpload instead of writing the converted file.
+	scipAvailable := true
+	if !codeintelUploadFlags.dryRun {
+		var err error
+		scipAvailable, err = isSCIPAvailable()
+		if err != nil {
 			return nil, false, err
 		}
-	} else {
-		if err := handleLSIF(out); err != nil {
-			return nil, false, err
+
+		if !scipAvailable {
+			if err := handleSCIP(out); err != nil {
+				return nil, false, err
+			}
+		} else {
+			if err := handleLSIF(out); err != nil {
+				return nil, false, err
+			}
 		}
 	}
 
@@ -171,7 +198,7 @@ func parseAndValidateCodeIntelUploadFlags(args []string) (*output.Output, bool,
 		return nil, false, err
 	}
 
-	return out, isSCIPAvailable, nil
+	return out, scipAvailable, nil
 }
 
 // codeintelUploadOutput returns an output object that should be used to print the progres
@@ -309,6 +336,47 @@ func convertSCIPToLSIFGraph(out *output.Output, inputFile, outputFile string) er
 	return nil
 }
 
+// readCodeIntelIndexFromStdin writes the index read from r to a file in a new
+// temporary directory, and returns its path. The file is named dump.lsif if
+// the index is in the LSIF format, and index.scip otherwise, as the conversion
+// between the formats and the upload go by the extension of the file.
+func readCodeIntelIndexFromStdin(r io.Reader) (string, error) {
+	br := bufio.NewReader(r)
+	name := "index.scip"
+	// LSIF dumps are JSON lines, whereas SCIP indexes are protobuf messages,
+	// which never start with '{'.
+	if prefix, _ := br.Peek(64); bytes.HasPrefix(bytes.TrimLeft(prefix, " \t\r\n"), []byte("{")) {
+		name = "dump.lsif"
+	}
+
+	dir, err := os.MkdirTemp("", "src-code-intel-upload-")
+	if err != nil {
+		return "", err
+	}
+	file := filepath.Join(dir, name)
+	n, err := writeCodeIntelIndex(file, br)
+	if err == nil && n == 0 {
+		err = errors.New("no index read from stdin")
+	}
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return "", err
+	}
+	return file, nil
+}
+
+func writeCodeIntelIndex(file string, r io.Reader) (int64, error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return n, errors.Wrap(err, "reading index from stdin")
+}
+
 func inferDefaultFile() (string, error) {
 	hasSCIP := true
 	const scipFilename = "index.scip"
@@ -477,6 +545,11 @@ func isFlagSet(fs *flag.FlagSet, name string) (found bool) {
 //
 // Note: This function must not be called before codeintelUploadFlagset.Parse.
 func inferIndexRoot() (string, error) {
+	if codeintelUploadFlags.stdinDir != "" {
+		// An index read from stdin is for the working directory, rather than
+		// for the temporary directory it's written to.
+		return codeintel.InferRoot("-")
+	}
 	return codeintel.InferRoot(codeintelUploadFlags.file)
 }
 
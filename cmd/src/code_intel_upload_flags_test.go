@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
 	"testing"
@@ -96,3 +97,25 @@ func TestReplaceBaseName(t *testing.T) {
 	require.Equal(t, filepath.Join("a", "d.e"),
 		replaceBaseName(filepath.Join("a", "b.c"), "d.e"))
 }
+
+func TestReadCodeIntelIndexFromStdin(t *testing.T) {
+	for _, tc := range []struct {
+		input    []byte
+		wantName string
+	}{
+		{input: exampleSCIPBytes(t), wantName: "index.scip"},
+		{input: []byte(exampleLSIFString), wantName: "dump.lsif"},
+	} {
+		file, err := readCodeIntelIndexFromStdin(bytes.NewReader(tc.input))
+		require.NoError(t, err)
+		t.Cleanup(func() { os.RemoveAll(filepath.Dir(file)) })
+
+		require.Equal(t, tc.wantName, filepath.Base(file))
+		contents, err := os.ReadFile(file)
+		require.NoError(t, err)
+		require.Equal(t, tc.input, contents)
+	}
+
+	_, err := readCodeIntelIndexFromStdin(bytes.NewReader(nil))
+	require.Error(t, err)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+	"github.com/sourcegraph/sourcegraph/lib/output"
+
+	"github.com/sourcegraph/src-cli/internal/api"
+)
+
+// codeintelUploadPollInterval is how often the processing state of an upload is
+// requested when -wait is set.
+const codeintelUploadPollInterval = 5 * time.Second
+
+const codeintelUploadStateQuery = `
+query CodeIntelUploadState($id: ID!) {
+	node(id: $id) {
+		... on LSIFUpload {
+			state
+			failure
+		}
+	}
+}
+`
+
+// codeintelUploadStateFunc returns the processing state of an upload and, if
+// processing failed, the reason.
+type codeintelUploadStateFunc func(ctx context.Context) (state, failure string, err error)
+
+// codeintelUploadState returns a codeintelUploadStateFunc that requests the state
+// of the upload with the given identifier from the API.
+func codeintelUploadState(client api.Client, uploadID int) codeintelUploadStateFunc {
+	id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("LSIFUpload:%d", uploadID)))
+
+	return func(ctx context.Context) (string, string, error) {
+		var result struct {
+			Node *struct {
+				State   string
+				Failure *string
+			}
+		}
+		if ok, err := client.NewRequest(codeintelUploadStateQuery, map[string]interface{}{
+			"id": id,
+		}).Do(ctx, &result); err != nil || !ok {
+			return "", "", err
+		}
+		if result.Node == nil {
+			return "", "", errors.Errorf("upload %d not found", uploadID)
+		}
+
+		var failure string
+		if result.Node.Failure != nil {
+			failure = *result.Node.Failure
+		}
+		return result.Node.State, failure, nil
+	}
+}
+
+// waitForCodeIntelUpload calls getState every interval until the upload has been
+// processed, and returns its final state. An error is returned if processing
+// failed, the upload was deleted, or it didn't finish within timeout. State
+// changes are written to out, unless it's nil.
+func waitForCodeIntelUpload(ctx context.Context, out *output.Output, getState codeintelUploadStateFunc, timeout, interval time.Duration) (string, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var last string
+	for {
+		state, failure, err := getState(ctx)
+		if err != nil {
+			if ctx.Err() == context.DeadlineExceeded {
+				return last, errors.Errorf("upload not processed within %s (last state: %s)", timeout, last)
+			}
+			return last, errors.Wrap(err, "checking upload state")
+		}
+
+		if state != last && out != nil {
+			out.WriteLine(output.Linef(output.EmojiHourglass, output.StyleItalic, "Upload state: %s", state))
+		}
+		last = state
+
+		switch state {
+		case "COMPLETED":
+			return state, nil
+		case "ERRORED":
+			if failure == "" {
+				failure = "unknown error"
+			}
+			return state, errors.Errorf("upload processing failed: %s", failure)
+		case "DELETING", "DELETED":
+			return state, errors.New("upload was deleted before processing completed")
+		}
+
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return last, errors.Errorf("upload not processed within %s (last state: %s)", timeout, last)
+			}
+			return last, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitForCodeIntelUpload(t *testing.T) {
+	states := func(states ...string) codeintelUploadStateFunc {
+		return func(ctx context.Context) (string, string, error) {
+			state := states[0]
+			if len(states) > 1 {
+				states = states[1:]
+			}
+			return state, "index is corrupt", nil
+		}
+	}
+
+	tests := []struct {
+		name      string
+		getState  codeintelUploadStateFunc
+		timeout   time.Duration
+		wantState string
+		wantErr   string
+	}{
+		{
+			name:      "completed",
+			getState:  states("QUEUED", "PROCESSING", "COMPLETED"),
+			wantState: "COMPLETED",
+		},
+		{
+			name:      "errored",
+			getState:  states("PROCESSING", "ERRORED"),
+			wantState: "ERRORED",
+			wantErr:   "upload processing failed: index is corrupt",
+		},
+		{
+			name:      "deleted",
+			getState:  states("DELETED"),
+			wantState: "DELETED",
+			wantErr:   "upload was deleted",
+		},
+		{
+			name:      "timeout",
+			getState:  states("QUEUED"),
+			timeout:   50 * time.Millisecond,
+			wantState: "QUEUED",
+			wantErr:   "upload not processed within 50ms (last state: QUEUED)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, err := waitForCodeIntelUpload(context.Background(), nil, tt.getState, tt.timeout, time.Millisecond)
+			if state != tt.wantState {
+				t.Errorf("state = %q, want %q", state, tt.wantState)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
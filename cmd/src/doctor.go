@@ -0,0 +1,224 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"time"
+
+	libapi "github.com/sourcegraph/sourcegraph/lib/api"
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
+	"github.com/sourcegraph/src-cli/internal/api"
+	"github.com/sourcegraph/src-cli/internal/cmderrors"
+	"github.com/sourcegraph/src-cli/internal/version"
+)
+
+func init() {
+	usage := `
+'src doctor' checks that src is configured correctly and can talk to the
+Sourcegraph instance. It checks:
+
+  - that the configuration (file and environment variables) can be read
+  - that the proxy, if configured, accepts connections
+  - that the Sourcegraph instance can be reached
+  - that the access token is valid
+  - that this version of src is at least the one recommended by the instance
+
+src exits with a non-zero code if a check fails.
+
+Examples:
+
+  Run the checks:
+
+    	$ src doctor
+
+  Print the results as JSON, for example to attach them to a support ticket:
+
+    	$ src doctor -json
+`
+
+	flagSet := flag.NewFlagSet("doctor", flag.ExitOnError)
+	var (
+		jsonFlag = flagSet.Bool("json", false, "Print the results as JSON.")
+		apiFlags = api.NewFlags(flagSet)
+	)
+
+	handler := func(args []string) error {
+		if err := flagSet.Parse(args); err != nil {
+			return err
+		}
+		if flagSet.NArg() != 0 {
+			return cmderrors.Usage("additional arguments not allowed")
+		}
+
+		var client api.Client
+		if cfg != nil {
+			client = cfg.apiClient(apiFlags, flagSet.Output())
+		}
+		checks := runDoctorChecks(context.Background(), cfg, cfgErr, client)
+
+		if *jsonFlag {
+			data, err := marshalIndent(checks)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+		} else {
+			for _, c := range checks {
+				fmt.Printf("[%s] %s: %s\n", c.Status, c.Name, c.Message)
+			}
+		}
+
+		for _, c := range checks {
+			if c.Status == doctorFail {
+				return cmderrors.ExitCode1
+			}
+		}
+		return nil
+	}
+
+	// Register the command.
+	commands = append(commands, &command{
+		flagSet: flagSet,
+		handler: handler,
+		usageFunc: func() {
+			fmt.Fprintf(flag.CommandLine.Output(), "Usage of 'src %s':\n", flagSet.Name())
+			flagSet.PrintDefaults()
+			fmt.Println(usage)
+		},
+		configOptional: true,
+	})
+}
+
+const (
+	doctorPass = "pass"
+	doctorFail = "fail"
+	doctorSkip = "skip"
+)
+
+// doctorCheck is the result of one of the checks of 'src doctor'.
+type doctorCheck struct {
+	Name    string `json:"name"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+// runDoctorChecks runs the checks of 'src doctor' against the configuration c,
+// which is nil if reading it failed with cfgErr. Checks that depend on a
+// failed check are skipped.
+func runDoctorChecks(ctx context.Context, c *config, cfgErr error, client api.Client) []doctorCheck {
+	var checks []doctorCheck
+	add := func(name, status, format string, args ...interface{}) {
+		checks = append(checks, doctorCheck{Name: name, Status: status, Message: fmt.Sprintf(format, args...)})
+	}
+	skipRest := func(reason string, names ...string) []doctorCheck {
+		for _, name := range names {
+			add(name, doctorSkip, "%s", reason)
+		}
+		return checks
+	}
+
+	// Configuration.
+	if c == nil {
+		if cfgErr == nil {
+			cfgErr = errors.New("no configuration")
+		}
+		add("configuration", doctorFail, "%s", cfgErr)
+		return skipRest("the configuration could not be read", "proxy", "endpoint", "access token", "version")
+	}
+	source := "environment variables and defaults"
+	if c.ConfigFilePath != "" {
+		source = c.ConfigFilePath
+	}
+	add("configuration", doctorPass, "read from %s, endpoint %s", source, c.Endpoint)
+
+	// Proxy. readConfig already rejects UNIX sockets that don't accept
+	// connections, but the socket may have gone away since.
+	switch {
+	case c.ProxyPath != "":
+		if ok, err := isValidUnixSocket(c.ProxyPath); err != nil {
+			add("proxy", doctorFail, "%s", err)
+		} else if !ok {
+			add("proxy", doctorFail, "socket %s does not exist", c.ProxyPath)
+		} else {
+			add("proxy", doctorPass, "socket %s accepts connections", c.ProxyPath)
+		}
+	case c.ProxyURL != nil:
+		address := proxyAddress(c.ProxyURL.Scheme, c.ProxyURL.Hostname(), c.ProxyURL.Port())
+		conn, err := net.DialTimeout("tcp", address, 10*time.Second)
+		if err != nil {
+			add("proxy", doctorFail, "%s", err)
+		} else {
+			conn.Close()
+			add("proxy", doctorPass, "%s accepts connections", address)
+		}
+	default:
+		add("proxy", doctorSkip, "no proxy configured")
+	}
+
+	// Endpoint.
+	if err := client.Ping(ctx); err != nil {
+		add("endpoint", doctorFail, "%s is not reachable: %s", c.Endpoint, err)
+		return skipRest("the endpoint is not reachable", "access token", "version")
+	}
+	add("endpoint", doctorPass, "%s is reachable", c.Endpoint)
+
+	// Access token.
+	if c.AccessToken == "" {
+		add("access token", doctorFail, "no access token configured, set SRC_ACCESS_TOKEN")
+	} else {
+		var result struct {
+			CurrentUser *struct {
+				Username string
+			}
+		}
+		if ok, err := client.NewQuery(`query DoctorCurrentUser { currentUser { username } }`).Do(ctx, &result); err != nil {
+			add("access token", doctorFail, "%s", err)
+		} else if !ok {
+			add("access token", doctorSkip, "no response")
+		} else if result.CurrentUser == nil {
+			add("access token", doctorFail, "the access token is not valid")
+		} else {
+			add("access token", doctorPass, "authenticated as %s", result.CurrentUser.Username)
+		}
+	}
+
+	// Version.
+	recommended, err := getRecommendedVersion(ctx, client)
+	switch {
+	case err != nil:
+		add("version", doctorFail, "getting the recommended version: %s", err)
+	case recommended == "":
+		add("version", doctorSkip, "the instance does not report a recommended version")
+	case version.BuildTag == version.DefaultBuildTag:
+		add("version", doctorSkip, "development build, the recommended version is %s", recommended)
+	default:
+		if ok, err := libapi.CheckSourcegraphVersion(version.BuildTag, ">= "+recommended+"-0", ""); err != nil {
+			add("version", doctorFail, "comparing versions: %s", err)
+		} else if !ok {
+			add("version", doctorFail, "src %s is older than the recommended version %s", version.BuildTag, recommended)
+		} else {
+			add("version", doctorPass, "src %s, the recommended version is %s", version.BuildTag, recommended)
+		}
+	}
+
+	return checks
+}
+
+// proxyAddress returns the address to connect to for a proxy URL, using the
+// default port of its scheme if it has none.
+func proxyAddress(scheme, host, port string) string {
+	if port == "" {
+		switch scheme {
+		case "https":
+			port = "443"
+		case "http":
+			port = "80"
+		default:
+			port = "1080"
+		}
+	}
+	return net.JoinHostPort(host, port)
+}
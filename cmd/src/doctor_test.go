@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
+	"github.com/sourcegraph/src-cli/internal/api"
+)
+
+func TestRunDoctorChecks(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+		case "/.api/graphql":
+			if r.Header.Get("Authorization") == "token valid" {
+				fmt.Fprint(w, `{"data":{"currentUser":{"username":"alice"}}}`)
+			} else {
+				fmt.Fprint(w, `{"data":{"currentUser":null}}`)
+			}
+		case "/.api/src-cli/version":
+			fmt.Fprint(w, `{"version":"5.0.0"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer s.Close()
+
+	statuses := func(checks []doctorCheck) map[string]string {
+		m := map[string]string{}
+		for _, c := range checks {
+			m[c.Name] = c.Status
+		}
+		return m
+	}
+	run := func(c *config) []doctorCheck {
+		flagSet := flag.NewFlagSet("test", flag.ExitOnError)
+		client := c.apiClient(api.NewFlags(flagSet), flagSet.Output())
+		return runDoctorChecks(context.Background(), c, nil, client)
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		checks := run(&config{Endpoint: s.URL, AccessToken: "valid"})
+		want := map[string]string{
+			"configuration": doctorPass,
+			"proxy":         doctorSkip,
+			"endpoint":      doctorPass,
+			"access token":  doctorPass,
+			// Tests run with the development build tag.
+			"version": doctorSkip,
+		}
+		if diff := cmp.Diff(want, statuses(checks)); diff != "" {
+			t.Errorf("wrong statuses (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		checks := run(&config{Endpoint: s.URL, AccessToken: "invalid"})
+		if got := statuses(checks)["access token"]; got != doctorFail {
+			t.Errorf("access token status = %q, want %q", got, doctorFail)
+		}
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		checks := run(&config{Endpoint: "http://127.0.0.1:0"})
+		want := map[string]string{
+			"configuration": doctorPass,
+			"proxy":         doctorSkip,
+			"endpoint":      doctorFail,
+			"access token":  doctorSkip,
+			"version":       doctorSkip,
+		}
+		if diff := cmp.Diff(want, statuses(checks)); diff != "" {
+			t.Errorf("wrong statuses (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("config error", func(t *testing.T) {
+		checks := runDoctorChecks(context.Background(), nil, errors.New("invalid proxy endpoint: ftp://proxy"), nil)
+		if checks[0].Status != doctorFail || !strings.Contains(checks[0].Message, "invalid proxy endpoint") {
+			t.Errorf("unexpected configuration check: %+v", checks[0])
+		}
+		if len(checks) != 5 {
+			t.Errorf("expected 5 checks, got %d", len(checks))
+		}
+	})
+}
+
+func TestProxyAddress(t *testing.T) {
+	for _, tc := range []struct{ scheme, host, port, want string }{
+		{"http", "proxy", "", "proxy:80"},
+		{"https", "proxy", "", "proxy:443"},
+		{"socks5", "proxy", "", "proxy:1080"},
+		{"http", "::1", "3128", "[::1]:3128"},
+	} {
+		if got := proxyAddress(tc.scheme, tc.host, tc.port); got != tc.want {
+			t.Errorf("proxyAddress(%q, %q, %q) = %q, want %q", tc.scheme, tc.host, tc.port, got, tc.want)
+		}
+	}
+}
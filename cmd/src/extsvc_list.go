@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/sourcegraph/src-cli/internal/api"
+	"github.com/sourcegraph/src-cli/internal/cmderrors"
 )
 
 func init() {
@@ -20,6 +21,15 @@ Examples:
 
     	$ src extsvc list -f '{{.ID}}'
 
+  List the external services whose last sync failed, with the error, and exit
+  with code 1 if there are any:
+
+    	$ src extsvc list -with-errors
+
+  List the sync status of all external services as JSON:
+
+    	$ src extsvc list -sync-status -json
+
 `
 
 	flagSet := flag.NewFlagSet("list", flag.ExitOnError)
@@ -29,9 +39,12 @@ Examples:
 		fmt.Println(usage)
 	}
 	var (
-		firstFlag  = flagSet.Int("first", -1, "Return only the first n external services. (use -1 for unlimited)")
-		formatFlag = flagSet.String("f", "", `Format for the output, using the syntax of Go package text/template. (e.g. "{{.|json}}")`)
-		apiFlags   = api.NewFlags(flagSet)
+		firstFlag      = flagSet.Int("first", -1, "Return only the first n external services. (use -1 for unlimited)")
+		formatFlag     = flagSet.String("f", "", `Format for the output, using the syntax of Go package text/template. (e.g. "{{.|json}}")`)
+		jsonFlag       = flagSet.Bool("json", false, "Print the external services as JSON.")
+		syncStatusFlag = flagSet.Bool("sync-status", false, "Include the status and error of the last sync of each external service.")
+		withErrorsFlag = flagSet.Bool("with-errors", false, "Only list the external services whose last sync failed, with their sync status.")
+		apiFlags       = api.NewFlags(flagSet)
 	)
 
 	handler := func(args []string) error {
@@ -39,11 +52,50 @@ Examples:
 			return err
 		}
 
+		if *jsonFlag && *formatFlag != "" {
+			return cmderrors.Usage("-json and -f can't be used together")
+		}
+
 		first := *firstFlag
 		if first == -1 {
 			first = 9999999 // GraphQL API doesn't support negative for unlimited query
 		}
 
+		ctx := context.Background()
+		client := cfg.apiClient(apiFlags, flagSet.Output())
+
+		queryVars := map[string]interface{}{
+			"first": first,
+		}
+
+		if *syncStatusFlag || *withErrorsFlag {
+			var result externalServicesSyncStatusResult
+			if ok, err := client.NewRequest(externalServicesSyncStatusQuery, queryVars).Do(ctx, &result); err != nil || !ok {
+				return err
+			}
+			statuses := extsvcSyncStatuses(result.ExternalServices.Nodes, *withErrorsFlag)
+			if err := printExtsvcSyncStatuses(statuses, *formatFlag, *jsonFlag); err != nil {
+				return err
+			}
+			if *withErrorsFlag && len(statuses) > 0 {
+				return cmderrors.ExitCode1
+			}
+			return nil
+		}
+
+		var result externalServicesListResult
+		if ok, err := client.NewRequest(externalServicesListQuery, queryVars).Do(ctx, &result); err != nil || !ok {
+			return err
+		}
+		if *jsonFlag {
+			data, err := marshalIndent(result.ExternalServices.Nodes)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
 		var formatStr string
 		if *formatFlag != "" {
 			formatStr = *formatFlag
@@ -55,17 +107,6 @@ Examples:
 		if err != nil {
 			return err
 		}
-
-		ctx := context.Background()
-		client := cfg.apiClient(apiFlags, flagSet.Output())
-
-		queryVars := map[string]interface{}{
-			"first": first,
-		}
-		var result externalServicesListResult
-		if ok, err := client.NewRequest(externalServicesListQuery, queryVars).Do(ctx, &result); err != nil || !ok {
-			return err
-		}
 		return execTemplate(tmpl, result.ExternalServices)
 	}
 
@@ -106,3 +147,108 @@ type externalServicesListResult struct {
 		}
 	}
 }
+
+const externalServicesSyncStatusQuery = `
+	query ($first: Int!) {
+		externalServices(first: $first) {
+			nodes {
+				id
+				kind
+				displayName
+				lastSyncAt
+				lastSyncError
+				syncJobs(first: 1) {
+					nodes {
+						state
+						finishedAt
+						failureMessage
+					}
+				}
+			}
+		}
+	}
+`
+
+type externalServicesSyncStatusResult struct {
+	ExternalServices struct {
+		Nodes []externalServiceSyncNode
+	}
+}
+
+type externalServiceSyncNode struct {
+	ID            string
+	Kind          string
+	DisplayName   string
+	LastSyncAt    *string
+	LastSyncError *string
+	SyncJobs      struct {
+		Nodes []struct {
+			State          string
+			FinishedAt     *string
+			FailureMessage *string
+		}
+	}
+}
+
+// extsvcSyncStatus is the sync status of an external service, as printed by
+// 'src extsvc list -sync-status'.
+type extsvcSyncStatus struct {
+	ID          string `json:"id"`
+	Kind        string `json:"kind"`
+	DisplayName string `json:"displayName"`
+	// State is the state of the latest sync job, such as COMPLETED or
+	// FAILED, or empty if the external service was never synced.
+	State      string `json:"state"`
+	LastSyncAt string `json:"lastSyncAt,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// extsvcSyncStatuses returns the sync statuses of nodes, or with onlyErrors
+// only those of the external services whose last sync failed.
+func extsvcSyncStatuses(nodes []externalServiceSyncNode, onlyErrors bool) []extsvcSyncStatus {
+	statuses := []extsvcSyncStatus{}
+	for _, node := range nodes {
+		status := extsvcSyncStatus{
+			ID:          node.ID,
+			Kind:        node.Kind,
+			DisplayName: node.DisplayName,
+		}
+		if node.LastSyncAt != nil {
+			status.LastSyncAt = *node.LastSyncAt
+		}
+		if node.LastSyncError != nil {
+			status.Error = *node.LastSyncError
+		}
+		if jobs := node.SyncJobs.Nodes; len(jobs) > 0 {
+			status.State = jobs[0].State
+			if status.Error == "" && jobs[0].FailureMessage != nil {
+				status.Error = *jobs[0].FailureMessage
+			}
+		}
+		if onlyErrors && status.Error == "" {
+			continue
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func printExtsvcSyncStatuses(statuses []extsvcSyncStatus, format string, asJSON bool) error {
+	if asJSON {
+		data, err := marshalIndent(statuses)
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if format == "" {
+		format = `{{range .}}ID: {{.ID}} | {{padRight .Kind 15 " "}} | {{padRight .DisplayName 30 " "}} | {{or .State "NEVER SYNCED"}}{{"\n"}}{{if .Error}}    error: {{.Error}}{{"\n"}}{{end}}{{end}}`
+	}
+	tmpl, err := parseTemplate(format)
+	if err != nil {
+		return err
+	}
+	return execTemplate(tmpl, statuses)
+}
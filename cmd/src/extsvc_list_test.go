@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtsvcSyncStatuses(t *testing.T) {
+	str := func(s string) *string { return &s }
+
+	node := func(id string, lastSyncError *string, state string, failureMessage *string) externalServiceSyncNode {
+		n := externalServiceSyncNode{ID: id, Kind: "GITHUB", DisplayName: id}
+		if state != "" {
+			n.SyncJobs.Nodes = append(n.SyncJobs.Nodes, struct {
+				State          string
+				FinishedAt     *string
+				FailureMessage *string
+			}{State: state, FailureMessage: failureMessage})
+		}
+		n.LastSyncError = lastSyncError
+		return n
+	}
+	nodes := []externalServiceSyncNode{
+		node("ok", nil, "COMPLETED", nil),
+		node("never", nil, "", nil),
+		node("sync-error", str("401 Unauthorized"), "COMPLETED", nil),
+		node("job-failed", nil, "FAILED", str("rate limited")),
+	}
+
+	all := extsvcSyncStatuses(nodes, false)
+	require.Equal(t, []extsvcSyncStatus{
+		{ID: "ok", Kind: "GITHUB", DisplayName: "ok", State: "COMPLETED"},
+		{ID: "never", Kind: "GITHUB", DisplayName: "never"},
+		{ID: "sync-error", Kind: "GITHUB", DisplayName: "sync-error", State: "COMPLETED", Error: "401 Unauthorized"},
+		{ID: "job-failed", Kind: "GITHUB", DisplayName: "job-failed", State: "FAILED", Error: "rate limited"},
+	}, all)
+
+	withErrors := extsvcSyncStatuses(nodes, true)
+	require.Equal(t, all[2:], withErrors)
+
+	require.Equal(t, []extsvcSyncStatus{}, extsvcSyncStatuses(nodes[:2], true))
+}
@@ -1,17 +1,22 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/csv"
 	"flag"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptrace"
 	"os"
 	"sort"
 	"strings"
 	"time"
 
+	"github.com/dustin/go-humanize"
 	"github.com/gorilla/websocket"
+	"github.com/sourcegraph/sourcegraph/lib/errors"
 
 	"github.com/sourcegraph/src-cli/internal/cmderrors"
 )
@@ -29,6 +34,7 @@ type Stats struct {
 type requestResult struct {
 	duration time.Duration
 	traceID  string // X-Trace header value
+	reused   bool   // whether the request was sent over an already open connection
 }
 
 func init() {
@@ -46,6 +52,23 @@ Examples:
     $ src gateway benchmark --gateway http://localhost:9992 --sourcegraph http://localhost:3082 --sgp <token>
     $ src gateway benchmark --requests 50 --csv results.csv --request-csv requests.csv --sgp <token>
     $ src gateway benchmark --gateway https://cody-gateway.sourcegraph.com --sourcegraph https://sourcegraph.com --sgp <token> --use-special-header
+    $ src gateway benchmark --requests 100 --max-p95 200ms --sgp <token>
+    $ src gateway benchmark --payload-sizes 1KB,64KB,1MB --sgp <token>
+    $ src gateway benchmark --grpc https://cody-gateway.sourcegraph.com:443 --sgp <token>
+
+By default, every request sends "ping" and expects "pong" in return. With
+-payload-sizes, the benchmark is run once for every size: each request sends
+a body of that many bytes and expects the endpoint to echo it back unchanged.
+Sizes are decimal (1KB is 1000 bytes) unless written in binary units (1KiB).
+
+The access token for -sourcegraph is read from SRC_ACCESS_TOKEN if --sgp is
+omitted. Use --sgp - to read it from standard input instead, so that it
+doesn't end up in your shell history or process listings:
+
+    $ pass show sourcegraph | src gateway benchmark --sourcegraph https://sourcegraph.com --sgp -
+
+The -grpc endpoint is benchmarked with the standard gRPC health checking
+service (grpc.health.v1.Health/Check), which the server must expose.
 `
 
 	flagSet := flag.NewFlagSet("benchmark", flag.ExitOnError)
@@ -56,8 +79,13 @@ Examples:
 		requestLevelCsvOutput = flagSet.String("request-csv", "", "Export request results to CSV file (provide filename)")
 		gatewayEndpoint       = flagSet.String("gateway", "", "Cody Gateway endpoint")
 		sgEndpoint            = flagSet.String("sourcegraph", "", "Sourcegraph endpoint")
-		sgpToken              = flagSet.String("sgp", "", "Sourcegraph personal access token for the called instance")
+		httpKeepAlive         = flagSet.Bool("http-keep-alive", true, "Reuse connections between HTTP requests, like the WebSocket and gRPC clients do. If false, every HTTP request opens a new connection")
+		payloadSizesFlag      = flagSet.String("payload-sizes", "", "Comma-separated request payload sizes to run the benchmark with, such as 1KB,64KB,1MB. Endpoints must echo the payload back")
+		grpcEndpoint          = flagSet.String("grpc", "", "gRPC endpoint, such as https://host:443. Use http:// for a connection without TLS")
+		sgpToken              = flagSet.String("sgp", "", "Sourcegraph personal access token for the called instance, or - to read it from standard input. Defaults to SRC_ACCESS_TOKEN")
 		useSpecialHeader      = flagSet.Bool("use-special-header", false, "Use special header to test the gateway")
+		maxP95                = flagSet.Duration("max-p95", 0, "Exit with a non-zero code if the P95 latency of any endpoint exceeds this duration")
+		maxAvg                = flagSet.Duration("max-avg", 0, "Exit with a non-zero code if the average latency of any endpoint exceeds this duration")
 	)
 
 	handler := func(args []string) error {
@@ -69,14 +97,26 @@ Examples:
 			return cmderrors.Usage("additional arguments not allowed")
 		}
 
+		if err := resolveGatewayToken(sgpToken, os.Stdin); err != nil {
+			return err
+		}
+
+		payloadSizes, err := parsePayloadSizes(*payloadSizesFlag)
+		if err != nil {
+			return cmderrors.Usage(err.Error())
+		}
+
 		if *useSpecialHeader {
 			fmt.Println("Using special header 'cody-core-gc-test'")
 		}
 
 		var (
 			httpClient = &http.Client{}
-			endpoints  = map[string]any{} // Values: URL `string`s or `*webSocketClient`s
+			endpoints  = map[string]any{} // Values: URL `string`s, `*webSocketClient`s or `*grpcClient`s
 		)
+		if !*httpKeepAlive {
+			httpClient.Transport = &http.Transport{DisableKeepAlives: true}
+		}
 		if *gatewayEndpoint != "" {
 			fmt.Println("Benchmarking Cody Gateway instance:", *gatewayEndpoint)
 			headers := http.Header{
@@ -115,48 +155,79 @@ Examples:
 			fmt.Println("warning: not benchmarking Sourcegraph instance (-sourcegraph endpoint not provided)")
 		}
 
+		if *grpcEndpoint != "" {
+			fmt.Println("Benchmarking gRPC endpoint:", *grpcEndpoint)
+			client, err := newGRPCClient(*grpcEndpoint, *sgpToken, *useSpecialHeader)
+			if err != nil {
+				return cmderrors.Usage(err.Error())
+			}
+			endpoints["grpc(s): ping"] = client
+		}
+
 		fmt.Printf("Starting benchmark with %d requests per endpoint...\n", *requestCount)
 
 		var eResults []endpointResult
 		rResults := map[string][]requestResult{}
-		for name, clientOrURL := range endpoints {
-			durations := make([]time.Duration, 0, *requestCount)
-			rResults[name] = make([]requestResult, 0, *requestCount)
-			fmt.Printf("\nTesting %s...", name)
-
-			for i := 0; i < *requestCount; i++ {
-				if ws, ok := clientOrURL.(*webSocketClient); ok {
-					result := benchmarkEndpointWebSocket(ws)
-					if result.duration > 0 {
-						durations = append(durations, result.duration)
-						rResults[name] = append(rResults[name], result)
+		for _, size := range payloadSizes {
+			payload, want := benchmarkPayload(size)
+			for endpointName, clientOrURL := range endpoints {
+				name := endpointName
+				if *payloadSizesFlag != "" {
+					name = fmt.Sprintf("%s [%s]", endpointName, humanize.Bytes(size))
+				}
+				if _, ok := clientOrURL.(*grpcClient); ok && size > 0 {
+					fmt.Printf("\nSkipping %s: the gRPC health check doesn't support payloads\n", name)
+					continue
+				}
+
+				durations := make([]time.Duration, 0, *requestCount)
+				var cold, warm []time.Duration
+				rResults[name] = make([]requestResult, 0, *requestCount)
+				fmt.Printf("\nTesting %s...", name)
+
+				for i := 0; i < *requestCount; i++ {
+					var result requestResult
+					switch c := clientOrURL.(type) {
+					case *webSocketClient:
+						result = benchmarkEndpointWebSocket(c, payload, want)
+					case *grpcClient:
+						result = benchmarkEndpointGRPC(c)
+					case string:
+						result = benchmarkEndpointHTTP(httpClient, c, *sgpToken, *useSpecialHeader, payload, want)
 					}
-				} else if url, ok := clientOrURL.(string); ok {
-					result := benchmarkEndpointHTTP(httpClient, url, *sgpToken, *useSpecialHeader)
 					if result.duration > 0 {
 						durations = append(durations, result.duration)
 						rResults[name] = append(rResults[name], result)
+						if result.reused {
+							warm = append(warm, result.duration)
+						} else {
+							cold = append(cold, result.duration)
+						}
 					}
 				}
+				fmt.Println()
+
+				stats := calculateStats(durations)
+
+				eResults = append(eResults, endpointResult{
+					name:       name,
+					avg:        stats.Avg,
+					median:     stats.Median,
+					p5:         stats.P5,
+					p75:        stats.P75,
+					p80:        stats.P80,
+					p95:        stats.P95,
+					total:      stats.Total,
+					successful: len(durations),
+					reconnects: len(cold),
+					coldAvg:    calculateStats(cold).Avg,
+					warmAvg:    calculateStats(warm).Avg,
+				})
 			}
-			fmt.Println()
-
-			stats := calculateStats(durations)
-
-			eResults = append(eResults, endpointResult{
-				name:       name,
-				avg:        stats.Avg,
-				median:     stats.Median,
-				p5:         stats.P5,
-				p75:        stats.P75,
-				p80:        stats.P80,
-				p95:        stats.P95,
-				total:      stats.Total,
-				successful: len(durations),
-			})
 		}
 
 		printResults(eResults, requestCount)
+		printConnectionResults(eResults)
 
 		if *csvOutput != "" {
 			if err := writeResultsToCSV(*csvOutput, eResults, requestCount); err != nil {
@@ -171,6 +242,14 @@ Examples:
 			fmt.Printf("\nRequest-level results exported to %s\n", *requestLevelCsvOutput)
 		}
 
+		if breaches := checkLatencyThresholds(eResults, *maxP95, *maxAvg); len(breaches) > 0 {
+			fmt.Println()
+			for _, b := range breaches {
+				fmt.Println(ansiColors["red"] + "SLA breach: " + b + ansiColors["nc"])
+			}
+			return cmderrors.ExitCode1
+		}
+
 		return nil
 	}
 
@@ -194,6 +273,9 @@ type webSocketClient struct {
 	URL         string
 	reqHeaders  http.Header
 	respHeaders http.Header
+
+	// used is whether a request has been sent over conn yet.
+	used bool
 }
 
 func (c *webSocketClient) reconnect() error {
@@ -209,6 +291,7 @@ func (c *webSocketClient) reconnect() error {
 		return fmt.Errorf("WebSocket dial(%s): %v", c.URL, err)
 	}
 	c.respHeaders = resp.Header
+	c.used = false
 	fmt.Println("Connected!")
 	return nil
 }
@@ -223,11 +306,99 @@ type endpointResult struct {
 	p95        time.Duration
 	total      time.Duration
 	successful int
+
+	// reconnects is the number of successful requests that had to open a new
+	// connection. Their latency is averaged in coldAvg, that of the requests
+	// that reused a connection in warmAvg.
+	reconnects int
+	coldAvg    time.Duration
+	warmAvg    time.Duration
+}
+
+// resolveGatewayToken replaces a token flag value of "-" with the first line of
+// stdin, and an empty one with SRC_ACCESS_TOKEN, so that tokens don't have to be
+// given on the command line.
+func resolveGatewayToken(token *string, stdin io.Reader) error {
+	switch *token {
+	case "-":
+		line, err := bufio.NewReader(stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return errors.Wrap(err, "reading token from standard input")
+		}
+		*token = strings.TrimSpace(line)
+		if *token == "" {
+			return cmderrors.Usage("no token given on standard input")
+		}
+	case "":
+		*token = os.Getenv("SRC_ACCESS_TOKEN")
+	}
+	return nil
+}
+
+// checkLatencyThresholds returns a description of every endpoint whose P95 or
+// average latency exceeds maxP95 or maxAvg, respectively. A zero threshold is
+// not checked. Endpoints without any successful request always breach a set
+// threshold, since their latency can't be measured.
+func checkLatencyThresholds(results []endpointResult, maxP95, maxAvg time.Duration) []string {
+	if maxP95 <= 0 && maxAvg <= 0 {
+		return nil
+	}
+
+	sorted := make([]endpointResult, len(results))
+	copy(sorted, results)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+
+	var breaches []string
+	for _, r := range sorted {
+		if r.successful == 0 {
+			breaches = append(breaches, fmt.Sprintf("%s: no successful requests", r.name))
+			continue
+		}
+		if maxP95 > 0 && r.p95 > maxP95 {
+			breaches = append(breaches, fmt.Sprintf("%s: P95 %s exceeds %s by %s", r.name, r.p95, maxP95, r.p95-maxP95))
+		}
+		if maxAvg > 0 && r.avg > maxAvg {
+			breaches = append(breaches, fmt.Sprintf("%s: average %s exceeds %s by %s", r.name, r.avg, maxAvg, r.avg-maxAvg))
+		}
+	}
+	return breaches
+}
+
+// parsePayloadSizes parses the comma-separated -payload-sizes flag. If it's
+// empty, the single size 0 is returned, which stands for the ping payload.
+func parsePayloadSizes(flag string) ([]uint64, error) {
+	if flag == "" {
+		return []uint64{0}, nil
+	}
+
+	var sizes []uint64
+	for _, s := range strings.Split(flag, ",") {
+		size, err := humanize.ParseBytes(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("invalid payload size %q: %v", s, err)
+		}
+		if size == 0 {
+			return nil, fmt.Errorf("invalid payload size %q: must be greater than zero", s)
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes, nil
 }
 
-func benchmarkEndpointHTTP(client *http.Client, url, accessToken string, useSpecialHeader bool) requestResult {
+// benchmarkPayload returns the request body to send for the given payload
+// size, and the response body expected in return. Size 0 is the ping
+// payload, any other size is expected to be echoed back.
+func benchmarkPayload(size uint64) (payload, want []byte) {
+	if size == 0 {
+		return []byte("ping"), []byte("pong")
+	}
+	payload = bytes.Repeat([]byte("x"), int(size))
+	return payload, payload
+}
+
+func benchmarkEndpointHTTP(client *http.Client, url, accessToken string, useSpecialHeader bool, payload, want []byte) requestResult {
 	start := time.Now()
-	req, err := http.NewRequest("POST", url, strings.NewReader("ping"))
+	req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
 	if err != nil {
 		fmt.Printf("Error creating request: %v\n", err)
 		return requestResult{}
@@ -238,6 +409,10 @@ func benchmarkEndpointHTTP(client *http.Client, url, accessToken string, useSpec
 	if useSpecialHeader {
 		req.Header.Set("cody-core-gc-test", "M2R{+6VI?1,M3n&<vpw1&AK>")
 	}
+	var reused bool
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused },
+	}))
 	resp, err := client.Do(req)
 	if err != nil {
 		fmt.Printf("Error calling %s: %v\n", url, err)
@@ -258,18 +433,19 @@ func benchmarkEndpointHTTP(client *http.Client, url, accessToken string, useSpec
 		fmt.Printf("Error reading response body: %v\n", err)
 		return requestResult{}
 	}
-	if string(body) != "pong" {
-		fmt.Printf("Expected 'pong' response, got: %q\n", string(body))
+	if !bytes.Equal(body, want) {
+		fmt.Printf("Expected %s response, got: %s\n", describePayload(want), describePayload(body))
 		return requestResult{}
 	}
 
 	return requestResult{
 		duration: time.Since(start),
 		traceID:  resp.Header.Get("X-Trace"),
+		reused:   reused,
 	}
 }
 
-func benchmarkEndpointWebSocket(client *webSocketClient) requestResult {
+func benchmarkEndpointWebSocket(client *webSocketClient, payload, want []byte) requestResult {
 	// Perform initial websocket connection, if needed.
 	if client.conn == nil {
 		if err := client.reconnect(); err != nil {
@@ -280,7 +456,7 @@ func benchmarkEndpointWebSocket(client *webSocketClient) requestResult {
 
 	// Perform the benchmarked request using the websocket.
 	start := time.Now()
-	err := client.conn.WriteMessage(websocket.TextMessage, []byte("ping"))
+	err := client.conn.WriteMessage(websocket.TextMessage, payload)
 	if err != nil {
 		fmt.Printf("WebSocket write error: %v\n", err)
 		if err := client.reconnect(); err != nil {
@@ -297,17 +473,29 @@ func benchmarkEndpointWebSocket(client *webSocketClient) requestResult {
 		}
 		return requestResult{}
 	}
-	if string(message) != "pong" {
-		fmt.Printf("Expected 'pong' response, got: %q\n", string(message))
+	if !bytes.Equal(message, want) {
+		fmt.Printf("Expected %s response, got: %s\n", describePayload(want), describePayload(message))
 		if err := client.reconnect(); err != nil {
 			fmt.Printf("Error reconnecting: %v\n", err)
 		}
 		return requestResult{}
 	}
+	reused := client.used
+	client.used = true
 	return requestResult{
 		duration: time.Since(start),
 		traceID:  client.respHeaders.Get("Content-Type"),
+		reused:   reused,
+	}
+}
+
+// describePayload formats a request or response body for error messages,
+// without dumping large payloads to the terminal.
+func describePayload(body []byte) string {
+	if len(body) > 16 {
+		return fmt.Sprintf("%d-byte", len(body))
 	}
+	return fmt.Sprintf("%q", body)
 }
 
 func calculateStats(durations []time.Duration) Stats {
@@ -442,6 +630,20 @@ func printResults(results []endpointResult, requestCount *int) {
 	}
 }
 
+func printConnectionResults(results []endpointResult) {
+	headerFmt := ansiColors["blue"] + "%-25s | %-10s | %-10s | %-10s" + ansiColors["nc"] + "\n"
+	fmt.Printf("\n"+headerFmt, "Endpoint    ", "Reconnects", "Cold avg", "Warm avg")
+	fmt.Println(ansiColors["blue"] + strings.Repeat("-", 64) + ansiColors["nc"])
+
+	for _, r := range results {
+		fmt.Printf("%-25s | %-10d | %-10s | %-10s\n",
+			r.name,
+			r.reconnects,
+			fmt.Sprintf("%.2fms", float64(r.coldAvg.Microseconds())/1000),
+			fmt.Sprintf("%.2fms", float64(r.warmAvg.Microseconds())/1000))
+	}
+}
+
 func writeResultsToCSV(filename string, results []endpointResult, requestCount *int) error {
 	file, err := os.Create(filename)
 	if err != nil {
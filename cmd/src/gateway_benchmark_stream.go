@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
@@ -31,6 +32,9 @@ Examples:
     $ src gateway benchmark-stream --requests 50 --csv results.csv --sgd <token> --sgp <token>
     $ src gateway benchmark-stream --gateway http://localhost:9992 --sourcegraph http://localhost:3082 --sgd <token> --sgp <token>
     $ src gateway benchmark-stream --requests 250 --gateway http://localhost:9992 --sourcegraph http://localhost:3082 --sgd <token> --sgp <token> --max-tokens 50 --provider fireworks --stream
+
+The access token for -sourcegraph is read from SRC_ACCESS_TOKEN if --sgp is
+omitted, or from standard input with --sgp -.
 `
 
 	flagSet := flag.NewFlagSet("benchmark-stream", flag.ExitOnError)
@@ -42,7 +46,7 @@ Examples:
 		gatewayEndpoint       = flagSet.String("gateway", "", "Cody Gateway endpoint")
 		sgEndpoint            = flagSet.String("sourcegraph", "", "Sourcegraph endpoint")
 		sgdToken              = flagSet.String("sgd", "", "Sourcegraph Dotcom user key for Cody Gateway")
-		sgpToken              = flagSet.String("sgp", "", "Sourcegraph personal access token for the called instance")
+		sgpToken              = flagSet.String("sgp", "", "Sourcegraph personal access token for the called instance, or - to read it from standard input. Defaults to SRC_ACCESS_TOKEN")
 		maxTokens             = flagSet.Int("max-tokens", 256, "Maximum number of tokens to generate")
 		provider              = flagSet.String("provider", "anthropic", "Provider to use for completion. Supported values: 'anthropic', 'fireworks'")
 		stream                = flagSet.Bool("stream", false, "Whether to stream completions. Default: false")
@@ -56,6 +60,9 @@ Examples:
 		if len(flagSet.Args()) != 0 {
 			return cmderrors.Usage("additional arguments not allowed")
 		}
+		if err := resolveGatewayToken(sgpToken, os.Stdin); err != nil {
+			return err
+		}
 		if *gatewayEndpoint != "" && *sgdToken == "" {
 			return cmderrors.Usage("must specify --sgp <Sourcegraph personal access token>")
 		}
@@ -234,7 +241,7 @@ func benchmarkCodeCompletion(client *http.Client, endpoint httpEndpoint) request
 	req, err := http.NewRequest("POST", endpoint.url, strings.NewReader(endpoint.body))
 	if err != nil {
 		fmt.Printf("Error creating request: %v\n", err)
-		return requestResult{0, ""}
+		return requestResult{}
 	}
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", endpoint.authHeader)
@@ -243,7 +250,7 @@ func benchmarkCodeCompletion(client *http.Client, endpoint httpEndpoint) request
 	resp, err := client.Do(req)
 	if err != nil {
 		fmt.Printf("Error calling %s: %v\n", endpoint.url, err)
-		return requestResult{0, ""}
+		return requestResult{}
 	}
 	defer func() {
 		err := resp.Body.Close()
@@ -254,12 +261,12 @@ func benchmarkCodeCompletion(client *http.Client, endpoint httpEndpoint) request
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
 		fmt.Printf("non-200 response: %v - %s\n", resp.Status, body)
-		return requestResult{0, ""}
+		return requestResult{}
 	}
 	_, err = io.ReadAll(resp.Body)
 	if err != nil {
 		fmt.Printf("Error reading response body: %v\n", err)
-		return requestResult{0, ""}
+		return requestResult{}
 	}
 
 	return requestResult{
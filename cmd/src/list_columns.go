@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
+	"github.com/sourcegraph/src-cli/internal/cmderrors"
+)
+
+// listFormats are the values of the -format flag of list commands that
+// support -columns.
+var listFormats = []string{"csv", "json"}
+
+// parseListColumnFlags validates the -format and -columns flags of a list
+// command and returns the selected columns.
+func parseListColumnFlags(format, columns string, available []string) ([]string, error) {
+	if format == "" {
+		if columns != "" {
+			return nil, cmderrors.Usage("-columns requires -format")
+		}
+		return nil, nil
+	}
+	if !slices.Contains(listFormats, format) {
+		return nil, cmderrors.Usagef("-format must be one of: %s", strings.Join(listFormats, ", "))
+	}
+	return parseColumns(columns, available)
+}
+
+// parseColumns returns the columns selected by the value of a -columns flag,
+// which is a comma-separated list of names out of available. An empty value
+// selects all available columns.
+func parseColumns(value string, available []string) ([]string, error) {
+	if value == "" {
+		return available, nil
+	}
+
+	var columns []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if !slices.Contains(available, name) {
+			return nil, cmderrors.Usagef("unknown column %q, available columns are: %s", name, strings.Join(available, ", "))
+		}
+		columns = append(columns, name)
+	}
+	return columns, nil
+}
+
+// writeColumns writes the given columns of rows to w, either as CSV with a
+// header, or as a JSON array of objects. List values are joined with
+// semicolons in CSV.
+func writeColumns(w io.Writer, format string, columns []string, rows []map[string]interface{}) error {
+	switch format {
+	case "csv":
+		cw := csv.NewWriter(w)
+		if err := cw.Write(columns); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			record := make([]string, len(columns))
+			for i, column := range columns {
+				switch v := row[column].(type) {
+				case []string:
+					record[i] = strings.Join(v, ";")
+				default:
+					record[i] = fmt.Sprint(v)
+				}
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case "json":
+		objects := make([]map[string]interface{}, 0, len(rows))
+		for _, row := range rows {
+			object := make(map[string]interface{}, len(columns))
+			for _, column := range columns {
+				object[column] = row[column]
+			}
+			objects = append(objects, object)
+		}
+		data, err := marshalIndent(objects)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	}
+
+	return errors.Errorf("unknown format %q", format)
+}
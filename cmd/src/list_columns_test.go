@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseListColumnFlags(t *testing.T) {
+	available := []string{"id", "username", "email"}
+
+	columns, err := parseListColumnFlags("csv", "email, username", available)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(columns, ","); got != "email,username" {
+		t.Errorf("wrong columns: %s", got)
+	}
+
+	columns, err = parseListColumnFlags("json", "", available)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(columns, ","); got != "id,username,email" {
+		t.Errorf("wrong default columns: %s", got)
+	}
+
+	for _, tc := range []struct{ format, columns string }{
+		{"", "email"},
+		{"xml", ""},
+		{"csv", "password"},
+	} {
+		if _, err := parseListColumnFlags(tc.format, tc.columns, available); err == nil {
+			t.Errorf("expected error for -format=%q -columns=%q", tc.format, tc.columns)
+		}
+	}
+}
+
+func TestWriteColumns(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"username": "alice", "siteAdmin": true, "organizations": []string{"a", "b"}},
+		{"username": "bob, jr", "siteAdmin": false, "organizations": []string{}},
+	}
+	columns := []string{"username", "siteAdmin", "organizations"}
+
+	var csvOut strings.Builder
+	if err := writeColumns(&csvOut, "csv", columns, rows); err != nil {
+		t.Fatal(err)
+	}
+	wantCSV := "username,siteAdmin,organizations\nalice,true,a;b\n\"bob, jr\",false,\n"
+	if csvOut.String() != wantCSV {
+		t.Errorf("wrong CSV. want=%q have=%q", wantCSV, csvOut.String())
+	}
+
+	var jsonOut strings.Builder
+	if err := writeColumns(&jsonOut, "json", []string{"username"}, rows); err != nil {
+		t.Fatal(err)
+	}
+	wantJSON := "[\n  {\n    \"username\": \"alice\"\n  },\n  {\n    \"username\": \"bob, jr\"\n  }\n]\n"
+	if jsonOut.String() != wantJSON {
+		t.Errorf("wrong JSON. want=%q have=%q", wantJSON, jsonOut.String())
+	}
+}
@@ -18,6 +18,7 @@ func init() {
 Usage:
 
     src login SOURCEGRAPH_URL
+    src login -check
 
 Examples:
 
@@ -28,6 +29,10 @@ Examples:
   Authenticate to Sourcegraph.com:
 
     $ src login https://sourcegraph.com
+
+  Check that the configured access token is still valid, without changing it:
+
+    $ src login -check
 `
 
 	flagSet := flag.NewFlagSet("login", flag.ExitOnError)
@@ -37,13 +42,21 @@ Examples:
 	}
 
 	var (
-		apiFlags = api.NewFlags(flagSet)
+		checkFlag = flagSet.Bool("check", false, "Verify the configured access token and print the user it belongs to, exiting with a non-zero status if it is invalid.")
+		apiFlags  = api.NewFlags(flagSet)
 	)
 
 	handler := func(args []string) error {
 		if err := flagSet.Parse(args); err != nil {
 			return err
 		}
+		if *checkFlag {
+			if flagSet.NArg() != 0 {
+				return cmderrors.Usage("-check uses SRC_ENDPOINT and does not accept a Sourcegraph URL")
+			}
+			return loginCheckCmd(context.Background(), cfg, cfg.apiClient(apiFlags, io.Discard), os.Stdout)
+		}
+
 		endpoint := cfg.Endpoint
 		if flagSet.NArg() >= 1 {
 			endpoint = flagSet.Arg(0)
@@ -125,3 +138,53 @@ func loginCmd(ctx context.Context, cfg *config, client api.Client, endpointArg s
 	fmt.Fprintln(out)
 	return nil
 }
+
+// loginCheckCmd verifies the access token that is already configured, and
+// prints the user it authenticates as. Unlike loginCmd, it doesn't explain how to
+// create a token, so that its output can be used in scripts.
+func loginCheckCmd(ctx context.Context, cfg *config, client api.Client, out io.Writer) error {
+	endpoint := cleanEndpoint(cfg.Endpoint)
+
+	printProblem := func(problem string) {
+		fmt.Fprintf(out, "❌ Problem: %s\n", problem)
+	}
+
+	if cfg.AccessToken == "" {
+		printProblem("No access token is configured. Run 'src login' for instructions.")
+		return cmderrors.ExitCode1
+	}
+
+	query := `query CurrentUser { currentUser { username siteAdmin primaryEmail { email } } }`
+	var result struct {
+		CurrentUser *struct {
+			Username     string
+			SiteAdmin    bool
+			PrimaryEmail *struct{ Email string }
+		}
+	}
+	if _, err := client.NewRequest(query, nil).Do(ctx, &result); err != nil {
+		if strings.HasPrefix(err.Error(), "error: 401 Unauthorized") || strings.HasPrefix(err.Error(), "error: 403 Forbidden") {
+			printProblem(fmt.Sprintf("The access token is invalid or expired on %s.", endpoint))
+		} else {
+			printProblem(fmt.Sprintf("Error communicating with %s: %s", endpoint, err))
+		}
+		return cmderrors.ExitCode1
+	}
+	if result.CurrentUser == nil {
+		printProblem(fmt.Sprintf("The access token is not associated with a user on %s.", endpoint))
+		return cmderrors.ExitCode1
+	}
+
+	email := "(none)"
+	if result.CurrentUser.PrimaryEmail != nil {
+		email = result.CurrentUser.PrimaryEmail.Email
+	}
+	siteAdmin := "no"
+	if result.CurrentUser.SiteAdmin {
+		siteAdmin = "yes"
+	}
+	fmt.Fprintf(out, "✔️  Authenticated as %s on %s\n", result.CurrentUser.Username, endpoint)
+	fmt.Fprintf(out, "   Email:      %s\n", email)
+	fmt.Fprintf(out, "   Site admin: %s\n", siteAdmin)
+	return nil
+}
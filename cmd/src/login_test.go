@@ -93,3 +93,68 @@ func TestLogin(t *testing.T) {
 		}
 	})
 }
+
+func TestLoginCheck(t *testing.T) {
+	check := func(t *testing.T, cfg *config) (output string, err error) {
+		t.Helper()
+
+		var out bytes.Buffer
+		err = loginCheckCmd(context.Background(), cfg, cfg.apiClient(nil, io.Discard), &out)
+		return strings.TrimSpace(out.String()), err
+	}
+
+	t.Run("no access token", func(t *testing.T) {
+		out, err := check(t, &config{Endpoint: "https://example.com"})
+		if err != cmderrors.ExitCode1 {
+			t.Fatal(err)
+		}
+		wantOut := "❌ Problem: No access token is configured. Run 'src login' for instructions."
+		if out != wantOut {
+			t.Errorf("got output %q, want %q", out, wantOut)
+		}
+	})
+
+	t.Run("invalid access token", func(t *testing.T) {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "", http.StatusUnauthorized)
+		}))
+		defer s.Close()
+
+		out, err := check(t, &config{Endpoint: s.URL, AccessToken: "x"})
+		if err != cmderrors.ExitCode1 {
+			t.Fatal(err)
+		}
+		wantOut := "❌ Problem: The access token is invalid or expired on " + s.URL + "."
+		if out != wantOut {
+			t.Errorf("got output %q, want %q", out, wantOut)
+		}
+	})
+
+	t.Run("no user", func(t *testing.T) {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, `{"data":{"currentUser":null}}`)
+		}))
+		defer s.Close()
+
+		_, err := check(t, &config{Endpoint: s.URL, AccessToken: "x"})
+		if err != cmderrors.ExitCode1 {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprintln(w, `{"data":{"currentUser":{"username":"alice","siteAdmin":true,"primaryEmail":{"email":"alice@example.com"}}}}`)
+		}))
+		defer s.Close()
+
+		out, err := check(t, &config{Endpoint: s.URL, AccessToken: "x"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantOut := "✔️  Authenticated as alice on " + s.URL + "\n   Email:      alice@example.com\n   Site admin: yes"
+		if out != wantOut {
+			t.Errorf("got output %q, want %q", out, wantOut)
+		}
+	})
+}
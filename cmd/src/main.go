@@ -40,10 +40,24 @@ Environment variables
 						- ~/src-proxy.sock
 						- %USERPROFILE%\src-proxy.sock
 						- C:\some\path\src-proxy.sock
+	SRC_PROXY_AUTH    The value of the Proxy-Authorization header sent to an HTTP(S) proxy,
+	                  such as "Basic <credentials>" or "Bearer <token>". Takes precedence
+	                  over credentials embedded in SRC_PROXY.
 
 The options are:
 
 	-v                               print verbose output
+	-json-errors                     print errors as JSON objects on stderr, for use in automation
+	-insecure-skip-verify            skip validation of TLS certificates against trusted chains
+	-cacert FILE                     trust the CA certificates in the PEM file FILE, in addition
+	                                 to the system ones
+
+-insecure-skip-verify and -cacert apply to all commands that send requests to
+Sourcegraph. Commands that accept the same flags use them instead, so they can
+override the global options. -insecure-skip-verify disables the protection TLS
+provides: anyone who can intercept the connection can impersonate the instance
+and read your access token. Prefer -cacert for instances that use a private or
+self-signed certificate.
 
 The commands are:
 
@@ -51,6 +65,7 @@ The commands are:
 	batch           manages batch changes
 	code-intel      manages code intelligence data
 	config          manages global, org, and user settings
+	doctor          checks the configuration and the connection to Sourcegraph
 	extensions,ext  manages extensions (experimental)
 	extsvc          manages external services
 	gateway         interacts with Cody Gateway
@@ -61,6 +76,7 @@ The commands are:
 	repos,repo      manages repositories
 	sbom            manages SBOM (Software Bill of Materials) data
 	search          search for results on Sourcegraph
+	search-saved    manages saved searches
 	serve-git       serves your local git repositories over HTTP for Sourcegraph to pull
 	users,user      manages users
 	codeowners      manages code ownership information
@@ -71,7 +87,11 @@ Use "src [command] -h" for more information about a command.
 `
 
 var (
-	verbose = flag.Bool("v", false, "print verbose output")
+	verbose    = flag.Bool("v", false, "print verbose output")
+	jsonErrors = flag.Bool("json-errors", false, "print errors as JSON objects on stderr")
+
+	insecureSkipVerify = flag.Bool("insecure-skip-verify", false, "skip validation of TLS certificates against trusted chains")
+	caCert             = flag.String("cacert", "", "trust the CA certificates in the given PEM file, in addition to the system ones")
 
 	// The following arguments are deprecated which is why they are no longer documented
 	configPath = flag.String("config", "", "")
@@ -79,6 +99,7 @@ var (
 
 	errConfigMerge                 = errors.New("when using a configuration file, zero or all environment variables must be set")
 	errConfigAuthorizationConflict = errors.New("when passing an 'Authorization' additional headers, SRC_ACCESS_TOKEN must never be set")
+	errConfigProxyAuth             = errors.New("SRC_PROXY_AUTH can only be used with an HTTP(S) proxy")
 )
 
 // commands contains all registered subcommands.
@@ -94,20 +115,36 @@ func main() {
 
 var cfg *config
 
+// cfgErr is the error reading the configuration, for commands with
+// configOptional set.
+var cfgErr error
+
 // config represents the config format.
 type config struct {
 	Endpoint          string            `json:"endpoint"`
 	AccessToken       string            `json:"accessToken"`
 	AdditionalHeaders map[string]string `json:"additionalHeaders"`
 	Proxy             string            `json:"proxy"`
+	ProxyAuth         string            `json:"proxyAuth"`
 	ProxyURL          *url.URL
 	ProxyPath         string
 	ConfigFilePath    string
+
+	// InsecureSkipVerify and CACert are set by the global flags of the same
+	// names.
+	InsecureSkipVerify bool
+	CACert             string
 }
 
 // apiClient returns an api.Client built from the configuration.
 func (c *config) apiClient(flags *api.Flags, out io.Writer) api.Client {
-	return api.NewClient(api.ClientOpts{
+	return api.NewClient(c.apiClientOpts(flags, out))
+}
+
+// apiClientOpts returns the options apiClient creates clients with, for
+// commands that need to adjust them.
+func (c *config) apiClientOpts(flags *api.Flags, out io.Writer) api.ClientOpts {
+	return api.ClientOpts{
 		Endpoint:          c.Endpoint,
 		AccessToken:       c.AccessToken,
 		AdditionalHeaders: c.AdditionalHeaders,
@@ -115,7 +152,11 @@ func (c *config) apiClient(flags *api.Flags, out io.Writer) api.Client {
 		Out:               out,
 		ProxyURL:          c.ProxyURL,
 		ProxyPath:         c.ProxyPath,
-	})
+		ProxyAuth:         c.ProxyAuth,
+
+		InsecureSkipVerify: c.InsecureSkipVerify,
+		CACert:             c.CACert,
+	}
 }
 
 // readConfig reads the config file from the given path.
@@ -147,6 +188,7 @@ func readConfig() (*config, error) {
 	envToken := os.Getenv("SRC_ACCESS_TOKEN")
 	envEndpoint := os.Getenv("SRC_ENDPOINT")
 	envProxy := os.Getenv("SRC_PROXY")
+	envProxyAuth := os.Getenv("SRC_PROXY_AUTH")
 
 	if userSpecified {
 		// If a config file is present, either zero or both required environment variables must be present.
@@ -173,6 +215,9 @@ func readConfig() (*config, error) {
 	if envProxy != "" {
 		cfg.Proxy = envProxy
 	}
+	if envProxyAuth != "" {
+		cfg.ProxyAuth = envProxyAuth
+	}
 
 	if cfg.Proxy != "" {
 
@@ -225,6 +270,15 @@ func readConfig() (*config, error) {
 		}
 	}
 
+	if cfg.ProxyAuth != "" {
+		if cfg.ProxyURL == nil || (cfg.ProxyURL.Scheme != "http" && cfg.ProxyURL.Scheme != "https") {
+			return nil, errConfigProxyAuth
+		}
+		if strings.ContainsAny(cfg.ProxyAuth, "\r\n") {
+			return nil, errors.New("SRC_PROXY_AUTH must not contain line breaks")
+		}
+	}
+
 	cfg.AdditionalHeaders = parseAdditionalHeaders()
 	// Ensure that we're not clashing additonal headers
 	_, hasAuthorizationAdditonalHeader := cfg.AdditionalHeaders["authorization"]
@@ -239,6 +293,14 @@ func readConfig() (*config, error) {
 
 	cfg.Endpoint = cleanEndpoint(cfg.Endpoint)
 
+	cfg.InsecureSkipVerify = *insecureSkipVerify
+	if *caCert != "" {
+		cfg.CACert, err = expandHomeDir(*caCert)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &cfg, nil
 }
 
@@ -35,6 +35,7 @@ func TestReadConfig(t *testing.T) {
 		envHeaders   string
 		envEndpoint  string
 		envProxy     string
+		envProxyAuth string
 		flagEndpoint string
 		want         *config
 		wantErr      string
@@ -177,6 +178,28 @@ func TestReadConfig(t *testing.T) {
 				AdditionalHeaders: map[string]string{},
 			},
 		},
+		{
+			name:         "proxy authorization from environment",
+			envProxy:     "https://proxy.com:8080",
+			envProxyAuth: "Bearer abc",
+			want: &config{
+				Endpoint:  "https://sourcegraph.com",
+				Proxy:     "https://proxy.com:8080",
+				ProxyAuth: "Bearer abc",
+				ProxyPath: "",
+				ProxyURL: &url.URL{
+					Scheme: "https",
+					Host:   "proxy.com:8080",
+				},
+				AdditionalHeaders: map[string]string{},
+			},
+		},
+		{
+			name:         "proxy authorization without HTTP proxy",
+			envProxy:     "socks5://localhost:1080",
+			envProxyAuth: "Bearer abc",
+			wantErr:      errConfigProxyAuth.Error(),
+		},
 		{
 			name:     "UNIX Domain Socket proxy using scheme and absolute path",
 			envProxy: "unix://" + socketPath,
@@ -297,6 +320,7 @@ func TestReadConfig(t *testing.T) {
 			setEnv("SRC_ACCESS_TOKEN", test.envToken)
 			setEnv("SRC_ENDPOINT", test.envEndpoint)
 			setEnv("SRC_PROXY", test.envProxy)
+			setEnv("SRC_PROXY_AUTH", test.envProxyAuth)
 
 			tmpDir := t.TempDir()
 			testHomeDir = tmpDir
@@ -21,6 +21,7 @@ The commands are:
 	create     creates an organization
 	delete     deletes an organization
 	members    manages organization members
+	settings   manages organization settings
 
 Use "src orgs [command] -h" for more information about a command.
 `
@@ -47,6 +48,7 @@ fragment OrgFields on Org {
     id
     name
     displayName
+    createdAt
     members {
         nodes {
 			id
@@ -60,7 +62,28 @@ type Org struct {
 	ID          string
 	Name        string
 	DisplayName string
+	CreatedAt   string
 	Members     struct {
 		Nodes []User
 	}
 }
+
+// orgColumns are the columns that can be selected with 'src orgs list
+// -columns'.
+var orgColumns = []string{"id", "name", "displayName", "createdAt", "memberCount", "members"}
+
+// columns returns the values of orgColumns for o.
+func (o Org) columns() map[string]interface{} {
+	members := []string{}
+	for _, m := range o.Members.Nodes {
+		members = append(members, m.Username)
+	}
+	return map[string]interface{}{
+		"id":          o.ID,
+		"name":        o.Name,
+		"displayName": o.DisplayName,
+		"createdAt":   o.CreatedAt,
+		"memberCount": len(members),
+		"members":     members,
+	}
+}
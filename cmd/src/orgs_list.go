@@ -4,6 +4,8 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/sourcegraph/src-cli/internal/api"
 )
@@ -24,6 +26,10 @@ Examples:
 
     	$ src orgs list -query='myquery'
 
+  Export the name and members of all organizations as JSON:
+
+    	$ src orgs list -first='-1' -format=json -columns=name,members
+
 `
 
 	flagSet := flag.NewFlagSet("list", flag.ExitOnError)
@@ -33,10 +39,12 @@ Examples:
 		fmt.Println(usage)
 	}
 	var (
-		firstFlag  = flagSet.Int("first", 1000, "Returns the first n organizations from the list. (use -1 for unlimited)")
-		queryFlag  = flagSet.String("query", "", `Returns organizations whose names match the query. (e.g. "alice")`)
-		formatFlag = flagSet.String("f", "{{.Name}}", `Format for the output, using the syntax of Go package text/template. (e.g. "{{.ID}}: {{.Name}} ({{.DisplayName}})" or "{{.|json}}")`)
-		apiFlags   = api.NewFlags(flagSet)
+		firstFlag     = flagSet.Int("first", 1000, "Returns the first n organizations from the list. (use -1 for unlimited)")
+		queryFlag     = flagSet.String("query", "", `Returns organizations whose names match the query. (e.g. "alice")`)
+		columnsFlag   = flagSet.String("columns", "", "Comma-separated columns to print with -format. Available: "+strings.Join(orgColumns, ", ")+". Default is all columns.")
+		outFormatFlag = flagSet.String("format", "", "Print the organizations as csv or json, with the columns selected by -columns, instead of using -f.")
+		formatFlag    = flagSet.String("f", "{{.Name}}", `Format for the output, using the syntax of Go package text/template. (e.g. "{{.ID}}: {{.Name}} ({{.DisplayName}})" or "{{.|json}}")`)
+		apiFlags      = api.NewFlags(flagSet)
 	)
 
 	handler := func(args []string) error {
@@ -46,6 +54,10 @@ Examples:
 
 		client := cfg.apiClient(apiFlags, flagSet.Output())
 
+		columns, err := parseListColumnFlags(*outFormatFlag, *columnsFlag, orgColumns)
+		if err != nil {
+			return err
+		}
 		tmpl, err := parseTemplate(*formatFlag)
 		if err != nil {
 			return err
@@ -77,6 +89,14 @@ Examples:
 			return err
 		}
 
+		if *outFormatFlag != "" {
+			rows := make([]map[string]interface{}, 0, len(result.Organizations.Nodes))
+			for _, org := range result.Organizations.Nodes {
+				rows = append(rows, org.columns())
+			}
+			return writeColumns(os.Stdout, *outFormatFlag, columns, rows)
+		}
+
 		for _, org := range result.Organizations.Nodes {
 			if err := execTemplate(tmpl, org); err != nil {
 				return err
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
+	"github.com/sourcegraph/src-cli/internal/api"
+)
+
+var orgsSettingsCommands commander
+
+func init() {
+	usage := `'src orgs settings' is a tool that manages organization settings on a Sourcegraph instance.
+
+Usage:
+
+	src orgs settings command [command options]
+
+The commands are:
+
+	get        prints the settings of an organization
+	set        replaces or merges the settings of an organization
+
+Use "src orgs settings [command] -h" for more information about a command.
+`
+
+	flagSet := flag.NewFlagSet("settings", flag.ExitOnError)
+	handler := func(args []string) error {
+		orgsSettingsCommands.run(flagSet, "src orgs settings", usage, args)
+		return nil
+	}
+
+	// Register the command.
+	orgsCommands = append(orgsCommands, &command{
+		flagSet: flagSet,
+		handler: handler,
+		usageFunc: func() {
+			fmt.Println(usage)
+		},
+	})
+}
+
+type orgSettings struct {
+	OrgID          string
+	LatestSettings *Settings
+}
+
+// getOrgSettings returns the ID and latest settings of the organization with
+// the given name. LatestSettings is nil if the organization has no settings.
+func getOrgSettings(ctx context.Context, client api.Client, name string) (*orgSettings, error) {
+	query := `query OrganizationSettings($name: String!) {
+  organization(name: $name) {
+    id
+    latestSettings {
+      id
+      contents
+    }
+  }
+}`
+
+	var result struct {
+		Organization *struct {
+			ID             string
+			LatestSettings *Settings
+		}
+	}
+	if _, err := client.NewRequest(query, map[string]interface{}{
+		"name": name,
+	}).Do(ctx, &result); err != nil {
+		return nil, err
+	}
+
+	if result.Organization == nil {
+		return nil, errors.Newf("organization %q not found", name)
+	}
+	return &orgSettings{
+		OrgID:          result.Organization.ID,
+		LatestSettings: result.Organization.LatestSettings,
+	}, nil
+}
+
+// mergeSettings deep-merges overlay into base: objects present in both are
+// merged recursively, and all other values in overlay replace the ones in base.
+func mergeSettings(base, overlay map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(base))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range overlay {
+		baseObj, baseIsObj := merged[k].(map[string]interface{})
+		overlayObj, overlayIsObj := v.(map[string]interface{})
+		if baseIsObj && overlayIsObj {
+			merged[k] = mergeSettings(baseObj, overlayObj)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/sourcegraph/src-cli/internal/api"
+	"github.com/sourcegraph/src-cli/internal/cmderrors"
+)
+
+func init() {
+	usage := `
+Examples:
+
+  Print the settings of the organization named abc-org:
+
+    	$ src orgs settings get abc-org
+
+  Save the settings of the organization named abc-org to a file:
+
+    	$ src orgs settings get abc-org > abc-org-settings.json
+
+`
+
+	flagSet := flag.NewFlagSet("get", flag.ExitOnError)
+	usageFunc := func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of 'src orgs settings %s':\n", flagSet.Name())
+		flagSet.PrintDefaults()
+		fmt.Println(usage)
+	}
+	var (
+		apiFlags = api.NewFlags(flagSet)
+	)
+
+	handler := func(args []string) error {
+		if err := flagSet.Parse(args); err != nil {
+			return err
+		}
+		if flagSet.NArg() != 1 {
+			return cmderrors.Usage("expected exactly one organization name")
+		}
+
+		client := cfg.apiClient(apiFlags, flagSet.Output())
+
+		settings, err := getOrgSettings(context.Background(), client, flagSet.Arg(0))
+		if err != nil {
+			return err
+		}
+
+		if settings.LatestSettings == nil {
+			fmt.Println("{}")
+			return nil
+		}
+		fmt.Println(settings.LatestSettings.Contents)
+		return nil
+	}
+
+	// Register the command.
+	orgsSettingsCommands = append(orgsSettingsCommands, &command{
+		flagSet:   flagSet,
+		handler:   handler,
+		usageFunc: usageFunc,
+	})
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
+	"github.com/sourcegraph/src-cli/internal/api"
+	"github.com/sourcegraph/src-cli/internal/cmderrors"
+)
+
+func init() {
+	usage := `
+Examples:
+
+  Replace the settings of the organization named abc-org with the contents of a file:
+
+    	$ src orgs settings set abc-org -f settings.json
+
+  Deep-merge the contents of a file into the current settings of abc-org:
+
+    	$ src orgs settings set abc-org -f settings.json -merge
+
+  The update fails if the settings were changed by someone else since they were
+  read, so concurrent updates never silently overwrite each other. Merging
+  removes comments from the settings.
+
+`
+
+	flagSet := flag.NewFlagSet("set", flag.ExitOnError)
+	usageFunc := func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of 'src orgs settings %s':\n", flagSet.Name())
+		flagSet.PrintDefaults()
+		fmt.Println(usage)
+	}
+	var (
+		fileFlag  = flagSet.String("f", "", "The file containing the settings JSON. Required.")
+		mergeFlag = flagSet.Bool("merge", false, "Deep-merge the settings in the file into the current settings instead of replacing them.")
+		apiFlags  = api.NewFlags(flagSet)
+	)
+
+	handler := func(args []string) error {
+		// Allow the organization name to come before the flags.
+		var name string
+		if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+			name, args = args[0], args[1:]
+		}
+		if err := flagSet.Parse(args); err != nil {
+			return err
+		}
+		if name == "" && flagSet.NArg() == 1 {
+			name = flagSet.Arg(0)
+		} else if flagSet.NArg() != 0 || name == "" {
+			return cmderrors.Usage("expected exactly one organization name")
+		}
+		if *fileFlag == "" {
+			return cmderrors.Usage("-f must be provided")
+		}
+
+		data, err := os.ReadFile(*fileFlag)
+		if err != nil {
+			return err
+		}
+		contents := string(data)
+		if _, err := jsonxToJSON(contents); err != nil {
+			return errors.Wrapf(err, "invalid settings in %s", *fileFlag)
+		}
+
+		ctx := context.Background()
+		client := cfg.apiClient(apiFlags, flagSet.Output())
+
+		current, err := getOrgSettings(ctx, client, name)
+		if err != nil {
+			return err
+		}
+
+		var lastID *int32
+		if current.LatestSettings != nil {
+			lastID = &current.LatestSettings.ID
+		}
+
+		if *mergeFlag && current.LatestSettings != nil {
+			var base, overlay map[string]interface{}
+			if err := jsonxUnmarshal(current.LatestSettings.Contents, &base); err != nil {
+				return errors.Wrap(err, "parsing current settings")
+			}
+			if err := jsonxUnmarshal(contents, &overlay); err != nil {
+				return errors.Wrapf(err, "parsing %s", *fileFlag)
+			}
+			merged, err := json.MarshalIndent(mergeSettings(base, overlay), "", "  ")
+			if err != nil {
+				return err
+			}
+			contents = string(merged)
+		}
+
+		query := `
+mutation OverwriteOrgSettings($input: SettingsMutationGroupInput!, $contents: String!) {
+  settingsMutation(input: $input) {
+    overwriteSettings(contents: $contents) {
+      empty {
+        alwaysNil
+      }
+    }
+  }
+}`
+		if _, err := client.NewRequest(query, map[string]interface{}{
+			"input": map[string]interface{}{
+				"subject": current.OrgID,
+				"lastID":  lastID,
+			},
+			"contents": contents,
+		}).Do(ctx, &struct{}{}); err != nil {
+			return err
+		}
+
+		fmt.Printf("Settings of organization %s updated.\n", name)
+		return nil
+	}
+
+	// Register the command.
+	orgsSettingsCommands = append(orgsSettingsCommands, &command{
+		flagSet:   flagSet,
+		handler:   handler,
+		usageFunc: usageFunc,
+	})
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMergeSettings(t *testing.T) {
+	base := map[string]interface{}{
+		"motd": []interface{}{"hello"},
+		"search.scopes": map[string]interface{}{
+			"a": "1",
+			"b": "2",
+		},
+		"experimentalFeatures": map[string]interface{}{"foo": true},
+	}
+	overlay := map[string]interface{}{
+		"motd": []interface{}{"goodbye"},
+		"search.scopes": map[string]interface{}{
+			"b": "3",
+			"c": "4",
+		},
+		"experimentalFeatures": false,
+	}
+
+	want := map[string]interface{}{
+		"motd": []interface{}{"goodbye"},
+		"search.scopes": map[string]interface{}{
+			"a": "1",
+			"b": "3",
+			"c": "4",
+		},
+		"experimentalFeatures": false,
+	}
+	if diff := cmp.Diff(want, mergeSettings(base, overlay)); diff != "" {
+		t.Errorf("unexpected merged settings (-want +got):\n%s", diff)
+	}
+}
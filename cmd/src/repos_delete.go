@@ -4,15 +4,24 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"os"
 
+	"github.com/mattn/go-isatty"
 	"github.com/sourcegraph/sourcegraph/lib/errors"
 
 	"github.com/sourcegraph/src-cli/internal/api"
+	"github.com/sourcegraph/src-cli/internal/cmderrors"
 )
 
 func init() {
 	flagSet := flag.NewFlagSet("delete", flag.ExitOnError)
-	apiFlags := api.NewFlags(flagSet)
+	var (
+		queryFlag  = flagSet.String("query", "", `Delete the repositories whose names match the query (e.g. "myorg/test-"), instead of the ones given as arguments. Requires -y or a confirmation.`)
+		yesFlag    = flagSet.Bool("y", false, "Delete the repositories matching -query without asking for confirmation.")
+		dryRunFlag = flagSet.Bool("dry-run", false, "Print the repositories that would be deleted, without deleting them.")
+		maxFlag    = flagSet.Int("max", 100, "Refuse to delete anything if -query matches more than this many repositories.")
+		apiFlags   = api.NewFlags(flagSet)
+	)
 
 	printUsage := func() {
 		fmt.Fprintf(flag.CommandLine.Output(), "Usage of 'src repos %s'\n", flagSet.Name())
@@ -25,30 +34,34 @@ Examples:
    Delete one or more repositories:
 
     	$ src repos delete github.com/my/repo github.com/my/repo2
+
+   List the repositories whose names match a query, and delete them after
+   confirmation:
+
+    	$ src repos delete -query 'github.com/my/test-'
+
+   Print the repositories that would be deleted:
+
+    	$ src repos delete -query 'github.com/my/test-' -dry-run
+
+   Delete the matching repositories without confirmation, such as in scripts:
+
+    	$ src repos delete -query 'github.com/my/test-' -y
 `
 		fmt.Fprint(flag.CommandLine.Output(), examples)
 	}
 
-	deleteRepository := func(ctx context.Context, client api.Client, repoName string) error {
-		repoID, err := fetchRepositoryID(ctx, client, repoName)
-		if err != nil {
-			return err
-		}
-
+	deleteRepository := func(ctx context.Context, client api.Client, repoID string) error {
 		query := `mutation DeleteRepository($repoID: ID!){
 			deleteRepository(repository: $repoID) {
 				alwaysNil
 			}
 		}`
 		var result struct{}
-		if ok, err := client.NewRequest(query, map[string]interface{}{
+		_, err := client.NewRequest(query, map[string]interface{}{
 			"repoID": repoID,
-		}).Do(ctx, &result); err != nil || !ok {
-			return err
-		}
-
-		fmt.Fprintf(flag.CommandLine.Output(), "Repository %q deleted\n", repoName)
-		return nil
+		}).Do(ctx, &result)
+		return err
 	}
 
 	deleteRepositories := func(args []string) error {
@@ -56,16 +69,67 @@ Examples:
 			return err
 		}
 
+		switch {
+		case *queryFlag != "" && flagSet.NArg() > 0:
+			return cmderrors.Usage("give either repository names or -query, not both")
+		case *queryFlag == "" && flagSet.NArg() == 0:
+			return cmderrors.Usage("expected repository names or -query")
+		}
+
 		ctx := context.Background()
 		client := cfg.apiClient(apiFlags, flagSet.Output())
+		out := flag.CommandLine.Output()
 
+		var repos []Repository
 		var errs errors.MultiError
-		for _, repoName := range flagSet.Args() {
-			err := deleteRepository(ctx, client, repoName)
+		if *queryFlag != "" {
+			var err error
+			repos, err = fetchRepositoriesToDelete(ctx, client, *queryFlag, *maxFlag)
+			if err != nil {
+				return err
+			}
+		} else {
+			for _, repoName := range flagSet.Args() {
+				repoID, err := fetchRepositoryID(ctx, client, repoName)
+				if err != nil {
+					errs = errors.Append(errs, errors.Wrapf(err, "Failed to delete repository %q", repoName))
+					continue
+				}
+				repos = append(repos, Repository{ID: repoID, Name: repoName})
+			}
+		}
+
+		if *dryRunFlag || *queryFlag != "" {
+			fmt.Fprintf(out, "%d repositories to delete:\n", len(repos))
+			for _, repo := range repos {
+				fmt.Fprintf(out, "  %s\n", repo.Name)
+			}
+		}
+		if *dryRunFlag {
+			return errs
+		}
+
+		// Explicit names are confirmation enough, but the repositories
+		// matching a query must be confirmed.
+		if *queryFlag != "" && !*yesFlag {
+			if !isatty.IsTerminal(os.Stdin.Fd()) {
+				return cmderrors.Usage("use -y to delete the repositories matching -query without confirmation")
+			}
+			ok, err := verify(fmt.Sprintf("Delete these %d repositories?", len(repos)))
 			if err != nil {
-				err = errors.Wrapf(err, "Failed to delete repository %q", repoName)
-				errs = errors.Append(errs, err)
+				return err
+			}
+			if !ok {
+				return errors.New("aborted")
+			}
+		}
+
+		for _, repo := range repos {
+			if err := deleteRepository(ctx, client, repo.ID); err != nil {
+				errs = errors.Append(errs, errors.Wrapf(err, "Failed to delete repository %q", repo.Name))
+				continue
 			}
+			fmt.Fprintf(out, "Repository %q deleted\n", repo.Name)
 		}
 		return errs
 	}
@@ -77,3 +141,38 @@ Examples:
 		usageFunc: printUsage,
 	})
 }
+
+// fetchRepositoriesToDelete returns the repositories whose names match query.
+// It fails if there are none, or more than max, so that an overly broad query
+// doesn't delete more than intended.
+func fetchRepositoriesToDelete(ctx context.Context, client api.Client, query string, max int) ([]Repository, error) {
+	gql := `query RepositoriesToDelete($first: Int, $query: String) {
+  repositories(first: $first, query: $query) {
+    nodes {
+      id
+      name
+    }
+  }
+}`
+
+	var result struct {
+		Repositories struct {
+			Nodes []Repository
+		}
+	}
+	if ok, err := client.NewRequest(gql, map[string]interface{}{
+		"first": max + 1,
+		"query": query,
+	}).Do(ctx, &result); err != nil || !ok {
+		return nil, err
+	}
+
+	repos := result.Repositories.Nodes
+	if len(repos) == 0 {
+		return nil, errors.Newf("no repositories match %q", query)
+	}
+	if len(repos) > max {
+		return nil, errors.Newf("more than %d repositories match %q, narrow the query or raise -max", max, query)
+	}
+	return repos, nil
+}
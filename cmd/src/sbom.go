@@ -8,7 +8,8 @@ import (
 var sbomCommands commander
 
 func init() {
-	usage := `'src sbom' fetches and verifies SBOM (Software Bill of Materials) data for Sourcegraph containers.
+	usage := `'src sbom' fetches and verifies SBOM (Software Bill of Materials) data for Sourcegraph containers,
+and validates SBOM files.
 
 Usage:
 
@@ -17,6 +18,7 @@ Usage:
 The commands are:
 
 	fetch                 fetch SBOMs for a released version of Sourcegraph
+	validate              validate an SPDX or CycloneDX SBOM file
 `
 	flagSet := flag.NewFlagSet("sbom", flag.ExitOnError)
 	handler := func(args []string) error {
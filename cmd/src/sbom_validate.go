@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+	"github.com/sourcegraph/sourcegraph/lib/output"
+
+	"github.com/sourcegraph/src-cli/internal/cmderrors"
+)
+
+func init() {
+	usage := `
+'src sbom validate' checks that an SBOM is well-formed before it is ingested.
+
+It detects whether the SBOM is an SPDX or CycloneDX JSON document, checks that
+the fields the format requires are set, and that every relationship or
+dependency references an element that exists in the SBOM.
+
+Usage:
+
+    src sbom validate -f <file> [-json]
+
+Examples:
+
+    $ src sbom validate -f sbom.json
+
+    $ cat sbom.json | src sbom validate -f - -json
+`
+
+	flagSet := flag.NewFlagSet("validate", flag.ExitOnError)
+	fileFlag := flagSet.String("f", "", "The SBOM file to validate, or - to read from standard input.")
+	jsonFlag := flagSet.Bool("json", false, "Print the result as JSON.")
+
+	handler := func(args []string) error {
+		if err := flagSet.Parse(args); err != nil {
+			return err
+		}
+
+		if len(flagSet.Args()) != 0 {
+			return cmderrors.Usage("additional arguments not allowed")
+		}
+		if *fileFlag == "" {
+			return cmderrors.Usage("file is required")
+		}
+
+		var data []byte
+		var err error
+		if *fileFlag == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(*fileFlag)
+		}
+		if err != nil {
+			return errors.Wrap(err, "reading SBOM")
+		}
+
+		result := validateSBOM(data)
+
+		if *jsonFlag {
+			data, err := marshalIndent(result)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+		} else {
+			out := output.NewOutput(flagSet.Output(), output.OutputOpts{Verbose: *verbose})
+			if len(result.Problems) == 0 {
+				out.WriteLine(output.Linef(output.EmojiSuccess, output.StyleSuccess, "%s is a valid %s %s SBOM", *fileFlag, result.Format, result.Version))
+			} else {
+				for _, p := range result.Problems {
+					if p.Path == "" {
+						out.WriteLine(output.Linef(output.EmojiFailure, output.StyleWarning, "%s", p.Message))
+					} else {
+						out.WriteLine(output.Linef(output.EmojiFailure, output.StyleWarning, "%s: %s", p.Path, p.Message))
+					}
+				}
+				out.Writef("Found %d problem(s) in %s %s SBOM %s", len(result.Problems), result.Format, result.Version, *fileFlag)
+			}
+		}
+
+		if len(result.Problems) > 0 {
+			return cmderrors.ExitCode1
+		}
+		return nil
+	}
+
+	sbomCommands = append(sbomCommands, &command{
+		flagSet: flagSet,
+		handler: handler,
+		usageFunc: func() {
+			fmt.Fprintf(flag.CommandLine.Output(), "Usage of 'src sbom %s':\n", flagSet.Name())
+			flagSet.PrintDefaults()
+			fmt.Println(usage)
+		},
+	})
+}
+
+// sbomValidationResult is the result of validating an SBOM.
+type sbomValidationResult struct {
+	Format   string        `json:"format"`
+	Version  string        `json:"version"`
+	Problems []sbomProblem `json:"problems"`
+}
+
+// sbomProblem is a problem found in an SBOM. Path is a JSON path to the
+// offending element, if there is one.
+type sbomProblem struct {
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func (r *sbomValidationResult) problem(path, format string, args ...interface{}) {
+	r.Problems = append(r.Problems, sbomProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// validateSBOM detects the format of the SBOM in data and validates it.
+func validateSBOM(data []byte) sbomValidationResult {
+	result := sbomValidationResult{Format: "unknown", Problems: []sbomProblem{}}
+
+	var header struct {
+		BOMFormat   string `json:"bomFormat"`
+		SPDXVersion string `json:"spdxVersion"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		result.problem("", "not a JSON document: %s", err)
+		return result
+	}
+
+	switch {
+	case header.BOMFormat != "":
+		result.Format = "CycloneDX"
+		validateCycloneDX(data, &result)
+	case header.SPDXVersion != "":
+		result.Format = "SPDX"
+		validateSPDX(data, &result)
+	default:
+		result.problem("", "unknown SBOM format: expected an SPDX document with spdxVersion or a CycloneDX document with bomFormat")
+	}
+	return result
+}
+
+type spdxDocument struct {
+	SPDXVersion       string `json:"spdxVersion"`
+	DataLicense       string `json:"dataLicense"`
+	SPDXID            string `json:"SPDXID"`
+	Name              string `json:"name"`
+	DocumentNamespace string `json:"documentNamespace"`
+	CreationInfo      *struct {
+		Created  string   `json:"created"`
+		Creators []string `json:"creators"`
+	} `json:"creationInfo"`
+	DocumentDescribes []string `json:"documentDescribes"`
+	Packages          []struct {
+		SPDXID string `json:"SPDXID"`
+		Name   string `json:"name"`
+	} `json:"packages"`
+	Files []struct {
+		SPDXID   string `json:"SPDXID"`
+		FileName string `json:"fileName"`
+	} `json:"files"`
+	Relationships []struct {
+		SPDXElementID      string `json:"spdxElementId"`
+		RelatedSPDXElement string `json:"relatedSpdxElement"`
+		RelationshipType   string `json:"relationshipType"`
+	} `json:"relationships"`
+}
+
+func validateSPDX(data []byte, result *sbomValidationResult) {
+	var doc spdxDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		result.problem("", "invalid SPDX document: %s", err)
+		return
+	}
+	result.Version = strings.TrimPrefix(doc.SPDXVersion, "SPDX-")
+
+	if !strings.HasPrefix(doc.SPDXVersion, "SPDX-") {
+		result.problem("spdxVersion", "must start with SPDX-, got %q", doc.SPDXVersion)
+	}
+	if doc.DataLicense == "" {
+		result.problem("dataLicense", "is required")
+	}
+	if doc.SPDXID != "SPDXRef-DOCUMENT" {
+		result.problem("SPDXID", "must be SPDXRef-DOCUMENT, got %q", doc.SPDXID)
+	}
+	if doc.Name == "" {
+		result.problem("name", "is required")
+	}
+	if doc.DocumentNamespace == "" {
+		result.problem("documentNamespace", "is required")
+	}
+	if doc.CreationInfo == nil {
+		result.problem("creationInfo", "is required")
+	} else {
+		if doc.CreationInfo.Created == "" {
+			result.problem("creationInfo.created", "is required")
+		}
+		if len(doc.CreationInfo.Creators) == 0 {
+			result.problem("creationInfo.creators", "must list at least one creator")
+		}
+	}
+
+	ids := map[string]bool{}
+	if doc.SPDXID != "" {
+		ids[doc.SPDXID] = true
+	}
+	addID := func(path, id string) {
+		if id == "" {
+			result.problem(path+".SPDXID", "is required")
+		} else if ids[id] {
+			result.problem(path+".SPDXID", "duplicate identifier %q", id)
+		}
+		ids[id] = true
+	}
+	for i, p := range doc.Packages {
+		path := fmt.Sprintf("packages[%d]", i)
+		addID(path, p.SPDXID)
+		if p.Name == "" {
+			result.problem(path+".name", "is required")
+		}
+	}
+	for i, f := range doc.Files {
+		path := fmt.Sprintf("files[%d]", i)
+		addID(path, f.SPDXID)
+		if f.FileName == "" {
+			result.problem(path+".fileName", "is required")
+		}
+	}
+
+	// Elements of other documents are referenced as DocumentRef-X:SPDXRef-Y,
+	// and can't be checked.
+	exists := func(id string) bool {
+		return ids[id] || strings.HasPrefix(id, "DocumentRef-")
+	}
+	for i, id := range doc.DocumentDescribes {
+		if !exists(id) {
+			result.problem(fmt.Sprintf("documentDescribes[%d]", i), "references unknown element %q", id)
+		}
+	}
+	for i, r := range doc.Relationships {
+		path := fmt.Sprintf("relationships[%d]", i)
+		if r.RelationshipType == "" {
+			result.problem(path+".relationshipType", "is required")
+		}
+		if !exists(r.SPDXElementID) {
+			result.problem(path+".spdxElementId", "references unknown element %q", r.SPDXElementID)
+		}
+		if r.RelatedSPDXElement != "NOASSERTION" && r.RelatedSPDXElement != "NONE" && !exists(r.RelatedSPDXElement) {
+			result.problem(path+".relatedSpdxElement", "references unknown element %q", r.RelatedSPDXElement)
+		}
+	}
+}
+
+type cycloneDXComponent struct {
+	Type       string               `json:"type"`
+	Name       string               `json:"name"`
+	BOMRef     string               `json:"bom-ref"`
+	Components []cycloneDXComponent `json:"components"`
+}
+
+type cycloneDXDocument struct {
+	BOMFormat   string `json:"bomFormat"`
+	SpecVersion string `json:"specVersion"`
+	Metadata    *struct {
+		Component *cycloneDXComponent `json:"component"`
+	} `json:"metadata"`
+	Components   []cycloneDXComponent `json:"components"`
+	Dependencies []struct {
+		Ref       string   `json:"ref"`
+		DependsOn []string `json:"dependsOn"`
+	} `json:"dependencies"`
+}
+
+func validateCycloneDX(data []byte, result *sbomValidationResult) {
+	var doc cycloneDXDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		result.problem("", "invalid CycloneDX document: %s", err)
+		return
+	}
+	result.Version = doc.SpecVersion
+
+	if doc.BOMFormat != "CycloneDX" {
+		result.problem("bomFormat", "must be CycloneDX, got %q", doc.BOMFormat)
+	}
+	if doc.SpecVersion == "" {
+		result.problem("specVersion", "is required")
+	}
+
+	refs := map[string]bool{}
+	var walk func(path string, c cycloneDXComponent)
+	walk = func(path string, c cycloneDXComponent) {
+		if c.Type == "" {
+			result.problem(path+".type", "is required")
+		}
+		if c.Name == "" {
+			result.problem(path+".name", "is required")
+		}
+		if c.BOMRef != "" {
+			if refs[c.BOMRef] {
+				result.problem(path+".bom-ref", "duplicate reference %q", c.BOMRef)
+			}
+			refs[c.BOMRef] = true
+		}
+		for i, child := range c.Components {
+			walk(fmt.Sprintf("%s.components[%d]", path, i), child)
+		}
+	}
+	if doc.Metadata != nil && doc.Metadata.Component != nil {
+		walk("metadata.component", *doc.Metadata.Component)
+	}
+	for i, c := range doc.Components {
+		walk(fmt.Sprintf("components[%d]", i), c)
+	}
+
+	for i, d := range doc.Dependencies {
+		path := fmt.Sprintf("dependencies[%d]", i)
+		if !refs[d.Ref] {
+			result.problem(path+".ref", "references unknown component %q", d.Ref)
+		}
+		for j, ref := range d.DependsOn {
+			if !refs[ref] {
+				result.problem(fmt.Sprintf("%s.dependsOn[%d]", path, j), "references unknown component %q", ref)
+			}
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidateSBOM(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		sbom string
+		want sbomValidationResult
+	}{
+		{
+			name: "valid SPDX",
+			sbom: `{
+				"spdxVersion": "SPDX-2.3",
+				"dataLicense": "CC0-1.0",
+				"SPDXID": "SPDXRef-DOCUMENT",
+				"name": "frontend",
+				"documentNamespace": "https://sourcegraph.com/frontend",
+				"creationInfo": {"created": "2024-01-01T00:00:00Z", "creators": ["Tool: syft"]},
+				"documentDescribes": ["SPDXRef-frontend"],
+				"packages": [{"SPDXID": "SPDXRef-frontend", "name": "frontend"}, {"SPDXID": "SPDXRef-zlib", "name": "zlib"}],
+				"relationships": [
+					{"spdxElementId": "SPDXRef-frontend", "relatedSpdxElement": "SPDXRef-zlib", "relationshipType": "DEPENDS_ON"},
+					{"spdxElementId": "SPDXRef-zlib", "relatedSpdxElement": "NOASSERTION", "relationshipType": "DEPENDS_ON"}
+				]
+			}`,
+			want: sbomValidationResult{Format: "SPDX", Version: "2.3", Problems: []sbomProblem{}},
+		},
+		{
+			name: "invalid SPDX",
+			sbom: `{
+				"spdxVersion": "SPDX-2.3",
+				"SPDXID": "SPDXRef-DOCUMENT",
+				"name": "frontend",
+				"documentNamespace": "https://sourcegraph.com/frontend",
+				"packages": [{"SPDXID": "SPDXRef-frontend", "name": "frontend"}, {"SPDXID": "SPDXRef-frontend"}],
+				"relationships": [{"spdxElementId": "SPDXRef-frontend", "relatedSpdxElement": "SPDXRef-zlib", "relationshipType": "DEPENDS_ON"}]
+			}`,
+			want: sbomValidationResult{Format: "SPDX", Version: "2.3", Problems: []sbomProblem{
+				{Path: "dataLicense", Message: "is required"},
+				{Path: "creationInfo", Message: "is required"},
+				{Path: "packages[1].SPDXID", Message: `duplicate identifier "SPDXRef-frontend"`},
+				{Path: "packages[1].name", Message: "is required"},
+				{Path: "relationships[0].relatedSpdxElement", Message: `references unknown element "SPDXRef-zlib"`},
+			}},
+		},
+		{
+			name: "valid CycloneDX",
+			sbom: `{
+				"bomFormat": "CycloneDX",
+				"specVersion": "1.5",
+				"metadata": {"component": {"type": "container", "name": "frontend", "bom-ref": "frontend"}},
+				"components": [{"type": "library", "name": "zlib", "bom-ref": "zlib", "components": [{"type": "file", "name": "zlib.h", "bom-ref": "zlib.h"}]}],
+				"dependencies": [{"ref": "frontend", "dependsOn": ["zlib"]}, {"ref": "zlib", "dependsOn": ["zlib.h"]}]
+			}`,
+			want: sbomValidationResult{Format: "CycloneDX", Version: "1.5", Problems: []sbomProblem{}},
+		},
+		{
+			name: "invalid CycloneDX",
+			sbom: `{
+				"bomFormat": "CycloneDX",
+				"components": [{"type": "library", "bom-ref": "zlib"}, {"type": "library", "name": "openssl", "bom-ref": "zlib"}],
+				"dependencies": [{"ref": "frontend", "dependsOn": ["zlib", "curl"]}]
+			}`,
+			want: sbomValidationResult{Format: "CycloneDX", Problems: []sbomProblem{
+				{Path: "specVersion", Message: "is required"},
+				{Path: "components[0].name", Message: "is required"},
+				{Path: "components[1].bom-ref", Message: `duplicate reference "zlib"`},
+				{Path: "dependencies[0].ref", Message: `references unknown component "frontend"`},
+				{Path: "dependencies[0].dependsOn[1]", Message: `references unknown component "curl"`},
+			}},
+		},
+		{
+			name: "unknown format",
+			sbom: `{"name": "frontend"}`,
+			want: sbomValidationResult{Format: "unknown", Problems: []sbomProblem{
+				{Message: "unknown SBOM format: expected an SPDX document with spdxVersion or a CycloneDX document with bomFormat"},
+			}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, validateSBOM([]byte(tt.sbom))); diff != "" {
+				t.Errorf("unexpected result (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
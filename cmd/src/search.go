@@ -18,6 +18,8 @@ import (
 	isatty "github.com/mattn/go-isatty"
 	"jaytaylor.com/html2text"
 
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
 	"github.com/sourcegraph/src-cli/internal/api"
 	"github.com/sourcegraph/src-cli/internal/cmderrors"
 	"github.com/sourcegraph/src-cli/internal/streaming"
@@ -37,6 +39,61 @@ Examples:
 
     	$ src search -json 'repogroup:sample error'
 
+  Perform a search in a single repository:
+
+    	$ src search -repo github.com/sourcegraph/src-cli 'error'
+
+  Print the number of matches of a query, for example for monitoring:
+
+    	$ src search -count-only 'repo:^github\.com/sourcegraph/src-cli$ TODO'
+
+  List the repositories matching a query (see 'src search repos -h'):
+
+    	$ src search repos -query 'file:^Dockerfile$'
+
+  Save a query and run it by name later (see 'src search-saved -h'):
+
+    	$ src search-saved save todos 'TODO' -stream
+    	$ src search-saved run todos
+
+  Stop a search after 30 seconds, printing the results found until then:
+
+    	$ src search -stream -timeout 30s 'repogroup:sample error'
+
+  -timeout bounds how long the search runs, while -display only limits how
+  many of the results are printed. The match counts reported by the server
+  include the results that weren't displayed.
+
+  Perform a streaming search and run the query suggested by the server, if any:
+
+    	$ src search -stream -follow-suggestion 'repogroup:sample error'
+
+  Export the file matches of a query as CSV, for example for a spreadsheet:
+
+    	$ src search -csv 'type:file error' > matches.csv
+
+  Print the repository, path and (0-based) line of every content match:
+
+    	$ src search -template '{{range $i, $c := .ChunkMatches}}{{if $i}}{{"\n"}}{{end}}{{$.Repository}}:{{$.Path}}:{{$c.ContentStart.Line}}{{end}}' 'type:file error'
+
+Templates:
+
+  With -template, the template is executed for every match, with the match
+  as its data. Which fields a match has depends on its type, given by .Type:
+
+    content  .Repository .Path .Commit .Branches .ChunkMatches, where every
+             chunk has .Content, .ContentStart and .Ranges. Lines and
+             columns are 0-based.
+    path     .Repository .Path .Commit .Branches
+    symbol   .Repository .Path .Commit .Branches .Symbols, where every symbol
+             has .Name .Kind .ContainerName .URL
+    repo     .Repository .Branches
+    commit   .Label .URL .Detail .Content .Ranges
+
+  Matches without a field used by the template are reported on stderr and
+  skipped. The same functions as in other src templates, such as color, are
+  available.
+
 Other tips:
 
   Make 'type:diff' searches have colored diffs by installing https://colordiff.org
@@ -44,6 +101,10 @@ Other tips:
     - Mac OS:        $ brew install colordiff
     - Windows:       $ npm install -g colordiff
 
+  For predictable diffs in scripts, use -stream with -diff-color=always or
+  -diff-color=never, which don't depend on COLORDIFF, NO_COLOR or colordiff,
+  and -context-lines to limit the context around changed lines.
+
   Disable color output by setting NO_COLOR=t (see https://no-color.org).
 
   Force color output on (not on by default when piped to other programs) by setting COLOR=t
@@ -63,8 +124,19 @@ Other tips:
 		explainJSONFlag = flagSet.Bool("explain-json", false, "Explain the JSON output schema and exit.")
 		apiFlags        = api.NewFlags(flagSet)
 		lessFlag        = flagSet.Bool("less", true, "Pipe output to 'less -R' (only if stdout is terminal, and not json flag).")
-		streamFlag      = flagSet.Bool("stream", false, "Consume results as stream. Streaming search only supports a subset of flags and parameters: trace, insecure-skip-verify, display, json.")
+		streamFlag      = flagSet.Bool("stream", false, "Consume results as stream. Streaming search only supports a subset of flags and parameters: trace, insecure-skip-verify, display, json. Falls back to the GraphQL API if the instance does not support streaming search.")
 		display         = flagSet.Int("display", -1, "Limit the number of results that are displayed. Only supported together with stream flag. Statistics continue to report all results.")
+		followFlag      = flagSet.Bool("follow-suggestion", false, "If the search returns an alert proposing exactly one query, run that query as well. Only supported together with stream flag.")
+		templateFlag    = flagSet.String("template", "", "A Go template executed for every match, or @FILE to read the template from a file. Implies -stream. See the usage for the available fields.")
+		countOnlyFlag   = flagSet.Bool("count-only", false, "Print only the number of matches, and exit with a non-zero code if there are none. Implies -stream. With -json, the number of searched repositories is printed as well.")
+		csvFlag         = flagSet.Bool("csv", false, "Print file matches as CSV rows of repository, path, line, column and matched text, with a header. Implies -stream. Other types of matches are skipped.")
+		includePathFlag = flagSet.String("include-path", "", "Only display matches in files whose path matches this glob, such as 'cmd/**/*.go'. Applied to the results on the client. Only supported together with stream flag.")
+		excludePathFlag = flagSet.String("exclude-path", "", "Don't display matches in files whose path matches this glob. Applied to the results on the client. Only supported together with stream flag.")
+		repoFilterFlag  = flagSet.String("repo-filter", "", "Only display matches in repositories whose name matches this regular expression. Applied to the results on the client. Only supported together with stream flag.")
+		timeoutFlag     = flagSet.Duration("timeout", 0, "Bound how long the search may take, such as 30s. Sets the timeout: filter of the query, replacing an existing one. If the search times out, the results found so far are printed and src exits with a non-zero code.")
+		contextFlag     = flagSet.Int("context-lines", -1, "Show at most this many unchanged lines around the changed lines of diffs in commit search results. By default, the context sent by the instance is shown. Only supported together with stream flag.")
+		diffColorFlag   = flagSet.String("diff-color", diffColorAuto, "Whether to color diffs in commit search results: 'auto' uses colordiff if it is installed and color is enabled, 'always' colors them even when piped or without colordiff, 'never' doesn't. Only supported together with stream flag.")
+		repoFlag        = flagSet.String("repo", "", "Only search the repository with exactly this name, by prepending an escaped repo:^name$ filter to the query.")
 	)
 
 	handler := func(args []string) error {
@@ -72,14 +144,75 @@ Other tips:
 			return err
 		}
 
-		if *streamFlag {
+		// A search takes a single query, so 'src search repos' followed by
+		// more arguments is the repos subcommand.
+		if flagSet.NArg() > 1 && flagSet.Arg(0) == "repos" {
+			return searchReposCommand.handler(flagSet.Args()[1:])
+		}
+
+		// fellBack is set if a streaming search was requested, but the
+		// instance doesn't support it.
+		var fellBack bool
+		if *streamFlag || *templateFlag != "" || *countOnlyFlag || *csvFlag {
 			opts := streaming.Opts{
 				Display: *display,
 				Trace:   apiFlags.Trace(),
 				Json:    *jsonFlag,
+				Timeout: *timeoutFlag,
+				CSV:     *csvFlag,
+
+				DiffColor: *diffColorFlag,
+			}
+			switch opts.DiffColor {
+			case diffColorAuto, diffColorAlways, diffColorNever:
+			default:
+				return cmderrors.Usagef("-diff-color must be one of %q, %q or %q", diffColorAuto, diffColorAlways, diffColorNever)
+			}
+			if *contextFlag >= 0 {
+				opts.DiffContextLines = contextFlag
+			}
+			if opts.CSV && (opts.Json || *templateFlag != "") {
+				return cmderrors.Usage("-csv can't be combined with -json or -template")
+			}
+			filter, err := streaming.NewMatchFilter(*includePathFlag, *excludePathFlag, *repoFilterFlag)
+			if err != nil {
+				return cmderrors.Usage(err.Error())
+			}
+			opts.Filter = filter
+			if strings.HasPrefix(*templateFlag, "@") {
+				data, err := os.ReadFile(strings.TrimPrefix(*templateFlag, "@"))
+				if err != nil {
+					return errors.Wrap(err, "reading template")
+				}
+				opts.Template = string(data)
+			} else {
+				opts.Template = *templateFlag
 			}
 			client := cfg.apiClient(apiFlags, flagSet.Output())
-			return streamSearch(flagSet.Arg(0), opts, client, os.Stdout)
+			query := scopeQueryToRepo(flagSet.Arg(0), *repoFlag, os.Stderr)
+			query = withSearchTimeout(query, *timeoutFlag)
+			if *countOnlyFlag {
+				return streamSearchCount(query, opts, client, os.Stdout)
+			}
+			if *followFlag {
+				err = streamSearchFollowingSuggestion(query, opts, client, os.Stdout)
+			} else {
+				err = streamSearch(query, opts, client, os.Stdout)
+			}
+			if !errors.Is(err, streaming.ErrStreamingUnsupported) {
+				return err
+			}
+
+			// Older instances don't provide the streaming endpoint. Results
+			// can still be rendered from the GraphQL API, unless a flag
+			// relies on the stream.
+			if opts.Template != "" || opts.CSV || opts.Filter != nil {
+				return errors.Wrap(err, "-template, -csv, -include-path, -exclude-path and -repo-filter require streaming search")
+			}
+			if *verbose {
+				fmt.Fprintln(os.Stderr, "Streaming search is not supported by this instance, falling back to the GraphQL API.")
+			}
+			fellBack = true
 		}
 
 		if *explainJSONFlag {
@@ -93,7 +226,7 @@ Other tips:
 		queryString := flagSet.Arg(0)
 
 		// For pagination, pipe our own output to 'less -R'
-		if *lessFlag && !*jsonFlag {
+		if *lessFlag && !*jsonFlag && !fellBack {
 			// But first we check whether we can use `less`. (Instead of
 			// combining the conditions here into one, we use a 2nd conditional
 			// so we don't need to do `exec.LookPath` if flags disable `less`)
@@ -131,6 +264,16 @@ Other tips:
 
 		client := cfg.apiClient(apiFlags, flagSet.Output())
 
+		queryString = scopeQueryToRepo(queryString, *repoFlag, os.Stderr)
+		queryString = withSearchTimeout(queryString, *timeoutFlag)
+
+		ctx := context.Background()
+		if *timeoutFlag > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, *timeoutFlag+streaming.TimeoutGrace)
+			defer cancel()
+		}
+
 		query := `fragment FileMatchFields on FileMatch {
 				repository {
 					name
@@ -261,7 +404,10 @@ Other tips:
 
 		if ok, err := client.NewRequest(query, map[string]interface{}{
 			"query": api.NullString(queryString),
-		}).Do(context.Background(), &result); err != nil || !ok {
+		}).Do(ctx, &result); err != nil || !ok {
+			if ctx.Err() == context.DeadlineExceeded {
+				return streaming.ErrTimeout
+			}
 			return err
 		}
 
@@ -290,7 +436,7 @@ Other tips:
 	}
 
 	// Register the command.
-	commands = append(commands, &command{
+	searchCommand = &command{
 		flagSet: flagSet,
 		handler: handler,
 		usageFunc: func() {
@@ -298,9 +444,13 @@ Other tips:
 			flagSet.PrintDefaults()
 			fmt.Println(usage)
 		},
-	})
+	}
+	commands = append(commands, searchCommand)
 }
 
+// searchCommand is 'src search'.
+var searchCommand *command
+
 // searchResults represents the data we get back from the GraphQL search request.
 type searchResults struct {
 	Results                    []map[string]interface{}
@@ -321,6 +471,36 @@ type searchResultsImproved struct {
 	searchResults
 }
 
+var repoFilterRegex = regexp.MustCompile(`(?i)(^|[\s(])-?(repo|r):`)
+
+// scopeQueryToRepo prepends a filter matching exactly the repository named
+// repo to query. If query already has a repo: filter, a warning is written to
+// w, since both filters have to match for a result to be returned.
+func scopeQueryToRepo(query, repo string, w io.Writer) string {
+	if repo == "" {
+		return query
+	}
+	if repoFilterRegex.MatchString(query) {
+		fmt.Fprintf(w, "warning: the query already contains a repo: filter, results must match both it and -repo %s\n", repo)
+	}
+	return "repo:^" + regexp.QuoteMeta(repo) + "$ " + query
+}
+
+var timeoutFilterRegex = regexp.MustCompile(`(?i)(^|\s)timeout:\S*`)
+
+// withSearchTimeout sets the timeout: filter of query to timeout, so that the
+// server bounds the search as well. An existing timeout: filter is replaced.
+func withSearchTimeout(query string, timeout time.Duration) string {
+	if timeout <= 0 {
+		return query
+	}
+	filter := "timeout:" + timeout.String()
+	if timeoutFilterRegex.MatchString(query) {
+		return timeoutFilterRegex.ReplaceAllString(query, "${1}"+filter)
+	}
+	return query + " " + filter
+}
+
 func envSetDefault(env []string, key, value string) []string {
 	set := false
 	for _, kv := range env {
@@ -512,6 +692,36 @@ func convertMatchToHighlights(m map[string]interface{}, isPreview bool) (highlig
 	return highlights
 }
 
+// isFileRelativeLineMatch reports whether the offsets of the line match m
+// are relative to the line in the file content instead of its preview, as they
+// are for structural search results. The pattern type can be set in settings
+// or inferred by the server, so besides the query, the match itself is
+// checked: a structural match can span multiple lines, or have offsets beyond
+// its preview.
+func isFileRelativeLineMatch(query string, m map[string]interface{}) bool {
+	if strings.Contains(strings.ToLower(query), "patterntype:structural") {
+		return true
+	}
+
+	preview, _ := m["preview"].(string)
+	if strings.Contains(preview, "\n") {
+		return true
+	}
+	offsetAndLengths, _ := m["offsetAndLengths"].([]interface{})
+	for _, offsetAndLength := range offsetAndLengths {
+		ol, ok := offsetAndLength.([]interface{})
+		if !ok || len(ol) != 2 {
+			continue
+		}
+		offset, _ := ol[0].(float64)
+		length, _ := ol[1].(float64)
+		if int(offset+length) > len([]rune(preview)) {
+			return true
+		}
+	}
+	return false
+}
+
 var searchTemplateFuncs = map[string]interface{}{
 	"searchSequentialLineNumber": func(lineMatches []interface{}, index int) bool {
 		prevIndex := index - 1
@@ -526,7 +736,7 @@ var searchTemplateFuncs = map[string]interface{}{
 		m := match.(map[string]interface{})
 		q := query.(string)
 		var highlights []highlight
-		if strings.Contains(q, "patterntype:structural") {
+		if isFileRelativeLineMatch(q, m) {
 			highlights = convertMatchToHighlights(m, false)
 			return applyHighlightsForFile(content.(string), highlights)
 		} else {
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/sourcegraph/src-cli/internal/streaming"
+)
+
+// Values of the -diff-color flag.
+const (
+	diffColorAuto   = "auto"
+	diffColorAlways = "always"
+	diffColorNever  = "never"
+)
+
+// diffDisplay controls how the diffs of commit matches are displayed.
+type diffDisplay struct {
+	// contextLines is the number of unchanged lines kept around changed
+	// lines, or -1 to keep the context the server sent.
+	contextLines int
+	// color is one of the diffColor constants.
+	color string
+}
+
+func newDiffDisplay(opts streaming.Opts) diffDisplay {
+	d := diffDisplay{contextLines: -1, color: opts.DiffColor}
+	if opts.DiffContextLines != nil {
+		d.contextLines = *opts.DiffContextLines
+	}
+	if d.color == "" {
+		d.color = diffColorAuto
+	}
+	return d
+}
+
+// templateFuncs returns the template functions that depend on d, to replace
+// the ones in streamSearchTemplateFuncs.
+func (d diffDisplay) templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"streamSearchHighlightCommit": d.highlightCommit,
+	}
+}
+
+func (d diffDisplay) highlightCommit(content string, ranges [][3]int32) string {
+	highlights := make([]highlight, len(ranges))
+	for _, r := range ranges {
+		highlights = append(highlights, highlight{
+			line:      int(r[0]),
+			character: int(r[1]),
+			length:    int(r[2]),
+		})
+	}
+	if strings.HasPrefix(content, "```diff") {
+		return d.highlightDiffPreview(content, highlights)
+	}
+	return applyHighlights(stripMarkdownMarkers(content), highlights, ansiColors["search-match"], ansiColors["nc"])
+}
+
+func (d diffDisplay) highlightDiffPreview(diffPreview string, highlights []highlight) string {
+	diff := stripMarkdownMarkers(diffPreview)
+	keep := diffContextLines(diff, d.contextLines)
+
+	switch d.color {
+	case diffColorNever:
+		return filterDiffLines(applyHighlights(diff, highlights, ansiColors["search-match"], ansiColors["nc"]), keep)
+	case diffColorAlways:
+		return colorizeDiff(filterDiffLines(applyHighlights(diff, highlights, uniqueStartOfMatchToken, uniqueEndOfMatchToken), keep))
+	}
+
+	useColordiff, err := strconv.ParseBool(os.Getenv("COLORDIFF"))
+	if err != nil {
+		useColordiff = true
+	}
+	if colorDisabled || !useColordiff {
+		// Only highlight the matches.
+		return filterDiffLines(applyHighlights(diff, highlights, ansiColors["search-match"], ansiColors["nc"]), keep)
+	}
+	path, err := exec.LookPath("colordiff")
+	if err != nil {
+		// colordiff not installed; only highlight the matches.
+		return filterDiffLines(applyHighlights(diff, highlights, ansiColors["search-match"], ansiColors["nc"]), keep)
+	}
+
+	// First highlight the matches, but use a special "end of match" token
+	// instead of no color (so that we don't terminate colors that colordiff
+	// adds).
+	diff = filterDiffLines(applyHighlights(diff, highlights, uniqueStartOfMatchToken, uniqueEndOfMatchToken), keep)
+
+	// Now highlight our diff with colordiff.
+	var buf bytes.Buffer
+	cmd := exec.Command(path)
+	cmd.Stdin = strings.NewReader(diff)
+	cmd.Stdout = &buf
+	if err := cmd.Run(); err != nil {
+		fmt.Println("warning: colordiff failed to colorize diff:", err)
+		return diff
+	}
+	colorized := buf.String()
+	var final []string
+	for _, line := range strings.Split(colorized, "\n") {
+		// Find where the start-of-match token is in the line.
+		somToken := strings.Index(line, uniqueStartOfMatchToken)
+
+		// Find which ANSI codes are to the left of our start-of-match token.
+		indices := ansiRegexp.FindAllStringIndex(line, -1)
+		matches := ansiRegexp.FindAllString(line, -1)
+		var left []string
+		for k, index := range indices {
+			if index[0] < somToken && index[1] < somToken {
+				left = append(left, matches[k])
+			}
+		}
+
+		// Replace our start-of-match token with the color we wish.
+		line = strings.ReplaceAll(line, uniqueStartOfMatchToken, ansiColors["search-match"])
+
+		// Replace our end-of-match token with the color terminator,
+		// and start all colors that were previously started to the left.
+		line = strings.ReplaceAll(line, uniqueEndOfMatchToken, ansiColors["nc"]+strings.Join(left, ""))
+
+		final = append(final, line)
+	}
+	return strings.Join(final, "\n")
+}
+
+// Tokens that mark the matches in a diff until it is colorized, so that the
+// colors of the diff lines can be restored after each match.
+const (
+	uniqueStartOfMatchToken = "pXRdMhZbgnPL355429nsO4qFgX86LfXTSmqH4Nr3#*(@)!*#()@!APPJB8ZRutvZ5fdL01273i6OdzLDm0UMC9372891skfJTl2c52yR1v"
+	uniqueEndOfMatchToken   = "v1Ry25c2lTJfks1982739CMU0mDLzdO6i37210Ldf5ZvtuRZ8BJPPA!@)(#*!)@(*#3rN4HqmSTXfL68XgFq4Osn924553LPngbZhMdRXp"
+)
+
+// colorizeDiff colors the lines of diff, in which matches are marked with the
+// match tokens, without relying on colordiff or the color settings, so that
+// -diff-color=always gives the same output everywhere.
+func colorizeDiff(diff string) string {
+	var (
+		reset = "\033[0m"
+		match = fg256Color(0) + bg256Color(11)
+	)
+	lines := strings.Split(diff, "\n")
+	for i, line := range lines {
+		var color string
+		switch plain := strings.TrimPrefix(line, uniqueStartOfMatchToken); {
+		case plain == "...":
+			// Left out context lines.
+		case strings.HasPrefix(plain, "@@"):
+			color = "\033[36m"
+		case isDiffFileHeader(plain):
+			color = "\033[1m"
+		case strings.HasPrefix(plain, "+"):
+			color = "\033[32m"
+		case strings.HasPrefix(plain, "-"):
+			color = "\033[31m"
+		}
+		line = strings.ReplaceAll(line, uniqueStartOfMatchToken, match)
+		line = strings.ReplaceAll(line, uniqueEndOfMatchToken, reset+color)
+		if color != "" && line != "" {
+			line = color + line + reset
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// diffContextLines returns which lines of diff to keep so that at most
+// contextLines unchanged lines are shown around the changed lines of each
+// hunk. Headers are always kept. If contextLines is negative, all lines are
+// kept and nil is returned.
+func diffContextLines(diff string, contextLines int) []bool {
+	if contextLines < 0 {
+		return nil
+	}
+	lines := strings.Split(diff, "\n")
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		if !isDiffChange(line) {
+			if !strings.HasPrefix(line, " ") {
+				// Headers, and the empty line at the end.
+				keep[i] = true
+			}
+			continue
+		}
+		keep[i] = true
+		for j := i - 1; j >= 0 && j >= i-contextLines && strings.HasPrefix(lines[j], " "); j-- {
+			keep[j] = true
+		}
+		for j := i + 1; j < len(lines) && j <= i+contextLines && strings.HasPrefix(lines[j], " "); j++ {
+			keep[j] = true
+		}
+	}
+	return keep
+}
+
+// filterDiffLines returns the lines of diff marked in keep, with a "..." line
+// where lines were left out. Highlighting must not change the number of
+// lines, so that the lines line up with keep. A nil keep keeps all lines.
+func filterDiffLines(diff string, keep []bool) string {
+	if keep == nil {
+		return diff
+	}
+	var kept []string
+	elided := false
+	for i, line := range strings.Split(diff, "\n") {
+		if i < len(keep) && !keep[i] {
+			elided = true
+			continue
+		}
+		if elided {
+			kept = append(kept, "...")
+			elided = false
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+func isDiffChange(line string) bool {
+	return (strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-")) && !isDiffFileHeader(line)
+}
+
+// isDiffFileHeader reports whether line starts the diff of a file, which in
+// commit search results is a line with the old and new path.
+func isDiffFileHeader(line string) bool {
+	if strings.HasPrefix(line, "+++ ") || strings.HasPrefix(line, "--- ") {
+		return true
+	}
+	return line != "" && !strings.HasPrefix(line, " ") && !strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "@@")
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffDisplay(t *testing.T) {
+	content := "```diff\n" + `cmd/main.go cmd/main.go
+@@ -1,9 +1,9 @@
+ package main
+ 
+ import "fmt"
+ 
+ func main() {
+-	fmt.Println("hello")
++	fmt.Println("goodbye")
+ }
+ 
+ // end
+` + "```"
+	// Highlight "goodbye", on the 9th line of the diff.
+	ranges := [][3]int32{{9, 15, 7}}
+
+	t.Run("context lines", func(t *testing.T) {
+		d := diffDisplay{contextLines: 1, color: diffColorNever}
+		got := stripANSI(d.highlightCommit(content, ranges))
+		want := `cmd/main.go cmd/main.go
+@@ -1,9 +1,9 @@
+...
+ func main() {
+-	fmt.Println("hello")
++	fmt.Println("goodbye")
+ }
+...
+`
+		if got != want {
+			t.Errorf("wrong diff\ngot:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("all context", func(t *testing.T) {
+		d := diffDisplay{contextLines: -1, color: diffColorNever}
+		got := stripANSI(d.highlightCommit(content, ranges))
+		if want := stripMarkdownMarkers(content) + "\n"; got != want {
+			t.Errorf("wrong diff\ngot:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("always color", func(t *testing.T) {
+		d := diffDisplay{contextLines: 0, color: diffColorAlways}
+		got := d.highlightCommit(content, ranges)
+		for _, want := range []string{
+			"\033[31m-\tfmt.Println(\"hello\")\033[0m",
+			"\033[32m+\tfmt.Println(\"" + fg256Color(0) + bg256Color(11) + "goodbye\033[0m\033[32m\")\033[0m",
+		} {
+			if !strings.Contains(got, want) {
+				t.Errorf("expected %q in diff:\n%q", want, got)
+			}
+		}
+		if strings.Contains(got, uniqueStartOfMatchToken) || strings.Contains(got, uniqueEndOfMatchToken) {
+			t.Errorf("match tokens left in diff:\n%q", got)
+		}
+	})
+}
+
+func stripANSI(s string) string {
+	return ansiRegexp.ReplaceAllString(s, "")
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/grafana/regexp"
+
+	"github.com/sourcegraph/src-cli/internal/api"
+	"github.com/sourcegraph/src-cli/internal/cmderrors"
+)
+
+// searchReposCommand is run by 'src search' when it's invoked as
+// 'src search repos [flags]'.
+var searchReposCommand *command
+
+func init() {
+	usage := `
+'src search repos' prints the names of the repositories matching a search
+query, once each.
+
+Usage:
+
+    src search repos [flags] -query QUERY
+
+Examples:
+
+  List the repositories with a Dockerfile:
+
+    	$ src search repos -query 'file:^Dockerfile$'
+
+  List them as JSON, along with their default branch:
+
+    	$ src search repos -json -default-branch -query 'file:^Dockerfile$'
+
+Unless the query contains them already, select:repo and count:all are added to
+the query, so that all matching repositories are returned.
+`
+
+	flagSet := flag.NewFlagSet("repos", flag.ExitOnError)
+	usageFunc := func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of 'src search %s':\n", flagSet.Name())
+		flagSet.PrintDefaults()
+		fmt.Println(usage)
+	}
+	flagSet.Usage = usageFunc
+	var (
+		queryFlag         = flagSet.String("query", "", "The search query. (required)")
+		jsonFlag          = flagSet.Bool("json", false, "Print the repositories as JSON.")
+		defaultBranchFlag = flagSet.Bool("default-branch", false, "Print the default branch of each repository next to its name.")
+		apiFlags          = api.NewFlags(flagSet)
+	)
+
+	handler := func(args []string) error {
+		if err := flagSet.Parse(args); err != nil {
+			return err
+		}
+		if *queryFlag == "" {
+			return cmderrors.Usage("-query is required")
+		}
+		if flagSet.NArg() != 0 {
+			return cmderrors.Usage("additional arguments not allowed")
+		}
+
+		client := cfg.apiClient(apiFlags, flagSet.Output())
+
+		query := `query SearchRepositories($query: String!) {
+  search(query: $query) {
+    results {
+      results {
+        __typename
+        ... on Repository {
+          name
+          defaultBranch {
+            displayName
+          }
+        }
+      }
+    }
+  }
+}`
+
+		var result struct {
+			Search struct {
+				Results struct {
+					Results []searchRepoResult
+				}
+			}
+		}
+		if ok, err := client.NewRequest(query, map[string]interface{}{
+			"query": repoSearchQuery(*queryFlag),
+		}).Do(context.Background(), &result); err != nil || !ok {
+			return err
+		}
+
+		repos := dedupeSearchRepos(result.Search.Results.Results)
+
+		if *jsonFlag {
+			if !*defaultBranchFlag {
+				for i := range repos {
+					repos[i].DefaultBranch = ""
+				}
+			}
+			data, err := marshalIndent(repos)
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+
+		for _, r := range repos {
+			if *defaultBranchFlag {
+				fmt.Fprintf(os.Stdout, "%s\t%s\n", r.Name, r.DefaultBranch)
+			} else {
+				fmt.Fprintln(os.Stdout, r.Name)
+			}
+		}
+		return nil
+	}
+
+	searchReposCommand = &command{
+		flagSet:   flagSet,
+		handler:   handler,
+		usageFunc: usageFunc,
+	}
+}
+
+type searchRepoResult struct {
+	Typename      string `json:"__typename"`
+	Name          string
+	DefaultBranch *struct {
+		DisplayName string
+	}
+}
+
+type searchRepo struct {
+	Name          string `json:"name"`
+	DefaultBranch string `json:"defaultBranch,omitempty"`
+}
+
+var (
+	selectFilterRegex = regexp.MustCompile(`(?i)(^|\s)select:`)
+	countFilterRegex  = regexp.MustCompile(`(?i)(^|\s)count:`)
+)
+
+// repoSearchQuery turns query into a query that returns every matching
+// repository once.
+func repoSearchQuery(query string) string {
+	if !selectFilterRegex.MatchString(query) {
+		query += " select:repo"
+	}
+	if !countFilterRegex.MatchString(query) {
+		query += " count:all"
+	}
+	return query
+}
+
+// dedupeSearchRepos returns the repositories in results, in order, without
+// duplicates.
+func dedupeSearchRepos(results []searchRepoResult) []searchRepo {
+	seen := map[string]bool{}
+	repos := []searchRepo{}
+	for _, r := range results {
+		if r.Typename != "Repository" || seen[r.Name] {
+			continue
+		}
+		seen[r.Name] = true
+
+		repo := searchRepo{Name: r.Name}
+		if r.DefaultBranch != nil {
+			repo.DefaultBranch = r.DefaultBranch.DisplayName
+		}
+		repos = append(repos, repo)
+	}
+	return repos
+}
@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
+	"github.com/sourcegraph/src-cli/internal/cmderrors"
+)
+
+var searchSavedCommands commander
+
+// The subcommands of 'src search-saved'.
+var (
+	searchSaveCommand *command
+	searchRunCommand  *command
+	searchListCommand *command
+)
+
+func init() {
+	usage := `'src search-saved' manages saved searches, which let you run queries you use
+often by name. They are stored in src-saved-searches.json in the sourcegraph
+directory of your user configuration directory, and never sent to the server.
+
+Usage:
+
+	src search-saved command [command options]
+
+The commands are:
+
+	save	save a query, along with flags for 'src search'
+	run	run a saved search with 'src search'
+	list	list the saved searches
+
+Use "src search-saved [command] -h" for more information about a command.
+`
+
+	flagSet := flag.NewFlagSet("search-saved", flag.ExitOnError)
+	commands = append(commands, &command{
+		flagSet: flagSet,
+		handler: func(args []string) error {
+			searchSavedCommands.run(flagSet, "src search-saved", usage, args)
+			return nil
+		},
+		usageFunc: func() {
+			fmt.Println(usage)
+		},
+	})
+}
+
+func init() {
+	usage := `
+Usage:
+
+    src search-saved save [-o] NAME QUERY [SEARCH FLAGS...]
+    src search-saved run NAME [SEARCH FLAGS...]
+    src search-saved list [-json]
+
+Examples:
+
+  Save a query, along with flags to use when it's run:
+
+    	$ src search-saved save todos 'repo:^github\.com/sourcegraph/src-cli$ TODO' -stream -display 20
+
+  Run it, adding another flag:
+
+    	$ src search-saved run todos -json
+
+  Replace it:
+
+    	$ src search-saved save -o todos 'repo:^github\.com/sourcegraph/ TODO' -stream
+
+  List the saved searches:
+
+    	$ src search-saved list
+
+Flags given to 'src search-saved run' are applied after the saved ones, so
+they take precedence.
+`
+
+	saveFlagSet := flag.NewFlagSet("save", flag.ExitOnError)
+	overwriteFlag := saveFlagSet.Bool("o", false, "Overwrite an existing saved search with the same name.")
+	runFlagSet := flag.NewFlagSet("run", flag.ExitOnError)
+	listFlagSet := flag.NewFlagSet("list", flag.ExitOnError)
+	listJSONFlag := listFlagSet.Bool("json", false, "Print the saved searches as JSON.")
+
+	usageFunc := func(flagSet *flag.FlagSet) func() {
+		return func() {
+			fmt.Fprintf(flag.CommandLine.Output(), "Usage of 'src search-saved %s':\n", flagSet.Name())
+			flagSet.PrintDefaults()
+			fmt.Println(usage)
+		}
+	}
+	for _, flagSet := range []*flag.FlagSet{saveFlagSet, runFlagSet, listFlagSet} {
+		flagSet.Usage = usageFunc(flagSet)
+	}
+
+	searchSaveCommand = &command{
+		flagSet: saveFlagSet,
+		handler: func(args []string) error {
+			if err := saveFlagSet.Parse(args); err != nil {
+				return err
+			}
+			if saveFlagSet.NArg() < 2 {
+				return cmderrors.Usage("expected a name and a query")
+			}
+			name, query, flags := saveFlagSet.Arg(0), saveFlagSet.Arg(1), saveFlagSet.Args()[2:]
+			if err := validateSavedSearchName(name); err != nil {
+				return cmderrors.Usage(err.Error())
+			}
+
+			path, err := savedSearchesPath()
+			if err != nil {
+				return err
+			}
+			searches, err := readSavedSearches(path)
+			if err != nil {
+				return err
+			}
+			if _, ok := searches[name]; ok && !*overwriteFlag {
+				return errors.Errorf("a saved search named %q already exists, use -o to overwrite it", name)
+			}
+			searches[name] = savedSearch{Query: query, Flags: flags}
+			return writeSavedSearches(path, searches)
+		},
+		usageFunc: usageFunc(saveFlagSet),
+	}
+
+	searchRunCommand = &command{
+		flagSet: runFlagSet,
+		handler: func(args []string) error {
+			if err := runFlagSet.Parse(args); err != nil {
+				return err
+			}
+			if runFlagSet.NArg() < 1 {
+				return cmderrors.Usage("expected the name of a saved search")
+			}
+			name := runFlagSet.Arg(0)
+
+			path, err := savedSearchesPath()
+			if err != nil {
+				return err
+			}
+			searches, err := readSavedSearches(path)
+			if err != nil {
+				return err
+			}
+			saved, ok := searches[name]
+			if !ok {
+				return errors.Errorf("no saved search named %q, see 'src search-saved list'", name)
+			}
+			return searchCommand.handler(saved.args(runFlagSet.Args()[1:]))
+		},
+		usageFunc: usageFunc(runFlagSet),
+	}
+
+	searchListCommand = &command{
+		flagSet: listFlagSet,
+		handler: func(args []string) error {
+			if err := listFlagSet.Parse(args); err != nil {
+				return err
+			}
+			if listFlagSet.NArg() != 0 {
+				return cmderrors.Usage("additional arguments not allowed")
+			}
+
+			path, err := savedSearchesPath()
+			if err != nil {
+				return err
+			}
+			searches, err := readSavedSearches(path)
+			if err != nil {
+				return err
+			}
+
+			if *listJSONFlag {
+				data, err := marshalIndent(searches)
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+				return nil
+			}
+
+			names := make([]string, 0, len(searches))
+			for name := range searches {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				s := searches[name]
+				if len(s.Flags) > 0 {
+					fmt.Printf("%s\t%s\t%s\n", name, s.Query, strings.Join(s.Flags, " "))
+				} else {
+					fmt.Printf("%s\t%s\n", name, s.Query)
+				}
+			}
+			return nil
+		},
+		usageFunc: usageFunc(listFlagSet),
+	}
+
+	searchSavedCommands = append(searchSavedCommands, searchSaveCommand, searchRunCommand, searchListCommand)
+}
+
+// savedSearch is a query saved with 'src search-saved save'.
+type savedSearch struct {
+	Query string   `json:"query"`
+	Flags []string `json:"flags,omitempty"`
+}
+
+// args returns the arguments for 'src search' that run s, with extraFlags
+// after the saved flags so that they take precedence.
+func (s savedSearch) args(extraFlags []string) []string {
+	args := make([]string, 0, len(s.Flags)+len(extraFlags)+2)
+	args = append(args, s.Flags...)
+	args = append(args, extraFlags...)
+	// The query may start with a dash, such as a negated filter.
+	return append(args, "--", s.Query)
+}
+
+func validateSavedSearchName(name string) error {
+	if name == "" || strings.HasPrefix(name, "-") || strings.ContainsAny(name, " \t\n") {
+		return errors.Errorf("invalid name %q: names must not be empty, start with a dash or contain whitespace", name)
+	}
+	return nil
+}
+
+// savedSearchesPath returns the path of the file saved searches are stored in.
+func savedSearchesPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", errors.Wrap(err, "getting user config dir")
+	}
+	return filepath.Join(dir, "sourcegraph", "src-saved-searches.json"), nil
+}
+
+// readSavedSearches reads the saved searches from path. If the file doesn't
+// exist, no searches have been saved yet.
+func readSavedSearches(path string) (map[string]savedSearch, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return map[string]savedSearch{}, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "reading saved searches")
+	}
+
+	searches := map[string]savedSearch{}
+	if err := json.Unmarshal(data, &searches); err != nil {
+		return nil, errors.Wrapf(err, "parsing saved searches in %s", path)
+	}
+	return searches, nil
+}
+
+func writeSavedSearches(path string, searches map[string]savedSearch) error {
+	data, err := marshalIndent(searches)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return errors.Wrap(err, "creating config directory")
+	}
+	return errors.Wrap(os.WriteFile(path, append(data, '\n'), 0600), "writing saved searches")
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSearchSave(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	if err := searchSaveCommand.handler([]string{"todos", "TODO", "-stream"}); err != nil {
+		t.Fatal(err)
+	}
+	err := searchSaveCommand.handler([]string{"todos", "FIXME"})
+	if err == nil || !strings.Contains(err.Error(), "use -o to overwrite") {
+		t.Fatalf("expected collision error, got %v", err)
+	}
+	if err := searchSaveCommand.handler([]string{"-o", "todos", "-repo:foo FIXME", "-json"}); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := savedSearchesPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	searches, err := readSavedSearches(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]savedSearch{
+		"todos": {Query: "-repo:foo FIXME", Flags: []string{"-json"}},
+	}
+	if diff := cmp.Diff(want, searches); diff != "" {
+		t.Errorf("wrong saved searches (-want +got):\n%s", diff)
+	}
+}
+
+func TestReadSavedSearches_Missing(t *testing.T) {
+	searches, err := readSavedSearches(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(searches) != 0 {
+		t.Errorf("expected no saved searches, got %v", searches)
+	}
+}
+
+func TestSavedSearchArgs(t *testing.T) {
+	s := savedSearch{Query: "-repo:foo error", Flags: []string{"-stream", "-display", "10"}}
+	got := s.args([]string{"-display", "20"})
+	want := []string{"-stream", "-display", "10", "-display", "20", "--", "-repo:foo error"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("wrong args (-want +got):\n%s", diff)
+	}
+}
+
+func TestValidateSavedSearchName(t *testing.T) {
+	for name, valid := range map[string]bool{
+		"todos":    true,
+		"my-todos": true,
+		"":         false,
+		"-todos":   false,
+		"my todos": false,
+	} {
+		if err := validateSavedSearchName(name); (err == nil) != valid {
+			t.Errorf("validateSavedSearchName(%q) = %v, want valid=%t", name, err, valid)
+		}
+	}
+}
@@ -2,35 +2,143 @@ package main
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 	"text/template"
 
 	"github.com/grafana/regexp"
 
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
 	"github.com/sourcegraph/src-cli/internal/api"
+	"github.com/sourcegraph/src-cli/internal/cmderrors"
 	"github.com/sourcegraph/src-cli/internal/streaming"
 )
 
 var labelRegexp = regexp.MustCompile(`(?:\[)(.*?)(?:])`)
 
 func streamSearch(query string, opts streaming.Opts, client api.Client, w io.Writer) error {
+	_, err := streamSearchProposals(query, opts, client, w)
+	return err
+}
+
+// streamSearchFollowingSuggestion runs query like streamSearch. If the server
+// proposes exactly one alternative query, that query is run as well. Proposals
+// made for the suggested query are not followed, to avoid loops.
+func streamSearchFollowingSuggestion(query string, opts streaming.Opts, client api.Client, w io.Writer) error {
+	proposed, err := streamSearchProposals(query, opts, client, w)
+	if err != nil || len(proposed) != 1 {
+		return err
+	}
+
+	suggested := proposed[0].Query
+	if opts.Json || opts.Template != "" || opts.CSV {
+		// Keep stdout valid JSON lines or the user's format.
+		fmt.Fprintf(os.Stderr, "results for suggested query: %s\n", suggested)
+	} else {
+		fmt.Fprintf(w, "\nresults for suggested query: %s\n\n", suggested)
+	}
+	return streamSearch(suggested, opts, client, w)
+}
+
+// streamSearchProposals runs query, writes the results to w and returns the
+// queries proposed by alerts in the response.
+func streamSearchProposals(query string, opts streaming.Opts, client api.Client, w io.Writer) ([]streaming.ProposedQuery, error) {
 	var d streaming.Decoder
-	if opts.Json {
+	if opts.Template != "" {
+		t, err := parseTemplate(opts.Template)
+		if err != nil {
+			return nil, err
+		}
+		t.Funcs(newDiffDisplay(opts).templateFuncs())
+		d = matchTemplateDecoder(t, w)
+	} else if opts.CSV {
+		d = csvDecoder(w)
+	} else if opts.Json {
 		d = jsonDecoder(w)
 	} else {
 		t, err := parseTemplate(streamingTemplate)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		t.Funcs(newDiffDisplay(opts).templateFuncs())
 		d = textDecoder(query, t, w)
 	}
-	return streaming.Search(query, opts, client, d)
+
+	if opts.Filter != nil {
+		d = opts.Filter.Wrap(d)
+	}
+
+	var proposed []streaming.ProposedQuery
+	onAlert := d.OnAlert
+	d.OnAlert = func(alert *streaming.EventAlert) {
+		proposed = append(proposed, alert.ProposedQueries...)
+		onAlert(alert)
+	}
+
+	if err := streaming.Search(query, opts, client, d); err != nil {
+		return proposed, err
+	}
+	if opts.Filter != nil && opts.Filter.Dropped > 0 {
+		// The counts reported by the server include the dropped matches.
+		logError(fmt.Sprintf("%d matches not displayed because of client-side filters\n", opts.Filter.Dropped))
+	}
+	return proposed, nil
+}
+
+// streamSearchCount runs query without fetching any matches and writes just
+// the number of matches to w, or, in JSON mode, an object that also includes
+// the number of repositories searched. If there are no matches, an exit code
+// error is returned.
+func streamSearchCount(query string, opts streaming.Opts, client api.Client, w io.Writer) error {
+	var final *streaming.Progress
+	d := streaming.Decoder{
+		OnProgress: func(progress *streaming.Progress) {
+			if progress.Done {
+				final = progress
+			}
+		},
+		OnError: func(eventError *streaming.EventError) {
+			logError(eventError.Message)
+		},
+	}
+
+	// The server doesn't need to send any matches to report their count.
+	opts.Display = 0
+	if err := streaming.Search(query, opts, client, d); err != nil {
+		return err
+	}
+	if final == nil {
+		return errors.New("search did not report a final match count")
+	}
+
+	if opts.Json {
+		data, err := json.Marshal(struct {
+			MatchCount        int  `json:"matchCount"`
+			RepositoriesCount *int `json:"repositoriesCount,omitempty"`
+			LimitHit          bool `json:"limitHit"`
+		}{
+			MatchCount:        final.MatchCount,
+			RepositoriesCount: final.RepositoriesCount,
+			LimitHit:          isLimitHit(final),
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, string(data))
+	} else {
+		fmt.Fprintln(w, final.MatchCount)
+	}
+
+	if final.MatchCount == 0 {
+		return cmderrors.ExitCode1
+	}
+	return nil
 }
 
 // jsonDecoder streams results as JSON to w.
@@ -83,6 +191,114 @@ func jsonDecoder(w io.Writer) streaming.Decoder {
 	}
 }
 
+// matchTemplateDecoder executes t for every match and writes the output,
+// followed by a newline, to w. Alerts and errors are written to stderr, so
+// that w only contains the formatted matches.
+func matchTemplateDecoder(t *template.Template, w io.Writer) streaming.Decoder {
+	return streaming.Decoder{
+		OnMatches: func(matches []streaming.EventMatch) {
+			for _, match := range matches {
+				// Render into a buffer first, so that matches the template
+				// fails on don't leave partial output behind.
+				var buf bytes.Buffer
+				if err := t.Execute(&buf, match); err != nil {
+					logError(fmt.Sprintf("error when executing template: %s\n", err))
+					continue
+				}
+				buf.WriteByte('\n')
+				if _, err := w.Write(buf.Bytes()); err != nil {
+					logError(err.Error())
+				}
+			}
+		},
+		OnAlert: func(alert *streaming.EventAlert) {
+			logError(fmt.Sprintf("alert: %s\n", alert.Title))
+		},
+		OnError: func(eventError *streaming.EventError) {
+			logError(eventError.Message)
+		},
+	}
+}
+
+// csvHeader is the header of the CSV output.
+var csvHeader = []string{"repository", "path", "line", "column", "matched_text"}
+
+// csvDecoder writes a CSV row to w for every range of a content match, and for
+// every path match, with empty line, column and matched text. Lines and
+// columns are 1-based. Other types of matches aren't files, so they are
+// skipped and only counted on stderr.
+func csvDecoder(w io.Writer) streaming.Decoder {
+	cw := csv.NewWriter(w)
+	headerWritten := false
+	writeHeader := func() {
+		if !headerWritten {
+			headerWritten = true
+			cw.Write(csvHeader)
+		}
+	}
+	flush := func() {
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			logError(err.Error())
+		}
+	}
+
+	skipped := 0
+	return streaming.Decoder{
+		OnProgress: func(progress *streaming.Progress) {
+			if !progress.Done {
+				return
+			}
+			writeHeader()
+			flush()
+			if skipped > 0 {
+				logError(fmt.Sprintf("%d matches that aren't in files were not included in the CSV output\n", skipped))
+			}
+		},
+		OnMatches: func(matches []streaming.EventMatch) {
+			writeHeader()
+			for _, match := range matches {
+				switch m := match.(type) {
+				case *streaming.EventContentMatch:
+					for _, chunk := range m.ChunkMatches {
+						for _, r := range chunk.Ranges {
+							cw.Write([]string{
+								m.Repository,
+								m.Path,
+								strconv.Itoa(r.Start.Line + 1),
+								strconv.Itoa(r.Start.Column + 1),
+								chunkRangeText(chunk, r),
+							})
+						}
+					}
+				case *streaming.EventPathMatch:
+					cw.Write([]string{m.Repository, m.Path, "", "", ""})
+				default:
+					skipped++
+				}
+			}
+			flush()
+		},
+		OnAlert: func(alert *streaming.EventAlert) {
+			logError(fmt.Sprintf("alert: %s\n", alert.Title))
+		},
+		OnError: func(eventError *streaming.EventError) {
+			logError(eventError.Message)
+		},
+	}
+}
+
+// chunkRangeText returns the text of chunk that r spans, or an empty string
+// if r lies outside of chunk.
+func chunkRangeText(chunk streaming.ChunkMatch, r streaming.Range) string {
+	start := r.Start.Offset - chunk.ContentStart.Offset
+	end := r.End.Offset - chunk.ContentStart.Offset
+	if start < 0 || end < start || end > len(chunk.Content) {
+		return ""
+	}
+	return chunk.Content[start:end]
+}
+
 func textDecoder(query string, t *template.Template, w io.Writer) streaming.Decoder {
 	return streaming.Decoder{
 		OnProgress: func(progress *streaming.Progress) {
@@ -365,20 +581,7 @@ var streamSearchTemplateFuncs = map[string]interface{}{
 		return string(result)
 	},
 
-	"streamSearchHighlightCommit": func(content string, ranges [][3]int32) string {
-		highlights := make([]highlight, len(ranges))
-		for _, r := range ranges {
-			highlights = append(highlights, highlight{
-				line:      int(r[0]),
-				character: int(r[1]),
-				length:    int(r[2]),
-			})
-		}
-		if strings.HasPrefix(content, "```diff") {
-			return streamSearchHighlightDiffPreview(content, highlights)
-		}
-		return applyHighlights(stripMarkdownMarkers(content), highlights, ansiColors["search-match"], ansiColors["nc"])
-	},
+	"streamSearchHighlightCommit": diffDisplay{contextLines: -1, color: diffColorAuto}.highlightCommit,
 
 	"streamSearchRenderCommitLabel": func(label string) string {
 		m := labelRegexp.FindAllStringSubmatch(label, -1)
@@ -404,66 +607,6 @@ var streamSearchTemplateFuncs = map[string]interface{}{
 	},
 }
 
-func streamSearchHighlightDiffPreview(diffPreview string, highlights []highlight) string {
-	useColordiff, err := strconv.ParseBool(os.Getenv("COLORDIFF"))
-	if err != nil {
-		useColordiff = true
-	}
-	if colorDisabled || !useColordiff {
-		// Only highlight the matches.
-		return applyHighlights(stripMarkdownMarkers(diffPreview), highlights, ansiColors["search-match"], ansiColors["nc"])
-	}
-	path, err := exec.LookPath("colordiff")
-	if err != nil {
-		// colordiff not installed; only highlight the matches.
-		return applyHighlights(stripMarkdownMarkers(diffPreview), highlights, ansiColors["search-match"], ansiColors["nc"])
-	}
-
-	// First highlight the matches, but use a special "end of match" token
-	// instead of no color (so that we don'streamingTemplate terminate colors that colordiff
-	// adds).
-	uniqueStartOfMatchToken := "pXRdMhZbgnPL355429nsO4qFgX86LfXTSmqH4Nr3#*(@)!*#()@!APPJB8ZRutvZ5fdL01273i6OdzLDm0UMC9372891skfJTl2c52yR1v"
-	uniqueEndOfMatchToken := "v1Ry25c2lTJfks1982739CMU0mDLzdO6i37210Ldf5ZvtuRZ8BJPPA!@)(#*!)@(*#3rN4HqmSTXfL68XgFq4Osn924553LPngbZhMdRXp"
-	diff := applyHighlights(stripMarkdownMarkers(diffPreview), highlights, uniqueStartOfMatchToken, uniqueEndOfMatchToken)
-
-	// Now highlight our diff with colordiff.
-	var buf bytes.Buffer
-	cmd := exec.Command(path)
-	cmd.Stdin = strings.NewReader(diff)
-	cmd.Stdout = &buf
-	if err := cmd.Run(); err != nil {
-		fmt.Println("warning: colordiff failed to colorize diff:", err)
-		return diff
-	}
-	colorized := buf.String()
-	var final []string
-	for _, line := range strings.Split(colorized, "\n") {
-		// fmt.Println("LINE", line)
-		// Find where the start-of-match token is in the line.
-		somToken := strings.Index(line, uniqueStartOfMatchToken)
-
-		// Find which ANSI codes are to the left of our start-of-match token.
-		indices := ansiRegexp.FindAllStringIndex(line, -1)
-		matches := ansiRegexp.FindAllString(line, -1)
-		var left []string
-		for k, index := range indices {
-			if index[0] < somToken && index[1] < somToken {
-				left = append(left, matches[k])
-			}
-		}
-
-		// Replace our start-of-match token with the color we wish.
-		line = strings.ReplaceAll(line, uniqueStartOfMatchToken, ansiColors["search-match"])
-
-		// Replace our end-of-match token with the color terminator,
-		// and start all colors that were previously started to the left.
-		line = strings.ReplaceAll(line, uniqueEndOfMatchToken, ansiColors["nc"]+strings.Join(left, ""))
-
-		final = append(final, line)
-	}
-	return strings.Join(final, "\n")
-}
-
 func stripMarkdownMarkers(content string) string {
 	content = strings.TrimPrefix(content, "```COMMIT_EDITMSG\n")
 	content = strings.TrimPrefix(content, "```diff\n")
@@ -7,10 +7,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/hexops/autogold"
 
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
 	"github.com/sourcegraph/src-cli/internal/api"
 	"github.com/sourcegraph/src-cli/internal/streaming"
 )
@@ -175,3 +179,212 @@ func TestSearchStream(t *testing.T) {
 	}
 
 }
+
+func TestSearchStreamFollowSuggestion(t *testing.T) {
+	var queries []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query().Get("q")
+		queries = append(queries, q)
+
+		writer, _ := streaming.NewWriter(w)
+		writer.Event("alert", streaming.EventAlert{
+			Title:           "No results",
+			ProposedQueries: []streaming.ProposedQuery{{Query: q + " patterntype:regexp"}},
+		})
+		writer.Event("done", nil)
+	}))
+	defer s.Close()
+
+	cfg = &config{
+		Endpoint: s.URL,
+	}
+	defer func() { cfg = nil }()
+
+	flagSet := flag.NewFlagSet("test", flag.ExitOnError)
+	client := cfg.apiClient(api.NewFlags(flagSet), flagSet.Output())
+
+	var out strings.Builder
+	if err := streamSearchFollowingSuggestion("foo", streaming.Opts{Display: -1}, client, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	// The suggestion for the suggested query must not be followed.
+	if want := []string{"foo", "foo patterntype:regexp"}; strings.Join(queries, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected queries. want=%q have=%q", want, queries)
+	}
+	if !strings.Contains(out.String(), "results for suggested query: foo patterntype:regexp") {
+		t.Fatalf("output does not label the suggested query results:\n%s", out.String())
+	}
+}
+
+func TestSearchStreamTemplate(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(mockStreamHandler))
+	defer s.Close()
+
+	cfg = &config{
+		Endpoint: s.URL,
+	}
+	defer func() { cfg = nil }()
+
+	flagSet := flag.NewFlagSet("test", flag.ExitOnError)
+	client := cfg.apiClient(api.NewFlags(flagSet), flagSet.Output())
+
+	// Commit matches have no repository field, so they are skipped.
+	var out strings.Builder
+	opts := streaming.Opts{Display: -1, Template: "{{.Type}} {{.Repository}}"}
+	if err := streamSearch("", opts, client, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "content org/repo\nrepo sourcegraph/sourcegraph\nsymbol org/repo\n"
+	if out.String() != want {
+		t.Fatalf("unexpected output. want=%q have=%q", want, out.String())
+	}
+}
+
+func TestSearchStreamCount(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		matchCount int
+		json       bool
+		want       string
+		wantErr    bool
+	}{
+		{name: "matches", matchCount: 42, want: "42\n"},
+		{name: "json", matchCount: 42, json: true, want: `{"matchCount":42,"repositoriesCount":3,"limitHit":false}` + "\n"},
+		{name: "no matches", matchCount: 0, want: "0\n", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var display string
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				display = r.URL.Query().Get("display")
+
+				repos := 3
+				writer, _ := streaming.NewWriter(w)
+				writer.Event("progress", streaming.Progress{Done: true, MatchCount: tc.matchCount, RepositoriesCount: &repos})
+				writer.Event("done", nil)
+			}))
+			defer s.Close()
+
+			cfg = &config{
+				Endpoint: s.URL,
+			}
+			defer func() { cfg = nil }()
+
+			flagSet := flag.NewFlagSet("test", flag.ExitOnError)
+			client := cfg.apiClient(api.NewFlags(flagSet), flagSet.Output())
+
+			var out strings.Builder
+			err := streamSearchCount("foo", streaming.Opts{Display: -1, Json: tc.json}, client, &out)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tc.want {
+				t.Fatalf("unexpected output. want=%q have=%q", tc.want, out.String())
+			}
+			if display != "0" {
+				t.Fatalf("expected no matches to be requested, got display=%q", display)
+			}
+		})
+	}
+}
+
+func TestSearchStreamUnsupported(t *testing.T) {
+	s := httptest.NewServer(http.NotFoundHandler())
+	defer s.Close()
+
+	cfg = &config{
+		Endpoint: s.URL,
+	}
+	defer func() { cfg = nil }()
+
+	flagSet := flag.NewFlagSet("test", flag.ExitOnError)
+	client := cfg.apiClient(api.NewFlags(flagSet), flagSet.Output())
+
+	var out strings.Builder
+	err := streamSearch("foo", streaming.Opts{Display: -1}, client, &out)
+	if !errors.Is(err, streaming.ErrStreamingUnsupported) {
+		t.Fatalf("expected ErrStreamingUnsupported, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+}
+
+func TestSearchStreamTimeout(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer, _ := streaming.NewWriter(w)
+		writer.Event("matches", []streaming.EventMatch{&streaming.EventRepoMatch{Type: streaming.RepoMatchType, Repository: "org/repo"}})
+		writer.Event("progress", streaming.Progress{
+			Done:       true,
+			MatchCount: 1,
+			Skipped:    []streaming.Skipped{{Reason: streaming.ShardTimeout, Title: "timed out"}},
+		})
+		writer.Event("done", nil)
+	}))
+	defer s.Close()
+
+	cfg = &config{
+		Endpoint: s.URL,
+	}
+	defer func() { cfg = nil }()
+
+	flagSet := flag.NewFlagSet("test", flag.ExitOnError)
+	client := cfg.apiClient(api.NewFlags(flagSet), flagSet.Output())
+
+	// The results received before the timeout are still printed.
+	var out strings.Builder
+	opts := streaming.Opts{Display: -1, Template: "{{.Repository}}", Timeout: time.Second}
+	if err := streamSearch("foo timeout:1s", opts, client, &out); !errors.Is(err, streaming.ErrTimeout) {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+	if want := "org/repo\n"; out.String() != want {
+		t.Fatalf("unexpected output. want=%q have=%q", want, out.String())
+	}
+}
+
+func TestSearchStreamCSV(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer, _ := streaming.NewWriter(w)
+		writer.Event("matches", []streaming.EventMatch{
+			&streaming.EventContentMatch{
+				Type:       streaming.ContentMatchType,
+				Repository: "org/repo",
+				Path:       "main.go",
+				ChunkMatches: []streaming.ChunkMatch{{
+					Content:      `	fmt.Println("a, b")`,
+					ContentStart: streaming.Location{Offset: 100, Line: 9},
+					Ranges: []streaming.Range{{
+						Start: streaming.Location{Offset: 113, Line: 9, Column: 13},
+						End:   streaming.Location{Offset: 119, Line: 9, Column: 19},
+					}},
+				}},
+			},
+			&streaming.EventPathMatch{Type: streaming.PathMatchType, Repository: "org/repo", Path: "README.md"},
+			&streaming.EventRepoMatch{Type: streaming.RepoMatchType, Repository: "org/repo"},
+		})
+		writer.Event("progress", streaming.Progress{Done: true, MatchCount: 3})
+		writer.Event("done", nil)
+	}))
+	defer s.Close()
+
+	cfg = &config{
+		Endpoint: s.URL,
+	}
+	defer func() { cfg = nil }()
+
+	flagSet := flag.NewFlagSet("test", flag.ExitOnError)
+	client := cfg.apiClient(api.NewFlags(flagSet), flagSet.Output())
+
+	var out strings.Builder
+	if err := streamSearch("", streaming.Opts{Display: -1, CSV: true}, client, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "repository,path,line,column,matched_text\n" +
+		`org/repo,main.go,10,14,"""a, b"""` + "\n" +
+		"org/repo,README.md,,,\n"
+	if out.String() != want {
+		t.Fatalf("unexpected output. want=%q have=%q", want, out.String())
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/grafana/regexp"
 )
@@ -131,3 +132,105 @@ func TestBuildVersionHasNewSearchInterface(t *testing.T) {
 		t.Errorf("Build version is after the new generic search interface was merged. Expected true, but got false.")
 	}
 }
+
+func TestScopeQueryToRepo(t *testing.T) {
+	tests := []struct {
+		query, repo string
+		want        string
+		warning     bool
+	}{
+		{query: "error", repo: "", want: "error"},
+		{query: "error", repo: "github.com/sourcegraph/src-cli", want: `repo:^github\.com/sourcegraph/src-cli$ error`},
+		{query: "lang:go error", repo: "gitlab.com/a+b/c", want: `repo:^gitlab\.com/a\+b/c$ lang:go error`},
+		{query: "repo:sourcegraph error", repo: "github.com/sourcegraph/src-cli", want: `repo:^github\.com/sourcegraph/src-cli$ repo:sourcegraph error`, warning: true},
+		{query: "error -r:foo", repo: "github.com/sourcegraph/src-cli", want: `repo:^github\.com/sourcegraph/src-cli$ error -r:foo`, warning: true},
+		{query: "repository", repo: "github.com/sourcegraph/src-cli", want: `repo:^github\.com/sourcegraph/src-cli$ repository`},
+	}
+	for _, tt := range tests {
+		var warnings bytes.Buffer
+		if got := scopeQueryToRepo(tt.query, tt.repo, &warnings); got != tt.want {
+			t.Errorf("scopeQueryToRepo(%q, %q) = %q, want %q", tt.query, tt.repo, got, tt.want)
+		}
+		if got := warnings.Len() > 0; got != tt.warning {
+			t.Errorf("scopeQueryToRepo(%q, %q) warned = %t, want %t", tt.query, tt.repo, got, tt.warning)
+		}
+	}
+}
+
+func TestRepoSearchQuery(t *testing.T) {
+	for query, want := range map[string]string{
+		"file:^Dockerfile$":             "file:^Dockerfile$ select:repo count:all",
+		"lang:go count:100 error":       "lang:go count:100 error select:repo",
+		"select:repo.path foo":          "select:repo.path foo count:all",
+		"repo:^github.com/sourcegraph/": "repo:^github.com/sourcegraph/ select:repo count:all",
+	} {
+		if got := repoSearchQuery(query); got != want {
+			t.Errorf("repoSearchQuery(%q) = %q, want %q", query, got, want)
+		}
+	}
+}
+
+func TestWithSearchTimeout(t *testing.T) {
+	tests := []struct {
+		query   string
+		timeout time.Duration
+		want    string
+	}{
+		{query: "error", timeout: 0, want: "error"},
+		{query: "error", timeout: 30 * time.Second, want: "error timeout:30s"},
+		{query: "timeout:10s error", timeout: 90 * time.Second, want: "timeout:1m30s error"},
+		{query: "error TIMEOUT:5s", timeout: time.Minute, want: "error timeout:1m0s"},
+	}
+	for _, tt := range tests {
+		if got := withSearchTimeout(tt.query, tt.timeout); got != tt.want {
+			t.Errorf("withSearchTimeout(%q, %s) = %q, want %q", tt.query, tt.timeout, got, tt.want)
+		}
+	}
+}
+
+func TestIsFileRelativeLineMatch(t *testing.T) {
+	lineMatch := func(preview string, offsetAndLengths ...[]interface{}) map[string]interface{} {
+		ols := make([]interface{}, 0, len(offsetAndLengths))
+		for _, ol := range offsetAndLengths {
+			ols = append(ols, ol)
+		}
+		return map[string]interface{}{"preview": preview, "lineNumber": float64(3), "offsetAndLengths": ols}
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		match map[string]interface{}
+		want  bool
+	}{
+		{
+			name:  "literal match within preview",
+			query: "foo",
+			match: lineMatch("x := foo()", []interface{}{float64(5), float64(3)}),
+			want:  false,
+		},
+		{
+			name:  "structural pattern type in query",
+			query: "foo(...) PatternType:structural",
+			match: lineMatch("x := foo()", []interface{}{float64(5), float64(5)}),
+			want:  true,
+		},
+		{
+			name:  "multi-line preview",
+			query: "foo(...)",
+			match: lineMatch("foo(\n)", []interface{}{float64(0), float64(6)}),
+			want:  true,
+		},
+		{
+			name:  "offsets beyond preview",
+			query: "foo(...)",
+			match: lineMatch("foo(", []interface{}{float64(0), float64(12)}),
+			want:  true,
+		},
+	}
+	for _, tt := range tests {
+		if got := isFileRelativeLineMatch(tt.query, tt.match); got != tt.want {
+			t.Errorf("%s: isFileRelativeLineMatch = %t, want %t", tt.name, got, tt.want)
+		}
+	}
+}
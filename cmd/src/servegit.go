@@ -6,6 +6,7 @@ import (
 	"io"
 	"log"
 	"os"
+	"time"
 
 	"github.com/sourcegraph/src-cli/internal/cmderrors"
 	"github.com/sourcegraph/src-cli/internal/servegit"
@@ -17,10 +18,21 @@ func init() {
 		fmt.Fprintf(flag.CommandLine.Output(), `'src serve-git' serves your local git repositories over HTTP for Sourcegraph to pull.
 
 USAGE
-  src [-v] serve-git [-list] [-addr :3434] [path/to/dir]
+  src [-v] serve-git [-list] [-addr :3434 | -bind :3434] [-token] [-watch [-watch-interval 2s]] [path/to/dir]
 
 By default 'src serve-git' will recursively serve your current directory on the address ':3434'.
 
+'src serve-git -bind 127.0.0.1:3434' only serves on the given interface; -bind is an alias of -addr.
+On startup the URLs at which Sourcegraph may reach the server are printed, such as the loopback,
+LAN and Docker host addresses, and 'src serve-git' fails early if the port is already in use.
+
+'src serve-git -token' generates a random token that must be part of the URL of every request,
+and prints the URL to configure in Sourcegraph. Requests without the token are rejected, and
+restarting 'src serve-git' rotates the token.
+
+'src serve-git -watch' checks the directory for added and removed repositories every -watch-interval,
+two seconds by default, and updates the list of repositories it serves without a restart.
+
 'src serve-git -list' will not start up the server. Instead it will write to stdout a list of
 repository names it would serve.
 
@@ -28,9 +40,13 @@ Documentation at https://sourcegraph.com/docs/admin/code_hosts/src_serve_git
 `)
 	}
 	var (
-		addrFlag = flagSet.String("addr", ":3434", "Address on which to serve (end with : for unused port)")
-		listFlag = flagSet.Bool("list", false, "list found repository names")
+		addrFlag          = flagSet.String("addr", ":3434", "Address on which to serve (end with : for unused port)")
+		listFlag          = flagSet.Bool("list", false, "list found repository names")
+		tokenFlag         = flagSet.Bool("token", false, "require a token generated at startup in the URL of every request")
+		watchFlag         = flagSet.Bool("watch", false, "watch the directory for added and removed repositories")
+		watchIntervalFlag = flagSet.Duration("watch-interval", 2*time.Second, "how often -watch checks the directory for changes")
 	)
+	flagSet.StringVar(addrFlag, "bind", ":3434", "Alias for -addr")
 
 	handler := func(args []string) error {
 		err := flagSet.Parse(args)
@@ -53,6 +69,16 @@ Documentation at https://sourcegraph.com/docs/admin/code_hosts/src_serve_git
 			return cmderrors.Usage("requires zero or one arguments")
 		}
 
+		if *watchIntervalFlag <= 0 {
+			return cmderrors.Usage("-watch-interval must be positive")
+		}
+
+		if !*listFlag {
+			if err := servegit.ValidateAddr(*addrFlag); err != nil {
+				return cmderrors.Usage(err.Error())
+			}
+		}
+
 		dbug := log.New(io.Discard, "", log.LstdFlags)
 		if *verbose {
 			dbug = log.New(os.Stderr, "DBUG serve-git: ", log.LstdFlags)
@@ -65,6 +91,17 @@ Documentation at https://sourcegraph.com/docs/admin/code_hosts/src_serve_git
 			Debug: dbug,
 		}
 
+		if *watchFlag {
+			s.WatchInterval = *watchIntervalFlag
+		}
+
+		if *tokenFlag && !*listFlag {
+			s.Token, err = servegit.NewToken()
+			if err != nil {
+				return err
+			}
+		}
+
 		if *listFlag {
 			repos, err := s.Repos()
 			if err != nil {
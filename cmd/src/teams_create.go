@@ -4,6 +4,7 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"os"
 	"strings"
 
 	"github.com/sourcegraph/sourcegraph/lib/errors"
@@ -20,6 +21,23 @@ Examples:
 
     	$ src teams create -name='engineering' [-display-name='Engineering Team'] [-parent-team='engineering-leadership'] [-read-only]
 
+  Create teams, subteams and memberships from an org chart file:
+
+    	$ src teams create -from-file='org-chart.yaml' [-dry-run]
+
+  The org chart is a YAML file of nested teams, members are usernames:
+
+    	teams:
+    	  - name: engineering
+    	    displayName: Engineering
+    	    members: [alice]
+    	    teams:
+    	      - name: frontend
+    	        members: [bob, carol]
+
+  Teams that already exist are kept and only missing members are added, so
+  the same org chart can be applied repeatedly.
+
 `
 
 	flagSet := flag.NewFlagSet("create", flag.ExitOnError)
@@ -33,6 +51,8 @@ Examples:
 		displayNameFlag = flagSet.String("display-name", "", "Optional additional display name for a more human-readable UI")
 		parentTeamFlag  = flagSet.String("parent-team", "", "Optional name or ID of the parent team")
 		readonlyFlag    = flagSet.Bool("read-only", false, "Optionally create the team as read-only marking it as externally managed in this UI")
+		fromFileFlag    = flagSet.String("from-file", "", "Create the teams, subteams and memberships described by the given org chart YAML file")
+		dryRunFlag      = flagSet.Bool("dry-run", false, "With -from-file, only print the teams and members that would be created")
 		apiFlags        = api.NewFlags(flagSet)
 	)
 
@@ -41,6 +61,24 @@ Examples:
 			return err
 		}
 
+		if *fromFileFlag != "" {
+			if *nameFlag != "" {
+				return errors.New("-name cannot be combined with -from-file")
+			}
+
+			chart, err := readOrgChart(*fromFileFlag)
+			if err != nil {
+				return err
+			}
+
+			applier := &orgChartApplier{
+				client: cfg.apiClient(apiFlags, flagSet.Output()),
+				dryRun: *dryRunFlag,
+				out:    os.Stdout,
+			}
+			return applier.apply(context.Background(), chart)
+		}
+
 		if *nameFlag == "" {
 			return errors.New("provide a name")
 		}
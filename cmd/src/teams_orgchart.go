@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sourcegraph/src-cli/internal/api"
+)
+
+// orgChart is the file format accepted by 'src teams create -from-file'.
+type orgChart struct {
+	Teams []orgChartTeam `yaml:"teams"`
+}
+
+type orgChartTeam struct {
+	Name        string         `yaml:"name"`
+	DisplayName string         `yaml:"displayName"`
+	ReadOnly    bool           `yaml:"readonly"`
+	Members     []string       `yaml:"members"`
+	Teams       []orgChartTeam `yaml:"teams"`
+}
+
+func readOrgChart(path string) (*orgChart, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "opening org chart")
+	}
+	defer f.Close()
+
+	var chart orgChart
+	if err := yaml.NewDecoder(f).Decode(&chart); err != nil {
+		return nil, errors.Wrap(err, "parsing org chart")
+	}
+
+	seen := map[string]bool{}
+	var validate func(teams []orgChartTeam) error
+	validate = func(teams []orgChartTeam) error {
+		for _, t := range teams {
+			if t.Name == "" {
+				return errors.New("org chart contains a team without a name")
+			}
+			if seen[t.Name] {
+				return errors.Newf("team %q appears more than once in the org chart", t.Name)
+			}
+			seen[t.Name] = true
+			if err := validate(t.Teams); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := validate(chart.Teams); err != nil {
+		return nil, err
+	}
+
+	return &chart, nil
+}
+
+// orgChartApplier creates the teams and memberships described by an org chart.
+// Teams that already exist are left as they are, apart from adding missing
+// members, so applying the same org chart twice is a no-op.
+type orgChartApplier struct {
+	client api.Client
+	dryRun bool
+	out    io.Writer
+}
+
+func (a *orgChartApplier) apply(ctx context.Context, chart *orgChart) error {
+	for _, t := range chart.Teams {
+		if err := a.applyTeam(ctx, t, "", 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyTeam applies t and then its subteams, so that parents always exist
+// before their children are created.
+func (a *orgChartApplier) applyTeam(ctx context.Context, t orgChartTeam, parent string, depth int) error {
+	indent := strings.Repeat("  ", depth)
+
+	existing, err := a.getTeam(ctx, t.Name)
+	if err != nil {
+		return errors.Wrapf(err, "looking up team %q", t.Name)
+	}
+
+	existingMembers := map[string]bool{}
+	if existing != nil {
+		fmt.Fprintf(a.out, "%s%s (exists)\n", indent, t.Name)
+		for _, m := range existing.Members.Nodes {
+			existingMembers[m.Username] = true
+		}
+	} else if a.dryRun {
+		fmt.Fprintf(a.out, "%s%s (would be created)\n", indent, t.Name)
+	} else {
+		if err := a.createTeam(ctx, t, parent); err != nil {
+			return errors.Wrapf(err, "creating team %q", t.Name)
+		}
+		fmt.Fprintf(a.out, "%s%s (created)\n", indent, t.Name)
+	}
+
+	var missing []string
+	for _, m := range t.Members {
+		if !existingMembers[m] {
+			missing = append(missing, m)
+		}
+	}
+	for _, m := range missing {
+		if a.dryRun {
+			fmt.Fprintf(a.out, "%s  + %s (would be added)\n", indent, m)
+			continue
+		}
+		if err := a.addMember(ctx, t.Name, m); err != nil {
+			return errors.Wrapf(err, "adding %q to team %q", m, t.Name)
+		}
+		fmt.Fprintf(a.out, "%s  + %s\n", indent, m)
+	}
+
+	for _, child := range t.Teams {
+		if err := a.applyTeam(ctx, child, t.Name, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type orgChartExistingTeam struct {
+	Team
+	Members struct {
+		Nodes []TeamMember
+	}
+}
+
+func (a *orgChartApplier) getTeam(ctx context.Context, name string) (*orgChartExistingTeam, error) {
+	query := `query OrgChartTeam($name: String!) {
+	team(name: $name) {
+		...TeamFields
+		members(first: 10000) {
+			nodes {
+				...TeamMemberFields
+			}
+		}
+	}
+}
+` + teamFragment + teamMemberFragment
+
+	var result struct {
+		Team *orgChartExistingTeam
+	}
+	if ok, err := a.client.NewRequest(query, map[string]interface{}{
+		"name": name,
+	}).Do(ctx, &result); err != nil || !ok {
+		return nil, err
+	}
+	return result.Team, nil
+}
+
+func (a *orgChartApplier) createTeam(ctx context.Context, t orgChartTeam, parent string) error {
+	query := `mutation CreateTeam(
+	$name: String!,
+	$displayName: String,
+	$parentTeam: String,
+	$readonly: Boolean
+) {
+	createTeam(
+		name: $name,
+		displayName: $displayName,
+		parentTeamName: $parentTeam,
+		readonly: $readonly,
+	) {
+		...TeamFields
+	}
+}
+` + teamFragment
+
+	var result struct {
+		CreateTeam Team
+	}
+	_, err := a.client.NewRequest(query, map[string]interface{}{
+		"name":        t.Name,
+		"displayName": api.NullString(t.DisplayName),
+		"parentTeam":  api.NullString(parent),
+		"readonly":    t.ReadOnly,
+	}).Do(ctx, &result)
+	return err
+}
+
+func (a *orgChartApplier) addMember(ctx context.Context, team, username string) error {
+	query := `mutation AddTeamMember(
+	$teamName: String!
+	$username: String,
+) {
+	addTeamMembers(
+		teamName: $teamName,
+		members: [{
+			username: $username,
+		}],
+	) {
+		...TeamFields
+	}
+}
+` + teamFragment
+
+	var result struct {
+		AddTeamMembers Team
+	}
+	_, err := a.client.NewRequest(query, map[string]interface{}{
+		"teamName": team,
+		"username": username,
+	}).Do(ctx, &result)
+	return err
+}
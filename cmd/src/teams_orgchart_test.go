@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sourcegraph/src-cli/internal/api"
+	mockclient "github.com/sourcegraph/src-cli/internal/api/mock"
+)
+
+// orgChartStubClient answers the requests of orgChartApplier from teams, and
+// records the mutations it receives.
+type orgChartStubClient struct {
+	*mockclient.Client
+
+	// teams maps the names of the existing teams to their members.
+	teams     map[string][]string
+	mutations []string
+}
+
+func (c *orgChartStubClient) NewRequest(query string, vars map[string]interface{}) api.Request {
+	var response interface{}
+	switch {
+	case strings.Contains(query, "query OrgChartTeam"):
+		var team *orgChartExistingTeam
+		if members, ok := c.teams[vars["name"].(string)]; ok {
+			team = &orgChartExistingTeam{Team: Team{Name: vars["name"].(string)}}
+			for _, m := range members {
+				team.Members.Nodes = append(team.Members.Nodes, TeamMember{Username: m})
+			}
+		}
+		response = map[string]interface{}{"team": team}
+	case strings.Contains(query, "mutation CreateTeam"):
+		c.mutations = append(c.mutations, "create "+vars["name"].(string)+" parent="+stringOrEmpty(vars["parentTeam"]))
+		response = map[string]interface{}{"createTeam": Team{Name: vars["name"].(string)}}
+	case strings.Contains(query, "mutation AddTeamMember"):
+		c.mutations = append(c.mutations, "add "+vars["username"].(string)+" to "+vars["teamName"].(string))
+		response = map[string]interface{}{"addTeamMembers": Team{Name: vars["teamName"].(string)}}
+	default:
+		panic("unexpected query: " + query)
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		panic(err)
+	}
+	req := &mockclient.Request{Response: string(data)}
+	req.On("Do", mock.Anything, mock.Anything).Return(true, nil)
+	return req
+}
+
+func stringOrEmpty(v interface{}) string {
+	if s, ok := v.(*string); ok && s != nil {
+		return *s
+	}
+	return ""
+}
+
+func TestOrgChartApplier(t *testing.T) {
+	chart := &orgChart{Teams: []orgChartTeam{{
+		Name:    "engineering",
+		Members: []string{"alice", "bob"},
+		Teams: []orgChartTeam{{
+			Name:    "search",
+			Members: []string{"carol"},
+		}},
+	}}}
+
+	t.Run("existing teams and missing members", func(t *testing.T) {
+		client := &orgChartStubClient{
+			Client: &mockclient.Client{},
+			teams:  map[string][]string{"engineering": {"alice"}},
+		}
+		var out bytes.Buffer
+		a := &orgChartApplier{client: client, out: &out}
+		require.NoError(t, a.apply(context.Background(), chart))
+
+		require.Equal(t, []string{
+			"add bob to engineering",
+			"create search parent=engineering",
+			"add carol to search",
+		}, client.mutations)
+		require.Equal(t, "engineering (exists)\n  + bob\n  search (created)\n    + carol\n", out.String())
+	})
+
+	t.Run("applied twice", func(t *testing.T) {
+		client := &orgChartStubClient{
+			Client: &mockclient.Client{},
+			teams: map[string][]string{
+				"engineering": {"alice", "bob"},
+				"search":      {"carol"},
+			},
+		}
+		var out bytes.Buffer
+		a := &orgChartApplier{client: client, out: &out}
+		require.NoError(t, a.apply(context.Background(), chart))
+
+		require.Empty(t, client.mutations)
+		require.Equal(t, "engineering (exists)\n  search (exists)\n", out.String())
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		client := &orgChartStubClient{
+			Client: &mockclient.Client{},
+			teams:  map[string][]string{"engineering": {"alice"}},
+		}
+		var out bytes.Buffer
+		a := &orgChartApplier{client: client, dryRun: true, out: &out}
+		require.NoError(t, a.apply(context.Background(), chart))
+
+		require.Empty(t, client.mutations)
+		require.Equal(t, "engineering (exists)\n  + bob (would be added)\n  search (would be created)\n    + carol (would be added)\n", out.String())
+	})
+}
@@ -49,6 +49,7 @@ fragment UserFields on User {
     username
     displayName
     siteAdmin
+    createdAt
     organizations {
 		nodes {
         	id
@@ -73,6 +74,7 @@ type User struct {
 	Username      string
 	DisplayName   string
 	SiteAdmin     bool
+	CreatedAt     string
 	Organizations struct {
 		Nodes []Org
 	}
@@ -81,6 +83,38 @@ type User struct {
 	URL             string
 }
 
+// userColumns are the columns that can be selected with 'src users list
+// -columns'.
+var userColumns = []string{"id", "username", "displayName", "email", "emails", "siteAdmin", "createdAt", "lastActiveTime", "organizations", "url"}
+
+// columns returns the values of userColumns for u.
+func (u User) columns() map[string]interface{} {
+	emails := []string{}
+	for _, e := range u.Emails {
+		emails = append(emails, e.Email)
+	}
+	var email string
+	if len(emails) > 0 {
+		email = emails[0]
+	}
+	orgs := []string{}
+	for _, org := range u.Organizations.Nodes {
+		orgs = append(orgs, org.Name)
+	}
+	return map[string]interface{}{
+		"id":             u.ID,
+		"username":       u.Username,
+		"displayName":    u.DisplayName,
+		"email":          email,
+		"emails":         emails,
+		"siteAdmin":      u.SiteAdmin,
+		"createdAt":      u.CreatedAt,
+		"lastActiveTime": u.UsageStatistics.LastActiveTime,
+		"organizations":  orgs,
+		"url":            u.URL,
+	}
+}
+
 type UserEmail struct {
 	Email    string
 	Verified bool
@@ -4,6 +4,8 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/sourcegraph/src-cli/internal/api"
 )
@@ -28,6 +30,10 @@ Examples:
 
     	$ src users list -tag=foo
 
+  Export the username, email and creation date of all users as CSV:
+
+    	$ src users list -first='-1' -format=csv -columns=username,email,createdAt
+
 `
 
 	flagSet := flag.NewFlagSet("list", flag.ExitOnError)
@@ -37,11 +43,13 @@ Examples:
 		fmt.Println(usage)
 	}
 	var (
-		firstFlag  = flagSet.Int("first", 1000, "Returns the first n users from the list. (use -1 for unlimited)")
-		queryFlag  = flagSet.String("query", "", `Returns users whose names match the query. (e.g. "alice")`)
-		tagFlag    = flagSet.String("tag", "", `Returns users with the given tag.`)
-		formatFlag = flagSet.String("f", "{{.Username}}", `Format for the output, using the syntax of Go package text/template. (e.g. "{{.ID}}: {{.Username}} ({{.DisplayName}})" or "{{.|json}}")`)
-		apiFlags   = api.NewFlags(flagSet)
+		firstFlag     = flagSet.Int("first", 1000, "Returns the first n users from the list. (use -1 for unlimited)")
+		queryFlag     = flagSet.String("query", "", `Returns users whose names match the query. (e.g. "alice")`)
+		tagFlag       = flagSet.String("tag", "", `Returns users with the given tag.`)
+		columnsFlag   = flagSet.String("columns", "", "Comma-separated columns to print with -format. Available: "+strings.Join(userColumns, ", ")+". Default is all columns.")
+		outFormatFlag = flagSet.String("format", "", "Print the users as csv or json, with the columns selected by -columns, instead of using -f.")
+		formatFlag    = flagSet.String("f", "{{.Username}}", `Format for the output, using the syntax of Go package text/template. (e.g. "{{.ID}}: {{.Username}} ({{.DisplayName}})" or "{{.|json}}")`)
+		apiFlags      = api.NewFlags(flagSet)
 	)
 
 	handler := func(args []string) error {
@@ -52,6 +60,10 @@ Examples:
 		ctx := context.Background()
 		client := cfg.apiClient(apiFlags, flagSet.Output())
 
+		columns, err := parseListColumnFlags(*outFormatFlag, *columnsFlag, userColumns)
+		if err != nil {
+			return err
+		}
 		tmpl, err := parseTemplate(*formatFlag)
 		if err != nil {
 			return err
@@ -92,6 +104,14 @@ first: $first,
 			return err
 		}
 
+		if *outFormatFlag != "" {
+			rows := make([]map[string]interface{}, 0, len(result.Users.Nodes))
+			for _, user := range result.Users.Nodes {
+				rows = append(rows, user.columns())
+			}
+			return writeColumns(os.Stdout, *outFormatFlag, columns, rows)
+		}
+
 		for _, user := range result.Users.Nodes {
 			if err := execTemplate(tmpl, user); err != nil {
 				return err
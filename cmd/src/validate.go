@@ -20,6 +20,7 @@ Usage:
 
 The commands are:
 
+	compose        validates a Sourcegraph deployment with docker-compose
 	install        validates a Sourcegraph installation
 	kube           validates a Sourcegraph deployment on a Kubernetes cluster
 
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/sourcegraph/src-cli/internal/validate/compose"
+)
+
+func init() {
+	usage := `'src validate compose' is a tool that validates a docker-compose based Sourcegraph deployment
+
+It inspects the containers of the deployment with the docker CLI, so it must be
+run on the Docker host, or with DOCKER_HOST set to it.
+
+Examples:
+
+	Run default deployment validation:
+		$ src validate compose
+
+	Only validate the containers of a docker-compose project:
+		$ src validate compose --project sourcegraph
+
+	Expect a different set of services to be running:
+		$ src validate compose --services sourcegraph-frontend-0,gitserver-0,pgsql
+
+	Suppress output (useful for CI/CD pipelines)
+		$ src validate compose --quiet
+`
+
+	flagSet := flag.NewFlagSet("compose", flag.ExitOnError)
+	usageFunc := func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of 'src validate %s':\n", flagSet.Name())
+		flagSet.PrintDefaults()
+		fmt.Println(usage)
+	}
+
+	var (
+		project  = flagSet.String("project", "", "(optional) the docker-compose project to validate, instead of the one of the sourcegraph-frontend-0 container")
+		services = flagSet.String("services", "", "(optional) comma-separated list of the services expected to be running, instead of the default Sourcegraph services")
+		quiet    = flagSet.Bool("quiet", false, "(optional) suppress output and return exit status only")
+	)
+
+	handler := func(args []string) error {
+		if err := flagSet.Parse(args); err != nil {
+			return err
+		}
+
+		// parse through flag options
+		var options []compose.Option
+
+		if *project != "" {
+			options = append(options, compose.WithProject(*project))
+		}
+
+		if *services != "" {
+			options = append(options, compose.WithServices(splitNames(*services)))
+		}
+
+		if *quiet {
+			options = append(options, compose.Quiet())
+		}
+
+		return compose.Validate(context.Background(), options...)
+	}
+
+	validateCommands = append(validateCommands, &command{
+		flagSet:   flagSet,
+		handler:   handler,
+		usageFunc: usageFunc,
+	})
+}
@@ -4,14 +4,16 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/azure"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
-	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
 
+	"github.com/sourcegraph/src-cli/internal/cmderrors"
 	"github.com/sourcegraph/src-cli/internal/validate/kube"
 
 	"github.com/sourcegraph/sourcegraph/lib/errors"
@@ -30,18 +32,46 @@ Examples:
 		
 	Specify the kubeconfig file())) location:
 		$ src validate kube --kubeconfig ~/.kube/config
+
+	Validate the cluster of another context than the current one:
+		$ src validate kube --context production
 	
 	Suppress output (useful for CI/CD pipelines)
 		$ src validate kube --quiet
 
+	Print the results of all checks as JSON:
+		$ src validate kube --output json
+
     Validate EKS cluster:
         $ src validate kube --eks
+
+    Validate an EKS cluster that can't be found from the current context:
+        $ src validate kube --eks --cluster-name sourcegraph
         
     Validate GKE cluster:
         $ src validate kube --gke
         
     Validate AKS cluster:
         $ src validate kube --aks
+
+    Only validate pods and services:
+        $ src validate kube --checks pods,services
+
+    Validate everything except persistent volume claims:
+        $ src validate kube --skip-checks pvcs
+
+Checks:
+
+    ` + strings.Join(kube.CheckNames(), ", ") + `
+
+    The eks-, gke- and aks- checks only run with --eks, --gke and --aks.
+
+    pvc-usage reads the usage of PVCs from the kubelets, which requires access
+    to the nodes/proxy resource. PVCs are reported as failures when 95% full.
+
+    config-refs reports the Secrets and ConfigMaps referenced by pods, through
+    volumes or their environment, that don't exist. It requires access to list
+    the Secrets of the namespace.
 `
 
 	flagSet := flag.NewFlagSet("kube", flag.ExitOnError)
@@ -52,12 +82,18 @@ Examples:
 	}
 
 	var (
-		kubeConfig *string
-		namespace  = flagSet.String("namespace", "", "(optional) specify the kubernetes namespace to use")
-		quiet      = flagSet.Bool("quiet", false, "(optional) suppress output and return exit status only")
-		eks        = flagSet.Bool("eks", false, "(optional) validate EKS cluster")
-		gke        = flagSet.Bool("gke", false, "(optional) validate GKE cluster")
-		aks        = flagSet.Bool("aks", false, "(optional) validate AKS cluster")
+		kubeConfig  *string
+		kubeContext = flagSet.String("context", "", "(optional) the kubeconfig context to use, instead of the current context")
+		namespace   = flagSet.String("namespace", "", "(optional) specify the kubernetes namespace to use")
+		quiet       = flagSet.Bool("quiet", false, "(optional) suppress output and return exit status only")
+		output      = flagSet.String("output", "text", "(optional) output format: text or json")
+		eks         = flagSet.Bool("eks", false, "(optional) validate EKS cluster")
+		gke         = flagSet.Bool("gke", false, "(optional) validate GKE cluster")
+		aks         = flagSet.Bool("aks", false, "(optional) validate AKS cluster")
+		cluster     = flagSet.String("cluster-name", "", "(optional) name of the EKS cluster to validate with --eks. Defaults to the cluster of the current context")
+		checks      = flagSet.String("checks", "", "(optional) comma-separated list of the checks to run, instead of all of them")
+		skipChecks  = flagSet.String("skip-checks", "", "(optional) comma-separated list of checks to skip")
+		diskUsage   = flagSet.Int("disk-usage-threshold", 80, "(optional) percentage of a PVC's capacity above which its usage is reported as a warning")
 	)
 
 	if home := homedir.HomeDir(); home != "" {
@@ -76,8 +112,16 @@ Examples:
 			return err
 		}
 
-		// use the current context in kubeConfig
-		config, err := clientcmd.BuildConfigFromFlags("", *kubeConfig)
+		if *diskUsage < 0 || *diskUsage > 100 {
+			return cmderrors.Usage("--disk-usage-threshold must be between 0 and 100")
+		}
+
+		if *output != "text" && *output != "json" {
+			return cmderrors.Usagef("invalid output format %q, expected text or json", *output)
+		}
+
+		// use the selected context in kubeConfig, or the current one
+		config, err := kube.ClientConfig(*kubeConfig, *kubeContext).ClientConfig()
 		if err != nil {
 			return errors.Wrap(err, "failed to load kubernetes config")
 		}
@@ -88,7 +132,10 @@ Examples:
 		}
 
 		// parse through flag options
-		var options []kube.Option
+		options := []kube.Option{
+			kube.WithKubeConfig(*kubeConfig, *kubeContext),
+			kube.WithDiskUsageThreshold(float64(*diskUsage) / 100),
+		}
 
 		if *namespace != "" {
 			options = append(options, kube.WithNamespace(*namespace))
@@ -98,10 +145,18 @@ Examples:
 			options = append(options, kube.Quiet())
 		}
 
+		if *output == "json" {
+			options = append(options, kube.JSONOutput(os.Stdout))
+		}
+
 		if *eks {
 			options = append(options, kube.GenerateAWSClients(ctx))
 		}
 
+		if *cluster != "" {
+			options = append(options, kube.WithClusterName(*cluster))
+		}
+
 		if *gke {
 			options = append(options, kube.Gke())
 		}
@@ -110,6 +165,14 @@ Examples:
 			options = append(options, kube.Aks())
 		}
 
+		if *checks != "" {
+			options = append(options, kube.WithChecks(splitNames(*checks)...))
+		}
+
+		if *skipChecks != "" {
+			options = append(options, kube.SkipChecks(splitNames(*skipChecks)...))
+		}
+
 		return kube.Validate(context.Background(), clientSet, config, options...)
 	}
 
@@ -119,3 +182,14 @@ Examples:
 		usageFunc: usageFunc,
 	})
 }
+
+// splitNames splits a comma-separated list of names given to a flag.
+func splitNames(list string) []string {
+	var names []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -17,6 +18,8 @@ import (
 	ioaux "github.com/jig/teereadcloser"
 	"github.com/kballard/go-shellquote"
 	"github.com/mattn/go-isatty"
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+	"golang.org/x/sync/singleflight"
 
 	"github.com/sourcegraph/src-cli/internal/version"
 )
@@ -30,6 +33,10 @@ type Client interface {
 	// NewRequest creates a GraphQL request.
 	NewRequest(query string, vars map[string]interface{}) Request
 
+	// NewRequestWithOpts creates a GraphQL request with options that
+	// override the defaults of the client for this request.
+	NewRequestWithOpts(query string, vars map[string]interface{}, opts RequestOpts) Request
+
 	// NewHTTPRequest creates an http.Request for the Sourcegraph API.
 	//
 	// path is joined against the API route. For example on Sourcegraph.com this
@@ -38,6 +45,10 @@ type Client interface {
 
 	// Do runs an http.Request against the Sourcegraph API.
 	Do(req *http.Request) (*http.Response, error)
+
+	// Ping checks that the Sourcegraph instance can be reached, through the
+	// configured proxy if any. It doesn't check the access token.
+	Ping(ctx context.Context) error
 }
 
 // Request instances represent GraphQL requests.
@@ -56,10 +67,34 @@ type Request interface {
 	DoRaw(ctx context.Context, result interface{}) (ok bool, err error)
 }
 
+// RequestOpts are the options of a single GraphQL request.
+type RequestOpts struct {
+	// Gzip compresses the body of the request. Compression pays off for
+	// large requests, such as mutations uploading specs, but only adds
+	// overhead to small queries.
+	Gzip bool
+
+	// Deduplicate makes concurrent requests with the same query and
+	// variables share a single network call and its response. It must only
+	// be set for queries without side effects. The shared call uses the
+	// context of the request that started it, so canceling that request
+	// fails the others too.
+	Deduplicate bool
+}
+
 // client is the internal concrete type implementing Client.
 type client struct {
 	opts       ClientOpts
 	httpClient *http.Client
+	limiter    *rateLimiter
+	breaker    *circuitBreaker
+	// inflight coalesces concurrent identical GraphQL requests that have
+	// RequestOpts.Deduplicate set.
+	inflight *singleflight.Group
+
+	// err is returned by every request if the client couldn't be configured,
+	// such as when the -cacert file can't be read.
+	err error
 }
 
 // request is the internal concrete type implementing Request.
@@ -67,6 +102,7 @@ type request struct {
 	client *client
 	query  string
 	vars   map[string]interface{}
+	opts   RequestOpts
 }
 
 // ClientOpts encapsulates the options given to NewClient.
@@ -85,6 +121,29 @@ type ClientOpts struct {
 
 	ProxyURL  *url.URL
 	ProxyPath string
+	// ProxyAuth, if set, is sent as the Proxy-Authorization header when
+	// connecting through an HTTP(S) proxy.
+	ProxyAuth string
+
+	// InsecureSkipVerify and CACert are the global TLS options. They are used
+	// unless the -insecure-skip-verify and -cacert flags in Flags are given
+	// explicitly.
+	InsecureSkipVerify bool
+	CACert             string
+
+	// WrapTransport, if set, is called with the fully configured transport
+	// and returns the http.RoundTripper the client uses instead. This allows
+	// callers to add middleware, such as for tracing or mocking requests.
+	WrapTransport func(http.RoundTripper) http.RoundTripper
+
+	// CircuitBreaker, if set, makes requests fail fast with ErrCircuitOpen
+	// after the endpoint failed repeatedly.
+	CircuitBreaker *CircuitBreakerOpts
+
+	// DisableGzip makes requests created with NewRequest and NewQuery send
+	// their bodies uncompressed. Requests created with NewRequestWithOpts
+	// decide for themselves.
+	DisableGzip bool
 }
 
 // NewClient creates a new API client.
@@ -103,7 +162,15 @@ func NewClient(opts ClientOpts) Client {
 	transport := http.DefaultTransport.(*http.Transport).Clone()
 	customTransport := false
 
-	if flags.insecureSkipVerify != nil && *flags.insecureSkipVerify {
+	insecureSkipVerify, caCert := opts.InsecureSkipVerify, opts.CACert
+	if flags.isSet("insecure-skip-verify") {
+		insecureSkipVerify = *flags.insecureSkipVerify
+	}
+	if flags.isSet("cacert") {
+		caCert = *flags.caCert
+	}
+
+	if insecureSkipVerify {
 		customTransport = true
 		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 	}
@@ -112,11 +179,21 @@ func NewClient(opts ClientOpts) Client {
 		transport.TLSClientConfig = &tls.Config{}
 	}
 
-	if applyProxy(transport, opts.ProxyURL, opts.ProxyPath) {
+	var err error
+	if caCert != "" {
+		customTransport = true
+		transport.TLSClientConfig.RootCAs, err = loadCACert(caCert)
+	}
+
+	if applyProxy(transport, opts.ProxyURL, opts.ProxyPath, opts.ProxyAuth) {
 		customTransport = true
 	}
 
-	if customTransport {
+	if opts.WrapTransport != nil {
+		httpClient = &http.Client{
+			Transport: opts.WrapTransport(transport),
+		}
+	} else if customTransport {
 		httpClient = &http.Client{
 			Transport: transport,
 		}
@@ -129,24 +206,95 @@ func NewClient(opts ClientOpts) Client {
 			AdditionalHeaders: opts.AdditionalHeaders,
 			Flags:             flags,
 			Out:               opts.Out,
+			DisableGzip:       opts.DisableGzip,
 		},
 		httpClient: httpClient,
+		limiter:    newRateLimiter(),
+		breaker:    newCircuitBreaker(opts.CircuitBreaker),
+		inflight:   &singleflight.Group{},
+		err:        err,
 	}
 }
+
+// loadCACert returns the system certificate pool with the certificates in the
+// PEM file at path added.
+func loadCACert(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading CA certificates")
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.Errorf("no PEM certificates found in %s", path)
+	}
+	return pool, nil
+}
 func (c *client) NewQuery(query string) Request {
 	return c.NewRequest(query, nil)
 }
 
 func (c *client) NewRequest(query string, vars map[string]interface{}) Request {
+	return c.NewRequestWithOpts(query, vars, RequestOpts{Gzip: !c.opts.DisableGzip})
+}
+
+func (c *client) NewRequestWithOpts(query string, vars map[string]interface{}, opts RequestOpts) Request {
 	return &request{
 		client: c,
 		query:  query,
 		vars:   vars,
+		opts:   opts,
 	}
 }
 
 func (c *client) Do(req *http.Request) (*http.Response, error) {
-	return c.httpClient.Do(req)
+	return c.do(req)
+}
+
+// do sends req, throttling it according to the rate limit state reported by
+// previous responses, unless the circuit breaker is open.
+func (c *client) do(req *http.Request) (*http.Response, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if err := c.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	if err := c.breaker.allow(); err != nil {
+		return nil, errors.Wrap(err, c.opts.Endpoint)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if req.Context().Err() != nil {
+		c.breaker.release()
+	} else {
+		c.breaker.record(err != nil || unavailable(resp))
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.limiter.Update(resp)
+
+	return resp, nil
+}
+
+func (c *client) Ping(ctx context.Context) error {
+	req, err := c.createHTTPRequest(ctx, "HEAD", "", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("unexpected response from %s: %s", c.opts.Endpoint, resp.Status)
+	}
+	return nil
 }
 
 func (c *client) NewHTTPRequest(ctx context.Context, method, p string, body io.Reader) (*http.Request, error) {
@@ -215,30 +363,83 @@ func (r *request) do(ctx context.Context, result interface{}) (bool, error) {
 		return false, err
 	}
 
+	var body io.ReadCloser
+	if r.opts.Deduplicate {
+		// The JSON object is the key: json.Marshal sorts map keys, so equal
+		// variables always marshal the same.
+		data, err, _ := r.client.inflight.Do(string(reqBody), func() (interface{}, error) {
+			body, err := r.send(ctx, reqBody)
+			if err != nil {
+				return nil, err
+			}
+			defer body.Close()
+			return io.ReadAll(body)
+		})
+		if err != nil {
+			return false, err
+		}
+		body = io.NopCloser(bytes.NewReader(data.([]byte)))
+	} else {
+		body, err = r.send(ctx, reqBody)
+		if err != nil {
+			return false, err
+		}
+	}
+	defer body.Close()
+
+	if *r.client.opts.Flags.dump {
+		var buf bytes.Buffer
+		body = ioaux.TeeReadCloser(body, &buf)
+		defer func() {
+			var out bytes.Buffer
+			_ = json.Indent(&out, buf.Bytes(), "    ", "    ")
+			fmt.Fprintf(r.client.opts.Out, "--> %s\n\n", out.String())
+		}()
+	}
+
+	// Decode the response.
+	if err := json.NewDecoder(body).Decode(result); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// send posts the JSON object reqBody to the GraphQL endpoint and returns the
+// body of the response, which the caller must close.
+func (r *request) send(ctx context.Context, reqBody []byte) (io.ReadCloser, error) {
 	var bufBody io.Reader = bytes.NewBuffer(reqBody)
-	bufBody = gzipReader(bufBody)
+	if r.opts.Gzip {
+		bufBody = gzipReader(bufBody)
+	}
 
 	// Create the HTTP request.
 	req, err := r.client.NewHTTPRequest(ctx, "POST", ".api/graphql", bufBody)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
-	// Use gzip compression.
-	req.Header.Set("Content-Encoding", "gzip")
+	if r.opts.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
 
 	// Perform the request.
-	resp, err := r.client.httpClient.Do(req)
+	resp, err := r.client.do(req)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	// Check trace header before we potentially early exit
 	if *r.client.opts.Flags.trace {
 		_, err := r.client.opts.Out.Write([]byte(fmt.Sprintf("x-trace: %s\n", resp.Header.Get("x-trace"))))
 		if err != nil {
-			return false, err
+			resp.Body.Close()
+			return nil, err
+		}
+		_, err = r.client.opts.Out.Write([]byte(fmt.Sprintf("rate-limit: %s\n", r.client.limiter)))
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
 		}
 	}
 
@@ -246,6 +447,7 @@ func (r *request) do(ctx context.Context, result interface{}) (bool, error) {
 	// confirm the status code. You can test this easily with e.g. an invalid
 	// endpoint like -endpoint=https://google.com
 	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
 		if resp.StatusCode == http.StatusUnauthorized && isatty.IsCygwinTerminal(os.Stdout.Fd()) {
 			fmt.Println("You may need to specify or update your access token to use this endpoint.")
 			fmt.Println("See https://github.com/sourcegraph/src-cli#readme")
@@ -253,28 +455,12 @@ func (r *request) do(ctx context.Context, result interface{}) (bool, error) {
 		}
 		body, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return false, err
+			return nil, err
 		}
-		return false, fmt.Errorf("error: %s\n\n%s", resp.Status, body)
+		return nil, fmt.Errorf("error: %s\n\n%s", resp.Status, body)
 	}
 
-	body := resp.Body
-	if *r.client.opts.Flags.dump {
-		var buf bytes.Buffer
-		body = ioaux.TeeReadCloser(resp.Body, &buf)
-		defer func() {
-			var out bytes.Buffer
-			_ = json.Indent(&out, buf.Bytes(), "    ", "    ")
-			fmt.Fprintf(r.client.opts.Out, "--> %s\n\n", out.String())
-		}()
-	}
-
-	// Decode the response.
-	if err := json.NewDecoder(body).Decode(result); err != nil {
-		return false, err
-	}
-
-	return true, nil
+	return resp.Body, nil
 }
 
 // Do executes the request. Successful requests will be unmarshalled into the
@@ -1,3 +1,130 @@
 package api
 
+import (
+	"bytes"
+	"context"
+	"encoding/pem"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
 // TODO: implement a super basic GraphQL server that can return canned results.
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestNewClient_WrapTransport(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Wrapped"); got != "yes" {
+			t.Errorf("X-Wrapped header = %q, want %q", got, "yes")
+		}
+	}))
+	defer ts.Close()
+
+	wrapped := 0
+	client := NewClient(ClientOpts{
+		Endpoint: ts.URL,
+		Out:      &bytes.Buffer{},
+		WrapTransport: func(next http.RoundTripper) http.RoundTripper {
+			return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				wrapped++
+				req.Header.Set("X-Wrapped", "yes")
+				return next.RoundTrip(req)
+			})
+		},
+	})
+
+	req, err := client.NewHTTPRequest(context.Background(), http.MethodGet, "graphql", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if wrapped != 1 {
+		t.Errorf("transport wrapped %d requests, want 1", wrapped)
+	}
+}
+
+func TestClient_Ping(t *testing.T) {
+	status := http.StatusOK
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	client := NewClient(ClientOpts{Endpoint: ts.URL, Out: &bytes.Buffer{}})
+	if err := client.Ping(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Client errors, such as a missing token, still mean the instance is up.
+	status = http.StatusUnauthorized
+	if err := client.Ping(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	status = http.StatusBadGateway
+	if err := client.Ping(context.Background()); err == nil {
+		t.Error("expected error for 502 response")
+	}
+}
+
+func TestNewClient_TLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	caCert := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := os.WriteFile(caCert, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	newFlags := func(t *testing.T, args ...string) *Flags {
+		t.Helper()
+		flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags := NewFlags(flagSet)
+		if err := flagSet.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return flags
+	}
+
+	for _, tt := range []struct {
+		name    string
+		opts    ClientOpts
+		args    []string
+		wantErr bool
+	}{
+		{name: "untrusted certificate", wantErr: true},
+		{name: "global insecure-skip-verify", opts: ClientOpts{InsecureSkipVerify: true}},
+		{name: "command insecure-skip-verify", args: []string{"-insecure-skip-verify"}},
+		{name: "command overrides global insecure-skip-verify", opts: ClientOpts{InsecureSkipVerify: true}, args: []string{"-insecure-skip-verify=false"}, wantErr: true},
+		{name: "global cacert", opts: ClientOpts{CACert: caCert}},
+		{name: "command cacert", args: []string{"-cacert", caCert}},
+		{name: "command overrides global cacert", opts: ClientOpts{CACert: filepath.Join(t.TempDir(), "missing.pem")}, args: []string{"-cacert", caCert}},
+		{name: "missing cacert", opts: ClientOpts{CACert: filepath.Join(t.TempDir(), "missing.pem")}, wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.Endpoint = ts.URL
+			opts.Out = &bytes.Buffer{}
+			opts.Flags = newFlags(t, tt.args...)
+
+			err := NewClient(opts).Ping(context.Background())
+			if tt.wantErr && err == nil {
+				t.Error("expected error")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
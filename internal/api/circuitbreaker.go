@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+)
+
+// ErrCircuitOpen is returned for requests that aren't sent because the circuit
+// breaker of the client is open.
+var ErrCircuitOpen = errors.New("endpoint unavailable (circuit open)")
+
+// CircuitBreakerOpts configure the circuit breaker of a client.
+type CircuitBreakerOpts struct {
+	// Failures is the number of consecutive failed requests within Window
+	// that opens the circuit.
+	Failures int
+	Window   time.Duration
+	// Cooldown is how long requests fail fast once the circuit is open. After
+	// it, a single request is sent to probe whether the endpoint is back.
+	Cooldown time.Duration
+}
+
+// circuitBreaker stops sending requests to an endpoint that keeps failing, so
+// that when an instance is down the requests queued up behind each other fail
+// immediately instead of each waiting for a timeout.
+//
+// A request fails if it can't be sent or the response says the server is
+// unavailable. A nil *circuitBreaker never opens. A circuitBreaker is safe for
+// concurrent use.
+type circuitBreaker struct {
+	opts CircuitBreakerOpts
+	now  func() time.Time
+
+	mu sync.Mutex
+	// failures is the number of consecutive failures since firstFailure.
+	failures     int
+	firstFailure time.Time
+	// openUntil is when the cooldown of an open circuit ends.
+	openUntil time.Time
+	// open is whether the circuit is open, and probing whether a request
+	// probing an open circuit is in flight.
+	open    bool
+	probing bool
+}
+
+func newCircuitBreaker(opts *CircuitBreakerOpts) *circuitBreaker {
+	if opts == nil || opts.Failures <= 0 {
+		return nil
+	}
+	return &circuitBreaker{opts: *opts, now: time.Now}
+}
+
+// allow returns ErrCircuitOpen if a request must not be sent.
+func (b *circuitBreaker) allow() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.open {
+		return nil
+	}
+	if b.probing || b.now().Before(b.openUntil) {
+		return ErrCircuitOpen
+	}
+	b.probing = true
+	return nil
+}
+
+// record records the outcome of a request that was allowed.
+func (b *circuitBreaker) record(failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if !failed {
+		b.failures = 0
+		b.open = false
+		b.probing = false
+		return
+	}
+
+	if b.probing {
+		// The endpoint is still down.
+		b.probing = false
+		b.openUntil = now.Add(b.opts.Cooldown)
+		return
+	}
+
+	if b.failures == 0 || now.Sub(b.firstFailure) > b.opts.Window {
+		b.failures = 0
+		b.firstFailure = now
+	}
+	b.failures++
+	if b.failures >= b.opts.Failures {
+		b.open = true
+		b.openUntil = now.Add(b.opts.Cooldown)
+	}
+}
+
+// release records that a request that was allowed was canceled, which says
+// nothing about the endpoint.
+func (b *circuitBreaker) release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+// unavailable reports whether resp says the server can't handle requests.
+func unavailable(resp *http.Response) bool {
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
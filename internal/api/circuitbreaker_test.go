@@ -0,0 +1,80 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newCircuitBreaker(&CircuitBreakerOpts{
+		Failures: 3,
+		Window:   10 * time.Second,
+		Cooldown: 30 * time.Second,
+	})
+	b.now = func() time.Time { return now }
+
+	send := func(failed bool) error {
+		if err := b.allow(); err != nil {
+			return err
+		}
+		b.record(failed)
+		return nil
+	}
+
+	// Failures spread over more than the window don't open the circuit.
+	for i := 0; i < 4; i++ {
+		if err := send(true); err != nil {
+			t.Fatalf("unexpected error before the circuit opened: %s", err)
+		}
+		now = now.Add(6 * time.Second)
+	}
+
+	// A success resets the failure count.
+	if err := send(false); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := send(true); err != nil {
+			t.Fatalf("unexpected error before the circuit opened: %s", err)
+		}
+	}
+	if err := send(false); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the circuit to be open, got %v", err)
+	}
+
+	// After the cooldown a single probe is let through, and a failed probe
+	// opens the circuit again.
+	now = now.Add(31 * time.Second)
+	if err := b.allow(); err != nil {
+		t.Fatalf("expected a probe after the cooldown, got %s", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected a single probe, got %v", err)
+	}
+	b.record(true)
+	if err := send(false); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected the circuit to be open after a failed probe, got %v", err)
+	}
+
+	// A successful probe closes the circuit.
+	now = now.Add(31 * time.Second)
+	if err := send(false); err != nil {
+		t.Fatalf("expected a probe after the cooldown, got %s", err)
+	}
+	if err := send(true); err != nil {
+		t.Fatalf("expected the circuit to be closed after a successful probe, got %s", err)
+	}
+}
+
+func TestCircuitBreaker_Disabled(t *testing.T) {
+	b := newCircuitBreaker(nil)
+	for i := 0; i < 100; i++ {
+		if err := b.allow(); err != nil {
+			t.Fatal(err)
+		}
+		b.record(true)
+	}
+}
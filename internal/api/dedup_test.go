@@ -0,0 +1,84 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_Deduplicate(t *testing.T) {
+	var calls atomic.Int32
+	arrived := make(chan struct{}, 10)
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		arrived <- struct{}{}
+		<-release
+		w.Write([]byte(`{"data": {"repository": {"id": "UmVwb3NpdG9yeTox"}}}`))
+	}))
+	defer ts.Close()
+
+	client := NewClient(ClientOpts{Endpoint: ts.URL, Out: &bytes.Buffer{}})
+
+	run := func(n int, query string, opts RequestOpts) []string {
+		ids := make([]string, n)
+		var wg sync.WaitGroup
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				var result struct {
+					Repository struct{ ID string }
+				}
+				if _, err := client.NewRequestWithOpts(query, map[string]interface{}{"name": "github.com/sourcegraph/src-cli"}, opts).Do(context.Background(), &result); err != nil {
+					t.Error(err)
+				}
+				ids[i] = result.Repository.ID
+			}(i)
+		}
+		wg.Wait()
+		return ids
+	}
+
+	t.Run("queries", func(t *testing.T) {
+		calls.Store(0)
+		go func() {
+			<-arrived
+			// Give the other requests time to join the one in flight.
+			time.Sleep(100 * time.Millisecond)
+			close(release)
+		}()
+
+		ids := run(5, `query Repository($name: String!) { repository(name: $name) { id } }`, RequestOpts{Deduplicate: true})
+		if got := calls.Load(); got != 1 {
+			t.Errorf("sent %d requests, want 1", got)
+		}
+		for _, id := range ids {
+			if id != "UmVwb3NpdG9yeTox" {
+				t.Errorf("wrong result %q", id)
+			}
+		}
+	})
+
+	t.Run("not opted in", func(t *testing.T) {
+		for name, query := range map[string]string{
+			"query":              `query Repository($name: String!) { repository(name: $name) { id } }`,
+			"commented mutation": "# Touches the repository.\nmutation Touch($name: String!) { touch(name: $name) { id } }",
+		} {
+			t.Run(name, func(t *testing.T) {
+				// release was closed by the previous test, so the server
+				// answers right away.
+				calls.Store(0)
+				run(2, query, RequestOpts{})
+				if got := calls.Load(); got != 2 {
+					t.Errorf("sent %d requests, want 2", got)
+				}
+			})
+		}
+	})
+}
@@ -12,7 +12,12 @@ type Flags struct {
 	getCurl            *bool
 	trace              *bool
 	insecureSkipVerify *bool
+	caCert             *string
 	userAgentTelemetry *bool
+
+	// flagSet is the flag set the flags were added to, used to tell whether
+	// they were given explicitly. It's nil for the default flags.
+	flagSet *flag.FlagSet
 }
 
 func (f *Flags) Trace() bool {
@@ -29,6 +34,21 @@ func (f *Flags) UserAgentTelemetry() bool {
 	return *(f.userAgentTelemetry)
 }
 
+// isSet reports whether the flag with the given name was given explicitly, in
+// which case it overrides the corresponding global option.
+func (f *Flags) isSet(name string) bool {
+	if f.flagSet == nil {
+		return false
+	}
+	set := false
+	f.flagSet.Visit(func(fl *flag.Flag) {
+		if fl.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
 // NewFlags instantiates a new Flags structure and attaches flags to the given
 // flag set.
 func NewFlags(flagSet *flag.FlagSet) *Flags {
@@ -36,19 +56,23 @@ func NewFlags(flagSet *flag.FlagSet) *Flags {
 		dump:               flagSet.Bool("dump-requests", false, "Log GraphQL requests and responses to stdout"),
 		getCurl:            flagSet.Bool("get-curl", false, "Print the curl command for executing this query and exit (WARNING: includes printing your access token!)"),
 		trace:              flagSet.Bool("trace", false, "Log the trace ID for requests. See https://docs.sourcegraph.com/admin/observability/tracing"),
-		insecureSkipVerify: flagSet.Bool("insecure-skip-verify", false, "Skip validation of TLS certificates against trusted chains"),
+		insecureSkipVerify: flagSet.Bool("insecure-skip-verify", false, "Skip validation of TLS certificates against trusted chains. Overrides the global -insecure-skip-verify option"),
+		caCert:             flagSet.String("cacert", "", "Path to a PEM file with CA certificates to trust in addition to the system ones. Overrides the global -cacert option"),
 		userAgentTelemetry: flagSet.Bool("user-agent-telemetry", defaultUserAgentTelemetry(), "Include the operating system and architecture in the User-Agent sent with requests to Sourcegraph"),
+		flagSet:            flagSet,
 	}
 }
 
 func defaultFlags() *Flags {
 	telemetry := defaultUserAgentTelemetry()
 	d := false
+	caCert := ""
 	return &Flags{
 		dump:               &d,
 		getCurl:            &d,
 		trace:              &d,
 		insecureSkipVerify: &d,
+		caCert:             &caCert,
 		userAgentTelemetry: &telemetry,
 	}
 }
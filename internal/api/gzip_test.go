@@ -3,7 +3,11 @@ package api
 import (
 	"bytes"
 	"compress/gzip"
+	"context"
+	"encoding/json"
 	"io"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -32,3 +36,62 @@ func TestGzipReader(t *testing.T) {
 		t.Errorf("unexpected gzipped contents (-want +got):\n%s", diff)
 	}
 }
+
+func TestRequestGzip(t *testing.T) {
+	var (
+		gotEncoding string
+		gotQuery    string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotEncoding = r.Header.Get("Content-Encoding")
+		body := io.Reader(r.Body)
+		if gotEncoding == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Errorf("unexpected error creating gzip.Reader: %s", err)
+				return
+			}
+			body = zr
+		}
+		var req struct{ Query string }
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			t.Errorf("unexpected error decoding request: %s", err)
+		}
+		gotQuery = req.Query
+		w.Write([]byte(`{"data": {}}`))
+	}))
+	defer ts.Close()
+
+	for _, tc := range []struct {
+		name         string
+		disableGzip  bool
+		opts         *RequestOpts
+		wantEncoding string
+	}{
+		{name: "default", wantEncoding: "gzip"},
+		{name: "default disabled", disableGzip: true, wantEncoding: ""},
+		{name: "request opts out", opts: &RequestOpts{Gzip: false}, wantEncoding: ""},
+		{name: "request opts in", disableGzip: true, opts: &RequestOpts{Gzip: true}, wantEncoding: "gzip"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewClient(ClientOpts{Endpoint: ts.URL, Out: &bytes.Buffer{}, DisableGzip: tc.disableGzip})
+			const query = `query { currentUser { id } }`
+
+			req := client.NewRequest(query, nil)
+			if tc.opts != nil {
+				req = client.NewRequestWithOpts(query, nil, *tc.opts)
+			}
+			var result struct{}
+			if _, err := req.Do(context.Background(), &result); err != nil {
+				t.Fatal(err)
+			}
+
+			if gotEncoding != tc.wantEncoding {
+				t.Errorf("Content-Encoding: want %q, got %q", tc.wantEncoding, gotEncoding)
+			}
+			if gotQuery != query {
+				t.Errorf("query: want %q, got %q", query, gotQuery)
+			}
+		})
+	}
+}
@@ -24,6 +24,11 @@ func (m *Client) NewRequest(query string, vars map[string]interface{}) api.Reque
 	return args.Get(0).(api.Request)
 }
 
+func (m *Client) NewRequestWithOpts(query string, vars map[string]interface{}, opts api.RequestOpts) api.Request {
+	args := m.Called(query, vars, opts)
+	return args.Get(0).(api.Request)
+}
+
 func (m *Client) NewGzippedRequest(query string, vars map[string]interface{}) api.Request {
 	args := m.Called(query, vars)
 	return args.Get(0).(api.Request)
@@ -52,6 +57,11 @@ func (m *Client) Do(req *http.Request) (*http.Response, error) {
 	return obj, args.Error(1)
 }
 
+func (m *Client) Ping(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
 type Request struct {
 	mock.Mock
 	Response string
@@ -11,7 +11,7 @@ import (
 	"net/url"
 )
 
-func applyProxy(transport *http.Transport, proxyURL *url.URL, proxyPath string) (applied bool) {
+func applyProxy(transport *http.Transport, proxyURL *url.URL, proxyPath, proxyAuth string) (applied bool) {
 	if proxyURL == nil && proxyPath == "" {
 		return false
 	}
@@ -24,9 +24,10 @@ func applyProxy(transport *http.Transport, proxyURL *url.URL, proxyPath string)
 		}
 		tlsConn := tls.Client(conn, &tls.Config{
 			ServerName: host,
-			// Pull InsecureSkipVerify from the target host transport
-			// so that insecure-skip-verify flag settings are honored for the proxy server
+			// Pull InsecureSkipVerify and RootCAs from the target host transport
+			// so that insecure-skip-verify and cacert settings are honored for the proxy server
 			InsecureSkipVerify: transport.TLSClientConfig.InsecureSkipVerify,
+			RootCAs:            transport.TLSClientConfig.RootCAs,
 		})
 		if err := tlsConn.HandshakeContext(ctx); err != nil {
 			return nil, err
@@ -89,8 +90,11 @@ func applyProxy(transport *http.Transport, proxyURL *url.URL, proxyPath string)
 				// A Host header is required per RFC 2616, section 14.23
 				connectReq += fmt.Sprintf("Host: %s\r\n", addr)
 
-				// use authentication if proxy credentials are present
-				if proxyURL.User != nil {
+				// use authentication if proxy credentials are present, preferring
+				// an explicitly configured header over credentials in the URL
+				if proxyAuth != "" {
+					connectReq += fmt.Sprintf("Proxy-Authorization: %s\r\n", proxyAuth)
+				} else if proxyURL.User != nil {
 					password, _ := proxyURL.User.Password()
 					auth := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
 					connectReq += fmt.Sprintf("Proxy-Authorization: Basic %s\r\n", auth)
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestApplyProxy_ProxyAuthorization(t *testing.T) {
+	tests := []struct {
+		name      string
+		proxyURL  string
+		proxyAuth string
+		want      string
+	}{
+		{
+			name:     "no credentials",
+			proxyURL: "http://%s",
+			want:     "",
+		},
+		{
+			name:     "credentials in URL",
+			proxyURL: "http://user:pass@%s",
+			want:     "Basic dXNlcjpwYXNz",
+		},
+		{
+			name:      "explicit header",
+			proxyURL:  "http://%s",
+			proxyAuth: "Bearer abc",
+			want:      "Bearer abc",
+		},
+		{
+			name:      "explicit header takes precedence",
+			proxyURL:  "http://user:pass@%s",
+			proxyAuth: "Bearer abc",
+			want:      "Bearer abc",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer l.Close()
+
+			headers := make(chan string, 1)
+			go func() {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+				req, err := http.ReadRequest(bufio.NewReader(conn))
+				if err != nil {
+					headers <- "error: " + err.Error()
+					return
+				}
+				headers <- req.Header.Get("Proxy-Authorization")
+				_, _ = conn.Write([]byte("HTTP/1.1 200 OK\r\n\r\n"))
+			}()
+
+			proxyURL, err := url.Parse(fmt.Sprintf(tc.proxyURL, l.Addr().String()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			if !applyProxy(transport, proxyURL, "", tc.proxyAuth) {
+				t.Fatal("proxy not applied")
+			}
+
+			conn, err := transport.DialContext(context.Background(), "tcp", "sourcegraph.test:443")
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn.Close()
+
+			if have := <-headers; have != tc.want {
+				t.Fatalf("wrong Proxy-Authorization header. want=%q have=%q", tc.want, have)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// rateLimiter throttles requests based on the rate limit headers returned by
+// Sourcegraph, so that we slow down before the server starts responding with
+// 429s. It behaves like a token bucket: the remaining requests reported by the
+// server are the available tokens, and the bucket is refilled once the
+// reported reset time has passed.
+//
+// A rateLimiter is safe for concurrent use, and a single instance is shared by
+// all requests made through a client.
+type rateLimiter struct {
+	now func() time.Time
+
+	mu sync.Mutex
+	// limit is the number of requests allowed per window, or -1 if unknown.
+	limit int
+	// remaining is the number of requests left in the current window, or -1
+	// if unknown.
+	remaining int
+	// reset is when the current window ends.
+	reset time.Time
+	// retryAfter is set when the server explicitly asked us to back off.
+	retryAfter time.Time
+}
+
+func newRateLimiter() *rateLimiter {
+	return &rateLimiter{
+		now:       time.Now,
+		limit:     -1,
+		remaining: -1,
+	}
+}
+
+// Wait blocks until a request may be sent, or ctx is done.
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	d := l.reserve()
+	if d <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// reserve takes a token from the bucket and returns how long the caller has
+// to wait before sending its request.
+func (l *rateLimiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Before(l.retryAfter) {
+		return l.retryAfter.Sub(now)
+	}
+
+	if l.remaining < 0 {
+		// We don't know anything about the limit, so don't throttle.
+		return 0
+	}
+
+	if !now.Before(l.reset) {
+		// The window has passed, so the bucket is full again.
+		l.remaining = l.limit
+	}
+
+	if l.remaining > 0 {
+		l.remaining--
+		return 0
+	}
+
+	// The bucket is empty: wait for the window to reset. The requests queued
+	// up behind this one will be told the same, and the next response will
+	// update our view of the limit.
+	return l.reset.Sub(now)
+}
+
+// Update records the rate limit state reported in resp.
+func (l *rateLimiter) Update(resp *http.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+
+	if limit, ok := headerInt(resp.Header, "X-RateLimit-Limit"); ok {
+		l.limit = limit
+	}
+	if remaining, ok := headerInt(resp.Header, "X-RateLimit-Remaining"); ok {
+		l.remaining = remaining
+		if l.limit < remaining {
+			l.limit = remaining
+		}
+	}
+	if reset, ok := headerInt(resp.Header, "X-RateLimit-Reset"); ok {
+		l.reset = now.Add(time.Duration(reset) * time.Second)
+	}
+
+	if retryAfter, ok := headerInt(resp.Header, "Retry-After"); ok {
+		l.retryAfter = now.Add(time.Duration(retryAfter) * time.Second)
+	} else if resp.StatusCode == http.StatusTooManyRequests {
+		l.retryAfter = now.Add(time.Second)
+	}
+}
+
+// String describes the current rate limit state, for use with -trace.
+func (l *rateLimiter) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.remaining < 0 {
+		return "unknown"
+	}
+
+	s := fmt.Sprintf("%d/%d remaining", l.remaining, l.limit)
+	now := l.now()
+	if now.Before(l.reset) {
+		s += fmt.Sprintf(", resets in %s", l.reset.Sub(now).Round(time.Second))
+	}
+	if now.Before(l.retryAfter) {
+		s += fmt.Sprintf(", retry after %s", l.retryAfter.Sub(now).Round(time.Second))
+	}
+	return s
+}
+
+func headerInt(h http.Header, key string) (int, bool) {
+	v := h.Get(key)
+	if v == "" {
+		return 0, false
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil || i < 0 {
+		return 0, false
+	}
+	return i, true
+}
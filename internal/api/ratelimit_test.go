@@ -0,0 +1,56 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := newRateLimiter()
+	l.now = func() time.Time { return now }
+
+	response := func(status int, headers map[string]string) *http.Response {
+		resp := &http.Response{StatusCode: status, Header: http.Header{}}
+		for k, v := range headers {
+			resp.Header.Set(k, v)
+		}
+		return resp
+	}
+
+	if d := l.reserve(); d != 0 {
+		t.Fatalf("unexpected wait without rate limit information: %s", d)
+	}
+
+	l.Update(response(http.StatusOK, map[string]string{
+		"X-RateLimit-Limit":     "10",
+		"X-RateLimit-Remaining": "2",
+		"X-RateLimit-Reset":     "30",
+	}))
+
+	for i := 0; i < 2; i++ {
+		if d := l.reserve(); d != 0 {
+			t.Fatalf("unexpected wait with remaining tokens: %s", d)
+		}
+	}
+	if d := l.reserve(); d != 30*time.Second {
+		t.Fatalf("expected to wait for the window to reset, got %s", d)
+	}
+
+	now = now.Add(31 * time.Second)
+	if d := l.reserve(); d != 0 {
+		t.Fatalf("unexpected wait after the window reset: %s", d)
+	}
+	if have, want := l.remaining, 9; have != want {
+		t.Fatalf("wrong remaining tokens after refill. want=%d have=%d", want, have)
+	}
+
+	l.Update(response(http.StatusTooManyRequests, map[string]string{"Retry-After": "5"}))
+	if d := l.reserve(); d != 5*time.Second {
+		t.Fatalf("expected to honour Retry-After, got %s", d)
+	}
+	if have, want := l.String(), "9/10 remaining, retry after 5s"; have != want {
+		t.Fatalf("wrong state. want=%q have=%q", want, have)
+	}
+}
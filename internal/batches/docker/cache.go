@@ -10,18 +10,37 @@ import (
 type ImageCache interface {
 	Get(name string) Image
 	Ensure(ctx context.Context, name string) (Image, error)
+	// EnsurePlatform is like Ensure, but for the given platform instead of
+	// the platform of the cache, unless platform is empty.
+	EnsurePlatform(ctx context.Context, name, platform string) (Image, error)
 }
 
-// imageCache is a cache of metadata about Docker images, indexed by name.
+// imageCache is a cache of metadata about Docker images, indexed by name and
+// platform.
 type imageCache struct {
-	images   map[string]Image
+	images   map[imageKey]Image
 	imagesMu sync.Mutex
+
+	platform string
+}
+
+type imageKey struct {
+	name     string
+	platform string
 }
 
 // NewImageCache creates a new image cache.
 func NewImageCache() ImageCache {
+	return NewPlatformImageCache("")
+}
+
+// NewPlatformImageCache creates a new image cache that ensures all images are
+// pulled for the given platform, such as linux/amd64. If platform is empty, the
+// platform is chosen by Docker.
+func NewPlatformImageCache(platform string) ImageCache {
 	return &imageCache{
-		images: make(map[string]Image),
+		images:   make(map[imageKey]Image),
+		platform: platform,
 	}
 }
 
@@ -29,22 +48,34 @@ func NewImageCache() ImageCache {
 // anything the Docker command line will accept as an image name: this will
 // generally be IMAGE or IMAGE:TAG.
 func (ic *imageCache) Get(name string) Image {
+	return ic.get(name, ic.platform)
+}
+
+func (ic *imageCache) get(name, platform string) Image {
 	ic.imagesMu.Lock()
 	defer ic.imagesMu.Unlock()
 
-	if image, ok := ic.images[name]; ok {
+	key := imageKey{name: name, platform: platform}
+	if image, ok := ic.images[key]; ok {
 		return image
 	}
 
-	image := &image{name: name}
-	ic.images[name] = image
+	image := &image{name: name, platform: platform}
+	ic.images[key] = image
 	return image
 }
 
 // Ensure returns the image cache entry for the given Docker image and makes sure
 // it exists on disk.
 func (ic *imageCache) Ensure(ctx context.Context, name string) (Image, error) {
-	img := ic.Get(name)
+	return ic.EnsurePlatform(ctx, name, "")
+}
+
+func (ic *imageCache) EnsurePlatform(ctx context.Context, name, platform string) (Image, error) {
+	if platform == "" {
+		platform = ic.platform
+	}
+	img := ic.get(name, platform)
 
 	if err := img.Ensure(ctx); err != nil {
 		return nil, errors.Wrapf(err, "pulling image %q", name)
@@ -35,6 +35,9 @@ type Image interface {
 
 type image struct {
 	name string
+	// platform is the os/arch[/variant] the image is required to be built
+	// for, or empty to use whatever Docker picks for the host.
+	platform string
 
 	// There are lots of once fields below: basically, we're going to try fairly
 	// hard to prevent performing the same operations on the same image over and
@@ -87,7 +90,12 @@ func (image *image) Ensure(ctx context.Context) error {
 				}
 				defer cancel()
 
-				args := []string{"image", "inspect", "--format", "{{ .Id }}", image.name}
+				format := "{{ .Id }}"
+				if image.platform != "" {
+					format = "{{ .Id }} {{ .Os }}/{{ .Architecture }}"
+				}
+
+				args := []string{"image", "inspect", "--format", format, image.name}
 				out, err := exec.CommandContext(dctx, "docker", args...).Output()
 				id := string(bytes.TrimSpace(out))
 
@@ -97,6 +105,14 @@ func (image *image) Ensure(ctx context.Context) error {
 					return "", err
 				}
 
+				if image.platform != "" {
+					var platform string
+					id, platform, _ = strings.Cut(id, " ")
+					if !PlatformMatches(image.platform, platform) {
+						return "", errors.Newf("image %q is for platform %s, not %s", image.name, platform, image.platform)
+					}
+				}
+
 				return id, nil
 			}
 
@@ -109,7 +125,11 @@ func (image *image) Ensure(ctx context.Context) error {
 				return err
 			} else if err != nil {
 				// Let's try pulling the image.
-				pullCmd := exec.CommandContext(ctx, "docker", "image", "pull", image.name)
+				pullArgs := []string{"image", "pull"}
+				if image.platform != "" {
+					pullArgs = append(pullArgs, "--platform", image.platform)
+				}
+				pullCmd := exec.CommandContext(ctx, "docker", append(pullArgs, image.name)...)
 				var stderr bytes.Buffer
 				pullCmd.Stderr = &stderr
 				if err := pullCmd.Run(); err != nil {
@@ -151,13 +171,13 @@ func (image *image) UIDGID(ctx context.Context) (UIDGID, error) {
 				return UIDGID{}, errors.Wrap(err, "getting digest")
 			}
 
-			args := []string{
-				"run",
-				"--rm",
+			args := []string{"run", "--rm"}
+			args = append(args, PlatformArgs(image.platform)...)
+			args = append(args,
 				"--entrypoint", "/bin/sh",
 				digest,
 				"-c", "id -u; id -g",
-			}
+			)
 			cmd := exec.CommandContext(ctx, "docker", args...)
 			cmd.Stdout = stdout
 
@@ -179,3 +199,29 @@ func (image *image) UIDGID(ctx context.Context) (UIDGID, error) {
 
 	return image.uidGid, image.uidGidErr
 }
+
+// PlatformArgs returns the arguments to pass to docker run to force the given
+// platform. It returns nil if platform is empty.
+func PlatformArgs(platform string) []string {
+	if platform == "" {
+		return nil
+	}
+	return []string{"--platform", platform}
+}
+
+// PlatformMatches reports whether the os/arch of have matches the os/arch of
+// the wanted platform. Variants such as the "v8" in "linux/arm64/v8" are
+// ignored.
+func PlatformMatches(want, have string) bool {
+	wantOS, wantArch := splitPlatform(want)
+	haveOS, haveArch := splitPlatform(have)
+	return wantOS == haveOS && wantArch == haveArch
+}
+
+func splitPlatform(platform string) (os, arch string) {
+	parts := strings.SplitN(platform, "/", 3)
+	if len(parts) < 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
@@ -131,6 +131,25 @@ func TestImage_Ensure(t *testing.T) {
 			image:   &image{name: "foo"},
 			wantErr: true,
 		},
+		"platform matches": {
+			expectations: []*expect.Expectation{
+				inspectPlatformSuccess("foo", "digest linux/amd64"),
+			},
+			image:   &image{name: "foo", platform: "linux/amd64"},
+			wantErr: false,
+		},
+		"platform mismatch requires pull": {
+			expectations: []*expect.Expectation{
+				inspectPlatformSuccess("foo", "digest linux/arm64"),
+				expect.NewGlob(
+					expect.Behaviour{ExitCode: 0},
+					"docker", "image", "pull", "--platform", "linux/amd64", "foo",
+				),
+				inspectPlatformSuccess("foo", "digest linux/amd64"),
+			},
+			image:   &image{name: "foo", platform: "linux/amd64"},
+			wantErr: false,
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			expect.Commands(t, tc.expectations...)
@@ -303,6 +322,22 @@ func TestImage_UIDGID(t *testing.T) {
 	}
 }
 
+func TestPlatformMatches(t *testing.T) {
+	for _, tc := range []struct {
+		want, have string
+		match      bool
+	}{
+		{want: "linux/amd64", have: "linux/amd64", match: true},
+		{want: "linux/arm64/v8", have: "linux/arm64", match: true},
+		{want: "linux/amd64", have: "linux/arm64", match: false},
+		{want: "linux/amd64", have: "", match: false},
+	} {
+		if have := PlatformMatches(tc.want, tc.have); have != tc.match {
+			t.Errorf("PlatformMatches(%q, %q): have=%v want=%v", tc.want, tc.have, have, tc.match)
+		}
+	}
+}
+
 func TestUIDGID(t *testing.T) {
 	have := UIDGID{UID: 1000, GID: 0}.String()
 	want := "1000:0"
@@ -321,6 +356,13 @@ func inspectSuccess(name, digest string) *expect.Expectation {
 	)
 }
 
+func inspectPlatformSuccess(name, output string) *expect.Expectation {
+	return expect.NewGlob(
+		expect.Behaviour{Stdout: []byte(output + "\n")},
+		"docker", "image", "inspect", "--format", `\{\{ .Id }} \{\{ .Os }}/\{\{ .Architecture }}`, name,
+	)
+}
+
 func inspectFailure(name string) *expect.Expectation {
 	return expect.NewGlob(
 		// docker image inspect returns 1 for non-existent images.
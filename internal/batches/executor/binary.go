@@ -0,0 +1,57 @@
+package executor
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sourcegraph/go-diff/diff"
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+)
+
+// BinaryFilesInDiff returns the sorted paths of all files in rawDiff whose
+// changes are binary. git marks those in the extended headers of the file
+// diff, either with a "GIT binary patch" when the diff was generated with
+// --binary, or with "Binary files ... differ" otherwise.
+func BinaryFilesInDiff(rawDiff []byte) ([]string, error) {
+	if len(rawDiff) == 0 {
+		return nil, nil
+	}
+
+	fileDiffs, err := diff.ParseMultiFileDiff(rawDiff)
+	if err != nil {
+		return nil, errors.Wrap(err, "parsing diff")
+	}
+
+	var paths []string
+	for _, fd := range fileDiffs {
+		if !isBinaryFileDiff(fd) {
+			continue
+		}
+		name := fd.NewName
+		if name == "" || name == "/dev/null" {
+			name = fd.OrigName
+		}
+		paths = append(paths, name)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+func isBinaryFileDiff(fd *diff.FileDiff) bool {
+	for _, h := range fd.Extended {
+		if strings.HasPrefix(h, "GIT binary patch") || strings.HasPrefix(h, "Binary files ") {
+			return true
+		}
+	}
+	return false
+}
+
+// errBinaryFilesChanged is returned by RunSteps when the steps changed binary
+// files and RunStepsOpts.FailOnBinaryFiles is set.
+type errBinaryFilesChanged struct {
+	paths []string
+}
+
+func (e errBinaryFilesChanged) Error() string {
+	return "steps changed binary files: " + strings.Join(e.paths, ", ")
+}
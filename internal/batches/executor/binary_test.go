@@ -0,0 +1,61 @@
+package executor
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBinaryFilesInDiff(t *testing.T) {
+	binaryPatch, err := os.ReadFile("testdata/binary.diff")
+	require.NoError(t, err)
+
+	tests := map[string]struct {
+		diff []byte
+		want []string
+	}{
+		"empty": {
+			diff: nil,
+			want: nil,
+		},
+		"text only": {
+			diff: []byte(`diff --git a.txt a.txt
+index ce01362..3b18e51 100644
+--- a.txt
++++ a.txt
+@@ -1 +1 @@
+-hello
++hello world
+`),
+			want: nil,
+		},
+		"binary patch": {
+			diff: binaryPatch,
+			want: []string{"logo.png"},
+		},
+		"binary files differ": {
+			diff: []byte(`diff --git a.txt a.txt
+index ce01362..3b18e51 100644
+--- a.txt
++++ a.txt
+@@ -1 +1 @@
+-hello
++hello world
+diff --git logo.png logo.png
+index c4ad9b8..e8f731c 100644
+Binary files logo.png and logo.png differ
+`),
+			want: []string{"logo.png"},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			have, err := BinaryFilesInDiff(tc.diff)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, have)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"github.com/sourcegraph/sourcegraph/lib/batches/execution/cache"
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+)
+
+// CacheKeyInfo describes the cache key of a single step of a task, and the
+// inputs it was computed from. It's used to debug unexpected cache misses:
+// comparing the inputs of two runs shows which of them changed.
+type CacheKeyInfo struct {
+	Task      *Task
+	StepIndex int
+	Key       string
+	Found     bool
+
+	// Rev is the revision of the repository that the steps run against.
+	Rev string
+	// Path is the workspace path in the repository.
+	Path string
+	// StepsHash is a hash of the step definitions up to and including
+	// StepIndex.
+	StepsHash string
+	// Containers are the container images of the steps up to and including
+	// StepIndex, as referenced in the batch spec.
+	Containers []string
+	// Env maps the names of the environment variables passed to the steps up
+	// to and including StepIndex to a hash of their values, so that secrets
+	// don't end up in the output.
+	Env map[string]string
+	// Mounts are the files mounted into the steps.
+	Mounts []cache.MountMetadata
+}
+
+// describeCacheKey computes the cache key for the step with the given index
+// in task, along with the inputs it depends on.
+func describeCacheKey(task *Task, globalEnv []string, workingDir string, stepIndex int) (*CacheKeyInfo, error) {
+	keyer := task.CacheKey(globalEnv, workingDir, stepIndex)
+	key, err := keyer.Key()
+	if err != nil {
+		return nil, errors.Wrap(err, "calculating execution cache key")
+	}
+
+	steps := task.Steps[:stepIndex+1]
+	info := &CacheKeyInfo{
+		Task:      task,
+		StepIndex: stepIndex,
+		Key:       key,
+		Rev:       task.Repository.Rev(),
+		Path:      task.Path,
+		Env:       map[string]string{},
+	}
+
+	raw, err := json.Marshal(steps)
+	if err != nil {
+		return nil, errors.Wrap(err, "serializing steps")
+	}
+	info.StepsHash = shortHash(string(raw))
+
+	for i, step := range steps {
+		info.Containers = append(info.Containers, step.Container)
+
+		env, err := step.Env.Resolve(globalEnv)
+		if err != nil {
+			return nil, errors.Wrapf(err, "resolving environment for step %d", i)
+		}
+		for k, v := range env {
+			info.Env[k] = shortHash(v)
+		}
+	}
+
+	info.Mounts, err = fileMetadataRetriever{workingDirectory: workingDir}.Get(steps)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting mount metadata")
+	}
+
+	return info, nil
+}
+
+// EnvNames returns the sorted names of the environment variables in Env.
+func (i *CacheKeyInfo) EnvNames() []string {
+	names := make([]string, 0, len(i.Env))
+	for k := range i.Env {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func shortHash(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:4])
+}
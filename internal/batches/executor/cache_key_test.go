@@ -0,0 +1,85 @@
+package executor
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
+)
+
+func TestDescribeCacheKey(t *testing.T) {
+	var steps []batcheslib.Step
+	require.NoError(t, json.Unmarshal([]byte(`[
+		{"run": "echo $TOKEN", "container": "alpine:3", "env": ["TOKEN"]},
+		{"run": "echo done", "container": "golang:1.22"}
+	]`), &steps))
+
+	task := &Task{Repository: testRepo1, Path: "sub", Steps: steps}
+
+	before, err := describeCacheKey(task, []string{"TOKEN=secret", "UNRELATED=1"}, "", 1)
+	require.NoError(t, err)
+
+	wantKey, err := task.CacheKey([]string{"TOKEN=secret", "UNRELATED=1"}, "", 1).Key()
+	require.NoError(t, err)
+	assert.Equal(t, wantKey, before.Key)
+	assert.Equal(t, "d34db33f", before.Rev)
+	assert.Equal(t, "sub", before.Path)
+	assert.Equal(t, []string{"alpine:3", "golang:1.22"}, before.Containers)
+	assert.Equal(t, []string{"TOKEN"}, before.EnvNames())
+	assert.NotContains(t, before.Env["TOKEN"], "secret")
+
+	after, err := describeCacheKey(task, []string{"TOKEN=rotated", "UNRELATED=2"}, "", 1)
+	require.NoError(t, err)
+	assert.NotEqual(t, before.Key, after.Key)
+	assert.NotEqual(t, before.Env["TOKEN"], after.Env["TOKEN"])
+	assert.Equal(t, before.StepsHash, after.StepsHash)
+
+	first, err := describeCacheKey(task, []string{"TOKEN=secret"}, "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alpine:3"}, first.Containers)
+	assert.NotEqual(t, before.StepsHash, first.StepsHash)
+}
+
+func TestTaskCacheKey_Platforms(t *testing.T) {
+	steps := []batcheslib.Step{
+		{Run: "echo one", Container: "alpine:3"},
+		{Run: "echo two", Container: "ubuntu:22.04"},
+	}
+	keyFor := func(task *Task, stepIndex int) string {
+		key, err := task.CacheKey(nil, "", stepIndex).Key()
+		require.NoError(t, err)
+		return key
+	}
+
+	plain := &Task{Repository: testRepo1, Steps: steps}
+	unset := &Task{Repository: testRepo1, Steps: steps, ImagePlatforms: map[string]string{}}
+	assert.Equal(t, keyFor(plain, 1), keyFor(unset, 1))
+
+	amd64 := &Task{Repository: testRepo1, Steps: steps, Platform: "linux/amd64"}
+	arm64 := &Task{Repository: testRepo1, Steps: steps, Platform: "linux/amd64", ImagePlatforms: map[string]string{"ubuntu:22.04": "linux/arm64"}}
+	assert.NotEqual(t, keyFor(plain, 1), keyFor(amd64, 1))
+	assert.NotEqual(t, keyFor(amd64, 1), keyFor(arm64, 1))
+
+	// The platforms of later steps don't affect the keys of earlier ones.
+	assert.Equal(t, keyFor(amd64, 0), keyFor(arm64, 0))
+	assert.Equal(t, plain.CacheKey(nil, "", 1).Slug(), arm64.CacheKey(nil, "", 1).Slug())
+}
+
+func TestTaskStepPlatform(t *testing.T) {
+	task := &Task{
+		Steps: []batcheslib.Step{
+			{Run: "echo one", Container: "alpine:3"},
+			{Run: "echo two", Container: "ubuntu:22.04"},
+		},
+		Platform:       "linux/amd64",
+		ImagePlatforms: map[string]string{"ubuntu:22.04": "linux/arm64"},
+	}
+	assert.Equal(t, "linux/amd64", task.StepPlatform(0))
+	assert.Equal(t, "linux/arm64", task.StepPlatform(1))
+	assert.Equal(t, "linux/amd64", task.StepPlatform(2))
+
+	assert.Equal(t, "", (&Task{Steps: task.Steps}).StepPlatform(1))
+}
@@ -2,12 +2,15 @@ package executor
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/sourcegraph/sourcegraph/lib/errors"
 
 	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
 	"github.com/sourcegraph/sourcegraph/lib/batches/execution"
 	"github.com/sourcegraph/sourcegraph/lib/batches/execution/cache"
+	"github.com/sourcegraph/sourcegraph/lib/batches/git"
 
 	"github.com/sourcegraph/src-cli/internal/batches/log"
 )
@@ -34,6 +37,37 @@ type NewCoordinatorOpts struct {
 	GlobalEnv   []string
 	BinaryDiffs bool
 
+	// FallbackAuthor, if set, is used as the commit author of changeset specs
+	// whose changeset template doesn't specify one.
+	FallbackAuthor *batcheslib.ChangesetSpecAuthor
+
+	// SrcIgnore, if set, removes the changes to ignored files from the diffs
+	// before changeset specs are built from them.
+	SrcIgnore *SrcIgnore
+	// FilesExcluded, if set, is called with the files whose changes were
+	// removed from the diff of a task by SrcIgnore.
+	FilesExcluded func(task *Task, paths []string)
+
+	// CacheKeyChecked, if set, is called with the cache key of every step of
+	// every task when checking the cache, for debugging cache misses.
+	CacheKeyChecked func(*CacheKeyInfo)
+
+	// TaskOutputs, if set, is called with the outputs of the last step of
+	// every task that completed, whether it was executed or its results were
+	// cached.
+	TaskOutputs func(task *Task, outputs map[string]interface{})
+
+	// TaskFailed, if set, is called with every task whose execution failed.
+	// When the RunTimeout is exceeded, it's also called with the tasks that
+	// weren't started, and the *RunTimeoutError.
+	TaskFailed func(task *Task, err error)
+
+	// RunTimeout, if set, limits how long ExecuteAndBuildSpecs executes tasks
+	// for. Once it's exceeded, the tasks being executed are canceled, no more
+	// tasks are started, and changeset specs are built for the tasks that
+	// completed.
+	RunTimeout time.Duration
+
 	IsRemote bool
 }
 
@@ -78,6 +112,12 @@ func (c *Coordinator) ClearCache(ctx context.Context, tasks []*Task) error {
 }
 
 func (c *Coordinator) checkCacheForTask(ctx context.Context, batchSpec *batcheslib.BatchSpec, task *Task) (specs []*batcheslib.ChangesetSpec, found bool, err error) {
+	if c.opts.CacheKeyChecked != nil {
+		if err := c.reportCacheKeys(ctx, task); err != nil {
+			return specs, false, err
+		}
+	}
+
 	if err := c.loadCachedStepResults(ctx, task, c.opts.GlobalEnv); err != nil {
 		return specs, false, err
 	}
@@ -86,6 +126,10 @@ func (c *Coordinator) checkCacheForTask(ctx context.Context, batchSpec *batchesl
 	// we build changeset specs and return.
 	// TODO: This doesn't consider skipped steps.
 	if task.CachedStepResultFound && task.CachedStepResult.StepIndex == len(task.Steps)-1 {
+		if c.opts.TaskOutputs != nil {
+			c.opts.TaskOutputs(task, task.CachedStepResult.Outputs)
+		}
+
 		// If the cached result resulted in an empty diff, we don't need to
 		// add it to the list of specs that are displayed to the user and
 		// send to the server. Instead, we can just report that the task is
@@ -102,6 +146,28 @@ func (c *Coordinator) checkCacheForTask(ctx context.Context, batchSpec *batchesl
 }
 
 func (c *Coordinator) buildChangesetSpecs(task *Task, batchSpec *batcheslib.BatchSpec, result execution.AfterStepResult) ([]*batcheslib.ChangesetSpec, error) {
+	if c.opts.SrcIgnore != nil {
+		filtered, excluded, err := c.opts.SrcIgnore.Filter(result.Diff)
+		if err != nil {
+			return nil, errors.Wrapf(err, "applying %s", SrcIgnoreFile)
+		}
+		if len(excluded) > 0 {
+			if c.opts.FilesExcluded != nil {
+				c.opts.FilesExcluded(task, excluded)
+			}
+			result.Diff = filtered
+			result.ChangedFiles, err = git.ChangesInDiff(filtered)
+			if err != nil {
+				return nil, errors.Wrap(err, "getting changed files")
+			}
+		}
+		// If only ignored files were changed, there's nothing left to
+		// create a changeset for.
+		if len(result.Diff) == 0 {
+			return nil, nil
+		}
+	}
+
 	version := 1
 	if c.opts.BinaryDiffs {
 		version = 2
@@ -127,7 +193,7 @@ func (c *Coordinator) buildChangesetSpecs(task *Task, batchSpec *batcheslib.Batc
 		},
 	}
 
-	return batcheslib.BuildChangesetSpecs(input, c.opts.BinaryDiffs, nil)
+	return batcheslib.BuildChangesetSpecs(input, c.opts.BinaryDiffs, c.opts.FallbackAuthor)
 }
 
 func (c *Coordinator) loadCachedStepResults(ctx context.Context, task *Task, globalEnv []string) error {
@@ -152,6 +218,24 @@ func (c *Coordinator) loadCachedStepResults(ctx context.Context, task *Task, glo
 	return nil
 }
 
+// reportCacheKeys calls CacheKeyChecked for each step of task.
+func (c *Coordinator) reportCacheKeys(ctx context.Context, task *Task) error {
+	for i := range task.Steps {
+		info, err := describeCacheKey(task, c.opts.GlobalEnv, c.opts.ExecOpts.WorkingDirectory, i)
+		if err != nil {
+			return errors.Wrapf(err, "describing cache key for step %d", i)
+		}
+
+		_, info.Found, err = c.opts.Cache.Get(ctx, task.CacheKey(c.opts.GlobalEnv, c.opts.ExecOpts.WorkingDirectory, i))
+		if err != nil {
+			return errors.Wrapf(err, "checking for cached diff for step %d", i)
+		}
+
+		c.opts.CacheKeyChecked(info)
+	}
+	return nil
+}
+
 func (c *Coordinator) buildSpecs(ctx context.Context, batchSpec *batcheslib.BatchSpec, taskResult taskResult, ui TaskExecutionUI) ([]*batcheslib.ChangesetSpec, error) {
 	if len(taskResult.stepResults) == 0 {
 		return nil, nil
@@ -180,10 +264,30 @@ func (c *Coordinator) buildSpecs(ctx context.Context, batchSpec *batcheslib.Batc
 func (c *Coordinator) ExecuteAndBuildSpecs(ctx context.Context, batchSpec *batcheslib.BatchSpec, tasks []*Task, ui TaskExecutionUI) ([]*batcheslib.ChangesetSpec, []string, error) {
 	ui.Start(tasks)
 
-	// Run executor.
-	c.exec.Start(ctx, tasks, ui)
+	execCtx := ctx
+	if c.opts.RunTimeout > 0 {
+		var cancel context.CancelFunc
+		execCtx, cancel = context.WithTimeout(ctx, c.opts.RunTimeout)
+		defer cancel()
+	}
+
+	// Run executor. Waiting uses the parent context, so that the tasks
+	// canceled when the run timeout is exceeded finish before results are
+	// collected.
+	c.exec.Start(execCtx, tasks, ui)
 	results, errs := c.exec.Wait(ctx)
 
+	if errors.Is(execCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+		if timeoutErr := newRunTimeoutError(c.opts.RunTimeout, tasks, results); timeoutErr != nil {
+			if c.opts.TaskFailed != nil {
+				for _, task := range timeoutErr.NotStarted {
+					c.opts.TaskFailed(task, timeoutErr)
+				}
+			}
+			errs = errors.Append(errs, timeoutErr)
+		}
+	}
+
 	// Write all step cache results to the cache.
 	for _, res := range results {
 		for _, stepRes := range res.stepResults {
@@ -200,9 +304,16 @@ func (c *Coordinator) ExecuteAndBuildSpecs(ctx context.Context, batchSpec *batch
 	for _, taskResult := range results {
 		// Don't build changeset specs for failed workspaces.
 		if taskResult.err != nil {
+			if c.opts.TaskFailed != nil {
+				c.opts.TaskFailed(taskResult.task, taskResult.err)
+			}
 			continue
 		}
 
+		if c.opts.TaskOutputs != nil && len(taskResult.stepResults) > 0 {
+			c.opts.TaskOutputs(taskResult.task, taskResult.stepResults[len(taskResult.stepResults)-1].Outputs)
+		}
+
 		taskSpecs, err := c.buildSpecs(ctx, batchSpec, taskResult, ui)
 		if err != nil {
 			return nil, nil, err
@@ -213,3 +324,52 @@ func (c *Coordinator) ExecuteAndBuildSpecs(ctx context.Context, batchSpec *batch
 
 	return specs, c.opts.Logger.LogFiles(), errs
 }
+
+// RunTimeoutError is returned by ExecuteAndBuildSpecs when the RunTimeout was
+// exceeded before all tasks were executed.
+type RunTimeoutError struct {
+	Timeout time.Duration
+
+	// Completed are the tasks that were executed to the end, whether they
+	// succeeded or failed.
+	Completed []*Task
+	// Canceled are the tasks that were being executed when the timeout was
+	// exceeded.
+	Canceled []*Task
+	// NotStarted are the tasks that weren't started before the timeout.
+	NotStarted []*Task
+}
+
+func (e *RunTimeoutError) Error() string {
+	return fmt.Sprintf(
+		"run timeout of %s exceeded: %d tasks completed, %d canceled, %d not started",
+		e.Timeout, len(e.Completed), len(e.Canceled), len(e.NotStarted),
+	)
+}
+
+func (e *RunTimeoutError) Category() ErrorCategory { return ErrorCategoryCanceled }
+
+// newRunTimeoutError sorts the tasks into the ones that completed, were
+// canceled or never started, according to the results. It returns nil if all
+// tasks completed.
+func newRunTimeoutError(timeout time.Duration, tasks []*Task, results []taskResult) *RunTimeoutError {
+	e := &RunTimeoutError{Timeout: timeout}
+	finished := make(map[*Task]bool, len(results))
+	for _, res := range results {
+		finished[res.task] = true
+		if res.err != nil && CategoryOf(res.err) == ErrorCategoryCanceled {
+			e.Canceled = append(e.Canceled, res.task)
+		} else {
+			e.Completed = append(e.Completed, res.task)
+		}
+	}
+	for _, task := range tasks {
+		if !finished[task] {
+			e.NotStarted = append(e.NotStarted, task)
+		}
+	}
+	if len(e.Canceled) == 0 && len(e.NotStarted) == 0 {
+		return nil
+	}
+	return e
+}
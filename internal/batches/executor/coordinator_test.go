@@ -2,15 +2,18 @@ package executor
 
 import (
 	"context"
+	"slices"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 
 	"github.com/sourcegraph/sourcegraph/lib/batches/execution"
 	"github.com/sourcegraph/sourcegraph/lib/batches/overridable"
+	"github.com/sourcegraph/sourcegraph/lib/errors"
 
 	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
 	"github.com/sourcegraph/sourcegraph/lib/batches/execution/cache"
@@ -53,6 +56,9 @@ func TestCoordinator_Execute(t *testing.T) {
 		return spec
 	}
 
+	templateWithoutAuthor := *testChangesetTemplate
+	templateWithoutAuthor.Commit.Author = nil
+
 	tests := []struct {
 		name string
 
@@ -183,6 +189,33 @@ func TestCoordinator_Execute(t *testing.T) {
 				}),
 			},
 		},
+		{
+			name:  "fallback author",
+			tasks: []*Task{srcCLITask},
+
+			batchSpec: &batcheslib.BatchSpec{
+				Name:              "my-batch-change",
+				Description:       "the description",
+				ChangesetTemplate: &templateWithoutAuthor,
+			},
+
+			executor: &dummyExecutor{
+				results: []taskResult{
+					{task: srcCLITask, stepResults: []execution.AfterStepResult{{Version: 2, Diff: []byte(`dummydiff1`)}}},
+				},
+			},
+			opts: NewCoordinatorOpts{
+				FallbackAuthor: &batcheslib.ChangesetSpecAuthor{Name: "Git User", Email: "git@example.com"},
+			},
+
+			wantCacheEntries: 1,
+			wantSpecs: []*batcheslib.ChangesetSpec{
+				buildSpecFor(testRepo1, func(spec *batcheslib.ChangesetSpec) {
+					spec.Commits[0].AuthorName = "Git User"
+					spec.Commits[0].AuthorEmail = "git@example.com"
+				}),
+			},
+		},
 		{
 			name: "transform group",
 
@@ -546,6 +579,96 @@ func (d *dummyTaskExecutionUI) StepsExecutionUI(t *Task) StepsExecutionUI {
 
 var _ taskExecutor = &dummyExecutor{}
 
+func TestCoordinator_Execute_RunTimeout(t *testing.T) {
+	completed := &Task{Repository: testRepo1, Steps: []batcheslib.Step{{Run: "echo fast"}}}
+	canceled := &Task{Repository: testRepo2, Steps: []batcheslib.Step{{Run: "sleep 1000"}}}
+	notStarted := &Task{Repository: testRepo1, Path: "sub", Steps: []batcheslib.Step{{Run: "echo late"}}}
+	for _, task := range []*Task{completed, canceled, notStarted} {
+		task.BatchChangeAttributes = &template.BatchChangeAttributes{}
+	}
+
+	var failed []*Task
+	coord := &Coordinator{
+		opts: NewCoordinatorOpts{
+			Cache:      newInMemoryExecutionCache(),
+			Logger:     mock.LogNoOpManager{},
+			RunTimeout: 50 * time.Millisecond,
+			TaskFailed: func(task *Task, err error) { failed = append(failed, task) },
+		},
+		exec: &slowExecutor{},
+	}
+
+	batchSpec := &batcheslib.BatchSpec{ChangesetTemplate: testChangesetTemplate}
+	specs, _, err := coord.ExecuteAndBuildSpecs(context.Background(), batchSpec, []*Task{completed, canceled, notStarted}, newDummyTaskExecutionUI())
+
+	var timeoutErr *RunTimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("expected a RunTimeoutError, got %v", err)
+	}
+	if !slices.Equal([]*Task{completed}, timeoutErr.Completed) {
+		t.Errorf("wrong completed tasks: %v", timeoutErr.Completed)
+	}
+	if !slices.Equal([]*Task{canceled}, timeoutErr.Canceled) {
+		t.Errorf("wrong canceled tasks: %v", timeoutErr.Canceled)
+	}
+	if !slices.Equal([]*Task{notStarted}, timeoutErr.NotStarted) {
+		t.Errorf("wrong tasks not started: %v", timeoutErr.NotStarted)
+	}
+	if CategoryOf(err) != ErrorCategoryCanceled {
+		t.Errorf("wrong error category %q", CategoryOf(err))
+	}
+
+	// The specs of the completed task are built, and the others can be
+	// retried.
+	if len(specs) != 1 || specs[0].BaseRepository != testRepo1.ID {
+		t.Errorf("expected a changeset spec for the completed task only, got %+v", specs)
+	}
+	if !slices.Equal([]*Task{notStarted, canceled}, failed) {
+		t.Errorf("wrong failed tasks: %v", failed)
+	}
+}
+
+// slowExecutor completes the first task right away, executes the second one
+// until it's canceled, and only gets to the others once that happened, like
+// an executor with a parallelism of two.
+type slowExecutor struct {
+	mu      sync.Mutex
+	results []taskResult
+	done    chan struct{}
+}
+
+func (x *slowExecutor) Start(ctx context.Context, tasks []*Task, ui TaskExecutionUI) {
+	x.done = make(chan struct{})
+	x.addResult(taskResult{task: tasks[0], stepResults: []execution.AfterStepResult{{Version: 2, Diff: []byte(`dummydiff1`)}}})
+
+	go func() {
+		defer close(x.done)
+		<-ctx.Done()
+		x.addResult(taskResult{task: tasks[1], err: withCategory(ctx.Err(), ErrorCategoryCanceled)})
+	}()
+
+	<-ctx.Done()
+}
+
+func (x *slowExecutor) Wait(context.Context) ([]taskResult, error) {
+	<-x.done
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	var err error
+	for _, res := range x.results {
+		if res.err != nil {
+			err = errors.Append(err, res.err)
+		}
+	}
+	return x.results, err
+}
+
+func (x *slowExecutor) addResult(res taskResult) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.results = append(x.results, res)
+}
+
 type dummyExecutor struct {
 	startCb       startCallback
 	startCbCalled bool
@@ -0,0 +1,156 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/sourcegraph/sourcegraph/lib/batches/execution"
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
+	"github.com/sourcegraph/src-cli/internal/batches/log"
+	"github.com/sourcegraph/src-cli/internal/batches/repozip"
+	"github.com/sourcegraph/src-cli/internal/lazyregexp"
+)
+
+// DeterminismReport is the result of executing the steps of a task twice.
+type DeterminismReport struct {
+	// Step is the 1-indexed first step whose diff differed between the two
+	// executions, or 0 if all diffs were the same.
+	Step int
+	// Lines are some of the added lines that differed between the two
+	// executions.
+	Lines []string
+	// Hints name the kinds of values found in Lines that commonly cause
+	// non-determinism, such as dates or UUIDs.
+	Hints []string
+}
+
+// Deterministic returns whether both executions produced the same diffs.
+func (r *DeterminismReport) Deterministic() bool {
+	return r.Step == 0
+}
+
+// maxReportedLines bounds the number of differing lines in a
+// DeterminismReport.
+const maxReportedLines = 5
+
+var nondeterminismHints = []struct {
+	name    string
+	pattern *lazyregexp.Regexp
+}{
+	{"a date or time", lazyregexp.New(`\d{4}-\d{2}-\d{2}([T ]\d{2}:\d{2}(:\d{2})?)?|\d{2}:\d{2}:\d{2}`)},
+	{"a UUID", lazyregexp.New(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)},
+	{"a Unix timestamp", lazyregexp.New(`\b1[0-9]{9}([0-9]{3})?\b`)},
+}
+
+// CheckDeterminism executes the steps of task twice, without using the cache,
+// and compares the diffs produced by each step. This is a heuristic: steps
+// that produce the same diff twice in a row may still be non-deterministic.
+func CheckDeterminism(ctx context.Context, opts NewExecutorOpts, task *Task) (*DeterminismReport, error) {
+	var runs [2][]execution.AfterStepResult
+	for i := range runs {
+		// Make sure nothing is restored from a previous execution.
+		t := *task
+		t.CachedStepResultFound = false
+		t.CachedStepResult = execution.AfterStepResult{}
+
+		results, err := RunSteps(ctx, &RunStepsOpts{
+			Task:        &t,
+			Logger:      &log.NoopTaskLogger{},
+			WC:          opts.Creator,
+			EnsureImage: opts.EnsureImage,
+			TempDir:     opts.TempDir,
+			GlobalEnv:   opts.GlobalEnv,
+			Timeout:     opts.Timeout,
+			RepoArchive: opts.RepoArchiveRegistry.Checkout(
+				repozip.RepoRevision{
+					RepoName: task.Repository.Name,
+					Commit:   task.Repository.Rev(),
+				},
+				task.ArchivePathToFetch(),
+			),
+			WorkingDirectory: opts.WorkingDirectory,
+			ForceRoot:        opts.ForceRoot,
+			Platform:         opts.Platform,
+			BinaryDiffs:      opts.BinaryDiffs,
+			UI:               NoopStepsExecUI{},
+		})
+		if err != nil {
+			return nil, errors.Wrapf(err, "executing steps in %s", task.Repository.Name)
+		}
+		runs[i] = results
+	}
+
+	return compareStepResults(runs[0], runs[1]), nil
+}
+
+func compareStepResults(a, b []execution.AfterStepResult) *DeterminismReport {
+	report := &DeterminismReport{}
+
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var diffA, diffB []byte
+		step := 0
+		if i < len(a) {
+			diffA = a[i].Diff
+			step = a[i].StepIndex + 1
+		}
+		if i < len(b) {
+			diffB = b[i].Diff
+			if step == 0 {
+				step = b[i].StepIndex + 1
+			}
+		}
+		if bytes.Equal(diffA, diffB) {
+			continue
+		}
+
+		report.Step = step
+		report.Lines = differingAddedLines(diffA, diffB)
+		for _, hint := range nondeterminismHints {
+			for _, line := range report.Lines {
+				if hint.pattern.MatchString(line) {
+					report.Hints = append(report.Hints, hint.name)
+					break
+				}
+			}
+		}
+		break
+	}
+
+	return report
+}
+
+// differingAddedLines returns the lines added in only one of the two diffs.
+func differingAddedLines(a, b []byte) []string {
+	linesA, linesB := addedLines(a), addedLines(b)
+
+	var lines []string
+	collect := func(from []string, other map[string]bool) {
+		for _, line := range from {
+			if !other[line] && len(lines) < maxReportedLines {
+				lines = append(lines, line)
+			}
+		}
+	}
+	collect(linesA, toSet(linesB))
+	collect(linesB, toSet(linesA))
+	return lines
+}
+
+func addedLines(diff []byte) []string {
+	var lines []string
+	for _, line := range bytes.Split(diff, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("+")) && !bytes.HasPrefix(line, []byte("+++")) {
+			lines = append(lines, string(line[1:]))
+		}
+	}
+	return lines
+}
+
+func toSet(lines []string) map[string]bool {
+	set := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		set[line] = true
+	}
+	return set
+}
@@ -0,0 +1,49 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/sourcegraph/sourcegraph/lib/batches/execution"
+)
+
+func TestCompareStepResults(t *testing.T) {
+	diffWith := func(line string) []byte {
+		return []byte("diff --git README.md README.md\n--- README.md\n+++ README.md\n@@ -1 +1,2 @@\n # Title\n+" + line + "\n")
+	}
+
+	t.Run("deterministic", func(t *testing.T) {
+		a := []execution.AfterStepResult{{StepIndex: 0, Diff: diffWith("hello")}}
+		b := []execution.AfterStepResult{{StepIndex: 0, Diff: diffWith("hello")}}
+
+		report := compareStepResults(a, b)
+		assert.True(t, report.Deterministic())
+	})
+
+	t.Run("timestamp in second step", func(t *testing.T) {
+		a := []execution.AfterStepResult{
+			{StepIndex: 0, Diff: diffWith("hello")},
+			{StepIndex: 1, Diff: diffWith("Generated at 2024-01-02T03:04:05")},
+		}
+		b := []execution.AfterStepResult{
+			{StepIndex: 0, Diff: diffWith("hello")},
+			{StepIndex: 1, Diff: diffWith("Generated at 2024-01-02T03:04:07")},
+		}
+
+		report := compareStepResults(a, b)
+		assert.False(t, report.Deterministic())
+		assert.Equal(t, 2, report.Step)
+		assert.Equal(t, []string{"Generated at 2024-01-02T03:04:05", "Generated at 2024-01-02T03:04:07"}, report.Lines)
+		assert.Equal(t, []string{"a date or time"}, report.Hints)
+	})
+
+	t.Run("uuid", func(t *testing.T) {
+		a := []execution.AfterStepResult{{StepIndex: 0, Diff: diffWith("id: 0b7c2f4e-5d1a-4c3e-9f0a-1b2c3d4e5f60")}}
+		b := []execution.AfterStepResult{{StepIndex: 0, Diff: diffWith("id: 7e1d0c9b-2a3f-4b5c-8d6e-0f1a2b3c4d5e")}}
+
+		report := compareStepResults(a, b)
+		assert.Equal(t, 1, report.Step)
+		assert.Equal(t, []string{"a UUID"}, report.Hints)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/sourcegraph/sourcegraph/lib/errors"
 
@@ -53,6 +54,11 @@ func readCacheFile(path string, result interface{}) (bool, error) {
 		return false, errors.Wrapf(err, "reading cache file %s", path)
 	}
 
+	// Bump the modification time, so that the least recently used results
+	// are evicted first when the cache size is bounded.
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+
 	return true, nil
 }
 
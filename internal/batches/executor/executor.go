@@ -38,6 +38,12 @@ func (e TaskExecutionErr) Error() string {
 	)
 }
 
+// Category returns the ErrorCategory of the underlying error, or
+// ErrorCategoryUnknown if the failure could not be classified.
+func (e TaskExecutionErr) Category() ErrorCategory {
+	return CategoryOf(e.Err)
+}
+
 func (e TaskExecutionErr) StatusText() string {
 	if stepErr, ok := e.Err.(stepFailedErr); ok {
 		return stepErr.SingleLineError()
@@ -45,6 +51,64 @@ func (e TaskExecutionErr) StatusText() string {
 	return e.Err.Error()
 }
 
+// ErrorCategory classifies why the execution of a task failed, so that
+// failures can be grouped and retried without parsing error messages.
+type ErrorCategory string
+
+const (
+	ErrorCategoryUnknown         ErrorCategory = "unknown"
+	ErrorCategoryTimeout         ErrorCategory = "timeout"
+	ErrorCategoryStepNonZeroExit ErrorCategory = "step-nonzero-exit"
+	ErrorCategoryImagePull       ErrorCategory = "image-pull"
+	ErrorCategoryWorkspaceSetup  ErrorCategory = "workspace-setup"
+	ErrorCategoryCanceled        ErrorCategory = "canceled"
+)
+
+// Retryable reports whether a failure in this category may succeed when the
+// task is executed again without changes to the batch spec.
+func (c ErrorCategory) Retryable() bool {
+	switch c {
+	case ErrorCategoryTimeout, ErrorCategoryImagePull, ErrorCategoryWorkspaceSetup, ErrorCategoryCanceled:
+		return true
+	default:
+		return false
+	}
+}
+
+// CategorizedError is implemented by errors that know their ErrorCategory.
+type CategorizedError interface {
+	error
+	Category() ErrorCategory
+}
+
+// CategoryOf returns the category of the first CategorizedError in err's
+// chain, or ErrorCategoryUnknown if there is none.
+func CategoryOf(err error) ErrorCategory {
+	var ce CategorizedError
+	if errors.As(err, &ce) {
+		return ce.Category()
+	}
+	return ErrorCategoryUnknown
+}
+
+// categorizedErr attaches an ErrorCategory to an error without changing its
+// message.
+type categorizedErr struct {
+	err      error
+	category ErrorCategory
+}
+
+func (e categorizedErr) Error() string           { return e.err.Error() }
+func (e categorizedErr) Unwrap() error           { return e.err }
+func (e categorizedErr) Category() ErrorCategory { return e.category }
+
+func withCategory(err error, category ErrorCategory) error {
+	if err == nil {
+		return nil
+	}
+	return categorizedErr{err: err, category: category}
+}
+
 // taskResult is a combination of a Task and the result of its execution.
 type taskResult struct {
 	task        *Task
@@ -52,7 +116,9 @@ type taskResult struct {
 	err         error
 }
 
-type imageEnsurer func(ctx context.Context, name string) (docker.Image, error)
+// imageEnsurer ensures the image with the given name exists for the platform,
+// or for the default platform if it's empty.
+type imageEnsurer func(ctx context.Context, name, platform string) (docker.Image, error)
 
 type NewExecutorOpts struct {
 	// Dependencies
@@ -69,6 +135,9 @@ type NewExecutorOpts struct {
 	IsRemote         bool
 	GlobalEnv        []string
 	ForceRoot        bool
+	Platform         string
+	// FailOnBinaryFiles fails tasks whose steps changed binary files.
+	FailOnBinaryFiles bool
 
 	BinaryDiffs bool
 }
@@ -182,11 +251,18 @@ func (x *executor) do(ctx context.Context, task *Task, ui TaskExecutionUI) (err
 		RepoArchive:      repoArchive,
 		WorkingDirectory: x.opts.WorkingDirectory,
 		ForceRoot:        x.opts.ForceRoot,
+		Platform:         x.opts.Platform,
 		BinaryDiffs:      x.opts.BinaryDiffs,
 
+		FailOnBinaryFiles: x.opts.FailOnBinaryFiles,
+
 		UI: ui.StepsExecutionUI(task),
 	}
 	stepResults, err := RunSteps(ctx, opts)
+	if err != nil && ctx.Err() != nil {
+		// The task didn't fail by itself, the whole run was canceled.
+		err = withCategory(err, ErrorCategoryCanceled)
+	}
 	if err != nil {
 		// Create a more visual error for the UI.
 		err = TaskExecutionErr{
@@ -196,6 +272,9 @@ func (x *executor) do(ctx context.Context, task *Task, ui TaskExecutionUI) (err
 		}
 		l.MarkErrored()
 	}
+	if l.Retained() {
+		task.LogFile = l.Path()
+	}
 	x.addResult(task, stepResults, err)
 
 	return err
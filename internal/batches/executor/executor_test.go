@@ -815,10 +815,81 @@ func testExecuteTasks(t *testing.T, tasks []*Task, archives ...mock.RepoArchive)
 }
 
 func imageMapEnsurer(m map[string]docker.Image) imageEnsurer {
-	return func(_ context.Context, container string) (docker.Image, error) {
+	return func(_ context.Context, container, _ string) (docker.Image, error) {
 		if i, ok := m[container]; ok {
 			return i, nil
 		}
 		return nil, errors.New(fmt.Sprintf("image for %s not found", container))
 	}
 }
+
+func TestTaskExecutionErr_Category(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCategory
+	}{
+		{
+			name: "timeout",
+			err:  &errTimeoutReached{timeout: time.Second},
+			want: ErrorCategoryTimeout,
+		},
+		{
+			name: "step exited non-zero",
+			err:  stepFailedErr{Err: errors.New("exit status 1"), ExitCode: 1},
+			want: ErrorCategoryStepNonZeroExit,
+		},
+		{
+			name: "step failed to start",
+			err:  stepFailedErr{Err: errors.New("docker not found"), ExitCode: -1},
+			want: ErrorCategoryUnknown,
+		},
+		{
+			name: "image pull",
+			err:  withCategory(errors.New("pulling image"), ErrorCategoryImagePull),
+			want: ErrorCategoryImagePull,
+		},
+		{
+			name: "wrapped workspace setup",
+			err:  errors.Wrap(withCategory(errors.New("disk full"), ErrorCategoryWorkspaceSetup), "outer"),
+			want: ErrorCategoryWorkspaceSetup,
+		},
+		{
+			name: "uncategorized",
+			err:  errors.New("something else"),
+			want: ErrorCategoryUnknown,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := TaskExecutionErr{Err: tc.err, Repository: "github.com/sourcegraph/src-cli", Logfile: "/tmp/log"}
+			if have := err.Category(); have != tc.want {
+				t.Errorf("wrong category. want=%q have=%q", tc.want, have)
+			}
+		})
+	}
+}
+
+func TestErrorCategory_Retryable(t *testing.T) {
+	for category, want := range map[ErrorCategory]bool{
+		ErrorCategoryUnknown:         false,
+		ErrorCategoryTimeout:         true,
+		ErrorCategoryStepNonZeroExit: false,
+		ErrorCategoryImagePull:       true,
+		ErrorCategoryWorkspaceSetup:  true,
+		ErrorCategoryCanceled:        true,
+	} {
+		if have := category.Retryable(); have != want {
+			t.Errorf("wrong retryable for %q. want=%t have=%t", category, want, have)
+		}
+	}
+}
+
+func TestCategorizedErr_KeepsMessage(t *testing.T) {
+	inner := errors.Wrap(errors.New("no space left on device"), "creating workspace")
+	err := withCategory(inner, ErrorCategoryWorkspaceSetup)
+	if have, want := err.Error(), inner.Error(); have != want {
+		t.Errorf("wrong message. want=%q have=%q", want, have)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/sourcegraph/sourcegraph/lib/batches/template"
 	"github.com/sourcegraph/sourcegraph/lib/errors"
 
+	"github.com/sourcegraph/src-cli/internal/batches/docker"
 	"github.com/sourcegraph/src-cli/internal/batches/log"
 	"github.com/sourcegraph/src-cli/internal/batches/repozip"
 	"github.com/sourcegraph/src-cli/internal/batches/util"
@@ -54,6 +55,12 @@ type RunStepsOpts struct {
 	// ForceRoot forces Docker containers to be run as root:root, rather than
 	// whatever the image's default user and group are.
 	ForceRoot bool
+	// Platform is passed to docker run as --platform, if set, for steps whose
+	// platform isn't set in the task.
+	Platform string
+	// FailOnBinaryFiles makes the execution fail if the steps changed any
+	// binary files.
+	FailOnBinaryFiles bool
 
 	BinaryDiffs bool
 }
@@ -76,14 +83,14 @@ func RunSteps(ctx context.Context, opts *RunStepsOpts) (stepResults []execution.
 	err = opts.RepoArchive.Ensure(ctx)
 	opts.UI.ArchiveDownloadFinished(err)
 	if err != nil {
-		return nil, errors.Wrap(err, "fetching repo")
+		return nil, withCategory(errors.Wrap(err, "fetching repo"), ErrorCategoryWorkspaceSetup)
 	}
 	defer opts.RepoArchive.Close()
 
 	opts.UI.WorkspaceInitializationStarted()
 	ws, err := opts.WC.Create(ctx, opts.Task.Repository, opts.Task.Steps, opts.RepoArchive)
 	if err != nil {
-		return nil, errors.Wrap(err, "creating workspace")
+		return nil, withCategory(errors.Wrap(err, "creating workspace"), ErrorCategoryWorkspaceSetup)
 	}
 	defer ws.Close(ctx)
 	opts.UI.WorkspaceInitializationFinished()
@@ -124,7 +131,7 @@ func RunSteps(ctx context.Context, opts *RunStepsOpts) (stepResults []execution.
 		// apply them.
 		if len(opts.Task.CachedStepResult.Diff) > 0 {
 			if err := ws.ApplyDiff(ctx, opts.Task.CachedStepResult.Diff); err != nil {
-				return nil, errors.Wrap(err, "applying diff of cache result")
+				return nil, withCategory(errors.Wrap(err, "applying diff of cache result"), ErrorCategoryWorkspaceSetup)
 			}
 		}
 
@@ -164,17 +171,22 @@ func RunSteps(ctx context.Context, opts *RunStepsOpts) (stepResults []execution.
 			continue
 		}
 
+		platform := opts.Task.StepPlatform(i)
+		if platform == "" {
+			platform = opts.Platform
+		}
+
 		// We need to grab the digest for the exact image we're using.
-		img, err := opts.EnsureImage(ctx, step.Container)
+		img, err := opts.EnsureImage(ctx, step.Container, platform)
 		if err != nil {
-			return nil, err
+			return nil, withCategory(err, ErrorCategoryImagePull)
 		}
 		digest, err := img.Digest(ctx)
 		if err != nil {
-			return nil, err
+			return nil, withCategory(err, ErrorCategoryImagePull)
 		}
 
-		stdoutBuffer, stderrBuffer, err := executeSingleStep(ctx, opts, ws, i, step, digest, &stepContext)
+		stdoutBuffer, stderrBuffer, err := executeSingleStep(ctx, opts, ws, i, step, digest, platform, &stepContext)
 		defer func() {
 			if err != nil {
 				exitCode := -1
@@ -239,6 +251,16 @@ func RunSteps(ctx context.Context, opts *RunStepsOpts) (stepResults []execution.
 		stepResults = stepResults[1:]
 	}
 
+	if opts.FailOnBinaryFiles && len(stepResults) > 0 {
+		paths, err := BinaryFilesInDiff(stepResults[len(stepResults)-1].Diff)
+		if err != nil {
+			return stepResults, errors.Wrap(err, "checking for binary file changes")
+		}
+		if len(paths) > 0 {
+			return stepResults, errBinaryFilesChanged{paths: paths}
+		}
+	}
+
 	return stepResults, err
 }
 
@@ -251,6 +273,7 @@ func executeSingleStep(
 	stepIdx int,
 	step batcheslib.Step,
 	imageDigest string,
+	platform string,
 	stepContext *template.StepContext,
 ) (stdout bytes.Buffer, stderr bytes.Buffer, err error) {
 	// ----------
@@ -266,22 +289,30 @@ func executeSingleStep(
 	defer cleanup()
 
 	// For now, we only support shell scripts provided via the Run field.
-	shell, containerTemp, err := probeImageForShell(ctx, imageDigest)
+	shell, containerTemp, err := probeImageForShell(ctx, imageDigest, platform)
 	if err != nil {
 		err = errors.Wrapf(err, "probing image %q for shell", step.Container)
 		opts.UI.StepPreparingFailed(stepIdx+1, err)
 		return bytes.Buffer{}, bytes.Buffer{}, err
 	}
 
-	runScriptFile, runScript, cleanup, err := createRunScriptFile(ctx, opts.TempDir, step.Run, stepContext)
+	// Parse and render the step.Files.
+	filesToMount, cleanup, err := createFilesToMount(opts.TempDir, step, stepContext)
 	if err != nil {
 		opts.UI.StepPreparingFailed(stepIdx+1, err)
 		return bytes.Buffer{}, bytes.Buffer{}, err
 	}
 	defer cleanup()
 
-	// Parse and render the step.Files.
-	filesToMount, cleanup, err := createFilesToMount(opts.TempDir, step, stepContext)
+	// Files with relative paths are copied into the workspace before the
+	// script runs.
+	prelude, err := stageWorkspaceFiles(filesToMount, containerTemp)
+	if err != nil {
+		opts.UI.StepPreparingFailed(stepIdx+1, err)
+		return bytes.Buffer{}, bytes.Buffer{}, err
+	}
+
+	runScriptFile, runScript, cleanup, err := createRunScriptFile(ctx, opts.TempDir, prelude, step.Run, stepContext)
 	if err != nil {
 		opts.UI.StepPreparingFailed(stepIdx+1, err)
 		return bytes.Buffer{}, bytes.Buffer{}, err
@@ -335,6 +366,8 @@ func executeSingleStep(
 		args = append(args, "--user", "0:0")
 	}
 
+	args = append(args, docker.PlatformArgs(platform)...)
+
 	for target, source := range filesToMount {
 		args = append(args, "--mount", fmt.Sprintf("type=bind,source=%s,target=%s,ro", source.Name(), target))
 	}
@@ -453,7 +486,7 @@ func setOutputs(stepOutputs batcheslib.Outputs, global map[string]interface{}, s
 	return nil
 }
 
-func probeImageForShell(ctx context.Context, image string) (shell, tempfile string, err error) {
+func probeImageForShell(ctx context.Context, image, platform string) (shell, tempfile string, err error) {
 	// We need to know two things to be able to run a shell script:
 	//
 	// 1. Which shell is available. We're going to look for /bin/bash and then
@@ -473,7 +506,8 @@ func probeImageForShell(ctx context.Context, image string) (shell, tempfile stri
 		stdout := new(bytes.Buffer)
 		stderr := new(bytes.Buffer)
 
-		args := []string{"run", "--rm", "--entrypoint", shell, image, "-c", "mktemp"}
+		args := append([]string{"run", "--rm"}, docker.PlatformArgs(platform)...)
+		args = append(args, "--entrypoint", shell, image, "-c", "mktemp")
 
 		cmd := exec.CommandContext(ctx, "docker", args...)
 		cmd.Stdout = stdout
@@ -534,10 +568,11 @@ func createFilesToMount(tempDir string, step batcheslib.Step, stepContext *templ
 	return filesToMount, cleanup, nil
 }
 
-// createRunScriptFile creates a temporary file and renders stepRun into it.
+// createRunScriptFile creates a temporary file and renders stepRun into it,
+// after the given prelude, which is not rendered.
 //
 // It returns the location of the file, its content, a function to cleanup the file and possible errors.
-func createRunScriptFile(ctx context.Context, tempDir string, stepRun string, stepCtx *template.StepContext) (string, string, func(), error) {
+func createRunScriptFile(ctx context.Context, tempDir string, prelude, stepRun string, stepCtx *template.StepContext) (string, string, func(), error) {
 	// Set up a temporary file on the host filesystem to contain the
 	// script.
 	runScriptFile, err := os.CreateTemp(tempDir, "")
@@ -550,6 +585,9 @@ func createRunScriptFile(ctx context.Context, tempDir string, stepRun string, st
 	// temp file we just created.
 	var runScript bytes.Buffer
 	out := io.MultiWriter(&runScript, runScriptFile)
+	if _, err := io.WriteString(out, prelude); err != nil {
+		return "", "", nil, errors.Wrap(err, "writing temporary file")
+	}
 	if err := template.RenderStepTemplate("step-run", stepRun, out, stepCtx); err != nil {
 		return "", "", nil, errors.Wrap(err, "parsing step run")
 	}
@@ -691,6 +729,13 @@ func (e stepFailedErr) Error() string {
 	return out.String()
 }
 
+func (e stepFailedErr) Category() ErrorCategory {
+	if e.ExitCode != -1 {
+		return ErrorCategoryStepNonZeroExit
+	}
+	return ErrorCategoryUnknown
+}
+
 func (e stepFailedErr) SingleLineError() string {
 	out := e.Err.Error()
 	if len(e.Stderr) > 0 {
@@ -706,6 +751,8 @@ func (e *errTimeoutReached) Error() string {
 	return fmt.Sprintf("Timeout reached. Execution took longer than %s.", e.timeout)
 }
 
+func (e *errTimeoutReached) Category() ErrorCategory { return ErrorCategoryTimeout }
+
 func reachedTimeout(cmdCtx context.Context, err error) bool {
 	if ee, ok := errors.Cause(err).(*exec.ExitError); ok {
 		if ee.String() == "signal: killed" && cmdCtx.Err() == context.DeadlineExceeded {
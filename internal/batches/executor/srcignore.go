@@ -0,0 +1,212 @@
+package executor
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sourcegraph/go-diff/diff"
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+)
+
+// SrcIgnoreFile is the name of the file, next to the batch spec, that lists
+// the files whose changes are dropped from the diffs produced by the steps.
+const SrcIgnoreFile = ".srcignore"
+
+// SrcIgnore is a set of gitignore style patterns read from a .srcignore file.
+//
+// The supported syntax is that of .gitignore: blank lines and lines starting
+// with # are skipped, a leading ! negates a pattern, a trailing / only
+// matches directories, patterns containing a / are matched relative to the
+// repository root while other patterns match at any depth, and ** matches
+// any number of directories. The last matching pattern wins.
+type SrcIgnore struct {
+	patterns []srcIgnorePattern
+}
+
+type srcIgnorePattern struct {
+	negate   bool
+	dirOnly  bool
+	anchored bool
+	segments []string
+}
+
+// ReadSrcIgnore reads the .srcignore file in dir. If there is none, nil is
+// returned.
+func ReadSrcIgnore(dir string) (*SrcIgnore, error) {
+	data, err := os.ReadFile(filepath.Join(dir, SrcIgnoreFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "reading %s", SrcIgnoreFile)
+	}
+	return ParseSrcIgnore(data)
+}
+
+// ParseSrcIgnore parses the contents of a .srcignore file.
+func ParseSrcIgnore(data []byte) (*SrcIgnore, error) {
+	ignore := &SrcIgnore{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var p srcIgnorePattern
+		if strings.HasPrefix(line, "!") {
+			p.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			// Escaped leading # or !.
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			p.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			p.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		p.segments = strings.Split(line, "/")
+		for _, seg := range p.segments {
+			if _, err := path.Match(seg, ""); err != nil {
+				return nil, errors.Wrapf(err, "invalid pattern %q in %s", scanner.Text(), SrcIgnoreFile)
+			}
+		}
+		ignore.patterns = append(ignore.patterns, p)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return ignore, nil
+}
+
+// Match returns whether the file at the given path, relative to the
+// repository root, is ignored.
+func (s *SrcIgnore) Match(name string) bool {
+	if s == nil {
+		return false
+	}
+
+	segments := strings.Split(strings.Trim(name, "/"), "/")
+	ignored := false
+	for _, p := range s.patterns {
+		if p.match(segments) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+func (p srcIgnorePattern) match(segments []string) bool {
+	// A pattern matches a file if it matches the file itself or one of its
+	// parent directories. Patterns with a trailing slash only match the
+	// latter.
+	for i := 1; i <= len(segments); i++ {
+		if p.dirOnly && i == len(segments) {
+			break
+		}
+
+		if p.anchored {
+			if matchSegments(p.segments, segments[:i]) {
+				return true
+			}
+		} else if ok, _ := path.Match(p.segments[0], segments[i-1]); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches a path, split into segments, against a pattern, where
+// a ** segment matches zero or more path segments.
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+// Filter removes the changes to ignored files from rawDiff. It returns the
+// remaining diff and the paths of the files whose changes were removed.
+func (s *SrcIgnore) Filter(rawDiff []byte) ([]byte, []string, error) {
+	if s == nil || len(rawDiff) == 0 {
+		return rawDiff, nil, nil
+	}
+
+	var (
+		filtered bytes.Buffer
+		excluded []string
+	)
+	for _, chunk := range splitFileDiffs(rawDiff) {
+		fd, err := diff.ParseFileDiff(chunk)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "parsing diff")
+		}
+
+		name := fd.NewName
+		if name == "" || name == "/dev/null" {
+			name = fd.OrigName
+		}
+		if s.Match(name) {
+			excluded = append(excluded, name)
+			continue
+		}
+		filtered.Write(chunk)
+	}
+
+	return filtered.Bytes(), excluded, nil
+}
+
+// splitFileDiffs splits a git diff into the raw diffs of the individual
+// files, so they can be dropped without having to reformat the rest.
+func splitFileDiffs(rawDiff []byte) [][]byte {
+	var (
+		chunks [][]byte
+		start  int
+	)
+	for offset := 0; offset < len(rawDiff); {
+		end := bytes.IndexByte(rawDiff[offset:], '\n')
+		if end == -1 {
+			end = len(rawDiff)
+		} else {
+			end += offset + 1
+		}
+		if offset > start && bytes.HasPrefix(rawDiff[offset:], []byte("diff --git ")) {
+			chunks = append(chunks, rawDiff[start:offset])
+			start = offset
+		}
+		offset = end
+	}
+	if start < len(rawDiff) {
+		chunks = append(chunks, rawDiff[start:])
+	}
+	return chunks
+}
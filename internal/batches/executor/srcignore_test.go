@@ -0,0 +1,77 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSrcIgnore_Match(t *testing.T) {
+	ignore, err := ParseSrcIgnore([]byte(`# Generated files
+*.lock
+go.sum
+/vendor/
+docs/**/*.gen.md
+build/
+!important.lock
+`))
+	require.NoError(t, err)
+
+	tests := map[string]bool{
+		"yarn.lock":                 true,
+		"web/yarn.lock":             true,
+		"important.lock":            false,
+		"go.sum":                    true,
+		"cmd/go.sum":                true,
+		"vendor/foo/foo.go":         true,
+		"pkg/vendor/foo.go":         false,
+		"vendor":                    false,
+		"docs/api.gen.md":           true,
+		"docs/a/b/api.gen.md":       true,
+		"docs/api.md":               false,
+		"build/out.txt":             true,
+		"web/build/out.txt":         true,
+		"build":                     false,
+		"main.go":                   false,
+		"internal/lockfile/lock.go": false,
+	}
+	for name, want := range tests {
+		assert.Equal(t, want, ignore.Match(name), name)
+	}
+
+	var nilIgnore *SrcIgnore
+	assert.False(t, nilIgnore.Match("yarn.lock"))
+}
+
+func TestSrcIgnore_Filter(t *testing.T) {
+	ignore, err := ParseSrcIgnore([]byte("*.lock\n"))
+	require.NoError(t, err)
+
+	const kept = `diff --git README.md README.md
+index 671e50a..258856f 100644
+--- README.md
++++ README.md
+@@ -1 +1 @@
+-# Hello
++# Hello World
+`
+	const dropped = `diff --git yarn.lock yarn.lock
+index 0b1c8ea..c1a7d41 100644
+--- yarn.lock
++++ yarn.lock
+@@ -1 +1 @@
+-foo@1.0.0
++foo@1.1.0
+`
+
+	filtered, excluded, err := ignore.Filter([]byte(dropped + kept + dropped))
+	require.NoError(t, err)
+	assert.Equal(t, kept, string(filtered))
+	assert.Equal(t, []string{"yarn.lock", "yarn.lock"}, excluded)
+
+	filtered, excluded, err = ignore.Filter([]byte(dropped))
+	require.NoError(t, err)
+	assert.Empty(t, filtered)
+	assert.Equal(t, []string{"yarn.lock"}, excluded)
+}
@@ -1,8 +1,12 @@
 package executor
 
 import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
 	"github.com/sourcegraph/sourcegraph/lib/batches/execution"
@@ -29,6 +33,28 @@ type Task struct {
 	// When this field is true, CachedStepResult is also populated.
 	CachedStepResultFound bool
 	CachedStepResult      execution.AfterStepResult
+	// LogFile is the path of the log of the execution of the task, if it's
+	// retained after the execution.
+	LogFile string
+	// Platform is the platform, such as linux/amd64, that the images of the
+	// steps are pulled and run for, unless ImagePlatforms has one for the
+	// image of a step. If neither is set, the choice is left to Docker.
+	Platform string
+	// ImagePlatforms maps image names to the platforms that the steps using
+	// them are pulled and run for.
+	ImagePlatforms map[string]string
+}
+
+// StepPlatform returns the platform of the step with the given index, or an
+// empty string if it isn't set. It only depends on the image of the step, so
+// it's unaffected by steps being left out of the task.
+func (t *Task) StepPlatform(stepIndex int) string {
+	if stepIndex < len(t.Steps) {
+		if platform, ok := t.ImagePlatforms[t.Steps[stepIndex].Container]; ok {
+			return platform
+		}
+	}
+	return t.Platform
 }
 
 func (t *Task) ArchivePathToFetch() string {
@@ -39,7 +65,7 @@ func (t *Task) ArchivePathToFetch() string {
 }
 
 func (t *Task) CacheKey(globalEnv []string, workingDir string, stepIndex int) cache.Keyer {
-	return &cache.CacheKey{
+	key := &cache.CacheKey{
 		Repository: batcheslib.Repository{
 			ID:          t.Repository.ID,
 			Name:        t.Repository.Name,
@@ -57,6 +83,33 @@ func (t *Task) CacheKey(globalEnv []string, workingDir string, stepIndex int) ca
 
 		StepIndex: stepIndex,
 	}
+
+	// Results of running the steps for a different platform may differ, so
+	// the platforms are part of the key. Without platforms, the key is the
+	// same as before platforms could be set.
+	platforms := make([]string, stepIndex+1)
+	for i := range platforms {
+		platforms[i] = t.StepPlatform(i)
+	}
+	if strings.Join(platforms, "") == "" {
+		return key
+	}
+	return &platformCacheKey{CacheKey: key, platforms: platforms}
+}
+
+// platformCacheKey is a cache key that includes the platforms of the steps.
+type platformCacheKey struct {
+	*cache.CacheKey
+	platforms []string
+}
+
+func (k *platformCacheKey) Key() (string, error) {
+	key, err := k.CacheKey.Key()
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256([]byte(key + "\x00" + strings.Join(k.platforms, "\x00")))
+	return fmt.Sprintf("%s-step-%d", base64.RawURLEncoding.EncodeToString(hash[:16]), k.StepIndex), nil
 }
 
 type fileMetadataRetriever struct {
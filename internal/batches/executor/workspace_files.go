@@ -0,0 +1,58 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/kballard/go-shellquote"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+)
+
+// stageWorkspaceFiles handles the entries of Step.Files with relative paths.
+// Files with absolute paths are mounted into the container as they are, but
+// files with relative paths are meant to be created in the workspace, so that
+// they become part of the diff. Since the workspace may live in a Docker
+// volume, they are mounted at a staging location next to containerTemp
+// instead, and copied into place by the returned script prelude, which has
+// to run before the step's own script.
+//
+// filesToMount is modified in place to mount the relative files at their
+// staging location.
+func stageWorkspaceFiles(filesToMount map[string]*os.File, containerTemp string) (string, error) {
+	var names []string
+	for name := range filesToMount {
+		if !path.IsAbs(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var prelude strings.Builder
+	for i, name := range names {
+		target := path.Clean(name)
+		if target == "." || target == ".." || strings.HasPrefix(target, "../") {
+			return "", errors.Errorf("step file %q: relative paths must point to a file within the workspace", name)
+		}
+
+		f := filesToMount[name]
+		delete(filesToMount, name)
+
+		// The file needs to be readable regardless of the user the container
+		// runs as, like the run script.
+		if err := os.Chmod(f.Name(), 0644); err != nil {
+			return "", errors.Wrap(err, "setting permissions on the temporary file")
+		}
+
+		staged := fmt.Sprintf("%s.files/%d", containerTemp, i)
+		filesToMount[staged] = f
+
+		fmt.Fprintf(&prelude, "mkdir -p -- %s && cp -- %s %s || exit 1\n",
+			shellquote.Join(path.Dir(target)), shellquote.Join(staged), shellquote.Join(target))
+	}
+
+	return prelude.String(), nil
+}
@@ -0,0 +1,60 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStageWorkspaceFiles(t *testing.T) {
+	tempFile := func(t *testing.T) *os.File {
+		t.Helper()
+		f, err := os.Create(filepath.Join(t.TempDir(), "file"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		return f
+	}
+
+	t.Run("relative and absolute paths", func(t *testing.T) {
+		mounted, config, readme := tempFile(t), tempFile(t), tempFile(t)
+		files := map[string]*os.File{
+			"/tmp/mounted.txt":    mounted,
+			"config/app.yaml":     config,
+			"./README with space": readme,
+		}
+
+		prelude, err := stageWorkspaceFiles(files, "/tmp/script.sh")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		wantPrelude := "mkdir -p -- . && cp -- /tmp/script.sh.files/0 'README with space' || exit 1\n" +
+			"mkdir -p -- config && cp -- /tmp/script.sh.files/1 config/app.yaml || exit 1\n"
+		if diff := cmp.Diff(wantPrelude, prelude); diff != "" {
+			t.Errorf("wrong prelude (-want +got):\n%s", diff)
+		}
+
+		wantFiles := map[string]*os.File{
+			"/tmp/mounted.txt":       mounted,
+			"/tmp/script.sh.files/0": readme,
+			"/tmp/script.sh.files/1": config,
+		}
+		samePointer := cmp.Comparer(func(a, b *os.File) bool { return a == b })
+		if diff := cmp.Diff(wantFiles, files, samePointer); diff != "" {
+			t.Errorf("wrong files to mount (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("path outside of workspace", func(t *testing.T) {
+		for _, name := range []string{"../secret", "a/../../secret", "."} {
+			files := map[string]*os.File{name: tempFile(t)}
+			if _, err := stageWorkspaceFiles(files, "/tmp/script.sh"); err == nil {
+				t.Errorf("expected error for %q, got none", name)
+			}
+		}
+	})
+}
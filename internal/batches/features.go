@@ -5,6 +5,7 @@ import (
 	"log"
 
 	"github.com/sourcegraph/sourcegraph/lib/api"
+	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
 	"github.com/sourcegraph/sourcegraph/lib/errors"
 )
 
@@ -13,9 +14,21 @@ import (
 type FeatureFlags struct {
 	Sourcegraph40 bool
 	BinaryDiffs   bool
+
+	TransformChanges bool
+	Workspaces       bool
+	OnBranches       bool
+	BatchSpecV2      bool
+
+	// Version is the version of Sourcegraph the flags were set from, and
+	// versionChecked whether it could be checked against the constraints.
+	Version        string
+	versionChecked bool
 }
 
 func (ff *FeatureFlags) SetFromVersion(version string, skipErrors bool) error {
+	ff.Version = version
+	ff.versionChecked = true
 	for _, feature := range []struct {
 		flag       *bool
 		constraint string
@@ -32,17 +45,59 @@ func (ff *FeatureFlags) SetFromVersion(version string, skipErrors bool) error {
 		// {&ff.FlagName, ">= 3.23.0-0", "2020-11-24"},
 		{&ff.Sourcegraph40, ">= 4.0.0-0", "2022-08-24"},
 		{&ff.BinaryDiffs, ">= 4.3.0-0", "2022-11-29"},
+		{&ff.TransformChanges, ">= 3.23.0-0", "2020-11-24"},
+		{&ff.Workspaces, ">= 3.25.0-0", "2021-02-10"},
+		{&ff.OnBranches, ">= 3.35.0-0", "2021-12-10"},
+		{&ff.BatchSpecV2, ">= 5.5.0-0", "2024-07-01"},
 	} {
 		value, err := api.CheckSourcegraphVersion(version, feature.constraint, feature.minDate)
 		if err != nil {
+			// The version is the same for every feature, so if it can't be
+			// checked for one, it can't be checked for any of them.
+			ff.versionChecked = false
 			if skipErrors {
 				log.Printf("failed to check version returned by Sourcegraph: %s. Assuming no feature flags.", version)
-			} else {
-				return errors.Wrap(err, fmt.Sprintf("failed to check version returned by Sourcegraph: %s", version))
+				return nil
 			}
+			return errors.Wrap(err, fmt.Sprintf("failed to check version returned by Sourcegraph: %s", version))
 		}
 		*feature.flag = value
 	}
 
 	return nil
 }
+
+// CheckBatchSpec returns an error naming each feature used by spec that the
+// Sourcegraph instance doesn't support, so that using them fails with a clear
+// message instead of an opaque error from the instance. If the version of the
+// instance couldn't be checked, nothing is reported.
+func (ff *FeatureFlags) CheckBatchSpec(spec *batcheslib.BatchSpec) error {
+	if ff == nil || !ff.versionChecked {
+		return nil
+	}
+
+	usesOnBranches := false
+	for _, on := range spec.On {
+		if len(on.Branches) > 0 {
+			usesOnBranches = true
+		}
+	}
+
+	var errs errors.MultiError
+	for _, feature := range []struct {
+		name       string
+		used       bool
+		supported  bool
+		minVersion string
+	}{
+		{"transformChanges", spec.TransformChanges != nil, ff.TransformChanges, "3.23"},
+		{"workspaces", len(spec.Workspaces) > 0, ff.Workspaces, "3.25"},
+		{"on.branches", usesOnBranches, ff.OnBranches, "3.35"},
+		{"version: 2", spec.Version == 2, ff.BatchSpecV2, "5.5"},
+	} {
+		if feature.used && !feature.supported {
+			errs = errors.Append(errs, errors.Newf("%s requires Sourcegraph %s or later, detected %s", feature.name, feature.minVersion, ff.Version))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,73 @@
+package batches
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+
+	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeatureFlags_SetFromVersion_UnknownVersion(t *testing.T) {
+	var buf bytes.Buffer
+	out := log.Writer()
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(out) })
+
+	ffs := &FeatureFlags{}
+	require.NoError(t, ffs.SetFromVersion("not a version", true))
+	assert.Equal(t, 1, strings.Count(buf.String(), "failed to check version"))
+	assert.False(t, ffs.OnBranches)
+
+	assert.Error(t, (&FeatureFlags{}).SetFromVersion("not a version", false))
+}
+
+func TestFeatureFlags_CheckBatchSpec(t *testing.T) {
+	spec, err := batcheslib.ParseBatchSpec([]byte(`
+version: 2
+name: features
+on:
+  - repository: github.com/sourcegraph/src-cli
+    branches: [main, release]
+workspaces:
+  - rootAtLocationOf: go.mod
+transformChanges:
+  group:
+    - directory: cmd
+      branch: cmd-changes
+`))
+	require.NoError(t, err)
+
+	features := func(t *testing.T, version string) *FeatureFlags {
+		t.Helper()
+		ffs := &FeatureFlags{}
+		require.NoError(t, ffs.SetFromVersion(version, false))
+		return ffs
+	}
+
+	t.Run("unsupported", func(t *testing.T) {
+		err := features(t, "3.30.0").CheckBatchSpec(spec)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "on.branches requires Sourcegraph 3.35 or later, detected 3.30.0")
+		assert.Contains(t, err.Error(), "version: 2 requires Sourcegraph 5.5 or later, detected 3.30.0")
+		assert.NotContains(t, err.Error(), "transformChanges")
+		assert.NotContains(t, err.Error(), "workspaces")
+	})
+
+	t.Run("supported", func(t *testing.T) {
+		assert.NoError(t, features(t, "5.5.0").CheckBatchSpec(spec))
+	})
+
+	t.Run("unknown version", func(t *testing.T) {
+		out := log.Writer()
+		log.SetOutput(&bytes.Buffer{})
+		t.Cleanup(func() { log.SetOutput(out) })
+
+		ffs := &FeatureFlags{}
+		require.NoError(t, ffs.SetFromVersion("not a version", true))
+		assert.NoError(t, ffs.CheckBatchSpec(spec))
+	})
+}
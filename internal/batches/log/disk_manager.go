@@ -45,11 +45,15 @@ func (lm *DiskManager) Close() error {
 	return errs
 }
 
+// LogFiles returns the paths of the log files that are retained: all of them
+// if logs are kept, and otherwise those of the tasks that errored.
 func (lm *DiskManager) LogFiles() []string {
 	var files []string
 
 	lm.tasks.Range(func(_, v interface{}) bool {
-		files = append(files, v.(*FileTaskLogger).Path())
+		if logger := v.(*FileTaskLogger); logger.Retained() {
+			files = append(files, logger.Path())
+		}
 		return true
 	})
 
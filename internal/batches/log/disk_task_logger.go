@@ -36,7 +36,7 @@ func (tl *FileTaskLogger) Close() error {
 		return err
 	}
 
-	if tl.errored || tl.keep {
+	if tl.Retained() {
 		return nil
 	}
 
@@ -63,6 +63,10 @@ func (tl *FileTaskLogger) Path() string {
 	return tl.f.Name()
 }
 
+func (tl *FileTaskLogger) Retained() bool {
+	return tl.errored || tl.keep
+}
+
 func (tl *FileTaskLogger) PrefixWriter(prefix string) io.Writer {
 	return &prefixWriter{tl, prefix}
 }
@@ -14,5 +14,8 @@ type TaskLogger interface {
 	Logf(string, ...interface{})
 	MarkErrored()
 	Path() string
+	// Retained reports whether the log file is kept when the logger is
+	// closed, which it is if the task errored or logs are kept.
+	Retained() bool
 	PrefixWriter(prefix string) io.Writer
 }
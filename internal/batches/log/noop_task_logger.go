@@ -20,6 +20,10 @@ func (tl *NoopTaskLogger) Path() string {
 	return "not-retained"
 }
 
+func (tl *NoopTaskLogger) Retained() bool {
+	return false
+}
+
 func (tl *NoopTaskLogger) PrefixWriter(prefix string) io.Writer {
 	return io.Discard
 }
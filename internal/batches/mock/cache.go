@@ -17,3 +17,6 @@ func (c *ImageCache) Ensure(ctx context.Context, name string) (docker.Image, err
 	img := c.Images[name]
 	return img, img.Ensure(ctx)
 }
+func (c *ImageCache) EnsurePlatform(ctx context.Context, name, _ string) (docker.Image, error) {
+	return c.Ensure(ctx, name)
+}
@@ -16,6 +16,7 @@ func (tl TaskNoOpLogger) Log(string)                           {}
 func (tl TaskNoOpLogger) Logf(string, ...interface{})          {}
 func (tl TaskNoOpLogger) MarkErrored()                         {}
 func (tl TaskNoOpLogger) Path() string                         { return "" }
+func (tl TaskNoOpLogger) Retained() bool                       { return false }
 func (tl TaskNoOpLogger) PrefixWriter(prefix string) io.Writer { return &bytes.Buffer{} }
 
 var _ log.LogManager = LogNoOpManager{}
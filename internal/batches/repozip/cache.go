@@ -0,0 +1,195 @@
+package repozip
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+)
+
+// CacheEntry is a file in the cache directory of batch spec executions that
+// can be removed without losing anything but the time to recreate it: a
+// repository archive, or a cached result of steps.
+type CacheEntry struct {
+	Path string
+	Size int64
+	// LastUse is the modification time of the file, which is bumped whenever
+	// a cached file is reused.
+	LastUse time.Time
+	// Archive is set for repository archives and the files fetched along with
+	// them, and unset for cached results of steps.
+	Archive bool
+}
+
+// cacheSlugPattern matches the names that util.SlugForRepo and
+// util.SlugForPathInRepo give to the entries for a repository at a commit.
+var cacheSlugPattern = regexp.MustCompile(`-[0-9a-f]{40}([0-9a-f]{24})?$`)
+
+// ListCacheEntries returns the entries of the cache directory dir. Repository
+// archives are the files in dir named after a repository and commit, and
+// cached results are the JSON files in the directories named that way. Other
+// files, such as the state of previous executions, and the workspaces of
+// running executions aren't entries, so they are never evicted or pruned. A
+// cache directory that doesn't exist is empty.
+func ListCacheEntries(dir string) ([]CacheEntry, error) {
+	var entries []CacheEntry
+	add := func(path string, d fs.DirEntry, archive bool) error {
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		entries = append(entries, CacheEntry{Path: path, Size: info.Size(), LastUse: info.ModTime(), Archive: archive})
+		return nil
+	}
+
+	dirEntries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "reading cache directory %s", dir)
+	}
+	for _, d := range dirEntries {
+		name, path := d.Name(), filepath.Join(dir, d.Name())
+		switch {
+		case d.Type().IsRegular() && cacheSlugPattern.MatchString(strings.TrimSuffix(name, ".zip")):
+			if err := add(path, d, true); err != nil {
+				return nil, errors.Wrapf(err, "reading cache directory %s", dir)
+			}
+
+		case d.IsDir() && cacheSlugPattern.MatchString(name):
+			results, err := os.ReadDir(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			} else if err != nil {
+				return nil, errors.Wrapf(err, "reading cache directory %s", path)
+			}
+			for _, r := range results {
+				if !r.Type().IsRegular() || filepath.Ext(r.Name()) != ".json" {
+					continue
+				}
+				if err := add(filepath.Join(path, r.Name()), r, false); err != nil {
+					return nil, errors.Wrapf(err, "reading cache directory %s", path)
+				}
+			}
+		}
+	}
+	return entries, nil
+}
+
+// EvictionResult describes the outcome of EvictLRU.
+type EvictionResult struct {
+	// Size is the total size of the archives left in the cache directory.
+	Size int64
+	// Evicted is the number of archives that were removed.
+	Evicted int
+	// Freed is the total size of the archives that were removed.
+	Freed int64
+}
+
+// EvictLRU removes the least recently used repository archives in dir until
+// the total size of the remaining ones is at most maxSize. The last use of an
+// archive is its modification time, which is bumped whenever it's reused.
+// Entries other than archives are left alone.
+func EvictLRU(dir string, maxSize int64) (*EvictionResult, error) {
+	entries, err := ListCacheEntries(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		archives []CacheEntry
+		result   EvictionResult
+	)
+	for _, e := range entries {
+		if e.Archive {
+			archives = append(archives, e)
+			result.Size += e.Size
+		}
+	}
+
+	sort.Slice(archives, func(i, j int) bool { return archives[i].LastUse.Before(archives[j].LastUse) })
+
+	for _, a := range archives {
+		if result.Size <= maxSize {
+			break
+		}
+		if err := os.Remove(a.Path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return &result, errors.Wrapf(err, "evicting %s from cache", a.Path)
+		}
+		result.Size -= a.Size
+		result.Freed += a.Size
+		result.Evicted++
+	}
+
+	return &result, nil
+}
+
+// InsufficientCacheSpaceError is returned by PrepareCache when the repository
+// archives in the cache directory can't be kept within their maximum size.
+type InsufficientCacheSpaceError struct {
+	Dir     string
+	MaxSize int64
+	// Size is the size of the archives after eviction.
+	Size int64
+	// Available is the free space on the filesystem holding the cache, or -1
+	// if it's unknown.
+	Available int64
+}
+
+func (e *InsufficientCacheSpaceError) Error() string {
+	if e.Size > e.MaxSize {
+		return fmt.Sprintf(
+			"repository archives in cache directory %s use %s after evicting old ones, more than the maximum of %s",
+			e.Dir, humanize.IBytes(uint64(e.Size)), humanize.IBytes(uint64(e.MaxSize)),
+		)
+	}
+	return fmt.Sprintf(
+		"repository archives in cache directory %s may grow by %s during execution, but only %s is free on disk; free up disk space or lower the maximum archive cache size",
+		e.Dir, humanize.IBytes(uint64(e.MaxSize-e.Size)), humanize.IBytes(uint64(e.Available)),
+	)
+}
+
+// PrepareCache evicts the least recently used archives in dir until they take
+// up at most maxSize, and then checks that the filesystem has enough free space
+// for them to grow back to maxSize. This way a run fails before it starts,
+// instead of midway through when the disk fills up.
+func PrepareCache(dir string, maxSize int64) (*EvictionResult, error) {
+	result, err := EvictLRU(dir, maxSize)
+	if err != nil {
+		return result, err
+	}
+	if result.Size > maxSize {
+		return result, &InsufficientCacheSpaceError{Dir: dir, MaxSize: maxSize, Size: result.Size, Available: -1}
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return result, err
+	}
+	available, ok, err := diskFree(dir)
+	if err != nil {
+		return result, errors.Wrapf(err, "determining free disk space for %s", dir)
+	}
+	if ok && available < maxSize-result.Size {
+		return result, &InsufficientCacheSpaceError{Dir: dir, MaxSize: maxSize, Size: result.Size, Available: available}
+	}
+
+	return result, nil
+}
+
+// touch marks a cached file as used, so that EvictLRU keeps it around for
+// longer. It's best effort, since not being able to update the timestamp
+// only affects the order of eviction.
+func touch(path string) {
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+}
@@ -0,0 +1,129 @@
+package repozip
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	commitA = "0123456789abcdef0123456789abcdef01234567"
+	commitB = "89abcdef0123456789abcdef0123456789abcdef"
+)
+
+// writeCacheFile writes a file of the given size below dir, last used age ago.
+func writeCacheFile(t *testing.T, dir, name string, size int, age time.Duration) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0600); err != nil {
+		t.Fatal(err)
+	}
+	lastUse := time.Now().Add(-age)
+	if err := os.Chtimes(path, lastUse, lastUse); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListCacheEntries(t *testing.T) {
+	dir := t.TempDir()
+
+	writeCacheFile(t, dir, "github.com-sourcegraph-a-"+commitA+".zip", 10, 0)
+	writeCacheFile(t, dir, "github.com-sourcegraph-a-c2VlZA-"+commitA, 10, 0)
+	writeCacheFile(t, dir, "github.com-sourcegraph-a-"+commitA+"/key.json", 10, 0)
+	// State and workspaces aren't cache entries.
+	writeCacheFile(t, dir, "failed-tasks.json", 10, 0)
+	writeCacheFile(t, dir, "diffs/0123456789abcdef.json", 10, 0)
+	writeCacheFile(t, dir, "workspace-github.com-sourcegraph-a-"+commitA+"123/main.go", 10, 0)
+	writeCacheFile(t, dir, "github.com-sourcegraph-a-"+commitA+"/notes.txt", 10, 0)
+
+	entries, err := ListCacheEntries(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		name := filepath.ToSlash(strings.TrimPrefix(e.Path, dir+string(os.PathSeparator)))
+		if e.Archive {
+			name += " (archive)"
+		}
+		got = append(got, name)
+	}
+	sort.Strings(got)
+
+	want := []string{
+		"github.com-sourcegraph-a-" + commitA + ".zip (archive)",
+		"github.com-sourcegraph-a-" + commitA + "/key.json",
+		"github.com-sourcegraph-a-c2VlZA-" + commitA + " (archive)",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("wrong entries (-want +got):\n%s", diff)
+	}
+}
+
+func TestEvictLRU(t *testing.T) {
+	dir := t.TempDir()
+
+	oldest := "github.com-sourcegraph-a-" + commitA + ".zip"
+	recent := "github.com-sourcegraph-b-" + commitA + ".zip"
+	newest := "github.com-sourcegraph-b-" + commitB + ".zip"
+	result := "github.com-sourcegraph-a-" + commitA + "/key.json"
+
+	writeCacheFile(t, dir, oldest, 100, 4*time.Hour)
+	writeCacheFile(t, dir, result, 50, 3*time.Hour)
+	writeCacheFile(t, dir, "failed-tasks.json", 50, 3*time.Hour)
+	writeCacheFile(t, dir, recent, 100, time.Hour)
+	writeCacheFile(t, dir, newest, 100, 0)
+
+	got, err := EvictLRU(dir, 220)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &EvictionResult{Size: 200, Evicted: 1, Freed: 100}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("wrong result (-want +got):\n%s", diff)
+	}
+
+	for name, exists := range map[string]bool{
+		oldest:              false,
+		result:              true,
+		"failed-tasks.json": true,
+		recent:              true,
+		newest:              true,
+	} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if got := err == nil; got != exists {
+			t.Errorf("%s: exists = %t, want %t", name, got, exists)
+		}
+	}
+}
+
+func TestEvictLRU_MissingDir(t *testing.T) {
+	result, err := EvictLRU(filepath.Join(t.TempDir(), "missing"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(&EvictionResult{}, result); diff != "" {
+		t.Errorf("wrong result (-want +got):\n%s", diff)
+	}
+}
+
+func TestPrepareCache_InsufficientSpace(t *testing.T) {
+	dir := t.TempDir()
+
+	// No filesystem has an exabyte to spare.
+	_, err := PrepareCache(dir, 1<<60)
+	if _, ok := err.(*InsufficientCacheSpaceError); !ok {
+		if _, ok, _ := diskFree(dir); ok {
+			t.Fatalf("expected InsufficientCacheSpaceError, got %v", err)
+		}
+	}
+}
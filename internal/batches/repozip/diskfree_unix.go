@@ -0,0 +1,15 @@
+//go:build !windows
+
+package repozip
+
+import "syscall"
+
+// diskFree returns the number of bytes available to unprivileged users on the
+// filesystem holding dir.
+func diskFree(dir string) (int64, bool, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, false, err
+	}
+	return int64(st.Bavail) * int64(st.Bsize), true, nil
+}
@@ -0,0 +1,7 @@
+package repozip
+
+// diskFree isn't implemented on Windows, so the free space check in
+// PrepareCache is skipped there.
+func diskFree(dir string) (int64, bool, error) {
+	return 0, false, nil
+}
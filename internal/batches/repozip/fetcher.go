@@ -283,6 +283,8 @@ func (rz *repoArchive) fetchArchiveAndFiles(ctx context.Context) (err error) {
 		if !ok {
 			return errors.New("failed to download repository archive: not found")
 		}
+	} else {
+		touch(rz.zipPath)
 	}
 
 	for _, addFile := range rz.additionalFiles {
@@ -292,6 +294,7 @@ func (rz *repoArchive) fetchArchiveAndFiles(ctx context.Context) (err error) {
 		}
 
 		if exists {
+			touch(addFile.localPath)
 			addFile.fetched = true
 			continue
 		}
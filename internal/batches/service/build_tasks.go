@@ -1,6 +1,12 @@
 package service
 
 import (
+	"os"
+	"sort"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+	"gopkg.in/yaml.v3"
+
 	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
 	"github.com/sourcegraph/sourcegraph/lib/batches/template"
 
@@ -14,15 +20,81 @@ type RepoWorkspace struct {
 	OnlyFetchWorkspace bool
 }
 
+// StepOverrides maps repository names to the 1-indexed steps that must not be
+// run in that repository.
+type StepOverrides map[string][]int
+
+// ReadStepOverrides reads StepOverrides from the YAML or JSON file at path. The
+// file maps repository names to lists of step numbers, for example:
+//
+//	github.com/sourcegraph/src-cli: [2, 3]
+func ReadStepOverrides(path string) (StepOverrides, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading step overrides")
+	}
+
+	var overrides StepOverrides
+	if err := yaml.Unmarshal(data, &overrides); err != nil {
+		return nil, errors.Wrap(err, "parsing step overrides")
+	}
+	return overrides, nil
+}
+
+// Validate checks that all overridden steps exist in a batch spec with the
+// given number of steps.
+func (o StepOverrides) Validate(numSteps int) error {
+	var errs errors.MultiError
+	repos := make([]string, 0, len(o))
+	for repo := range o {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+
+	for _, repo := range repos {
+		for _, step := range o[repo] {
+			if step < 1 || step > numSteps {
+				errs = errors.Append(errs, errors.Newf("step overrides for %s: step %d does not exist; the batch spec has %d steps", repo, step, numSteps))
+			}
+		}
+	}
+	return errs
+}
+
+// stepsFor returns the steps to run in the given repository.
+func (o StepOverrides) stepsFor(repo string, steps []batcheslib.Step) []batcheslib.Step {
+	skip, ok := o[repo]
+	if !ok || len(skip) == 0 {
+		return steps
+	}
+
+	skipped := make(map[int]bool, len(skip))
+	for _, step := range skip {
+		skipped[step-1] = true
+	}
+
+	filtered := make([]batcheslib.Step, 0, len(steps))
+	for i, step := range steps {
+		if !skipped[i] {
+			filtered = append(filtered, step)
+		}
+	}
+	return filtered
+}
+
 // buildTasks returns *executor.Tasks for all the workspaces determined for the given spec.
-func buildTasks(attributes *template.BatchChangeAttributes, steps []batcheslib.Step, workspaces []RepoWorkspace) []*executor.Task {
+//
+// Steps overridden for a repository are omitted from its tasks. Since the
+// steps are part of the cache key, those tasks won't reuse cache entries from
+// runs with the full list of steps.
+func buildTasks(attributes *template.BatchChangeAttributes, steps []batcheslib.Step, workspaces []RepoWorkspace, overrides StepOverrides) []*executor.Task {
 	tasks := make([]*executor.Task, 0, len(workspaces))
 
 	for _, ws := range workspaces {
 		task := &executor.Task{
 			Repository:         ws.Repo,
 			Path:               ws.Path,
-			Steps:              steps,
+			Steps:              overrides.stepsFor(ws.Repo.Name, steps),
 			OnlyFetchWorkspace: ws.OnlyFetchWorkspace,
 
 			BatchChangeAttributes: attributes,
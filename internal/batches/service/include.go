@@ -0,0 +1,138 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// includableSpecLists are the top-level lists of a batch spec whose items can
+// be included from other files.
+var includableSpecLists = []string{"on", "steps"}
+
+// ResolveIncludes replaces the include items in the on and steps lists of the
+// raw batch spec with the items of the files they reference, and returns the
+// resulting raw batch spec. An include item looks like this:
+//
+//	steps:
+//	  - include: shared/lint.yaml
+//
+// The included file is a YAML or JSON list of items, or a mapping with a list
+// under the same key as the list it is included in. Its items can include
+// other files in turn. Paths are resolved against the directory of the
+// including file, which is dir for the batch spec itself.
+//
+// The result is not validated; use ParseBatchSpec for that.
+func ResolveIncludes(dir string, rawSpec []byte) ([]byte, error) {
+	var spec yaml.Node
+	if err := yaml.Unmarshal(rawSpec, &spec); err != nil {
+		return nil, errors.Wrap(err, "parsing batch spec")
+	}
+	root := documentMapping(&spec)
+	if root == nil {
+		return nil, errors.New("batch spec is not a mapping")
+	}
+
+	found := false
+	for _, key := range includableSpecLists {
+		list := mappingValue(root, key)
+		if list == nil || list.Kind != yaml.SequenceNode {
+			continue
+		}
+		items, n, err := resolveIncludedItems(key, dir, list.Content, nil)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			list.Content = items
+			found = true
+		}
+	}
+	if !found {
+		return rawSpec, nil
+	}
+
+	resolved, err := yaml.Marshal(&spec)
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding batch spec")
+	}
+	return resolved, nil
+}
+
+// resolveIncludedItems returns items with the include items replaced, and the
+// number of includes that were resolved. stack holds the files being included,
+// to detect cycles.
+func resolveIncludedItems(key, dir string, items []*yaml.Node, stack []string) ([]*yaml.Node, int, error) {
+	var (
+		resolved []*yaml.Node
+		count    int
+	)
+	for _, item := range items {
+		path, ok := includePath(item)
+		if !ok {
+			resolved = append(resolved, item)
+			continue
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		path = filepath.Clean(path)
+
+		for _, including := range stack {
+			if including == path {
+				return nil, 0, errors.Newf("include cycle in %s: %s", key, strings.Join(append(stack, path), " -> "))
+			}
+		}
+
+		included, err := readIncludedItems(key, path)
+		if err != nil {
+			return nil, 0, err
+		}
+		included, _, err = resolveIncludedItems(key, filepath.Dir(path), included, append(stack, path))
+		if err != nil {
+			return nil, 0, err
+		}
+		resolved = append(resolved, included...)
+		count++
+	}
+	return resolved, count, nil
+}
+
+// includePath returns the path of an include item, which is a mapping with a
+// single include key.
+func includePath(item *yaml.Node) (string, bool) {
+	if item.Kind != yaml.MappingNode || len(item.Content) != 2 || item.Content[0].Value != "include" {
+		return "", false
+	}
+	return item.Content[1].Value, item.Content[1].Kind == yaml.ScalarNode
+}
+
+// readIncludedItems reads the items of the list with the given key from the
+// included file at path.
+func readIncludedItems(key, path string) ([]*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading included %s", key)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrapf(err, "parsing included %s in %s", key, path)
+	}
+
+	node := &doc
+	if node.Kind == yaml.DocumentNode && len(node.Content) == 1 {
+		node = node.Content[0]
+	}
+	if node.Kind == yaml.MappingNode {
+		if list := mappingValue(node, key); list != nil {
+			node = list
+		}
+	}
+	if node.Kind != yaml.SequenceNode {
+		return nil, errors.Newf("included file %s must be a list of %s, or have a %s key with one", path, key, key)
+	}
+	return node.Content, nil
+}
@@ -1,9 +1,11 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -14,6 +16,7 @@ import (
 	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
 	templatelib "github.com/sourcegraph/sourcegraph/lib/batches/template"
 	"github.com/sourcegraph/sourcegraph/lib/errors"
+	"gopkg.in/yaml.v3"
 
 	"github.com/sourcegraph/src-cli/internal/api"
 	"github.com/sourcegraph/src-cli/internal/batches"
@@ -23,20 +26,31 @@ import (
 )
 
 type Service struct {
-	client api.Client
+	client        api.Client
+	repoBatchSize int
 }
 
 type Opts struct {
 	Client api.Client
+
+	// RepoBatchSize, if positive, is the maximum number of entries of the on
+	// list of a batch spec that ResolveWorkspacesForBatchSpec resolves in a
+	// single request.
+	RepoBatchSize int
 }
 
 var (
 	ErrMalformedOnQueryOrRepository = errors.New("malformed 'on' field; missing either a repository name or a query")
+	// ErrRelativeMountWithoutDir is returned by ParseBatchSpec if a step mounts
+	// a relative path, but no batch spec directory to resolve it against was
+	// given.
+	ErrRelativeMountWithoutDir = errors.New("relative mount path used, but the batch spec directory is unknown")
 )
 
 func New(opts *Opts) *Service {
 	return &Service{
-		client: opts.Client,
+		client:        opts.Client,
+		repoBatchSize: opts.RepoBatchSize,
 	}
 }
 
@@ -88,7 +102,6 @@ func (svc *Service) DetermineLicenseAndFeatureFlags(ctx context.Context, skipErr
 
 	ffs := &batches.FeatureFlags{}
 	return lr, ffs, ffs.SetFromVersion(version, skipErrors)
-
 }
 
 const applyBatchChangeMutation = `
@@ -136,11 +149,12 @@ func (svc *Service) CreateBatchSpec(ctx context.Context, namespace, spec string,
 	var result struct {
 		CreateBatchSpec graphql.CreateBatchSpecResponse
 	}
-	if ok, err := svc.client.NewRequest(createBatchSpecMutation, map[string]interface{}{
+	// Batch specs can reference thousands of changeset specs.
+	if ok, err := svc.client.NewRequestWithOpts(createBatchSpecMutation, map[string]interface{}{
 		"namespace":      namespace,
 		"spec":           spec,
 		"changesetSpecs": ids,
-	}).Do(ctx, &result); err != nil || !ok {
+	}, api.RequestOpts{Gzip: true}).Do(ctx, &result); err != nil || !ok {
 		return "", "", err
 	}
 	return result.CreateBatchSpec.ID, result.CreateBatchSpec.ApplyURL, nil
@@ -170,9 +184,10 @@ func (svc *Service) CreateChangesetSpec(ctx context.Context, spec *batcheslib.Ch
 			ID string
 		}
 	}
-	if ok, err := svc.client.NewRequest(createChangesetSpecMutation, map[string]interface{}{
+	// Changeset specs contain the whole diff, so they're worth compressing.
+	if ok, err := svc.client.NewRequestWithOpts(createChangesetSpecMutation, map[string]interface{}{
 		"spec": string(raw),
-	}).Do(ctx, &result); err != nil || !ok {
+	}, api.RequestOpts{Gzip: true}).Do(ctx, &result); err != nil || !ok {
 		return "", err
 	}
 
@@ -207,36 +222,64 @@ query ResolveWorkspacesForBatchSpec($spec: String!) {
 }
 `
 
-func (svc *Service) ResolveWorkspacesForBatchSpec(ctx context.Context, spec *batcheslib.BatchSpec, allowUnsupported, allowIgnored bool) ([]RepoWorkspace, []*graphql.Repository, error) {
-	raw, err := json.Marshal(spec)
-	if err != nil {
-		return nil, nil, errors.Wrap(err, "marshalling changeset spec JSON")
-	}
+// resolvedWorkspace is a workspace returned by the
+// resolveWorkspacesForBatchSpec query.
+type resolvedWorkspace struct {
+	OnlyFetchWorkspace bool
+	Ignored            bool
+	Unsupported        bool
+	Repository         *graphql.Repository
+	Branch             *graphql.Branch
+	Path               string
+	SearchResultPaths  []string
+}
 
-	var result struct {
-		ResolveWorkspacesForBatchSpec []struct {
-			OnlyFetchWorkspace bool
-			Ignored            bool
-			Unsupported        bool
-			Repository         *graphql.Repository
-			Branch             *graphql.Branch
-			Path               string
-			SearchResultPaths  []string
+// ResolveWorkspacesForBatchSpec returns the workspaces the batch spec is
+// executed in, and their repositories.
+//
+// If the service has a RepoBatchSize, the entries of the on list are resolved
+// in chunks of that size, one request per chunk, so that specs matching very
+// many repositories don't time out in a single request. A workspace matched by
+// several chunks is only returned once, with the search result paths of all of
+// them.
+func (svc *Service) ResolveWorkspacesForBatchSpec(ctx context.Context, spec *batcheslib.BatchSpec, allowUnsupported, allowIgnored bool) ([]RepoWorkspace, []*graphql.Repository, error) {
+	var resolved []resolvedWorkspace
+	if svc.repoBatchSize > 0 && len(spec.On) > svc.repoBatchSize {
+		// seen maps the workspaces returned so far to their index in resolved.
+		seen := make(map[[3]string]int)
+		for start := 0; start < len(spec.On); start += svc.repoBatchSize {
+			chunk := *spec
+			chunk.On = spec.On[start:min(start+svc.repoBatchSize, len(spec.On))]
+			workspaces, err := svc.resolveWorkspaces(ctx, &chunk)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, w := range workspaces {
+				key := [3]string{w.Repository.ID, w.Branch.Name, w.Path}
+				if i, ok := seen[key]; ok {
+					// Each chunk only finds the search results of its own
+					// entries of the on list.
+					resolved[i].SearchResultPaths = mergePaths(resolved[i].SearchResultPaths, w.SearchResultPaths)
+					continue
+				}
+				seen[key] = len(resolved)
+				resolved = append(resolved, w)
+			}
+		}
+	} else {
+		var err error
+		if resolved, err = svc.resolveWorkspaces(ctx, spec); err != nil {
+			return nil, nil, err
 		}
-	}
-	if ok, err := svc.client.NewRequest(resolveWorkspacesForBatchSpecQuery, map[string]interface{}{
-		"spec": string(raw),
-	}).Do(ctx, &result); err != nil || !ok {
-		return nil, nil, err
 	}
 
 	unsupported := batches.UnsupportedRepoSet{}
 	ignored := batches.IgnoredRepoSet{}
 
-	repos := make([]*graphql.Repository, 0, len(result.ResolveWorkspacesForBatchSpec))
+	repos := make([]*graphql.Repository, 0, len(resolved))
 	seenRepos := make(map[string]struct{})
-	workspaces := make([]RepoWorkspace, 0, len(result.ResolveWorkspacesForBatchSpec))
-	for _, w := range result.ResolveWorkspacesForBatchSpec {
+	workspaces := make([]RepoWorkspace, 0, len(resolved))
+	for _, w := range resolved {
 		fileMatches := make(map[string]bool)
 		for _, path := range w.SearchResultPaths {
 			fileMatches[path] = true
@@ -283,6 +326,39 @@ func (svc *Service) ResolveWorkspacesForBatchSpec(ctx context.Context, spec *bat
 	return workspaces, repos, nil
 }
 
+// mergePaths returns the paths in a, followed by the ones in b that aren't in
+// a.
+func mergePaths(a, b []string) []string {
+	seen := make(map[string]bool, len(a))
+	for _, p := range a {
+		seen[p] = true
+	}
+	for _, p := range b {
+		if !seen[p] {
+			seen[p] = true
+			a = append(a, p)
+		}
+	}
+	return a
+}
+
+func (svc *Service) resolveWorkspaces(ctx context.Context, spec *batcheslib.BatchSpec) ([]resolvedWorkspace, error) {
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshalling changeset spec JSON")
+	}
+
+	var result struct {
+		ResolveWorkspacesForBatchSpec []resolvedWorkspace
+	}
+	if ok, err := svc.client.NewRequest(resolveWorkspacesForBatchSpecQuery, map[string]interface{}{
+		"spec": string(raw),
+	}).Do(ctx, &result); err != nil || !ok {
+		return nil, err
+	}
+	return result.ResolveWorkspacesForBatchSpec, nil
+}
+
 // EnsureDockerImages iterates over the steps within the batch spec to ensure the
 // images exist and to determine the exact content digest to be used when running
 // each step, including any required by the service itself.
@@ -388,8 +464,8 @@ func (svc *Service) EnsureDockerImages(
 	return images, nil
 }
 
-func (svc *Service) BuildTasks(attributes *templatelib.BatchChangeAttributes, steps []batcheslib.Step, workspaces []RepoWorkspace) []*executor.Task {
-	return buildTasks(attributes, steps, workspaces)
+func (svc *Service) BuildTasks(attributes *templatelib.BatchChangeAttributes, steps []batcheslib.Step, workspaces []RepoWorkspace, overrides StepOverrides) []*executor.Task {
+	return buildTasks(attributes, steps, workspaces, overrides)
 }
 
 func (svc *Service) CreateImportChangesetSpecs(ctx context.Context, batchSpec *batcheslib.BatchSpec) ([]*batcheslib.ChangesetSpec, error) {
@@ -474,6 +550,215 @@ func (e *duplicateBranchesErr) Error() string {
 	return out.String()
 }
 
+// MergeChangesetTemplate merges the changeset template in rawTemplate into the
+// raw batch spec and returns the resulting raw batch spec. rawTemplate can
+// either be a YAML or JSON document with a top-level changesetTemplate key, or
+// the changesetTemplate block itself.
+//
+// Fields defined in the batch spec take precedence over the ones in the
+// template. The merge is done on the YAML nodes, so that the comments of both
+// documents are kept. The result is not validated; use ParseBatchSpec for that.
+func MergeChangesetTemplate(rawSpec, rawTemplate []byte) ([]byte, error) {
+	var spec yaml.Node
+	if err := yaml.Unmarshal(rawSpec, &spec); err != nil {
+		return nil, errors.Wrap(err, "parsing batch spec")
+	}
+	specRoot := documentMapping(&spec)
+	if specRoot == nil {
+		return nil, errors.New("batch spec is not a mapping")
+	}
+
+	var tmpl yaml.Node
+	if err := yaml.Unmarshal(rawTemplate, &tmpl); err != nil {
+		return nil, errors.Wrap(err, "parsing changeset template")
+	}
+	tmplRoot := documentMapping(&tmpl)
+	if tmplRoot == nil {
+		return nil, errors.New("changeset template is not a mapping")
+	}
+	if nested := mappingValue(tmplRoot, "changesetTemplate"); nested != nil {
+		tmplRoot = nested
+	}
+	if tmplRoot.Kind != yaml.MappingNode {
+		return nil, errors.New("changesetTemplate is not a mapping")
+	}
+
+	specTmpl := mappingValue(specRoot, "changesetTemplate")
+	if specTmpl == nil || specTmpl.Kind != yaml.MappingNode {
+		specTmpl = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		setMappingValue(specRoot, "changesetTemplate", specTmpl)
+	}
+	mergeMappings(specTmpl, tmplRoot)
+
+	return encodeBatchSpec(&spec)
+}
+
+// encodeBatchSpec encodes a merged batch spec with the indentation batch specs
+// are usually written with.
+func encodeBatchSpec(spec *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(spec); err != nil {
+		return nil, errors.Wrap(err, "encoding merged batch spec")
+	}
+	if err := enc.Close(); err != nil {
+		return nil, errors.Wrap(err, "encoding merged batch spec")
+	}
+	return buf.Bytes(), nil
+}
+
+// appendComments appends the comments of src to the ones of dst.
+func appendComments(dst, src *yaml.Node) {
+	join := func(a, b string) string {
+		if a == "" || a == b {
+			return b
+		}
+		if b == "" {
+			return a
+		}
+		return a + "\n" + b
+	}
+	dst.HeadComment = join(dst.HeadComment, src.HeadComment)
+	dst.LineComment = join(dst.LineComment, src.LineComment)
+	dst.FootComment = join(dst.FootComment, src.FootComment)
+}
+
+func documentMapping(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) == 1 {
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.MappingNode {
+		return nil
+	}
+	return doc
+}
+
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// mappingKey returns the node of key in m, or nil if m doesn't have key.
+func mappingKey(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i]
+		}
+	}
+	return nil
+}
+
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// appendedSpecLists are the top-level lists of a batch spec that
+// MergeBatchSpecs appends to, rather than replaces.
+var appendedSpecLists = map[string]bool{
+	"on":               true,
+	"steps":            true,
+	"importChangesets": true,
+	"workspaces":       true,
+}
+
+// MergeBatchSpecs merges the raw batch spec rawOverlay into rawBase and returns
+// the resulting raw batch spec. Both can be YAML or JSON documents.
+//
+// Mappings are merged recursively, with the values in the overlay taking
+// precedence. The items of the top-level on, steps, importChangesets and
+// workspaces lists of the overlay are appended to the ones of the base, and
+// all other lists of the overlay replace the ones of the base.
+//
+// The merge is done on the YAML nodes, so that the comments of both documents
+// are kept. The result is not validated; use ParseBatchSpec for that.
+func MergeBatchSpecs(rawBase, rawOverlay []byte) ([]byte, error) {
+	var base yaml.Node
+	if err := yaml.Unmarshal(rawBase, &base); err != nil {
+		return nil, errors.Wrap(err, "parsing batch spec")
+	}
+	baseRoot := documentMapping(&base)
+	if baseRoot == nil {
+		return nil, errors.New("batch spec is not a mapping")
+	}
+
+	var overlay yaml.Node
+	if err := yaml.Unmarshal(rawOverlay, &overlay); err != nil {
+		return nil, errors.Wrap(err, "parsing batch spec overlay")
+	}
+	overlayRoot := documentMapping(&overlay)
+	if overlayRoot == nil {
+		return nil, errors.New("batch spec overlay is not a mapping")
+	}
+
+	appendComments(&base, &overlay)
+	appendComments(baseRoot, overlayRoot)
+	for i := 0; i+1 < len(overlayRoot.Content); i += 2 {
+		key, value := overlayRoot.Content[i], overlayRoot.Content[i+1]
+		existing := mappingValue(baseRoot, key.Value)
+		switch {
+		case existing == nil:
+			baseRoot.Content = append(baseRoot.Content, key, value)
+		case appendedSpecLists[key.Value] && existing.Kind == yaml.SequenceNode && value.Kind == yaml.SequenceNode:
+			appendComments(mappingKey(baseRoot, key.Value), key)
+			existing.Content = append(existing.Content, value.Content...)
+		default:
+			appendComments(mappingKey(baseRoot, key.Value), key)
+			setMappingValue(baseRoot, key.Value, overrideNode(existing, value))
+		}
+	}
+
+	return encodeBatchSpec(&base)
+}
+
+// overrideNode returns the result of overriding dst with src: mappings are
+// merged recursively, and anything else is replaced by src.
+func overrideNode(dst, src *yaml.Node) *yaml.Node {
+	if dst.Kind != yaml.MappingNode || src.Kind != yaml.MappingNode {
+		return src
+	}
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		if existing := mappingValue(dst, key.Value); existing != nil {
+			appendComments(mappingKey(dst, key.Value), key)
+			setMappingValue(dst, key.Value, overrideNode(existing, value))
+		} else {
+			dst.Content = append(dst.Content, key, value)
+		}
+	}
+	return dst
+}
+
+// mergeMappings adds the keys of src that are missing in dst to dst, recursing
+// into mappings present in both.
+func mergeMappings(dst, src *yaml.Node) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		existing := mappingValue(dst, key.Value)
+		switch {
+		case existing == nil:
+			dst.Content = append(dst.Content, key, value)
+		case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			appendComments(mappingKey(dst, key.Value), key)
+			mergeMappings(existing, value)
+		}
+	}
+}
+
+// ParseBatchSpec parses and validates the given raw batch spec. Relative mount
+// paths are resolved against dir. If dir is empty, relative mount paths are
+// rejected with ErrRelativeMountWithoutDir and absolute mount paths must be
+// within the current working directory.
 func (svc *Service) ParseBatchSpec(dir string, data []byte) (*batcheslib.BatchSpec, error) {
 	spec, err := batcheslib.ParseBatchSpec(data)
 	if err != nil {
@@ -486,9 +771,21 @@ func (svc *Service) ParseBatchSpec(dir string, data []byte) (*batcheslib.BatchSp
 }
 
 func validateMount(batchSpecDir string, spec *batcheslib.BatchSpec) error {
+	dirKnown := batchSpecDir != ""
+	if !dirKnown {
+		wd, err := os.Getwd()
+		if err != nil {
+			return errors.Wrap(err, "getting working directory")
+		}
+		batchSpecDir = wd
+	}
+
 	for i, step := range spec.Steps {
 		for _, mount := range step.Mount {
 			if !filepath.IsAbs(mount.Path) {
+				if !dirKnown {
+					return errors.Wrapf(ErrRelativeMountWithoutDir, "step %d mount path %s", i+1, mount.Path)
+				}
 				// Try to build the absolute path since Docker will only mount absolute paths
 				mount.Path = filepath.Join(batchSpecDir, mount.Path)
 			}
@@ -538,18 +835,95 @@ changesetTemplate:
     message: Append Hello World to all README.md files
 `
 
-func (svc *Service) GenerateExampleSpec(ctx context.Context, fileName string) error {
+const minimalExampleSpecTmpl = `version: 2
+name: NAME-OF-YOUR-BATCH-CHANGE
+on:
+  - repositoriesMatchingQuery: file:README.md
+steps:
+  - run: echo "Hello World" | tee -a $(find -name README.md)
+    container: alpine:3
+changesetTemplate:
+  title: Hello World
+  branch: BRANCH-NAME-IN-EACH-REPOSITORY
+  commit:
+    author:
+      name: {{ .Author.Name }}
+      email: {{ .Author.Email }}
+    message: Append Hello World to all README.md files
+`
+
+// ExampleSpecOpts configures the batch spec generated by GenerateExampleSpec
+// and WriteExampleSpec.
+type ExampleSpecOpts struct {
+	// Minimal generates a spec with only the required fields and no comments.
+	Minimal bool
+
+	// Dir is the directory GenerateExampleSpec creates the file in. Defaults to
+	// the current working directory.
+	Dir string
+	// Force makes GenerateExampleSpec overwrite an existing file.
+	Force bool
+}
+
+// GenerateExampleSpec creates a new file called fileName and writes an example
+// batch spec into it, returning the path of the created file. Unless
+// opts.Force is set, it fails if the file already exists and suggests the next
+// available file name instead.
+func (svc *Service) GenerateExampleSpec(ctx context.Context, fileName string, opts ExampleSpecOpts) (string, error) {
+	path := fileName
+	if opts.Dir != "" && !filepath.IsAbs(fileName) {
+		if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+			return "", errors.Wrapf(err, "failed to create directory %s", opts.Dir)
+		}
+		path = filepath.Join(opts.Dir, fileName)
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if opts.Force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
 	// Try to create file. Bail out, if it already exists.
-	f, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	f, err := os.OpenFile(path, flags, 0644)
 	if err != nil {
 		if os.IsExist(err) {
-			return fmt.Errorf("file %s already exists", fileName)
+			if next, ok := nextAvailableFileName(path); ok {
+				return "", errors.Newf("file %s already exists; use -force to overwrite it, or try %s", path, next)
+			}
+			return "", errors.Newf("file %s already exists; use -force to overwrite it", path)
 		}
-		return errors.Wrapf(err, "failed to create file %s", fileName)
+		return "", errors.Wrapf(err, "failed to create file %s", path)
 	}
 	defer f.Close()
 
-	tmpl, err := template.New("").Parse(exampleSpecTmpl)
+	if err := svc.WriteExampleSpec(ctx, f, opts); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// nextAvailableFileName returns the first path of the form name-N.ext that
+// doesn't exist yet, for N up to 100.
+func nextAvailableFileName(path string) (string, bool) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; i <= 100; i++ {
+		candidate := fmt.Sprintf("%s-%d%s", base, i, ext)
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// WriteExampleSpec writes an example batch spec to w.
+func (svc *Service) WriteExampleSpec(ctx context.Context, w io.Writer, opts ExampleSpecOpts) error {
+	spec := exampleSpecTmpl
+	if opts.Minimal {
+		spec = minimalExampleSpecTmpl
+	}
+
+	tmpl, err := template.New("").Parse(spec)
 	if err != nil {
 		return err
 	}
@@ -559,16 +933,14 @@ func (svc *Service) GenerateExampleSpec(ctx context.Context, fileName string) er
 		Email: "batch-changes@sourcegraph.com",
 	}
 	// Try to get better default values from git, ignore any errors.
-	gitAuthorName, err1 := getGitConfig("user.name")
-	gitAuthorEmail, err2 := getGitConfig("user.email")
-	if err1 == nil && err2 == nil && gitAuthorName != "" && gitAuthorEmail != "" {
-		author.Name = gitAuthorName
-		author.Email = gitAuthorEmail
+	if gitAuthor, err := GitConfigAuthor(); err == nil {
+		author.Name = gitAuthor.Name
+		author.Email = gitAuthor.Email
 	}
 
-	err = tmpl.Execute(f, map[string]interface{}{"Author": author})
+	err = tmpl.Execute(w, map[string]interface{}{"Author": author})
 	if err != nil {
-		return errors.Wrap(err, "failed to write batch spec to file")
+		return errors.Wrap(err, "failed to write batch spec")
 	}
 
 	return nil
@@ -670,11 +1042,13 @@ query Repository($name: String!, $queryCommit: Boolean!, $rev: String!) {
 
 func (svc *Service) resolveRepositoryName(ctx context.Context, name string) (*graphql.Repository, error) {
 	var result struct{ Repository *graphql.Repository }
-	if ok, err := svc.client.NewRequest(repositoryNameQuery, map[string]interface{}{
+	// Resolving workspaces looks up the same repositories from many
+	// goroutines, so they share the responses of identical lookups.
+	if ok, err := svc.client.NewRequestWithOpts(repositoryNameQuery, map[string]interface{}{
 		"name":        name,
 		"queryCommit": false,
 		"rev":         "",
-	}).Do(ctx, &result); err != nil || !ok {
+	}, api.RequestOpts{Deduplicate: true}).Do(ctx, &result); err != nil || !ok {
 		return nil, err
 	}
 	if result.Repository == nil {
@@ -683,6 +1057,20 @@ func (svc *Service) resolveRepositoryName(ctx context.Context, name string) (*gr
 	return result.Repository, nil
 }
 
+// GitConfigAuthor returns the commit author configured in the local git
+// config through user.name and user.email.
+func GitConfigAuthor() (*batcheslib.ChangesetSpecAuthor, error) {
+	name, err := getGitConfig("user.name")
+	if err != nil || name == "" {
+		return nil, errors.New("git config user.name is not set")
+	}
+	email, err := getGitConfig("user.email")
+	if err != nil || email == "" {
+		return nil, errors.New("git config user.email is not set")
+	}
+	return &batcheslib.ChangesetSpecAuthor{Name: name, Email: email}, nil
+}
+
 func getGitConfig(attribute string) (string, error) {
 	cmd := exec.Command("git", "config", "--get", attribute)
 	out, err := cmd.CombinedOutput()
@@ -1,8 +1,12 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -10,13 +14,17 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	testifymock "github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/sourcegraph/sourcegraph/lib/errors"
 
 	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
 
+	"github.com/sourcegraph/src-cli/internal/api"
+	mockclient "github.com/sourcegraph/src-cli/internal/api/mock"
 	"github.com/sourcegraph/src-cli/internal/batches/docker"
+	"github.com/sourcegraph/src-cli/internal/batches/executor"
 	"github.com/sourcegraph/src-cli/internal/batches/graphql"
 	"github.com/sourcegraph/src-cli/internal/batches/mock"
 )
@@ -541,6 +549,26 @@ changesetTemplate:
 `, tempOutsideDir),
 			expectedErr: errors.New("handling mount: step 1 mount path is not in the same directory or subdirectory as the batch spec"),
 		},
+		{
+			name: "mount relative path without batch spec directory",
+			rawSpec: `
+name: test-spec
+description: A test spec
+steps:
+  - run: /tmp/sample.sh
+    container: alpine:3
+    mount:
+      - path: ./sample.sh
+        mountpoint: /tmp/sample.sh
+changesetTemplate:
+  title: Test Mount
+  body: Test a mounted path
+  branch: test
+  commit:
+    message: Test
+`,
+			expectedErr: errors.New("handling mount: step 1 mount path ./sample.sh: relative mount path used, but the batch spec directory is unknown"),
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -554,3 +582,500 @@ changesetTemplate:
 		})
 	}
 }
+
+func TestService_WriteExampleSpec(t *testing.T) {
+	svc := &Service{}
+
+	for name, opts := range map[string]ExampleSpecOpts{
+		"default": {},
+		"minimal": {Minimal: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, svc.WriteExampleSpec(context.Background(), &buf, opts))
+
+			spec, err := svc.ParseBatchSpec("", buf.Bytes())
+			require.NoError(t, err)
+			assert.Equal(t, "NAME-OF-YOUR-BATCH-CHANGE", spec.Name)
+			assert.Len(t, spec.Steps, 1)
+
+			if opts.Minimal {
+				assert.NotContains(t, buf.String(), "#")
+			}
+		})
+	}
+}
+
+func TestService_GenerateExampleSpec(t *testing.T) {
+	svc := &Service{}
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "specs")
+
+	path, err := svc.GenerateExampleSpec(ctx, "batch.yaml", ExampleSpecOpts{Dir: dir})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "batch.yaml"), path)
+	assert.FileExists(t, path)
+
+	t.Run("exists", func(t *testing.T) {
+		_, err := svc.GenerateExampleSpec(ctx, "batch.yaml", ExampleSpecOpts{Dir: dir})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), filepath.Join(dir, "batch-1.yaml"))
+	})
+
+	t.Run("force", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(path, []byte("garbage"), 0644))
+
+		_, err := svc.GenerateExampleSpec(ctx, "batch.yaml", ExampleSpecOpts{Dir: dir, Force: true})
+		require.NoError(t, err)
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "garbage")
+	})
+}
+
+func TestStepOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overrides.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("github.com/sourcegraph/compliance: [2]\n"), 0644))
+
+	overrides, err := ReadStepOverrides(path)
+	require.NoError(t, err)
+
+	steps := []batcheslib.Step{{Run: "one"}, {Run: "two"}, {Run: "three"}}
+	require.NoError(t, overrides.Validate(len(steps)))
+	assert.Error(t, overrides.Validate(1))
+
+	tasks := buildTasks(nil, steps, []RepoWorkspace{
+		{Repo: &graphql.Repository{Name: "github.com/sourcegraph/compliance"}},
+		{Repo: &graphql.Repository{Name: "github.com/sourcegraph/other"}},
+	}, overrides)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, []batcheslib.Step{{Run: "one"}, {Run: "three"}}, tasks[0].Steps)
+	assert.Equal(t, steps, tasks[1].Steps)
+}
+
+func TestStepOverridesWithPlatforms(t *testing.T) {
+	steps := []batcheslib.Step{
+		{Run: "one", Container: "alpine:3"},
+		{Run: "two", Container: "ubuntu:22.04"},
+	}
+	overrides := StepOverrides{"github.com/sourcegraph/compliance": {1}}
+
+	branch := &graphql.Branch{Name: "main", Target: graphql.Target{OID: "deadbeef"}}
+	tasks := buildTasks(nil, steps, []RepoWorkspace{
+		{Repo: &graphql.Repository{Name: "github.com/sourcegraph/compliance", DefaultBranch: branch}},
+		{Repo: &graphql.Repository{Name: "github.com/sourcegraph/other", DefaultBranch: branch}},
+	}, overrides)
+	require.Len(t, tasks, 2)
+	for _, task := range tasks {
+		task.Platform = "linux/amd64"
+		task.ImagePlatforms = map[string]string{"alpine:3": "linux/arm64"}
+	}
+
+	// The second step keeps its platform in the repository that skips the
+	// first one.
+	require.Len(t, tasks[0].Steps, 1)
+	assert.Equal(t, "linux/amd64", tasks[0].StepPlatform(0))
+	assert.Equal(t, "linux/arm64", tasks[1].StepPlatform(0))
+	assert.Equal(t, "linux/amd64", tasks[1].StepPlatform(1))
+
+	// So does its cache key, which matches that of a task with only the
+	// second step.
+	only := &executor.Task{Repository: tasks[0].Repository, Steps: steps[1:], Platform: "linux/amd64"}
+	got, err := tasks[0].CacheKey(nil, "", 0).Key()
+	require.NoError(t, err)
+	want, err := only.CacheKey(nil, "", 0).Key()
+	require.NoError(t, err)
+	assert.Equal(t, want, got)
+}
+
+func TestMergeChangesetTemplate(t *testing.T) {
+	svc := &Service{}
+	rawSpec := `
+name: test-spec
+steps:
+  - run: echo hi
+    container: alpine:3
+changesetTemplate:
+  title: Spec title
+  commit:
+    message: Spec message
+`
+
+	t.Run("spec fields win", func(t *testing.T) {
+		merged, err := MergeChangesetTemplate([]byte(rawSpec), []byte(`
+changesetTemplate:
+  title: Shared title
+  body: Shared body
+  branch: shared-branch
+  commit:
+    message: Shared message
+    author:
+      name: Bot
+      email: bot@example.com
+`))
+		require.NoError(t, err)
+
+		spec, err := svc.ParseBatchSpec("", merged)
+		require.NoError(t, err)
+		assert.Equal(t, &batcheslib.ChangesetTemplate{
+			Title:  "Spec title",
+			Body:   "Shared body",
+			Branch: "shared-branch",
+			Commit: batcheslib.ExpandedGitCommitDescription{
+				Message: "Spec message",
+				Author:  &batcheslib.GitCommitAuthor{Name: "Bot", Email: "bot@example.com"},
+			},
+		}, spec.ChangesetTemplate)
+	})
+
+	t.Run("bare template block", func(t *testing.T) {
+		merged, err := MergeChangesetTemplate([]byte(rawSpec), []byte("branch: shared-branch\n"))
+		require.NoError(t, err)
+
+		spec, err := svc.ParseBatchSpec("", merged)
+		require.NoError(t, err)
+		assert.Equal(t, "shared-branch", spec.ChangesetTemplate.Branch)
+	})
+
+	t.Run("comments are kept", func(t *testing.T) {
+		merged, err := MergeChangesetTemplate([]byte(`# Say hi.
+name: test-spec # the name
+changesetTemplate:
+  title: Spec title
+  # Spec commit.
+  commit:
+    message: Spec message
+`), []byte(`changesetTemplate:
+  # Shared branch.
+  branch: shared-branch
+  # Shared author.
+  commit:
+    author:
+      name: Bot # the bot
+      email: bot@example.com
+`))
+		require.NoError(t, err)
+		assert.Equal(t, `# Say hi.
+name: test-spec # the name
+changesetTemplate:
+  title: Spec title
+  # Spec commit.
+  # Shared author.
+  commit:
+    message: Spec message
+    author:
+      name: Bot # the bot
+      email: bot@example.com
+  # Shared branch.
+  branch: shared-branch
+`, string(merged))
+	})
+
+	t.Run("merged template still invalid", func(t *testing.T) {
+		merged, err := MergeChangesetTemplate([]byte(rawSpec), []byte("body: Shared body\n"))
+		require.NoError(t, err)
+
+		_, err = svc.ParseBatchSpec("", merged)
+		assert.Error(t, err)
+	})
+}
+
+func TestMergeBatchSpecs(t *testing.T) {
+	svc := &Service{}
+	base := `
+name: base-spec
+on:
+  - repositoriesMatchingQuery: repo:base
+steps:
+  - run: echo base
+    container: alpine:3
+    env:
+      LEVEL: base
+      SHARED: base
+changesetTemplate:
+  title: Base title
+  body: Base body
+  branch: base-branch
+  commit:
+    message: Base message
+`
+
+	t.Run("overlay", func(t *testing.T) {
+		merged, err := MergeBatchSpecs([]byte(base), []byte(`
+name: overlay-spec
+on:
+  - repository: github.com/sourcegraph/overlay
+steps:
+  - run: echo overlay
+    container: alpine:3
+changesetTemplate:
+  title: Overlay title
+  commit:
+    message: Overlay message
+`))
+		require.NoError(t, err)
+
+		spec, err := svc.ParseBatchSpec("", merged)
+		require.NoError(t, err)
+		assert.Equal(t, "overlay-spec", spec.Name)
+		require.Len(t, spec.On, 2)
+		assert.Equal(t, "repo:base", spec.On[0].RepositoriesMatchingQuery)
+		assert.Equal(t, "github.com/sourcegraph/overlay", spec.On[1].Repository)
+		require.Len(t, spec.Steps, 2)
+		assert.Equal(t, "echo base", spec.Steps[0].Run)
+		assert.Equal(t, "echo overlay", spec.Steps[1].Run)
+		assert.Equal(t, "Overlay title", spec.ChangesetTemplate.Title)
+		assert.Equal(t, "Base body", spec.ChangesetTemplate.Body)
+		assert.Equal(t, "Overlay message", spec.ChangesetTemplate.Commit.Message)
+	})
+
+	t.Run("comments are kept", func(t *testing.T) {
+		merged, err := MergeBatchSpecs([]byte(`# Base spec.
+
+name: base-spec # the name
+# Base repositories.
+on:
+  - repositoriesMatchingQuery: repo:base # all of them
+changesetTemplate:
+  title: Base title
+  # Base branch.
+  branch: base-branch
+`), []byte(`# Production overlay.
+
+# Production repositories.
+on:
+  - repository: github.com/sourcegraph/overlay # pinned
+changesetTemplate:
+  # Production branch.
+  branch: production-branch # overridden
+  body: Overlay body
+`))
+		require.NoError(t, err)
+		assert.Equal(t, `# Base spec.
+# Production overlay.
+
+name: base-spec # the name
+# Base repositories.
+# Production repositories.
+on:
+  - repositoriesMatchingQuery: repo:base # all of them
+  - repository: github.com/sourcegraph/overlay # pinned
+changesetTemplate:
+  title: Base title
+  # Base branch.
+  # Production branch.
+  branch: production-branch # overridden
+  body: Overlay body
+`, string(merged))
+	})
+
+	t.Run("JSON overlay", func(t *testing.T) {
+		merged, err := MergeBatchSpecs([]byte(base), []byte(`{"changesetTemplate": {"branch": "json-branch"}}`))
+		require.NoError(t, err)
+
+		spec, err := svc.ParseBatchSpec("", merged)
+		require.NoError(t, err)
+		assert.Equal(t, "json-branch", spec.ChangesetTemplate.Branch)
+		assert.Len(t, spec.Steps, 1)
+	})
+
+	t.Run("merged spec still invalid", func(t *testing.T) {
+		merged, err := MergeBatchSpecs([]byte(base), []byte("steps:\n  - run: echo no container\n"))
+		require.NoError(t, err)
+
+		_, err = svc.ParseBatchSpec("", merged)
+		assert.Error(t, err)
+	})
+
+	t.Run("overlay is not a mapping", func(t *testing.T) {
+		_, err := MergeBatchSpecs([]byte(base), []byte("- run: echo\n"))
+		assert.Error(t, err)
+	})
+}
+
+func TestResolveIncludes(t *testing.T) {
+	svc := &Service{}
+	write := func(t *testing.T, dir, name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	t.Run("steps and on", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, "shared/steps.yaml", `
+- run: echo shared
+  container: alpine:3
+- include: nested.yaml
+`)
+		write(t, dir, "shared/nested.yaml", `
+steps:
+  - run: echo nested
+    container: alpine:3
+`)
+		write(t, dir, "repos.yaml", `
+on:
+  - repository: github.com/sourcegraph/included
+`)
+
+		resolved, err := ResolveIncludes(dir, []byte(`
+name: include-spec
+on:
+  - repositoriesMatchingQuery: repo:spec
+  - include: repos.yaml
+steps:
+  - include: shared/steps.yaml
+  - run: echo spec
+    container: alpine:3
+changesetTemplate:
+  title: Include spec
+  body: Include spec
+  branch: include-spec
+  commit:
+    message: Include spec
+`))
+		require.NoError(t, err)
+
+		spec, err := svc.ParseBatchSpec("", resolved)
+		require.NoError(t, err)
+		require.Len(t, spec.On, 2)
+		assert.Equal(t, "repo:spec", spec.On[0].RepositoriesMatchingQuery)
+		assert.Equal(t, "github.com/sourcegraph/included", spec.On[1].Repository)
+		require.Len(t, spec.Steps, 3)
+		assert.Equal(t, "echo shared", spec.Steps[0].Run)
+		assert.Equal(t, "echo nested", spec.Steps[1].Run)
+		assert.Equal(t, "echo spec", spec.Steps[2].Run)
+	})
+
+	t.Run("no includes", func(t *testing.T) {
+		raw := []byte("name: spec\nsteps:\n  - run: echo\n    container: alpine:3\n")
+		resolved, err := ResolveIncludes(t.TempDir(), raw)
+		require.NoError(t, err)
+		assert.Equal(t, raw, resolved)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, "a.yaml", "- include: b.yaml\n")
+		write(t, dir, "b.yaml", "- include: a.yaml\n")
+
+		_, err := ResolveIncludes(dir, []byte("name: spec\nsteps:\n  - include: a.yaml\n"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "include cycle in steps")
+		assert.Contains(t, err.Error(), filepath.Join(dir, "a.yaml")+" -> "+filepath.Join(dir, "b.yaml")+" -> "+filepath.Join(dir, "a.yaml"))
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := ResolveIncludes(t.TempDir(), []byte("name: spec\nsteps:\n  - include: missing.yaml\n"))
+		assert.Error(t, err)
+	})
+
+	t.Run("not a list", func(t *testing.T) {
+		dir := t.TempDir()
+		write(t, dir, "steps.yaml", "run: echo\n")
+
+		_, err := ResolveIncludes(dir, []byte("name: spec\nsteps:\n  - include: steps.yaml\n"))
+		assert.Error(t, err)
+	})
+}
+
+func TestService_ResolveWorkspacesForBatchSpec_RepoBatchSize(t *testing.T) {
+	workspace := func(repo, path string, searchResultPaths ...string) string {
+		paths, err := json.Marshal(searchResultPaths)
+		require.NoError(t, err)
+		return fmt.Sprintf(`{"repository": {"id": %q, "name": %q}, "branch": {"name": "main"}, "path": %q, "searchResultPaths": %s}`, repo, repo, path, paths)
+	}
+	// responses are the workspaces returned for each entry of the on list, in
+	// the order of the entries. The search results of an entry only contain
+	// the files it matched.
+	responses := map[string][]string{
+		"github.com/sourcegraph/a": {
+			workspace("github.com/sourcegraph/a", "", "README.md"),
+			workspace("github.com/sourcegraph/a", "", "main.go", "README.md"),
+		},
+		"github.com/sourcegraph/b": {
+			workspace("github.com/sourcegraph/b", "") + ", " + workspace("github.com/sourcegraph/b", "sub", "sub/a.go"),
+			workspace("github.com/sourcegraph/b", "") + ", " + workspace("github.com/sourcegraph/b", "sub", "sub/b.go"),
+		},
+		"github.com/sourcegraph/c": {
+			workspace("github.com/sourcegraph/c", ""),
+		},
+	}
+	calls := map[string]int{}
+
+	var chunks [][]string
+	client := requestClient(func(query string, vars map[string]interface{}) api.Request {
+		require.Equal(t, resolveWorkspacesForBatchSpecQuery, query)
+		var spec batcheslib.BatchSpec
+		require.NoError(t, json.Unmarshal([]byte(vars["spec"].(string)), &spec))
+
+		var chunk, results []string
+		for _, on := range spec.On {
+			chunk = append(chunk, on.Repository)
+			results = append(results, responses[on.Repository][calls[on.Repository]])
+			calls[on.Repository]++
+		}
+		chunks = append(chunks, chunk)
+
+		req := &mockclient.Request{Response: `{"resolveWorkspacesForBatchSpec": [` + strings.Join(results, ", ") + `]}`}
+		req.On("Do", testifymock.Anything, testifymock.Anything).Return(true, nil)
+		return req
+	})
+
+	svc := New(&Opts{Client: client, RepoBatchSize: 2})
+	workspaces, repos, err := svc.ResolveWorkspacesForBatchSpec(context.Background(), &batcheslib.BatchSpec{
+		Name: "chunked",
+		On: []batcheslib.OnQueryOrRepository{
+			{Repository: "github.com/sourcegraph/a"},
+			{Repository: "github.com/sourcegraph/b"},
+			// Matched again in the second chunk.
+			{Repository: "github.com/sourcegraph/b"},
+			{Repository: "github.com/sourcegraph/c"},
+			{Repository: "github.com/sourcegraph/a"},
+		},
+	}, false, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]string{
+		{"github.com/sourcegraph/a", "github.com/sourcegraph/b"},
+		{"github.com/sourcegraph/b", "github.com/sourcegraph/c"},
+		{"github.com/sourcegraph/a"},
+	}, chunks)
+
+	got := map[string][]string{}
+	for _, w := range workspaces {
+		got[w.Repo.Name+":"+w.Path] = w.Repo.SortedFileMatches()
+	}
+	assert.Equal(t, map[string][]string{
+		"github.com/sourcegraph/a:":    {"README.md", "main.go"},
+		"github.com/sourcegraph/b:":    {},
+		"github.com/sourcegraph/b:sub": {"sub/a.go", "sub/b.go"},
+		"github.com/sourcegraph/c:":    {},
+	}, got)
+	assert.Len(t, workspaces, 4)
+	assert.Len(t, repos, 3)
+}
+
+// requestClient is an api.Client whose NewRequest calls the function. Its
+// other methods must not be called.
+type requestClient func(query string, vars map[string]interface{}) api.Request
+
+func (c requestClient) NewQuery(query string) api.Request { return c(query, nil) }
+
+func (c requestClient) NewRequest(query string, vars map[string]interface{}) api.Request {
+	return c(query, vars)
+}
+
+func (c requestClient) NewRequestWithOpts(query string, vars map[string]interface{}, _ api.RequestOpts) api.Request {
+	return c(query, vars)
+}
+
+func (c requestClient) NewHTTPRequest(context.Context, string, string, io.Reader) (*http.Request, error) {
+	panic("unexpected call")
+}
+
+func (c requestClient) Do(*http.Request) (*http.Response, error) { panic("unexpected call") }
+
+func (c requestClient) Ping(context.Context) error { panic("unexpected call") }
@@ -25,14 +25,21 @@ type ExecUI interface {
 	DeterminingWorkspaces()
 	DeterminingWorkspacesSuccess(workspacesCount, reposCount int, unsupported batches.UnsupportedRepoSet, ignored batches.IgnoredRepoSet)
 
+	CheckingDeterminism(repo string)
+	CheckingDeterminismSuccess(report *executor.DeterminismReport)
+
 	CheckingCache()
 	CheckingCacheSuccess(cachedSpecsFound int, tasksToExecute int)
+	CacheKeyChecked(info *executor.CacheKeyInfo)
 
 	ExecutingTasks(verbose bool, parallelism int) executor.TaskExecutionUI
 	ExecutingTasksSkippingErrors(err error)
 
 	LogFilesKept(files []string)
 
+	BinaryFilesChanged(repo string, paths []string)
+	FilesExcluded(repo string, paths []string)
+
 	NoChangesetSpecs()
 	UploadingChangesetSpecs(num int)
 	UploadingChangesetSpecsProgress(done, total int)
@@ -80,6 +80,14 @@ func (ui *JSONLines) DeterminingWorkspacesSuccess(workspacesCount, reposCount in
 	})
 }
 
+func (ui *JSONLines) CheckingDeterminism(repo string) {
+	// Not supported in executor mode.
+}
+
+func (ui *JSONLines) CheckingDeterminismSuccess(report *executor.DeterminismReport) {
+	// Not supported in executor mode.
+}
+
 func (ui *JSONLines) CheckingCache() {
 	logOperationStart(batcheslib.LogEventOperationCheckingCache, &batcheslib.CheckingCacheMetadata{})
 }
@@ -90,6 +98,10 @@ func (ui *JSONLines) CheckingCacheSuccess(cachedSpecsFound int, tasksToExecute i
 	})
 }
 
+func (ui *JSONLines) CacheKeyChecked(info *executor.CacheKeyInfo) {
+	// Cache keys are only reported in verbose mode of the TUI.
+}
+
 func (ui *JSONLines) ExecutingTasks(_ bool, _ int) executor.TaskExecutionUI {
 	return &taskExecutionJSONLines{
 		binaryDiffs: ui.BinaryDiffs,
@@ -109,6 +121,16 @@ func (ui *JSONLines) LogFilesKept(files []string) {
 	}
 }
 
+func (ui *JSONLines) BinaryFilesChanged(repo string, paths []string) {
+	// There is no log event for binary file changes, the server inspects the
+	// resulting diffs itself.
+}
+
+func (ui *JSONLines) FilesExcluded(repo string, paths []string) {
+	// There is no log event for excluded files, the resulting diffs already
+	// reflect them.
+}
+
 func (ui *JSONLines) NoChangesetSpecs() {
 	ui.UploadingChangesetSpecsSuccess([]graphql.ChangesetSpecID{})
 }
@@ -185,6 +207,15 @@ Error: %s
 	logOperationFailure(batcheslib.LogEventOperationDockerWatchDog, &batcheslib.DockerWatchDogMetadata{Error: message})
 }
 
+// executingTaskFailureMetadata extends the metadata of a failed task with the
+// category of its error and whether it's retryable, so that consumers don't
+// have to parse the message.
+type executingTaskFailureMetadata struct {
+	batcheslib.ExecutingTaskMetadata
+	Category  executor.ErrorCategory `json:"category"`
+	Retryable bool                   `json:"retryable"`
+}
+
 type taskExecutionJSONLines struct {
 	linesTasks  map[*executor.Task]batcheslib.JSONLinesTask
 	binaryDiffs bool
@@ -240,9 +271,14 @@ func (ui *taskExecutionJSONLines) TaskFinished(task *executor.Task, err error) {
 	}
 
 	if err != nil {
-		logOperationFailure(batcheslib.LogEventOperationExecutingTask, &batcheslib.ExecutingTaskMetadata{
-			TaskID: lt.ID,
-			Error:  err.Error(),
+		category := executor.CategoryOf(err)
+		logOperationFailure(batcheslib.LogEventOperationExecutingTask, &executingTaskFailureMetadata{
+			ExecutingTaskMetadata: batcheslib.ExecutingTaskMetadata{
+				TaskID: lt.ID,
+				Error:  err.Error(),
+			},
+			Category:  category,
+			Retryable: category.Retryable(),
 		})
 		return
 	}
@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	batcheslib "github.com/sourcegraph/sourcegraph/lib/batches"
+
+	"github.com/sourcegraph/src-cli/internal/batches/executor"
+	"github.com/sourcegraph/src-cli/internal/batches/graphql"
+)
+
+func TestTaskExecutionJSONLines_TaskFinishedCategory(t *testing.T) {
+	task := &executor.Task{Repository: &graphql.Repository{Name: "github.com/sourcegraph/src-cli"}}
+	ui := &taskExecutionJSONLines{}
+	ui.Start([]*executor.Task{task})
+
+	out := captureStdout(t, func() {
+		ui.TaskFinished(task, executor.TaskExecutionErr{
+			Err:        &executor.RunTimeoutError{},
+			Repository: task.Repository.Name,
+		})
+	})
+
+	var event struct {
+		Operation batcheslib.LogEventOperation `json:"operation"`
+		Status    batcheslib.LogEventStatus    `json:"status"`
+		Metadata  struct {
+			TaskID    string `json:"taskID"`
+			Category  string `json:"category"`
+			Retryable bool   `json:"retryable"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(out, &event); err != nil {
+		t.Fatalf("parsing %q: %v", out, err)
+	}
+	if event.Operation != batcheslib.LogEventOperationExecutingTask || event.Status != batcheslib.LogEventStatusFailure {
+		t.Fatalf("unexpected event %s %s", event.Operation, event.Status)
+	}
+	if want := ui.linesTasks[task].ID; event.Metadata.TaskID != want {
+		t.Errorf("wrong task ID: want %q, got %q", want, event.Metadata.TaskID)
+	}
+	if diff := cmp.Diff(string(executor.ErrorCategoryCanceled), event.Metadata.Category); diff != "" {
+		t.Errorf("wrong category (-want +got):\n%s", diff)
+	}
+	if !event.Metadata.Retryable {
+		t.Error("canceled task is not retryable")
+	}
+}
+
+// captureStdout returns what f writes to os.Stdout.
+func captureStdout(t *testing.T, f func()) []byte {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	f()
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
@@ -279,6 +279,15 @@ func (ui *taskExecTUI) TaskFinished(task *executor.Task, err error) {
 	}
 
 	delete(ui.statusBars, bar)
+
+	// Point at the log right away, rather than only in the error summary.
+	if task.LogFile != "" {
+		if ts.err != nil {
+			ui.progress.WriteLine(output.Linef(output.EmojiFailure, output.StyleWarning, "%s failed, see the log in %s", ts.displayName, task.LogFile))
+		} else {
+			ui.progress.Verbosef("%s: log in %s", ts.displayName, task.LogFile)
+		}
+	}
 }
 
 func (ui *taskExecTUI) TaskChangesetSpecsBuilt(task *executor.Task, specs []*batcheslib.ChangesetSpec) {
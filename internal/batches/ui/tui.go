@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"math"
 	"os/exec"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/neelance/parallel"
 
@@ -144,6 +147,27 @@ func (ui *TUI) maybeWorkspaceCountWarning(count, limit int) {
 	}
 }
 
+func (ui *TUI) CheckingDeterminism(repo string) {
+	ui.pending = batchCreatePending(ui.Out, fmt.Sprintf("Executing steps twice in %s to check for non-determinism", repo))
+}
+
+func (ui *TUI) CheckingDeterminismSuccess(report *executor.DeterminismReport) {
+	if report.Deterministic() {
+		batchCompletePending(ui.pending, "Steps produced the same changes when executed twice")
+		return
+	}
+
+	batchCompleteWarning(ui.pending, fmt.Sprintf("Step %d produced different changes when executed twice. Its results will likely never be cached.", report.Step))
+	block := ui.Out.Block(output.Line("", output.StyleWarning, "Lines that differed between the executions:"))
+	for _, line := range report.Lines {
+		block.Write(line)
+	}
+	if len(report.Hints) > 0 {
+		block.Writef("These lines seem to contain %s.", strings.Join(report.Hints, ", "))
+	}
+	block.Close()
+}
+
 func (ui *TUI) CheckingCache() {
 	ui.pending = batchCreatePending(ui.Out, "Checking cache for changeset specs")
 }
@@ -165,6 +189,31 @@ func (ui *TUI) CheckingCacheSuccess(cachedSpecsFound int, uncachedTasks int) {
 	}
 }
 
+func (ui *TUI) CacheKeyChecked(info *executor.CacheKeyInfo) {
+	result := "miss"
+	if info.Found {
+		result = "hit"
+	}
+
+	name := info.Task.Repository.Name
+	if info.Path != "" {
+		name += "/" + info.Path
+	}
+
+	ui.pending.Verbosef("Cache key for %s, step %d: %s (%s)", name, info.StepIndex+1, info.Key, result)
+	ui.pending.Verbosef("  rev: %s, steps: %s, containers: %s", info.Rev, info.StepsHash, strings.Join(info.Containers, ", "))
+	if names := info.EnvNames(); len(names) > 0 {
+		env := make([]string, len(names))
+		for i, k := range names {
+			env[i] = k + "=" + info.Env[k]
+		}
+		ui.pending.Verbosef("  env: %s", strings.Join(env, ", "))
+	}
+	for _, m := range info.Mounts {
+		ui.pending.Verbosef("  mount: %s (%d bytes, modified %s)", m.Path, m.Size, m.Modified.Format(time.RFC3339))
+	}
+}
+
 func (ui *TUI) ExecutingTasks(verbose bool, parallelism int) executor.TaskExecutionUI {
 	ui.progressPrinter = newTaskExecTUI(ui.Out, verbose, parallelism)
 	return ui.progressPrinter
@@ -184,6 +233,26 @@ func (ui *TUI) LogFilesKept(files []string) {
 	}
 }
 
+func (ui *TUI) BinaryFilesChanged(repo string, paths []string) {
+	block := ui.Out.Block(output.Linef(output.EmojiWarning, output.StyleWarning, "Binary files were changed in %s. Use the -no-binary flag to fail the execution instead.", repo))
+	defer block.Close()
+
+	for _, path := range paths {
+		block.Write(path)
+	}
+}
+
+func (ui *TUI) FilesExcluded(repo string, paths []string) {
+	var plural string
+	if len(paths) != 1 {
+		plural = "s"
+	}
+	ui.Out.WriteLine(output.Linef("", output.StyleSuggestion, "Excluded changes to %d file%s in %s because of %s", len(paths), plural, repo, executor.SrcIgnoreFile))
+	for _, path := range paths {
+		ui.Out.Verbosef("  %s", path)
+	}
+}
+
 func (ui *TUI) NoChangesetSpecs() {
 	ui.Out.WriteLine(output.Linef(output.EmojiWarning, output.StyleWarning, `No changeset specs created`))
 }
@@ -366,8 +435,10 @@ func printExecutionError(out *output.Output, err error) {
 			block = out.Block(output.Line(output.EmojiFailure, output.StyleWarning, "Error:"))
 		}
 
+		categories := map[executor.ErrorCategory]int{}
 		for _, e := range errs {
 			if taskErr, ok := e.(executor.TaskExecutionErr); ok {
+				categories[taskErr.Category()]++
 				block.Write(formatTaskExecutionErr(taskErr))
 			} else {
 				if err == context.Canceled {
@@ -378,6 +449,10 @@ func printExecutionError(out *output.Output, err error) {
 			}
 		}
 
+		if len(errs) > 1 && len(categories) > 0 {
+			block.Write(formatErrorCategories(categories))
+		}
+
 		if block != nil {
 			block.Close()
 		}
@@ -442,6 +517,22 @@ func formatTaskExecutionErr(err executor.TaskExecutionErr) string {
 	)
 }
 
+// formatErrorCategories summarises how many task failures fall into each
+// executor.ErrorCategory, in a stable order.
+func formatErrorCategories(categories map[executor.ErrorCategory]int) string {
+	keys := make([]string, 0, len(categories))
+	for c := range categories {
+		keys = append(keys, string(c))
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s: %d", k, categories[executor.ErrorCategory(k)]))
+	}
+	return fmt.Sprintf("Failures by category: %s", strings.Join(parts, ", "))
+}
+
 func batchCreatePending(out *output.Output, message string) output.Pending {
 	return out.Pending(output.Line("", batchPendingColor, message))
 }
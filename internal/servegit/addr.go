@@ -0,0 +1,117 @@
+package servegit
+
+import (
+	"net"
+	"strings"
+	"syscall"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+)
+
+// ValidateAddr returns an error if addr isn't a host:port address that can be
+// listened on. The host and port may be empty, for all interfaces and an
+// unused port.
+func ValidateAddr(addr string) error {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return errors.Newf("invalid address %q: expected host:port, such as 127.0.0.1:3434", addr)
+	}
+	if port != "" {
+		if _, err := net.LookupPort("tcp", port); err != nil {
+			return errors.Newf("invalid port %q in address %q", port, addr)
+		}
+	}
+	if host != "" && net.ParseIP(host) == nil {
+		if _, err := net.LookupHost(host); err != nil {
+			return errors.Newf("cannot resolve host %q in address %q", host, addr)
+		}
+	}
+	return nil
+}
+
+// listen validates s.Addr and listens on it.
+func (s *Serve) listen() (net.Listener, error) {
+	if err := ValidateAddr(s.Addr); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp", s.Addr)
+	if errors.Is(err, syscall.EADDRINUSE) {
+		return nil, errors.Newf("address %s is already in use", s.Addr)
+	} else if err != nil {
+		return nil, errors.Wrap(err, "listen")
+	}
+	return ln, nil
+}
+
+// hostAddr is an IP address of a network interface of this host.
+type hostAddr struct {
+	iface string
+	ip    net.IP
+}
+
+// hostAddrs returns the IP addresses of the network interfaces that are up.
+func hostAddrs() []hostAddr {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var addrs []hostAddr
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		ifaceAddrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range ifaceAddrs {
+			if ipNet, ok := a.(*net.IPNet); ok {
+				addrs = append(addrs, hostAddr{iface: iface.Name, ip: ipNet.IP})
+			}
+		}
+	}
+	return addrs
+}
+
+// candidateURLs returns the URLs, with a description each, at which a
+// Sourcegraph instance may reach a server listening on addr with the given
+// path prefix. If addr listens on all interfaces, these are the addresses of
+// the interfaces in addrs, and host.docker.internal for instances running in
+// Docker Desktop on this host.
+func candidateURLs(addr, prefix string, addrs []hostAddr) [][2]string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil
+	}
+	url := func(host string) string {
+		return "http://" + net.JoinHostPort(host, port) + prefix
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || !ip.IsUnspecified() {
+		description := "the address listened on"
+		if ip != nil && ip.IsLoopback() {
+			description = "loopback only; not reachable from other hosts or containers"
+		}
+		return [][2]string{{url(host), description}}
+	}
+
+	var urls [][2]string
+	for _, a := range addrs {
+		if a.ip.IsLinkLocalUnicast() || (ip.To4() != nil && a.ip.To4() == nil) {
+			continue
+		}
+		var description string
+		switch {
+		case a.ip.IsLoopback():
+			description = "loopback, for Sourcegraph running directly on this host"
+		case a.iface == "docker0" || strings.HasPrefix(a.iface, "br-"):
+			description = "Docker bridge " + a.iface + ", for Sourcegraph running in Docker on this host"
+		default:
+			description = "network interface " + a.iface
+		}
+		urls = append(urls, [2]string{url(a.ip.String()), description})
+	}
+	urls = append(urls, [2]string{url("host.docker.internal"), "for Sourcegraph running in Docker Desktop on this host"})
+	return urls
+}
@@ -0,0 +1,90 @@
+package servegit
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestValidateAddr(t *testing.T) {
+	for _, addr := range []string{":3434", ":", "127.0.0.1:3434", "[::1]:3434", "0.0.0.0:0", "localhost:3434"} {
+		if err := ValidateAddr(addr); err != nil {
+			t.Errorf("ValidateAddr(%q): unexpected error: %v", addr, err)
+		}
+	}
+	for _, addr := range []string{"3434", "127.0.0.1", ":99999", ":notaport", "[::1:3434"} {
+		if err := ValidateAddr(addr); err == nil {
+			t.Errorf("ValidateAddr(%q): expected error", addr)
+		}
+	}
+}
+
+func TestListenAddrInUse(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	s := &Serve{Addr: ln.Addr().String()}
+	_, err = s.listen()
+	if err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Fatalf("expected address in use error, got %v", err)
+	}
+}
+
+func TestCandidateURLs(t *testing.T) {
+	addrs := []hostAddr{
+		{iface: "lo", ip: net.ParseIP("127.0.0.1")},
+		{iface: "lo", ip: net.ParseIP("::1")},
+		{iface: "eth0", ip: net.ParseIP("192.168.1.20")},
+		{iface: "eth0", ip: net.ParseIP("fe80::1")},
+		{iface: "docker0", ip: net.ParseIP("172.17.0.1")},
+	}
+
+	urlsOf := func(candidates [][2]string) []string {
+		var urls []string
+		for _, c := range candidates {
+			urls = append(urls, c[0])
+		}
+		return urls
+	}
+
+	cases := []struct {
+		addr   string
+		prefix string
+		want   []string
+	}{{
+		addr: "0.0.0.0:3434",
+		want: []string{
+			"http://127.0.0.1:3434",
+			"http://192.168.1.20:3434",
+			"http://172.17.0.1:3434",
+			"http://host.docker.internal:3434",
+		},
+	}, {
+		addr:   "[::]:3434",
+		prefix: "/abc",
+		want: []string{
+			"http://127.0.0.1:3434/abc",
+			"http://[::1]:3434/abc",
+			"http://192.168.1.20:3434/abc",
+			"http://172.17.0.1:3434/abc",
+			"http://host.docker.internal:3434/abc",
+		},
+	}, {
+		addr: "127.0.0.1:3434",
+		want: []string{"http://127.0.0.1:3434"},
+	}, {
+		addr: "192.168.1.20:3434",
+		want: []string{"http://192.168.1.20:3434"},
+	}}
+	for _, tc := range cases {
+		got := urlsOf(candidateURLs(tc.addr, tc.prefix, addrs))
+		if d := cmp.Diff(tc.want, got); d != "" {
+			t.Errorf("candidateURLs(%q) mismatch (-want +got):\n%s", tc.addr, d)
+		}
+	}
+}
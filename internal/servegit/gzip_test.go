@@ -0,0 +1,109 @@
+package servegit
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	for header, want := range map[string]bool{
+		"":                        false,
+		"gzip":                    true,
+		"deflate, gzip":           true,
+		"gzip;q=0.5, identity":    true,
+		"gzip;q=0":                false,
+		"br, identity;q=0.5":      false,
+		"deflate, gzip; q=0.000 ": false,
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("Accept-Encoding", header)
+		if got := acceptsGzip(r); got != want {
+			t.Errorf("Accept-Encoding %q: want %v, got %v", header, want, got)
+		}
+	}
+}
+
+func TestGzipResponses(t *testing.T) {
+	root := gitInitRepos(t, "project1")
+	ts := httptest.NewServer((&Serve{
+		Info:  testLogger(t),
+		Debug: discardLogger,
+		Addr:  testAddress,
+		Root:  root,
+	}).handler())
+	t.Cleanup(ts.Close)
+
+	// The transport would otherwise ask for gzip and decompress the
+	// responses transparently.
+	client := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	get := func(acceptEncoding string) *http.Response {
+		req, err := http.NewRequest("GET", ts.URL+"/v1/list-repos", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { res.Body.Close() })
+		return res
+	}
+
+	var list struct{ Items []Repo }
+
+	res := get("gzip")
+	if got := res.Header.Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("want gzip Content-Encoding, got %q", got)
+	}
+	zr, err := gzip.NewReader(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.NewDecoder(zr).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 || list.Items[0].Name != "project1" {
+		t.Errorf("unexpected repositories %+v", list.Items)
+	}
+
+	res = get("")
+	if got := res.Header.Get("Content-Encoding"); got != "" {
+		t.Fatalf("want no Content-Encoding, got %q", got)
+	}
+	if err := json.NewDecoder(res.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGzipClone(t *testing.T) {
+	root := gitInitRepos(t, "project1")
+	commit := exec.Command("git", "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--allow-empty", "-m", "initial")
+	commit.Dir = filepath.Join(root, "project1")
+	if out, err := commit.CombinedOutput(); err != nil {
+		t.Fatalf("git commit: %s\n%s", err, out)
+	}
+
+	ts := httptest.NewServer(gzipHandler((&Serve{
+		Info:  testLogger(t),
+		Debug: discardLogger,
+		Addr:  testAddress,
+		Root:  root,
+	}).handler(), func(*http.Request) bool { return false }))
+	t.Cleanup(ts.Close)
+
+	// git asks for gzip. The handler doesn't compress pack files, which the
+	// outer gzipHandler does here, to check that compressed streaming
+	// responses work too.
+	clone := exec.Command("git", "clone", ts.URL+"/repos/project1/.git", filepath.Join(t.TempDir(), "clone"))
+	if out, err := clone.CombinedOutput(); err != nil {
+		t.Fatalf("git clone: %s\n%s", err, out)
+	}
+}
@@ -2,13 +2,16 @@ package servegit
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"html/template"
 	"io/fs"
 	"log"
-	"net"
 	"net/http"
+	"net/url"
 	"os/exec"
 	pathpkg "path"
 	"path/filepath"
@@ -20,23 +23,69 @@ import (
 )
 
 type Serve struct {
-	Addr  string
-	Root  string
-	Info  *log.Logger
-	Debug *log.Logger
+	Addr string
+	Root string
+	// Token, if set, must be the first path element of every request, so
+	// that only those who know the URL with the token can read the
+	// repositories. Requests without it are answered with 404 Not Found.
+	Token string
+	// WatchInterval, if set, is how often Root is checked for added and
+	// removed repositories. The list of repositories is then kept up to date
+	// in the background, instead of scanning Root on every request for it.
+	WatchInterval time.Duration
+	Info          *log.Logger
+	Debug         *log.Logger
+
+	watcher *repoWatcher
+}
+
+// NewToken returns a random token to use as Serve.Token.
+func NewToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.Wrap(err, "generating token")
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// URL returns the URL the repositories are served at, including the token.
+func (s *Serve) URL() string {
+	return "http://" + s.Addr + s.pathPrefix()
+}
+
+func (s *Serve) pathPrefix() string {
+	if s.Token == "" {
+		return ""
+	}
+	return "/" + s.Token
 }
 
 func (s *Serve) Start() error {
-	ln, err := net.Listen("tcp", s.Addr)
+	ln, err := s.listen()
 	if err != nil {
-		return errors.Wrap(err, "listen")
+		return err
 	}
 
 	// Update Addr to what listener actually used.
 	s.Addr = ln.Addr().String()
 
 	s.Info.Printf("listening on http://%s", s.Addr)
+	if urls := candidateURLs(s.Addr, s.pathPrefix(), hostAddrs()); len(urls) > 0 {
+		s.Info.Printf("Sourcegraph may reach this server at:")
+		for _, u := range urls {
+			s.Info.Printf("  %s (%s)", u[0], u[1])
+		}
+	}
 	s.Info.Printf("serving git repositories from %s", s.Root)
+	if s.Token != "" {
+		s.Info.Printf("requests must include the token; use this URL in Sourcegraph: %s", s.URL())
+	}
+	if s.WatchInterval > 0 {
+		if err := s.startWatching(nil); err != nil {
+			return err
+		}
+		s.Info.Printf("watching %s for added and removed repositories", s.Root)
+	}
 
 	if err := (&http.Server{Handler: s.handler()}).Serve(ln); err != nil {
 		return errors.Wrap(err, "serving")
@@ -71,10 +120,10 @@ func (s *Serve) handler() http.Handler {
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
 		err := indexHTML.Execute(w, map[string]interface{}{
-			"Explain": explainAddr(s.Addr),
+			"Explain": explainURL(s.URL()),
 			"Links": []string{
-				"/v1/list-repos",
-				"/repos/",
+				s.pathPrefix() + "/v1/list-repos",
+				s.pathPrefix() + "/repos/",
 			},
 		})
 		if err != nil {
@@ -83,7 +132,7 @@ func (s *Serve) handler() http.Handler {
 	})
 
 	mux.HandleFunc("/v1/list-repos", func(w http.ResponseWriter, r *http.Request) {
-		repos, err := s.Repos()
+		repos, err := s.listRepos()
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -130,11 +179,37 @@ func (s *Serve) handler() http.Handler {
 		fs.ServeHTTP(w, r)
 	})))
 
+	// Repository lists, ref advertisements and the files shown for
+	// convenience compress well. Pack files don't: their objects are already
+	// zlib compressed, and gzip only shaves off about 2% of a typical pack.
+	compressed := gzipHandler(mux, isPackResponse)
+
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		mux.ServeHTTP(w, r)
+		if s.Token != "" {
+			token, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+				s.Debug.Printf("rejected request without valid token: %s %s", r.Method, r.RemoteAddr)
+				http.NotFound(w, r)
+				return
+			}
+			r2 := new(http.Request)
+			*r2 = *r
+			r2.URL = new(url.URL)
+			*r2.URL = *r.URL
+			r2.URL.Path = "/" + rest
+			r2.URL.RawPath = ""
+			r = r2
+		}
+		compressed.ServeHTTP(w, r)
 	})
 }
 
+// isPackResponse reports whether r asks for a pack file, whose objects are
+// already compressed by git, so that compressing them again doesn't pay off.
+func isPackResponse(r *http.Request) bool {
+	return strings.HasSuffix(r.URL.Path, "/git-upload-pack")
+}
+
 // Checks if git thinks the given path is a valid .git folder for a repository
 func isBareRepo(path string) bool {
 	c := exec.Command("git", "--git-dir", path, "rev-parse", "--is-bare-repository")
@@ -250,10 +325,10 @@ func (s *Serve) Repos() ([]Repo, error) {
 	return repos, nil
 }
 
-func explainAddr(addr string) string {
-	return fmt.Sprintf(`Serving the repositories at http://%s.
+func explainURL(u string) string {
+	return fmt.Sprintf(`Serving the repositories at %s.
 
 See https://sourcegraph.com/docs/admin/code_hosts/src_serve_git for
 instructions to configure in Sourcegraph.
-`, addr)
+`, u)
 }
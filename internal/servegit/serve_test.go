@@ -61,6 +61,56 @@ func TestReposHandler(t *testing.T) {
 	}
 }
 
+func TestTokenHandler(t *testing.T) {
+	root := gitInitRepos(t, "project1")
+	h := (&Serve{
+		Info:  testLogger(t),
+		Debug: discardLogger,
+		Addr:  testAddress,
+		Root:  root,
+		Token: "s3cr3t",
+	}).handler()
+	ts := httptest.NewServer(h)
+	t.Cleanup(ts.Close)
+
+	get := func(path string) (int, string) {
+		res, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.StatusCode, string(b)
+	}
+
+	for _, path := range []string{"/", "/v1/list-repos", "/repos/project1/.git/info/refs", "/wrong/v1/list-repos", "/s3cr3/v1/list-repos"} {
+		if code, _ := get(path); code != http.StatusNotFound {
+			t.Errorf("GET %s without the token: want status 404, got %d", path, code)
+		}
+	}
+
+	code, index := get("/s3cr3t/")
+	if code != http.StatusOK {
+		t.Fatalf("GET index with the token: want status 200, got %d", code)
+	}
+	for _, sub := range []string{"http://" + testAddress + "/s3cr3t", "/s3cr3t/v1/list-repos", "/s3cr3t/repos/"} {
+		if !strings.Contains(index, sub) {
+			t.Errorf("index page does not contain substring %q", sub)
+		}
+	}
+
+	code, list := get("/s3cr3t/v1/list-repos")
+	if code != http.StatusOK {
+		t.Fatalf("GET list-repos with the token: want status 200, got %d", code)
+	}
+	if !strings.Contains(list, `"/repos/project1/.git"`) {
+		t.Errorf("list-repos does not contain project1:\n%s", list)
+	}
+}
+
 func testReposHandler(t *testing.T, h http.Handler, repos []Repo) {
 	ts := httptest.NewServer(h)
 	t.Cleanup(ts.Close)
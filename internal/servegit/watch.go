@@ -0,0 +1,172 @@
+package servegit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// repoWatcher keeps the list of repositories served up to date while
+// Serve.WatchInterval is set, so that list-repos doesn't have to scan Root on
+// every request.
+type repoWatcher struct {
+	mu    sync.Mutex
+	repos []Repo
+}
+
+func (w *repoWatcher) get() []Repo {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.repos
+}
+
+func (w *repoWatcher) set(repos []Repo) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.repos = repos
+}
+
+// listRepos returns the repositories to advertise: the watched list when
+// watching, or else the result of scanning Root.
+func (s *Serve) listRepos() ([]Repo, error) {
+	if s.watcher != nil {
+		return s.watcher.get(), nil
+	}
+	return s.Repos()
+}
+
+// startWatching scans Root, and then rescans it in the background whenever
+// the directories in it change, until stop is closed.
+func (s *Serve) startWatching(stop <-chan struct{}) error {
+	// The fingerprint is taken before scanning, so that changes made during
+	// the scan aren't missed.
+	last, _ := dirsFingerprint(s.Root)
+	repos, err := s.Repos()
+	if err != nil {
+		return err
+	}
+	s.watcher = &repoWatcher{repos: repos}
+	go s.watch(last, stop)
+	return nil
+}
+
+// watch polls Root for changes every WatchInterval, and rescans it once the
+// changes have settled, that is once nothing changed for a whole interval.
+// last is the fingerprint of Root when it was last scanned.
+// This way cloning a repository, which changes the tree for a while, only
+// leads to a single rescan.
+//
+// Polling is used rather than filesystem notifications, since those would
+// need a new dependency and a watch on every directory below Root, which
+// can exceed the limits of the OS for large trees.
+func (s *Serve) watch(last string, stop <-chan struct{}) {
+	ticker := time.NewTicker(s.WatchInterval)
+	defer ticker.Stop()
+
+	changed := false
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		fingerprint, err := dirsFingerprint(s.Root)
+		if err != nil {
+			s.Debug.Printf("watching %s: %v", s.Root, err)
+			continue
+		}
+		if fingerprint != last {
+			last = fingerprint
+			changed = true
+			continue
+		}
+		if !changed {
+			continue
+		}
+		changed = false
+
+		repos, err := s.Repos()
+		if err != nil {
+			s.Info.Printf("WARN: rescanning %s: %v", s.Root, err)
+			continue
+		}
+		added, removed := diffRepos(s.watcher.get(), repos)
+		s.watcher.set(repos)
+		if added > 0 || removed > 0 {
+			s.Info.Printf("serving %d repositories (%d added, %d removed)", len(repos), added, removed)
+		}
+	}
+}
+
+// dirsFingerprint returns a hash of the paths and modification times of the
+// directories below root, which changes whenever a repository is added to or
+// removed from root. It doesn't descend into repositories, so that changes
+// to their contents don't count. The .git directories of worktrees are
+// included, so that a repository whose initialization is still in progress
+// is picked up once it completes.
+func dirsFingerprint(root string) (string, error) {
+	h := sha256.New()
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root {
+				return err
+			}
+			// The directory may have been removed while walking; the next
+			// poll sees the result.
+			return nil
+		}
+		if !d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		fmt.Fprintf(h, "%s\x00%d\n", path, info.ModTime().UnixNano())
+		if d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if path != root && looksLikeRepo(path) {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// looksLikeRepo reports whether dir appears to be a git worktree or bare
+// repository, without running git.
+func looksLikeRepo(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return true
+	}
+	_, headErr := os.Stat(filepath.Join(dir, "HEAD"))
+	_, objectsErr := os.Stat(filepath.Join(dir, "objects"))
+	return headErr == nil && objectsErr == nil
+}
+
+// diffRepos returns how many repositories were added and removed between the
+// before and after lists.
+func diffRepos(before, after []Repo) (added, removed int) {
+	seen := make(map[string]bool, len(before))
+	for _, r := range before {
+		seen[r.Name] = true
+	}
+	for _, r := range after {
+		if seen[r.Name] {
+			delete(seen, r.Name)
+		} else {
+			added++
+		}
+	}
+	return added, len(seen)
+}
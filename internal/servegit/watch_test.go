@@ -0,0 +1,96 @@
+package servegit
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWatch(t *testing.T) {
+	root := gitInitRepos(t, "project1", "dir/project2")
+
+	s := &Serve{
+		Info:          testLogger(t),
+		Debug:         discardLogger,
+		Root:          root,
+		WatchInterval: 10 * time.Millisecond,
+	}
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	if err := s.startWatching(stop); err != nil {
+		t.Fatal(err)
+	}
+
+	waitForRepos := func(want ...string) {
+		t.Helper()
+		var got []string
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			repos, err := s.listRepos()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = got[:0]
+			for _, r := range repos {
+				got = append(got, r.Name)
+			}
+			sort.Strings(got)
+			if cmp.Equal(want, got) {
+				return
+			}
+		}
+		t.Fatalf("repositories mismatch (-want +got):\n%s", cmp.Diff(want, got))
+	}
+
+	waitForRepos("dir/project2", "project1")
+
+	// A repository added to an existing directory.
+	p := filepath.Join(root, "dir", "project3")
+	if err := os.MkdirAll(p, 0755); err != nil {
+		t.Fatal(err)
+	}
+	gitInit(t, p)
+	waitForRepos("dir/project2", "dir/project3", "project1")
+
+	// A removed repository.
+	if err := os.RemoveAll(filepath.Join(root, "project1")); err != nil {
+		t.Fatal(err)
+	}
+	waitForRepos("dir/project2", "dir/project3")
+}
+
+func TestDirsFingerprint(t *testing.T) {
+	root := gitInitRepos(t, "project1")
+	src := filepath.Join(root, "project1", "src")
+	if err := os.Mkdir(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	fingerprint := func() string {
+		t.Helper()
+		fp, err := dirsFingerprint(root)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fp
+	}
+	before := fingerprint()
+
+	// Changes deeper inside a repository don't count.
+	if err := os.WriteFile(filepath.Join(src, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := fingerprint(); got != before {
+		t.Error("fingerprint changed when a file was added to a repository")
+	}
+
+	if err := os.Mkdir(filepath.Join(root, "project2"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got := fingerprint(); got == before {
+		t.Error("fingerprint didn't change when a directory was added")
+	}
+}
@@ -3,6 +3,7 @@ package streaming
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 )
 
 // EventMatch is an interface which only the top level match event types
@@ -155,6 +156,16 @@ const (
 	PathMatchType
 )
 
+// String returns the name of the match type, as used in the JSON
+// representation of matches.
+func (t MatchType) String() string {
+	b, err := t.MarshalJSON()
+	if err != nil {
+		return strconv.Itoa(int(t))
+	}
+	return string(b[1 : len(b)-1])
+}
+
 func (t MatchType) MarshalJSON() ([]byte, error) {
 	switch t {
 	case ContentMatchType:
@@ -0,0 +1,107 @@
+package streaming
+
+import (
+	"github.com/gobwas/glob"
+	"github.com/grafana/regexp"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+)
+
+// MatchFilter drops matches on the client, after the server returned them.
+// It doesn't change the progress reported by the server, which still counts
+// the dropped matches.
+type MatchFilter struct {
+	includePath glob.Glob
+	excludePath glob.Glob
+	repo        *regexp.Regexp
+
+	// Dropped is the number of matches that were dropped so far.
+	Dropped int
+}
+
+// NewMatchFilter creates a filter that keeps only matches in files whose path
+// matches includePath and doesn't match excludePath, and only matches in
+// repositories whose name matches the repo regular expression. Globs match
+// the path relative to the repository root, and ** matches any number of
+// directories. Empty arguments don't filter anything. If all are empty, nil is
+// returned.
+func NewMatchFilter(includePath, excludePath, repo string) (*MatchFilter, error) {
+	if includePath == "" && excludePath == "" && repo == "" {
+		return nil, nil
+	}
+
+	f := &MatchFilter{}
+	var err error
+	if includePath != "" {
+		if f.includePath, err = glob.Compile(includePath, '/'); err != nil {
+			return nil, errors.Wrapf(err, "invalid include path %q", includePath)
+		}
+	}
+	if excludePath != "" {
+		if f.excludePath, err = glob.Compile(excludePath, '/'); err != nil {
+			return nil, errors.Wrapf(err, "invalid exclude path %q", excludePath)
+		}
+	}
+	if repo != "" {
+		if f.repo, err = regexp.Compile(repo); err != nil {
+			return nil, errors.Wrapf(err, "invalid repository filter %q", repo)
+		}
+	}
+	return f, nil
+}
+
+// Keep returns whether match passes the filter. The path filters only apply
+// to matches in files, the repository filter to all matches that belong to a
+// single repository.
+func (f *MatchFilter) Keep(match EventMatch) bool {
+	var repo, path string
+	switch m := match.(type) {
+	case *EventContentMatch:
+		repo, path = m.Repository, m.Path
+	case *EventPathMatch:
+		repo, path = m.Repository, m.Path
+	case *EventSymbolMatch:
+		repo, path = m.Repository, m.Path
+	case *EventRepoMatch:
+		repo = m.Repository
+	default:
+		return true
+	}
+
+	if f.repo != nil && !f.repo.MatchString(repo) {
+		return false
+	}
+	if path != "" {
+		if f.includePath != nil && !f.includePath.Match(path) {
+			return false
+		}
+		if f.excludePath != nil && f.excludePath.Match(path) {
+			return false
+		}
+	}
+	return true
+}
+
+// Wrap returns a copy of d that only passes the matches kept by f on to
+// d.OnMatches.
+func (f *MatchFilter) Wrap(d Decoder) Decoder {
+	onMatches := d.OnMatches
+	if onMatches == nil {
+		return d
+	}
+
+	d.OnMatches = func(matches []EventMatch) {
+		kept := make([]EventMatch, 0, len(matches))
+		for _, m := range matches {
+			if f.Keep(m) {
+				kept = append(kept, m)
+			} else {
+				f.Dropped++
+			}
+		}
+		if len(kept) > 0 {
+			onMatches(kept)
+		}
+	}
+	return d
+}
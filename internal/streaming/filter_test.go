@@ -0,0 +1,77 @@
+package streaming
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMatchFilter(t *testing.T) {
+	f, err := NewMatchFilter("cmd/**/*.go", "**/*_test.go", "^github\\.com/sourcegraph/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		match EventMatch
+		want  bool
+	}{
+		{&EventContentMatch{Repository: "github.com/sourcegraph/src-cli", Path: "cmd/src/main.go"}, true},
+		{&EventContentMatch{Repository: "github.com/sourcegraph/src-cli", Path: "cmd/src/main_test.go"}, false},
+		{&EventPathMatch{Repository: "github.com/sourcegraph/src-cli", Path: "internal/api/api.go"}, false},
+		{&EventSymbolMatch{Repository: "github.com/other/src-cli", Path: "cmd/src/main.go"}, false},
+		{&EventRepoMatch{Repository: "github.com/sourcegraph/sourcegraph"}, true},
+		{&EventRepoMatch{Repository: "github.com/other/sourcegraph"}, false},
+		{&EventCommitMatch{Label: "github.com/other/sourcegraph"}, true},
+	} {
+		if got := f.Keep(tc.match); got != tc.want {
+			t.Errorf("Keep(%+v) = %t, want %t", tc.match, got, tc.want)
+		}
+	}
+}
+
+func TestMatchFilter_Empty(t *testing.T) {
+	f, err := NewMatchFilter("", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f != nil {
+		t.Errorf("expected no filter, got %+v", f)
+	}
+}
+
+func TestMatchFilter_Invalid(t *testing.T) {
+	if _, err := NewMatchFilter("", "", "("); err == nil {
+		t.Error("expected error for invalid regular expression")
+	}
+	if _, err := NewMatchFilter("[", "", ""); err == nil {
+		t.Error("expected error for invalid glob")
+	}
+}
+
+func TestMatchFilter_Wrap(t *testing.T) {
+	f, err := NewMatchFilter("", "*.md", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []EventMatch
+	d := f.Wrap(Decoder{
+		OnMatches: func(matches []EventMatch) { got = append(got, matches...) },
+	})
+	d.OnMatches([]EventMatch{
+		&EventPathMatch{Repository: "a", Path: "README.md"},
+		&EventPathMatch{Repository: "a", Path: "main.go"},
+	})
+	d.OnMatches([]EventMatch{
+		&EventPathMatch{Repository: "b", Path: "CHANGELOG.md"},
+	})
+
+	want := []EventMatch{&EventPathMatch{Repository: "a", Path: "main.go"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("wrong matches (-want +got):\n%s", diff)
+	}
+	if f.Dropped != 2 {
+		t.Errorf("Dropped = %d, want 2", f.Dropped)
+	}
+}
@@ -3,25 +3,89 @@ package streaming
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
 
 	"github.com/sourcegraph/src-cli/internal/api"
 )
 
+// ErrStreamingUnsupported is returned by Search if the instance doesn't
+// provide the streaming search endpoint.
+var ErrStreamingUnsupported = errors.New("the Sourcegraph instance does not support streaming search")
+
+// ErrTimeout is returned by Search if the search didn't complete within
+// Opts.Timeout. The results found until then have been decoded.
+var ErrTimeout = errors.New("search timed out, the results are incomplete")
+
+// TimeoutGrace is how much longer than Opts.Timeout the client waits for the
+// server, which needs some time to send its results after its own timeout.
+const TimeoutGrace = 10 * time.Second
+
 // Opts contains the search options supported by Search.
 type Opts struct {
 	Display int
 	Trace   bool
 	Json    bool
+
+	// Template, if set, is a Go template that is executed for every match
+	// instead of the default output.
+	Template string
+
+	// CSV, if set, prints file matches as CSV rows instead of the default
+	// output.
+	CSV bool
+
+	// Filter, if set, drops matches before they are displayed.
+	Filter *MatchFilter
+
+	// DiffContextLines, if set, is the number of unchanged lines shown
+	// around the changed lines of commit diffs. DiffColor is "auto", "always"
+	// or "never", and defaults to "auto".
+	DiffContextLines *int
+	DiffColor        string
+
+	// Timeout, if positive, bounds how long the search may take. The query
+	// is expected to contain a timeout: filter with the same value, so that
+	// the server stops searching in time to send the results found so far.
+	Timeout time.Duration
 }
 
 // Search calls the streaming search endpoint and uses decoder to decode the
 // response body.
 func Search(query string, opts Opts, client api.Client, decoder Decoder) error {
+	ctx := context.Background()
+	var timedOut bool
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout+TimeoutGrace)
+		defer cancel()
+
+		// If the server hit the timeout, it reports the shards it didn't
+		// search in the final progress event.
+		onProgress := decoder.OnProgress
+		decoder.OnProgress = func(p *Progress) {
+			if p.Done {
+				for _, skipped := range p.Skipped {
+					if skipped.Reason == ShardTimeout {
+						timedOut = true
+					}
+				}
+			}
+			if onProgress != nil {
+				onProgress(p)
+			}
+		}
+	}
+
 	// Create request.
-	req, err := client.NewHTTPRequest(context.Background(), "GET", ".api/search/stream?q="+url.QueryEscape(query), nil)
+	req, err := client.NewHTTPRequest(ctx, "GET", ".api/search/stream?q="+url.QueryEscape(query), nil)
 	if err != nil {
 		return err
 	}
@@ -42,13 +106,27 @@ func Search(query string, opts Opts, client api.Client, decoder Decoder) error {
 	// Send request.
 	resp, err := client.Do(req)
 	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return ErrTimeout
+		}
 		return fmt.Errorf("error sending request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrStreamingUnsupported
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return errors.Errorf("error from streaming search: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
 	// Process response.
 	err = decoder.ReadAll(resp.Body)
 	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return ErrTimeout
+		}
 		return fmt.Errorf("error during decoding: %w", err)
 	}
 
@@ -59,5 +137,8 @@ func Search(query string, opts Opts, client api.Client, decoder Decoder) error {
 			return err
 		}
 	}
+	if timedOut {
+		return ErrTimeout
+	}
 	return nil
 }
@@ -0,0 +1,380 @@
+// Package compose validates Sourcegraph deployments that run with docker-compose,
+// by inspecting their containers with the docker CLI.
+package compose
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
+	"github.com/sourcegraph/src-cli/internal/exec"
+	"github.com/sourcegraph/src-cli/internal/validate"
+)
+
+// serviceLabel and projectLabel are the labels docker-compose sets on the
+// containers it creates.
+const (
+	serviceLabel = "com.docker.compose.service"
+	projectLabel = "com.docker.compose.project"
+)
+
+// DefaultServices are the services of a Sourcegraph docker-compose deployment
+// that are expected to be running.
+var DefaultServices = []string{
+	"sourcegraph-frontend-0",
+	"sourcegraph-frontend-internal",
+	"gitserver-0",
+	"repo-updater",
+	"worker",
+	"searcher-0",
+	"symbols-0",
+	"zoekt-indexserver-0",
+	"zoekt-webserver-0",
+	"pgsql",
+	"codeintel-db",
+	"redis-cache",
+	"redis-store",
+}
+
+type Option = func(config *Config)
+
+type Config struct {
+	project    string
+	services   []string
+	output     io.Writer
+	exitStatus bool
+	containers []Container
+}
+
+// WithProject limits validation to the containers of the given docker-compose
+// project. By default, the project of the sourcegraph-frontend-0 container is
+// validated.
+func WithProject(project string) Option {
+	return func(config *Config) {
+		config.project = project
+	}
+}
+
+// WithServices replaces the services that are expected to be running.
+func WithServices(services []string) Option {
+	return func(config *Config) {
+		config.services = services
+	}
+}
+
+func Quiet() Option {
+	return func(config *Config) {
+		config.output = io.Discard
+		config.exitStatus = true
+	}
+}
+
+// Container is the part of the output of 'docker inspect' that is validated.
+type Container struct {
+	Name         string
+	RestartCount int
+	State        struct {
+		Status string
+		Health *struct {
+			Status string
+		}
+	}
+	Config struct {
+		Labels map[string]string
+	}
+	NetworkSettings struct {
+		Networks map[string]json.RawMessage
+	}
+}
+
+// Service returns the docker-compose service of the container.
+func (c *Container) Service() string {
+	return c.Config.Labels[serviceLabel]
+}
+
+// Project returns the docker-compose project of the container.
+func (c *Container) Project() string {
+	return c.Config.Labels[projectLabel]
+}
+
+type validation struct {
+	Validate   func(ctx context.Context, config *Config) ([]validate.Result, error)
+	WaitMsg    string
+	SuccessMsg string
+	ErrMsg     string
+}
+
+// Validate will call a series of validation functions in a table driven tests style.
+func Validate(ctx context.Context, opts ...Option) error {
+	cfg := &Config{
+		services:   DefaultServices,
+		output:     os.Stdout,
+		exitStatus: false,
+	}
+
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	log.SetOutput(cfg.output)
+
+	containers, err := Containers(ctx, cfg.project)
+	if err != nil {
+		return err
+	}
+	cfg.containers = containers
+
+	validations := []validation{
+		{Services, "validating services", "services validated", "validating services failed"},
+		{Health, "validating container health", "container health validated", "validating container health failed"},
+		{Networks, "validating networks", "networks validated", "validating networks failed"},
+	}
+
+	var totalFailCount int
+
+	for _, v := range validations {
+		log.Printf("%s %s...", validate.HourglassEmoji, v.WaitMsg)
+		results, err := v.Validate(ctx, cfg)
+		if err != nil {
+			return errors.Wrapf(err, v.ErrMsg)
+		}
+
+		var failCount int
+		var warnCount int
+
+		for _, r := range results {
+			switch r.Status {
+			case validate.Failure:
+				log.Printf("  %s failure: %s", validate.FailureEmoji, r.Message)
+				failCount++
+			case validate.Warning:
+				log.Printf("  %s warning: %s", validate.WarningSign, r.Message)
+				warnCount++
+			}
+		}
+
+		if failCount > 0 || warnCount > 0 {
+			log.Printf("\n%s %s", validate.FlashingLightEmoji, v.ErrMsg)
+		}
+
+		if failCount > 0 {
+			log.Printf("  %s %d total failure(s)", validate.EmojiFingerPointRight, failCount)
+
+			totalFailCount = totalFailCount + failCount
+		}
+
+		if warnCount > 0 {
+			log.Printf("  %s %d total warning(s)", validate.EmojiFingerPointRight, warnCount)
+		}
+
+		if failCount == 0 && warnCount == 0 {
+			log.Printf("%s %s!", validate.SuccessEmoji, v.SuccessMsg)
+		}
+	}
+
+	if totalFailCount > 0 {
+		return errors.Newf("validation failed: %d failures", totalFailCount)
+	}
+
+	return nil
+}
+
+// Containers returns the docker-compose containers of the given project,
+// including the ones that aren't running. If project is empty, it's the project
+// of the sourcegraph-frontend-0 container, so that other docker-compose projects
+// on the same host aren't validated.
+func Containers(ctx context.Context, project string) ([]Container, error) {
+	filter := "label=" + projectLabel
+	if project != "" {
+		filter += "=" + project
+	}
+
+	out, err := exec.CommandContext(ctx, "docker", "ps", "--all", "--quiet", "--filter", filter).Output()
+	if err != nil {
+		return nil, errors.Wrap(err, "listing docker containers")
+	}
+	ids := strings.Fields(string(out))
+	if len(ids) == 0 {
+		if project != "" {
+			return nil, errors.Newf("no containers found for docker-compose project '%s'", project)
+		}
+		return nil, errors.New("no docker-compose containers found")
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", append([]string{"inspect"}, ids...)...)
+	cmd.Stderr = &stderr
+	out, err = cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "inspecting docker containers: %s", strings.TrimSpace(stderr.String()))
+	}
+
+	var containers []Container
+	if err := json.Unmarshal(out, &containers); err != nil {
+		return nil, errors.Wrap(err, "parsing docker inspect output")
+	}
+	for i := range containers {
+		containers[i].Name = strings.TrimPrefix(containers[i].Name, "/")
+	}
+	if project == "" {
+		return frontendProjectContainers(containers)
+	}
+	return containers, nil
+}
+
+// frontendProjectContainers returns the containers of the docker-compose project
+// with the sourcegraph-frontend-0 container.
+func frontendProjectContainers(containers []Container) ([]Container, error) {
+	var projects []string
+	for _, c := range containers {
+		if c.Service() == "sourcegraph-frontend-0" {
+			projects = append(projects, c.Project())
+		}
+	}
+	switch len(projects) {
+	case 0:
+		return nil, errors.New("no docker-compose project with a sourcegraph-frontend-0 container found, use --project to select one")
+	case 1:
+	default:
+		sort.Strings(projects)
+		return nil, errors.Newf("several docker-compose projects with a sourcegraph-frontend-0 container found (%s), use --project to select one", strings.Join(projects, ", "))
+	}
+
+	var result []Container
+	for _, c := range containers {
+		if c.Project() == projects[0] {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+// Services will validate that the expected services have a running container.
+func Services(ctx context.Context, config *Config) ([]validate.Result, error) {
+	return validateServices(config.containers, config.services), nil
+}
+
+func validateServices(containers []Container, services []string) []validate.Result {
+	var results []validate.Result
+
+	byService := map[string][]Container{}
+	for _, c := range containers {
+		byService[c.Service()] = append(byService[c.Service()], c)
+	}
+
+	for _, service := range services {
+		cs, ok := byService[service]
+		if !ok {
+			results = append(results, validate.Result{
+				Status:  validate.Failure,
+				Message: fmt.Sprintf("service '%s' has no container", service),
+			})
+			continue
+		}
+
+		for _, c := range cs {
+			if c.State.Status != "running" {
+				results = append(results, validate.Result{
+					Status:  validate.Failure,
+					Message: fmt.Sprintf("container '%s' of service '%s' is %s", c.Name, service, c.State.Status),
+				})
+			}
+		}
+	}
+
+	return results
+}
+
+// Health will validate that the containers with a health check are healthy.
+func Health(ctx context.Context, config *Config) ([]validate.Result, error) {
+	return validateHealth(config.containers), nil
+}
+
+func validateHealth(containers []Container) []validate.Result {
+	var results []validate.Result
+
+	for _, c := range containers {
+		if c.State.Health != nil {
+			switch c.State.Health.Status {
+			case "unhealthy":
+				results = append(results, validate.Result{
+					Status:  validate.Failure,
+					Message: fmt.Sprintf("container '%s' is unhealthy", c.Name),
+				})
+			case "starting":
+				results = append(results, validate.Result{
+					Status:  validate.Warning,
+					Message: fmt.Sprintf("container '%s' is still starting", c.Name),
+				})
+			}
+		}
+
+		if c.RestartCount > 50 {
+			results = append(results, validate.Result{
+				Status:  validate.Warning,
+				Message: fmt.Sprintf("container '%s' has high restart count: %d restarts", c.Name, c.RestartCount),
+			})
+		}
+	}
+
+	return results
+}
+
+// Networks will validate that the containers can reach each other, by
+// checking that they share a network with the frontend.
+func Networks(ctx context.Context, config *Config) ([]validate.Result, error) {
+	return validateNetworks(config.containers), nil
+}
+
+func validateNetworks(containers []Container) []validate.Result {
+	var frontend *Container
+	for i, c := range containers {
+		if c.Service() == "sourcegraph-frontend-0" {
+			frontend = &containers[i]
+			break
+		}
+	}
+	if frontend == nil {
+		return []validate.Result{{
+			Status:  validate.Warning,
+			Message: "no sourcegraph-frontend-0 container to check the networks of other containers against",
+		}}
+	}
+
+	var results []validate.Result
+
+	for _, c := range containers {
+		if c.Name == frontend.Name {
+			continue
+		}
+
+		shared := false
+		for network := range c.NetworkSettings.Networks {
+			if _, ok := frontend.NetworkSettings.Networks[network]; ok {
+				shared = true
+				break
+			}
+		}
+		if !shared {
+			networks := make([]string, 0, len(c.NetworkSettings.Networks))
+			for network := range c.NetworkSettings.Networks {
+				networks = append(networks, network)
+			}
+			sort.Strings(networks)
+			results = append(results, validate.Result{
+				Status:  validate.Failure,
+				Message: fmt.Sprintf("container '%s' does not share a network with '%s' (networks: %s)", c.Name, frontend.Name, strings.Join(networks, ", ")),
+			})
+		}
+	}
+
+	return results
+}
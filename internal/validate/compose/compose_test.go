@@ -0,0 +1,137 @@
+package compose
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/sourcegraph/src-cli/internal/validate"
+)
+
+// testContainer returns a running container of the given service of the
+// sourcegraph project, attached to the given networks.
+func testContainer(service string, networks ...string) Container {
+	c := Container{Name: service}
+	c.State.Status = "running"
+	c.Config.Labels = map[string]string{serviceLabel: service, projectLabel: "sourcegraph"}
+	c.NetworkSettings.Networks = map[string]json.RawMessage{}
+	for _, n := range networks {
+		c.NetworkSettings.Networks[n] = json.RawMessage(`{}`)
+	}
+	return c
+}
+
+func TestValidateServices(t *testing.T) {
+	stopped := testContainer("worker")
+	stopped.State.Status = "exited"
+
+	result := validateServices(
+		[]Container{testContainer("gitserver-0"), stopped},
+		[]string{"gitserver-0", "worker", "repo-updater"},
+	)
+	want := []validate.Result{
+		{Status: validate.Failure, Message: "container 'worker' of service 'worker' is exited"},
+		{Status: validate.Failure, Message: "service 'repo-updater' has no container"},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got %+v, want %+v", result, want)
+	}
+}
+
+func TestValidateHealth(t *testing.T) {
+	setHealth := func(c Container, status string) Container {
+		c.State.Health = &struct{ Status string }{Status: status}
+		return c
+	}
+	restarting := testContainer("searcher-0")
+	restarting.RestartCount = 51
+
+	result := validateHealth([]Container{
+		setHealth(testContainer("gitserver-0"), "healthy"),
+		setHealth(testContainer("worker"), "unhealthy"),
+		setHealth(testContainer("pgsql"), "starting"),
+		restarting,
+		testContainer("redis-cache"),
+	})
+	want := []validate.Result{
+		{Status: validate.Failure, Message: "container 'worker' is unhealthy"},
+		{Status: validate.Warning, Message: "container 'pgsql' is still starting"},
+		{Status: validate.Warning, Message: "container 'searcher-0' has high restart count: 51 restarts"},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got %+v, want %+v", result, want)
+	}
+}
+
+func TestValidateNetworks(t *testing.T) {
+	t.Run("shared networks", func(t *testing.T) {
+		result := validateNetworks([]Container{
+			testContainer("sourcegraph-frontend-0", "sourcegraph"),
+			testContainer("gitserver-0", "sourcegraph"),
+			testContainer("worker", "other", "sourcegraph"),
+			testContainer("pgsql", "b", "a"),
+		})
+		want := []validate.Result{
+			{Status: validate.Failure, Message: "container 'pgsql' does not share a network with 'sourcegraph-frontend-0' (networks: a, b)"},
+		}
+		if !reflect.DeepEqual(result, want) {
+			t.Errorf("got %+v, want %+v", result, want)
+		}
+	})
+
+	t.Run("no frontend", func(t *testing.T) {
+		result := validateNetworks([]Container{testContainer("gitserver-0", "sourcegraph")})
+		if len(result) != 1 || result[0].Status != validate.Warning {
+			t.Errorf("expected a warning, got %+v", result)
+		}
+	})
+}
+
+func TestFrontendProjectContainers(t *testing.T) {
+	// foreign returns a container of a docker-compose project on the same host
+	// that isn't Sourcegraph.
+	foreign := func(service string) Container {
+		c := testContainer(service, "other")
+		c.Name = "other-" + service
+		c.Config.Labels[projectLabel] = "other"
+		return c
+	}
+
+	t.Run("foreign project", func(t *testing.T) {
+		frontend := testContainer("sourcegraph-frontend-0", "sourcegraph")
+		gitserver := testContainer("gitserver-0", "sourcegraph")
+
+		result, err := frontendProjectContainers([]Container{
+			frontend,
+			foreign("postgres"),
+			gitserver,
+			foreign("web"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []Container{frontend, gitserver}
+		if !reflect.DeepEqual(result, want) {
+			t.Errorf("got %+v, want %+v", result, want)
+		}
+		if results := validateNetworks(result); len(results) != 0 {
+			t.Errorf("foreign containers were validated: %+v", results)
+		}
+	})
+
+	t.Run("no frontend", func(t *testing.T) {
+		if _, err := frontendProjectContainers([]Container{foreign("web")}); err == nil {
+			t.Error("no error without a sourcegraph-frontend-0 container")
+		}
+	})
+
+	t.Run("several frontends", func(t *testing.T) {
+		_, err := frontendProjectContainers([]Container{
+			testContainer("sourcegraph-frontend-0"),
+			foreign("sourcegraph-frontend-0"),
+		})
+		if err == nil {
+			t.Error("no error with several sourcegraph-frontend-0 containers")
+		}
+	})
+}
@@ -2,16 +2,12 @@ package kube
 
 import (
 	"context"
-	"fmt"
 	"log"
-	"path/filepath"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
 
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
@@ -121,7 +117,17 @@ func EksEbsCsiDrivers(ctx context.Context, config *Config) ([]validate.Result, e
 	var results []validate.Result
 	var ebsTestParams EbsTestObjects
 
-	addons, err := getAddons(ctx, config.eksClient)
+	clusterName, err := getClusterName(ctx, config)
+	if err != nil {
+		results = append(results, validate.Result{
+			Status:  validate.Failure,
+			Message: "EKS: could not determine the cluster name",
+		})
+
+		return results, err
+	}
+
+	addons, err := getAddons(ctx, config.eksClient, clusterName)
 	if err != nil {
 		results = append(results, validate.Result{
 			Status:  validate.Failure,
@@ -252,9 +258,8 @@ func validateRolePolicy(rolePolicy RolePolicy) (result []validate.Result) {
 	return result
 }
 
-func getAddons(ctx context.Context, client *eks.Client) ([]string, error) {
-	clusterName := getClusterName(ctx, client)
-	inputs := &eks.ListAddonsInput{ClusterName: clusterName}
+func getAddons(ctx context.Context, client *eks.Client, clusterName string) ([]string, error) {
+	inputs := &eks.ListAddonsInput{ClusterName: &clusterName}
 	outputs, err := client.ListAddons(ctx, inputs)
 
 	if err != nil {
@@ -327,23 +332,76 @@ func getEBSCSIPolicy(ctx context.Context, client *iam.Client, sa string) (RolePo
 	return RolePolicy{}, nil
 }
 
-func getClusterName(ctx context.Context, client *eks.Client) *string {
-	home := homedir.HomeDir()
-	pathToKubeConfig := filepath.Join(home, ".kube", "config")
+// WithClusterName sets the name of the EKS cluster to validate, instead of
+// discovering it from the current kube context.
+func WithClusterName(name string) Option {
+	return func(config *Config) {
+		config.clusterName = name
+	}
+}
 
-	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		&clientcmd.ClientConfigLoadingRules{ExplicitPath: pathToKubeConfig},
-		&clientcmd.ConfigOverrides{
-			CurrentContext: "",
-		}).RawConfig()
+// getClusterName returns the name of the EKS cluster to validate. Unless it is
+// set with WithClusterName, it is taken from the ARN of the current kube context
+// or its cluster, or else found by matching the API server of the context
+// against the endpoints of the EKS clusters of the account.
+func getClusterName(ctx context.Context, config *Config) (string, error) {
+	if config.clusterName != "" {
+		return config.clusterName, nil
+	}
 
+	rawConfig, err := ClientConfig(config.kubeConfig, config.kubeContext).RawConfig()
 	if err != nil {
-		fmt.Printf("error while checking current context: %s\n", err)
-		return nil
+		return "", errors.Wrap(err, "checking current context")
+	}
+	currentContext := rawConfig.CurrentContext
+	if config.kubeContext != "" {
+		currentContext = config.kubeContext
+	}
+
+	if name := eksClusterNameFromARN(currentContext); name != "" {
+		return name, nil
+	}
+	kubeContext, ok := rawConfig.Contexts[currentContext]
+	if !ok {
+		return "", errors.Newf("context %q not found in kubeconfig", currentContext)
+	}
+	if name := eksClusterNameFromARN(kubeContext.Cluster); name != "" {
+		return name, nil
+	}
+
+	cluster, ok := rawConfig.Clusters[kubeContext.Cluster]
+	if !ok || cluster.Server == "" {
+		return "", errors.Newf("could not determine the EKS cluster of context %q, use --cluster-name", currentContext)
+	}
+	paginator := eks.NewListClustersPaginator(config.eksClient, &eks.ListClustersInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", errors.Wrap(err, "listing EKS clusters")
+		}
+		for _, name := range page.Clusters {
+			name := name
+			out, err := config.eksClient.DescribeCluster(ctx, &eks.DescribeClusterInput{Name: &name})
+			if err != nil {
+				return "", errors.Wrapf(err, "describing EKS cluster %s", name)
+			}
+			if out.Cluster != nil && out.Cluster.Endpoint != nil && *out.Cluster.Endpoint == cluster.Server {
+				return name, nil
+			}
+		}
 	}
 
-	currentContext := strings.Split(config.CurrentContext, "/")
-	clusterName := currentContext[len(currentContext)-1]
+	return "", errors.Newf("no EKS cluster has the API server %s of context %q, use --cluster-name", cluster.Server, currentContext)
+}
 
-	return &clusterName
+// eksClusterNameFromARN returns the cluster name of an EKS cluster ARN, such
+// as arn:aws:eks:us-east-1:123456789012:cluster/sourcegraph, which is what
+// 'aws eks update-kubeconfig' names contexts and clusters. It returns "" if arn
+// isn't an EKS cluster ARN.
+func eksClusterNameFromARN(arn string) string {
+	parts := strings.SplitN(arn, ":", 6)
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "eks" || !strings.HasPrefix(parts[5], "cluster/") {
+		return ""
+	}
+	return strings.TrimPrefix(parts[5], "cluster/")
 }
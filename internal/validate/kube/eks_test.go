@@ -333,3 +333,17 @@ func testEBSCSIRole() *iam.ListAttachedRolePoliciesOutput {
 		AttachedPolicies: []iamTypes.AttachedPolicy{},
 	}
 }
+
+func TestEksClusterNameFromARN(t *testing.T) {
+	for arn, want := range map[string]string{
+		"arn:aws:eks:us-east-1:123456789012:cluster/sourcegraph":      "sourcegraph",
+		"arn:aws-cn:eks:cn-north-1:123456789012:cluster/sg-cluster-1": "sg-cluster-1",
+		"arn:aws:iam::123456789012:role/sourcegraph":                  "",
+		"gke_project_us-central1_sourcegraph":                         "",
+		"sourcegraph":                                                 "",
+	} {
+		if got := eksClusterNameFromARN(arn); got != want {
+			t.Errorf("eksClusterNameFromARN(%q) = %q, want %q", arn, got, want)
+		}
+	}
+}
@@ -2,12 +2,15 @@ package kube
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
@@ -40,6 +43,20 @@ type Config struct {
 	eksClient  *eks.Client
 	ec2Client  *ec2.Client
 	iamClient  *iam.Client
+	// clusterName is the name of the EKS cluster, discovered from the
+	// current context if empty.
+	clusterName string
+	checks      []string
+	skipChecks  []string
+	// kubeConfig and kubeContext are the kubeconfig file and context the
+	// clientset was built from. Empty values mean the defaults.
+	kubeConfig  string
+	kubeContext string
+	// diskUsageThreshold is the fraction of a PVC's capacity above which its
+	// usage is reported as a warning.
+	diskUsageThreshold float64
+	// jsonOutput receives the report of all results as JSON, if set.
+	jsonOutput io.Writer
 }
 
 func WithNamespace(namespace string) Option {
@@ -55,13 +72,183 @@ func Quiet() Option {
 	}
 }
 
+// WithKubeConfig sets the kubeconfig file and context the clientset was built
+// from, which are used to check the cluster provider. An empty path means
+// ~/.kube/config, and an empty context means the current context of the file.
+func WithKubeConfig(path, context string) Option {
+	return func(config *Config) {
+		config.kubeConfig = path
+		config.kubeContext = context
+	}
+}
+
+// ClientConfig loads the kubeconfig file at path, or ~/.kube/config if path is
+// empty, using context instead of the current context if it isn't empty.
+func ClientConfig(path, context string) clientcmd.ClientConfig {
+	if path == "" {
+		path = filepath.Join(homedir.HomeDir(), ".kube", "config")
+	}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: path},
+		&clientcmd.ConfigOverrides{
+			CurrentContext: context,
+		})
+}
+
+// JSONOutput writes a Report of the results of all checks to w as JSON, once
+// validation completes, instead of logging them.
+func JSONOutput(w io.Writer) Option {
+	return func(config *Config) {
+		config.output = io.Discard
+		config.jsonOutput = w
+	}
+}
+
+// WithDiskUsageThreshold sets the fraction of a PVC's capacity, between 0 and
+// 1, above which its usage is reported as a warning. The default is 0.8.
+func WithDiskUsageThreshold(threshold float64) Option {
+	return func(config *Config) {
+		config.diskUsageThreshold = threshold
+	}
+}
+
+// WithChecks limits validation to the checks with the given names.
+func WithChecks(names ...string) Option {
+	return func(config *Config) {
+		config.checks = names
+	}
+}
+
+// SkipChecks excludes the checks with the given names from validation.
+func SkipChecks(names ...string) Option {
+	return func(config *Config) {
+		config.skipChecks = names
+	}
+}
+
 type validation struct {
+	Name string
+	// Provider is the cloud provider the check is specific to, if any. Such
+	// checks only run when validation for the provider is enabled.
+	Provider   string
 	Validate   func(ctx context.Context, config *Config) ([]validate.Result, error)
 	WaitMsg    string
 	SuccessMsg string
 	ErrMsg     string
 }
 
+// validations is the table of checks run by Validate, in order.
+var validations = []validation{
+	{"pods", "", Pods, "validating pods", "pods validated", "validating pods failed"},
+	{"config-refs", "", ConfigRefs, "validating secret and configmap references", "secret and configmap references validated", "validating secret and configmap references failed"},
+	{"services", "", Services, "validating services", "services validated", "validating services failed"},
+	{"pvcs", "", PVCs, "validating pvcs", "pvcs validated", "validating pvcs failed"},
+	{"pvc-usage", "", PVCUsage, "validating pvc usage", "pvc usage validated", "validating pvc usage failed"},
+	{"versions", "", Versions, "validating versions", "versions validated", "validating versions failed"},
+	{"eks-ebs-csi-drivers", "eks", EksEbsCsiDrivers, "EKS: validating ebs-csi drivers", "EKS: ebs-csi drivers validated", "EKS: validating ebs-csi drivers failed"},
+	{"eks-vpc", "eks", EksVpc, "EKS: validating vpc", "EKS: vpc validated", "EKS: validating vpc failed"},
+	{"gke-persistent-volumes", "gke", GkeGcePersistentDiskCSIDrivers, "GKE: validating persistent volumes", "GKE: persistent volumes validated", "GKE: validating peristent volumes failed"},
+	{"aks-persistent-volumes", "aks", AksCsiDrivers, "AKS: validating persistent volumes", "AKS: persistent volumes validated", "AKS: validating persistent volumes failed"},
+}
+
+// CheckNames returns the names of the checks that can be passed to WithChecks
+// and SkipChecks.
+func CheckNames() []string {
+	names := make([]string, 0, len(validations))
+	for _, v := range validations {
+		names = append(names, v.Name)
+	}
+	return names
+}
+
+// selectValidations returns the validations to run for config, in table order.
+func selectValidations(config *Config) ([]validation, error) {
+	known := map[string]validation{}
+	for _, v := range validations {
+		known[v.Name] = v
+	}
+	providers := map[string]bool{"": true, "eks": config.eks, "gke": config.gke, "aks": config.aks}
+
+	selected := map[string]bool{}
+	for _, name := range config.checks {
+		v, ok := known[name]
+		if !ok {
+			return nil, errors.Newf("unknown check %q, valid checks are: %s", name, strings.Join(CheckNames(), ", "))
+		}
+		if !providers[v.Provider] {
+			return nil, errors.Newf("check %q requires --%s", name, v.Provider)
+		}
+		selected[name] = true
+	}
+	skipped := map[string]bool{}
+	for _, name := range config.skipChecks {
+		if _, ok := known[name]; !ok {
+			return nil, errors.Newf("unknown check %q, valid checks are: %s", name, strings.Join(CheckNames(), ", "))
+		}
+		skipped[name] = true
+	}
+
+	var result []validation
+	for _, v := range validations {
+		if !providers[v.Provider] || skipped[v.Name] {
+			continue
+		}
+		if len(selected) > 0 && !selected[v.Name] {
+			continue
+		}
+		result = append(result, v)
+	}
+	if len(result) == 0 {
+		return nil, errors.New("no checks selected")
+	}
+	return result, nil
+}
+
+// Report is the result of Validate, as written by JSONOutput.
+type Report struct {
+	Checks   []CheckReport `json:"checks"`
+	Failures int           `json:"failures"`
+	Warnings int           `json:"warnings"`
+}
+
+// CheckReport is the result of one check. Its status is the most severe status
+// of its results.
+type CheckReport struct {
+	Name    string          `json:"name"`
+	Status  validate.Status `json:"status"`
+	Results []ResultReport  `json:"results"`
+}
+
+type ResultReport struct {
+	Status  validate.Status `json:"status"`
+	Message string          `json:"message"`
+}
+
+// add adds the results of the check with the given name to the report.
+func (r *Report) add(name string, results []validate.Result) {
+	check := CheckReport{Name: name, Status: validate.Success, Results: []ResultReport{}}
+	for _, result := range results {
+		check.Results = append(check.Results, ResultReport{Status: result.Status, Message: result.Message})
+		switch result.Status {
+		case validate.Failure:
+			check.Status = validate.Failure
+			r.Failures++
+		case validate.Warning:
+			if check.Status != validate.Failure {
+				check.Status = validate.Warning
+			}
+			r.Warnings++
+		}
+	}
+	r.Checks = append(r.Checks, check)
+}
+
+func (r *Report) write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return errors.Wrap(enc.Encode(r), "writing report")
+}
+
 // Validate will call a series of validation functions in a table driven tests style.
 func Validate(ctx context.Context, clientSet *kubernetes.Clientset, restConfig *rest.Config, opts ...Option) error {
 	cfg := &Config{
@@ -73,6 +260,8 @@ func Validate(ctx context.Context, clientSet *kubernetes.Clientset, restConfig *
 		eks:        false,
 		gke:        false,
 		aks:        false,
+
+		diskUsageThreshold: 0.8,
 	}
 
 	for _, opt := range opts {
@@ -81,72 +270,66 @@ func Validate(ctx context.Context, clientSet *kubernetes.Clientset, restConfig *
 
 	log.SetOutput(cfg.output)
 
-	validations := []validation{
-		{Pods, "validating pods", "pods validated", "validating pods failed"},
-		{Services, "validating services", "services validated", "validating services failed"},
-		{PVCs, "validating pvcs", "pvcs validated", "validating pvcs failed"},
+	validations, err := selectValidations(cfg)
+	if err != nil {
+		return err
 	}
 
 	if cfg.eks {
-		if err := CurrentContextSetTo("eks"); err != nil {
+		if err := CurrentContextSetTo(cfg, "eks"); err != nil {
 			return errors.Newf("%s %s", validate.FailureEmoji, err)
 		}
 
 		GenerateAWSClients(ctx)
-
-		validations = append(validations, validation{
-			Validate:   EksEbsCsiDrivers,
-			WaitMsg:    "EKS: validating ebs-csi drivers",
-			SuccessMsg: "EKS: ebs-csi drivers validated",
-			ErrMsg:     "EKS: validating ebs-csi drivers failed",
-		})
-
-		validations = append(validations, validation{
-			Validate:   EksVpc,
-			WaitMsg:    "EKS: validating vpc",
-			SuccessMsg: "EKS: vpc validated",
-			ErrMsg:     "EKS: validating vpc failed",
-		})
 	}
 
 	if cfg.gke {
-		if err := CurrentContextSetTo("gke"); err != nil {
+		if err := CurrentContextSetTo(cfg, "gke"); err != nil {
 			return errors.Newf("%s %s", validate.FailureEmoji, err)
 		}
 
 		Gke()
-
-		validations = append(validations, validation{
-			Validate:   GkeGcePersistentDiskCSIDrivers,
-			WaitMsg:    "GKE: validating persistent volumes",
-			SuccessMsg: "GKE: persistent volumes validated",
-			ErrMsg:     "GKE: validating peristent volumes failed",
-		})
 	}
 
 	if cfg.aks {
-		if err := CurrentContextSetTo("aks"); err != nil {
+		if err := CurrentContextSetTo(cfg, "aks"); err != nil {
 			return errors.Newf("%s %s", validate.FailureEmoji, err)
 		}
 
 		Aks()
+	}
 
-		validations = append(validations, validation{
-			Validate:   AksCsiDrivers,
-			WaitMsg:    "AKS: validating persistent volumes",
-			SuccessMsg: "AKS: persistent volumes validated",
-			ErrMsg:     "AKS: validating persistent volumes failed",
-		})
+	report := runValidations(ctx, cfg, validations)
+
+	if cfg.jsonOutput != nil {
+		if err := report.write(cfg.jsonOutput); err != nil {
+			return err
+		}
 	}
 
-	var totalFailCount int
+	if report.Failures > 0 {
+		return errors.Newf("validation failed: %d failures", report.Failures)
+	}
+
+	return nil
+}
+
+// runValidations runs the given validations and logs their results. A
+// validation that returns an error is reported as failed, so that the checks
+// after it still run and the report is complete.
+func runValidations(ctx context.Context, cfg *Config, validations []validation) Report {
+	report := Report{Checks: []CheckReport{}}
 
 	for _, v := range validations {
 		log.Printf("%s %s...", validate.HourglassEmoji, v.WaitMsg)
 		results, err := v.Validate(ctx, cfg)
 		if err != nil {
-			return errors.Wrapf(err, v.ErrMsg)
+			results = append(results, validate.Result{
+				Status:  validate.Failure,
+				Message: fmt.Sprintf("%s: %s", v.ErrMsg, err),
+			})
 		}
+		report.add(v.Name, results)
 
 		var failCount int
 		var warnCount int
@@ -160,6 +343,8 @@ func Validate(ctx context.Context, clientSet *kubernetes.Clientset, restConfig *
 			case validate.Warning:
 				log.Printf("  %s warning: %s", validate.WarningSign, r.Message)
 				warnCount++
+			case validate.Info:
+				log.Printf("  %s %s", validate.InfoEmoji, r.Message)
 			case validate.Success:
 				succCount++
 			}
@@ -171,8 +356,6 @@ func Validate(ctx context.Context, clientSet *kubernetes.Clientset, restConfig *
 
 		if failCount > 0 {
 			log.Printf("  %s %d total failure(s)", validate.EmojiFingerPointRight, failCount)
-
-			totalFailCount = totalFailCount + failCount
 		}
 
 		if warnCount > 0 {
@@ -184,11 +367,7 @@ func Validate(ctx context.Context, clientSet *kubernetes.Clientset, restConfig *
 		}
 	}
 
-	if totalFailCount > 0 {
-		return errors.Newf("validation failed: %d failures", totalFailCount)
-	}
-
-	return nil
+	return report
 }
 
 // Pods will validate all pods in a given namespace.
@@ -283,6 +462,186 @@ func validatePod(pod *corev1.Pod) []validate.Result {
 	return results
 }
 
+// ConfigRefs will validate that the Secrets and ConfigMaps referenced by the
+// pods in a given namespace exist. Pods referencing missing ones crash-loop
+// or never start, with errors that don't name the missing object.
+func ConfigRefs(ctx context.Context, config *Config) ([]validate.Result, error) {
+	pods, err := config.clientSet.CoreV1().Pods(config.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := config.clientSet.CoreV1().Secrets(config.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	configMaps, err := config.clientSet.CoreV1().ConfigMaps(config.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	existing := map[configRef]bool{}
+	for _, secret := range secrets.Items {
+		existing[configRef{kind: "secret", name: secret.Name}] = true
+	}
+	for _, configMap := range configMaps.Items {
+		existing[configRef{kind: "configmap", name: configMap.Name}] = true
+	}
+
+	return validateConfigRefs(pods.Items, existing), nil
+}
+
+// configRef is a reference to a Secret or ConfigMap; kind is "secret" or
+// "configmap".
+type configRef struct {
+	kind string
+	name string
+}
+
+func validateConfigRefs(pods []corev1.Pod, existing map[configRef]bool) []validate.Result {
+	var results []validate.Result
+	for _, pod := range pods {
+		for _, ref := range podConfigRefs(&pod.Spec) {
+			if existing[ref] {
+				continue
+			}
+			results = append(results, validate.Result{
+				Status:  validate.Failure,
+				Message: fmt.Sprintf("%s '%s' referenced by pod '%s' does not exist", ref.kind, ref.name, pod.Name),
+			})
+		}
+	}
+	return results
+}
+
+// podConfigRefs returns the Secrets and ConfigMaps the pod needs to start, in
+// the order they are first referenced by its volumes and the environment of
+// its containers. References marked optional are left out.
+func podConfigRefs(spec *corev1.PodSpec) []configRef {
+	var refs []configRef
+	seen := map[configRef]bool{}
+	add := func(kind, name string, optional *bool) {
+		ref := configRef{kind: kind, name: name}
+		if name == "" || (optional != nil && *optional) || seen[ref] {
+			return
+		}
+		seen[ref] = true
+		refs = append(refs, ref)
+	}
+
+	for _, v := range spec.Volumes {
+		if v.Secret != nil {
+			add("secret", v.Secret.SecretName, v.Secret.Optional)
+		}
+		if v.ConfigMap != nil {
+			add("configmap", v.ConfigMap.Name, v.ConfigMap.Optional)
+		}
+		if v.Projected != nil {
+			for _, source := range v.Projected.Sources {
+				if source.Secret != nil {
+					add("secret", source.Secret.Name, source.Secret.Optional)
+				}
+				if source.ConfigMap != nil {
+					add("configmap", source.ConfigMap.Name, source.ConfigMap.Optional)
+				}
+			}
+		}
+	}
+
+	containers := append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, c := range containers {
+		for _, envFrom := range c.EnvFrom {
+			if envFrom.SecretRef != nil {
+				add("secret", envFrom.SecretRef.Name, envFrom.SecretRef.Optional)
+			}
+			if envFrom.ConfigMapRef != nil {
+				add("configmap", envFrom.ConfigMapRef.Name, envFrom.ConfigMapRef.Optional)
+			}
+		}
+		for _, env := range c.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if ref := env.ValueFrom.SecretKeyRef; ref != nil {
+				add("secret", ref.Name, ref.Optional)
+			}
+			if ref := env.ValueFrom.ConfigMapKeyRef; ref != nil {
+				add("configmap", ref.Name, ref.Optional)
+			}
+		}
+	}
+
+	return refs
+}
+
+// sourcegraphContainerRegexp matches the names of the containers of the
+// Sourcegraph services that must run the same version.
+var sourcegraphContainerRegexp = regexp.MustCompile(`^(sourcegraph-frontend|frontend|worker|repo-updater|gitserver)$`)
+
+// Versions will validate that the Sourcegraph services in a given namespace run
+// the same version.
+func Versions(ctx context.Context, config *Config) ([]validate.Result, error) {
+	pods, err := config.clientSet.CoreV1().Pods(config.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return validateVersions(pods.Items), nil
+}
+
+func validateVersions(pods []corev1.Pod) []validate.Result {
+	// containersByTag maps image tags to the containers running them, as
+	// pod/container.
+	containersByTag := map[string][]string{}
+	var tags []string
+
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			if !sourcegraphContainerRegexp.MatchString(container.Name) {
+				continue
+			}
+			tag := imageTag(container.Image)
+			if _, ok := containersByTag[tag]; !ok {
+				tags = append(tags, tag)
+			}
+			containersByTag[tag] = append(containersByTag[tag], pod.Name+"/"+container.Name)
+		}
+	}
+
+	if len(tags) == 0 {
+		return []validate.Result{{
+			Status:  validate.Warning,
+			Message: "no frontend, worker, repo-updater or gitserver containers found to compare versions of",
+		}}
+	}
+
+	if len(tags) == 1 {
+		return []validate.Result{{
+			Status:  validate.Success,
+			Message: fmt.Sprintf("Sourcegraph services run version '%s'", tags[0]),
+		}}
+	}
+
+	sort.Strings(tags)
+	versions := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		versions = append(versions, fmt.Sprintf("'%s' (%s)", tag, strings.Join(containersByTag[tag], ", ")))
+	}
+	return []validate.Result{{
+		Status:  validate.Failure,
+		Message: fmt.Sprintf("Sourcegraph services run different versions: %s", strings.Join(versions, "; ")),
+	}}
+}
+
+// imageTag returns the tag of a container image reference, without the digest.
+// Images without a tag are reported as "latest", which is what they run.
+func imageTag(image string) string {
+	image, _, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		return image[i+1:]
+	}
+	return "latest"
+}
+
 // Services will validate all  services in a given namespace.
 func Services(ctx context.Context, config *Config) ([]validate.Result, error) {
 	services, err := config.clientSet.CoreV1().Services(config.namespace).List(ctx, metav1.ListOptions{})
@@ -373,8 +732,136 @@ func validatePVC(pvc *corev1.PersistentVolumeClaim) []validate.Result {
 	return results
 }
 
-func CurrentContextSetTo(clusterService string) error {
-	currentContext, err := GetCurrentContext()
+// diskFullThreshold is the fraction of a PVC's capacity above which it is
+// considered full.
+const diskFullThreshold = 0.95
+
+// PVCUsage will validate that the persistent volume claims in a given namespace
+// have space left. Their usage is read from the kubelet stats of the nodes that
+// run the pods mounting them.
+func PVCUsage(ctx context.Context, config *Config) ([]validate.Result, error) {
+	pods, err := config.clientSet.CoreV1().Pods(config.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := map[string]bool{}
+	for _, pod := range pods.Items {
+		for _, v := range pod.Spec.Volumes {
+			if v.PersistentVolumeClaim != nil && pod.Spec.NodeName != "" {
+				nodes[pod.Spec.NodeName] = true
+			}
+		}
+	}
+	nodeNames := make([]string, 0, len(nodes))
+	for node := range nodes {
+		nodeNames = append(nodeNames, node)
+	}
+	sort.Strings(nodeNames)
+
+	var results []validate.Result
+
+	usage := map[string]volumeStats{}
+	for _, node := range nodeNames {
+		summary, err := nodeStatsSummary(ctx, config, node)
+		if err != nil {
+			// Reading the stats requires access to the nodes/proxy
+			// resource, which users validating a namespace often lack.
+			results = append(results, validate.Result{
+				Status:  validate.Info,
+				Message: fmt.Sprintf("usage of pvcs on node '%s' is not accessible: %s", node, err),
+			})
+			continue
+		}
+		for _, pod := range summary.Pods {
+			for _, v := range pod.Volumes {
+				if v.PVCRef != nil && v.PVCRef.Namespace == config.namespace {
+					usage[v.PVCRef.Name] = v.volumeStats
+				}
+			}
+		}
+	}
+
+	for name, stats := range usage {
+		results = append(results, validatePVCUsage(name, stats, config.diskUsageThreshold)...)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Message < results[j].Message
+	})
+
+	return results, nil
+}
+
+type volumeStats struct {
+	UsedBytes     uint64 `json:"usedBytes"`
+	CapacityBytes uint64 `json:"capacityBytes"`
+}
+
+// statsSummary is the part of the kubelet stats summary of a node that contains
+// the usage of the volumes of its pods.
+type statsSummary struct {
+	Pods []struct {
+		Volumes []struct {
+			volumeStats
+			PVCRef *struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"pvcRef"`
+		} `json:"volume"`
+	} `json:"pods"`
+}
+
+func nodeStatsSummary(ctx context.Context, config *Config, node string) (*statsSummary, error) {
+	data, err := config.clientSet.CoreV1().RESTClient().Get().
+		Resource("nodes").
+		Name(node).
+		SubResource("proxy").
+		Suffix("stats/summary").
+		DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var summary statsSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, errors.Wrap(err, "parsing stats summary")
+	}
+	return &summary, nil
+}
+
+func validatePVCUsage(name string, stats volumeStats, threshold float64) []validate.Result {
+	if stats.CapacityBytes == 0 {
+		return nil
+	}
+
+	used := float64(stats.UsedBytes) / float64(stats.CapacityBytes)
+	message := fmt.Sprintf("pvc '%s' is %.0f%% full (%s of %s used)", name, used*100, formatBytes(stats.UsedBytes), formatBytes(stats.CapacityBytes))
+	switch {
+	case used >= diskFullThreshold:
+		return []validate.Result{{Status: validate.Failure, Message: message}}
+	case used >= threshold:
+		return []validate.Result{{Status: validate.Warning, Message: message}}
+	}
+	return nil
+}
+
+// formatBytes formats n with the largest binary unit that keeps it above 1.
+func formatBytes(n uint64) string {
+	units := []string{"B", "Ki", "Mi", "Gi", "Ti"}
+	value := float64(n)
+	i := 0
+	for value >= 1024 && i < len(units)-1 {
+		value /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%dB", n)
+	}
+	return fmt.Sprintf("%.1f%s", value, units[i])
+}
+
+func CurrentContextSetTo(config *Config, clusterService string) error {
+	currentContext, err := GetCurrentContext(config)
 	if err != nil {
 		return err
 	}
@@ -412,19 +899,18 @@ func CurrentContextSetTo(clusterService string) error {
 	return nil
 }
 
-func GetCurrentContext() (string, error) {
-	home := homedir.HomeDir()
-	pathToKubeConfig := filepath.Join(home, ".kube", "config")
-
-	config, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
-		&clientcmd.ClientConfigLoadingRules{ExplicitPath: pathToKubeConfig},
-		&clientcmd.ConfigOverrides{
-			CurrentContext: "",
-		}).RawConfig()
-
+// GetCurrentContext returns the name of the kube context being validated.
+func GetCurrentContext(config *Config) (string, error) {
+	rawConfig, err := ClientConfig(config.kubeConfig, config.kubeContext).RawConfig()
 	if err != nil {
 		return "", err
 	}
 
-	return config.CurrentContext, nil
+	if config.kubeContext != "" {
+		if _, ok := rawConfig.Contexts[config.kubeContext]; !ok {
+			return "", errors.Newf("context %q not found in kubeconfig", config.kubeContext)
+		}
+		return config.kubeContext, nil
+	}
+	return rawConfig.CurrentContext, nil
 }
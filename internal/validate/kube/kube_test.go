@@ -1,11 +1,16 @@
 package kube
 
 import (
+	"context"
+	"reflect"
+	"strings"
 	"testing"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
+	"github.com/sourcegraph/sourcegraph/lib/errors"
+
 	"github.com/sourcegraph/src-cli/internal/validate"
 )
 
@@ -393,3 +398,347 @@ func testPVC() *corev1.PersistentVolumeClaim {
 		},
 	}
 }
+
+func TestSelectValidations(t *testing.T) {
+	cases := []struct {
+		name    string
+		config  Config
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "all checks",
+			want: []string{"pods", "config-refs", "services", "pvcs", "pvc-usage", "versions"},
+		},
+		{
+			name:   "all checks with provider",
+			config: Config{eks: true},
+			want:   []string{"pods", "config-refs", "services", "pvcs", "pvc-usage", "versions", "eks-ebs-csi-drivers", "eks-vpc"},
+		},
+		{
+			name:   "selected checks in table order",
+			config: Config{checks: []string{"pvcs", "pods"}},
+			want:   []string{"pods", "pvcs"},
+		},
+		{
+			name:   "skipped checks",
+			config: Config{skipChecks: []string{"config-refs", "services", "pvc-usage", "versions"}},
+			want:   []string{"pods", "pvcs"},
+		},
+		{
+			name:    "unknown check",
+			config:  Config{checks: []string{"connections"}},
+			wantErr: `unknown check "connections", valid checks are: pods, config-refs, services, pvcs, pvc-usage, versions, eks-ebs-csi-drivers`,
+		},
+		{
+			name:    "provider check without provider",
+			config:  Config{checks: []string{"gke-persistent-volumes"}},
+			wantErr: `check "gke-persistent-volumes" requires --gke`,
+		},
+		{
+			name:    "nothing selected",
+			config:  Config{checks: []string{"pods"}, skipChecks: []string{"pods"}},
+			wantErr: "no checks selected",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := selectValidations(&tc.config)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, v := range result {
+				names = append(names, v.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tc.want, ",") {
+				t.Errorf("got checks %v, want %v", names, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidatePVCUsage(t *testing.T) {
+	const gi = 1024 * 1024 * 1024
+
+	cases := []struct {
+		name   string
+		stats  volumeStats
+		result []validate.Result
+	}{
+		{
+			name:  "below threshold",
+			stats: volumeStats{UsedBytes: 50 * gi, CapacityBytes: 100 * gi},
+		},
+		{
+			name:  "above threshold",
+			stats: volumeStats{UsedBytes: 85 * gi, CapacityBytes: 100 * gi},
+			result: []validate.Result{
+				{Status: validate.Warning, Message: "pvc 'gitserver-0' is 85% full (85.0Gi of 100.0Gi used)"},
+			},
+		},
+		{
+			name:  "full",
+			stats: volumeStats{UsedBytes: 99 * gi, CapacityBytes: 100 * gi},
+			result: []validate.Result{
+				{Status: validate.Failure, Message: "pvc 'gitserver-0' is 99% full (99.0Gi of 100.0Gi used)"},
+			},
+		},
+		{
+			name:  "unknown capacity",
+			stats: volumeStats{UsedBytes: 99 * gi},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			result := validatePVCUsage("gitserver-0", tc.stats, 0.8)
+			if !reflect.DeepEqual(result, tc.result) {
+				t.Errorf("got %v, want %v", result, tc.result)
+			}
+		})
+	}
+}
+
+func TestValidateVersions(t *testing.T) {
+	pod := func(name string, containers ...corev1.Container) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec:       corev1.PodSpec{Containers: containers},
+		}
+	}
+
+	cases := []struct {
+		name   string
+		pods   []corev1.Pod
+		result []validate.Result
+	}{
+		{
+			name: "same versions",
+			pods: []corev1.Pod{
+				pod("frontend-1", corev1.Container{Name: "frontend", Image: "index.docker.io/sourcegraph/frontend:5.3.0@sha256:abc"}),
+				pod("gitserver-0", corev1.Container{Name: "gitserver", Image: "sourcegraph/gitserver:5.3.0"}),
+				pod("pgsql-0", corev1.Container{Name: "pgsql", Image: "sourcegraph/postgres-12-alpine:5.2.0"}),
+			},
+			result: []validate.Result{
+				{Status: validate.Success, Message: "Sourcegraph services run version '5.3.0'"},
+			},
+		},
+		{
+			name: "different versions",
+			pods: []corev1.Pod{
+				pod("frontend-1", corev1.Container{Name: "frontend", Image: "sourcegraph/frontend:5.3.0"}),
+				pod("worker-1", corev1.Container{Name: "worker", Image: "sourcegraph/worker:5.2.0"}, corev1.Container{Name: "jaeger-agent", Image: "sourcegraph/jaeger-agent:5.1.0"}),
+				pod("gitserver-0", corev1.Container{Name: "gitserver", Image: "sourcegraph/gitserver:5.2.0"}),
+			},
+			result: []validate.Result{
+				{Status: validate.Failure, Message: "Sourcegraph services run different versions: '5.2.0' (worker-1/worker, gitserver-0/gitserver); '5.3.0' (frontend-1/frontend)"},
+			},
+		},
+		{
+			name: "no sourcegraph containers",
+			pods: []corev1.Pod{
+				pod("pgsql-0", corev1.Container{Name: "pgsql", Image: "sourcegraph/postgres-12-alpine:5.2.0"}),
+			},
+			result: []validate.Result{
+				{Status: validate.Warning, Message: "no frontend, worker, repo-updater or gitserver containers found to compare versions of"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			result := validateVersions(tc.pods)
+			if !reflect.DeepEqual(result, tc.result) {
+				t.Errorf("got %+v, want %+v", result, tc.result)
+			}
+		})
+	}
+}
+
+func TestValidateConfigRefs(t *testing.T) {
+	optional := true
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "frontend-1"},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{
+				{Name: "tls", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "frontend-tls"}}},
+				{Name: "extra", VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "extra-config"},
+					Optional:             &optional,
+				}}},
+			},
+			InitContainers: []corev1.Container{{
+				Name: "migrator",
+				EnvFrom: []corev1.EnvFromSource{
+					{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "pgsql-auth"}}},
+				},
+			}},
+			Containers: []corev1.Container{{
+				Name: "frontend",
+				EnvFrom: []corev1.EnvFromSource{
+					{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "frontend-env"}}},
+					{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "pgsql-auth"}}},
+				},
+				Env: []corev1.EnvVar{{
+					Name: "REDIS_PASSWORD",
+					ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{Name: "redis-auth"},
+						Key:                  "password",
+					}},
+				}},
+			}},
+		},
+	}
+
+	cases := []struct {
+		name     string
+		existing map[configRef]bool
+		result   []validate.Result
+	}{
+		{
+			name: "all exist",
+			existing: map[configRef]bool{
+				{kind: "secret", name: "frontend-tls"}:    true,
+				{kind: "secret", name: "pgsql-auth"}:      true,
+				{kind: "secret", name: "redis-auth"}:      true,
+				{kind: "configmap", name: "frontend-env"}: true,
+			},
+		},
+		{
+			name: "missing",
+			existing: map[configRef]bool{
+				{kind: "secret", name: "pgsql-auth"}: true,
+				// A ConfigMap doesn't satisfy a reference to a Secret of the same name.
+				{kind: "configmap", name: "redis-auth"}: true,
+			},
+			result: []validate.Result{
+				{Status: validate.Failure, Message: "secret 'frontend-tls' referenced by pod 'frontend-1' does not exist"},
+				{Status: validate.Failure, Message: "configmap 'frontend-env' referenced by pod 'frontend-1' does not exist"},
+				{Status: validate.Failure, Message: "secret 'redis-auth' referenced by pod 'frontend-1' does not exist"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			result := validateConfigRefs([]corev1.Pod{pod}, tc.existing)
+			if !reflect.DeepEqual(result, tc.result) {
+				t.Errorf("got %+v, want %+v", result, tc.result)
+			}
+		})
+	}
+}
+
+func TestImageTag(t *testing.T) {
+	for image, want := range map[string]string{
+		"sourcegraph/frontend:5.3.0":                   "5.3.0",
+		"sourcegraph/frontend:5.3.0@sha256:abc":        "5.3.0",
+		"localhost:5000/sourcegraph/frontend":          "latest",
+		"localhost:5000/sourcegraph/frontend:insiders": "insiders",
+		"sourcegraph/frontend@sha256:abc":              "latest",
+	} {
+		if got := imageTag(image); got != want {
+			t.Errorf("imageTag(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	var report Report
+	report.add("pods", []validate.Result{
+		{Status: validate.Warning, Message: "pod 'a' has high restart count"},
+		{Status: validate.Failure, Message: "pod 'b' is not ready"},
+	})
+	report.add("services", nil)
+	report.add("pvcs", []validate.Result{
+		{Status: validate.Warning, Message: "pvc 'c' is pending"},
+	})
+
+	var out strings.Builder
+	if err := report.write(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{
+  "checks": [
+    {
+      "name": "pods",
+      "status": "Failure",
+      "results": [
+        {
+          "status": "Warning",
+          "message": "pod 'a' has high restart count"
+        },
+        {
+          "status": "Failure",
+          "message": "pod 'b' is not ready"
+        }
+      ]
+    },
+    {
+      "name": "services",
+      "status": "Success",
+      "results": []
+    },
+    {
+      "name": "pvcs",
+      "status": "Warning",
+      "results": [
+        {
+          "status": "Warning",
+          "message": "pvc 'c' is pending"
+        }
+      ]
+    }
+  ],
+  "failures": 1,
+  "warnings": 2
+}
+`
+	if got := out.String(); got != want {
+		t.Errorf("wrong report\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRunValidations(t *testing.T) {
+	ok := func(ctx context.Context, config *Config) ([]validate.Result, error) {
+		return []validate.Result{{Status: validate.Warning, Message: "pvc 'c' is pending"}}, nil
+	}
+	broken := func(ctx context.Context, config *Config) ([]validate.Result, error) {
+		return nil, errors.New("connection refused")
+	}
+
+	report := runValidations(context.Background(), &Config{}, []validation{
+		{Name: "pods", Validate: broken, ErrMsg: "validating pods failed"},
+		{Name: "pvcs", Validate: ok, ErrMsg: "validating pvcs failed"},
+	})
+
+	want := Report{
+		Checks: []CheckReport{
+			{Name: "pods", Status: validate.Failure, Results: []ResultReport{
+				{Status: validate.Failure, Message: "validating pods failed: connection refused"},
+			}},
+			{Name: "pvcs", Status: validate.Warning, Results: []ResultReport{
+				{Status: validate.Warning, Message: "pvc 'c' is pending"},
+			}},
+		},
+		Failures: 1,
+		Warnings: 1,
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("got %+v, want %+v", report, want)
+	}
+}
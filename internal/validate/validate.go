@@ -5,6 +5,7 @@ var (
 	FailureEmoji          = "🛑"
 	FlashingLightEmoji    = "🚨"
 	HourglassEmoji        = "⌛"
+	InfoEmoji             = "ℹ️ "
 	SuccessEmoji          = "✅"
 	WarningSign           = "⚠️ " // why does this need an extra space to align?!?!
 )
@@ -15,6 +16,9 @@ const (
 	Failure Status = "Failure"
 	Warning Status = "Warning"
 	Success Status = "Success"
+	// Info results don't indicate a problem, but something the user should
+	// know, such as a check that couldn't be completed.
+	Info Status = "Info"
 )
 
 type Result struct {